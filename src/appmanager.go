@@ -21,18 +21,20 @@ type appManager struct {
 }
 
 func newAppManager(DB *sqlx.DB) *appManager {
-	a := make([]meta.App, 4)
+	a := make([]meta.App, 5)
 	a[0] = apps.NewEntriesApp(DB)
 	a[1] = apps.NewLedgersApp(DB)
 	a[2] = apps.NewAccountsApp(DB)
 	a[3] = apps.NewJournalsApp(DB)
+	a[4] = apps.NewInvoicesApp(DB)
 
 	// Map the name(=type) of an app to its index in `apps`
-	appIds := make(map[meta.AppType]int, 4)
+	appIds := make(map[meta.AppType]int, 5)
 	appIds[meta.ENTRIESAPP] = 0
 	appIds[meta.LEDGERSAPP] = 1
 	appIds[meta.ACCOUNTSAPP] = 2
 	appIds[meta.JOURNALSAPP] = 3
+	appIds[meta.INVOICESAPP] = 4
 
 	return &appManager{
 		apps:   a,
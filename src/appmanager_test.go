@@ -110,7 +110,7 @@ func insertItemForApp(t *testing.T, DB *sqlx.DB, appType meta.AppType) int {
 		ledgerId, err := (&database.Ledger{Name: "L", Type: database.EXPENSELEDGER}).Insert(DB)
 		require.NoError(t, err)
 		require.NoError(t, database.UpdateCache(DB))
-		entry := database.Entry{Journal: journalId}
+		entry := database.Entry{Journal: journalId, Suspense: true}
 		entryId, err := entry.Insert(DB, []database.EntryRow{
 			{Ledger: ledgerId, Value: 100, Description: "row"},
 		})
@@ -76,6 +76,26 @@ func TestMakeLoadListCmd(t *testing.T) {
 			},
 			newApp: apps.NewEntriesApp,
 		},
+		{
+			appType:   meta.INVOICESAPP,
+			modelType: meta.INVOICEMODEL,
+			insertItems: func(t *testing.T, DB *sqlx.DB) int {
+				t.Helper()
+				accountId, err := (&database.Account{Name: "A1", Type: database.DEBTOR}).Insert(DB)
+				require.NoError(t, err)
+				journalId, err := (&database.Journal{Name: "J1", Type: database.INCOMEJOURNAL}).Insert(DB)
+				require.NoError(t, err)
+				ledgerId, err := (&database.Ledger{Name: "L1", Type: database.INCOMELEDGER}).Insert(DB)
+				require.NoError(t, err)
+				invoice := database.Invoice{Account: accountId, Journal: journalId, Ledger: ledgerId, Lines: database.InvoiceLines{}}
+				_, err = invoice.Insert(DB)
+				require.NoError(t, err)
+				_, err = invoice.Insert(DB)
+				require.NoError(t, err)
+				return 2
+			},
+			newApp: apps.NewInvoicesApp,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -0,0 +1,152 @@
+package apps
+
+import (
+	"fmt"
+	"terminaccounting/database"
+	"terminaccounting/meta"
+	"terminaccounting/view"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmoiron/sqlx"
+)
+
+type invoicesApp struct {
+	DB *sqlx.DB
+
+	viewWidth, viewHeight int
+
+	currentView view.View
+}
+
+func NewInvoicesApp(DB *sqlx.DB) meta.App {
+	model := &invoicesApp{DB: DB}
+
+	model.currentView = view.NewListView(model)
+
+	return model
+}
+
+func (app *invoicesApp) Init() tea.Cmd {
+	return app.currentView.Init()
+}
+
+func (app *invoicesApp) Update(message tea.Msg) (meta.App, tea.Cmd) {
+	switch message := message.(type) {
+	case tea.WindowSizeMsg:
+		app.viewWidth = message.Width
+		app.viewHeight = message.Height
+
+		var cmd tea.Cmd
+		app.currentView, cmd = app.currentView.Update(message)
+
+		return app, cmd
+
+	case meta.SwitchAppViewMsg:
+		if message.App != nil && *message.App != meta.INVOICESAPP {
+			panic("wrong app type, something went wrong")
+		}
+
+		switch message.ViewType {
+		case meta.LISTVIEWTYPE:
+			app.currentView = view.NewListView(app)
+
+		case meta.DETAILVIEWTYPE:
+			invoice := message.Data.(database.Invoice)
+
+			app.currentView = view.NewInvoicesDetailView(app.DB, invoice.Id)
+
+		case meta.CREATEVIEWTYPE:
+			app.currentView = view.NewInvoicesCreateView(app.DB)
+
+		case meta.UPDATEVIEWTYPE:
+			invoiceId := message.Data.(int)
+
+			app.currentView = view.NewInvoicesUpdateView(app.DB, invoiceId)
+
+		case meta.DELETEVIEWTYPE:
+			invoiceId := message.Data.(int)
+
+			app.currentView = view.NewInvoicesDeleteView(app.DB, invoiceId)
+
+		default:
+			panic(fmt.Sprintf("unexpected meta.ViewType: %#v", message.ViewType))
+		}
+
+		return app, app.currentView.Init()
+	}
+
+	var cmd tea.Cmd
+	app.currentView, cmd = app.currentView.Update(message)
+
+	return app, cmd
+}
+
+func (app *invoicesApp) View() string {
+	style := meta.BodyStyle(app.viewWidth, app.viewHeight)
+
+	return style.Render(app.currentView.View())
+}
+
+func (app *invoicesApp) Name() string {
+	return "Invoices"
+}
+
+func (app *invoicesApp) Type() meta.AppType {
+	return meta.INVOICESAPP
+}
+
+func (app *invoicesApp) CurrentViewType() meta.ViewType {
+	return app.currentView.Type()
+}
+
+func (app *invoicesApp) Colour() lipgloss.Color {
+	return meta.INVOICESCOLOUR
+}
+
+func (app *invoicesApp) CurrentMotionSet() meta.Trie[tea.Msg] {
+	return app.currentView.MotionSet()
+}
+
+func (app *invoicesApp) CurrentCommandSet() meta.Trie[tea.Msg] {
+	return app.currentView.CommandSet()
+}
+
+func (app *invoicesApp) CurrentViewAllowsInsertMode() bool {
+	return app.currentView.AllowsInsertMode()
+}
+
+func (app *invoicesApp) CurrentViewAllowsSearchMode() bool {
+	return app.currentView.AllowsSearchMode()
+}
+
+func (app *invoicesApp) AcceptedModels() map[meta.ModelType]struct{} {
+	return app.currentView.AcceptedModels()
+}
+
+func (app *invoicesApp) MakeLoadListCmd() tea.Cmd {
+	return func() tea.Msg {
+		rows, err := database.SelectInvoices(app.DB)
+		if err != nil {
+			return meta.MessageCmd(fmt.Errorf("FAILED TO LOAD INVOICES: %v", err))
+		}
+
+		items := make([]list.Item, len(rows))
+		for i, row := range rows {
+			items[i] = row
+		}
+
+		return meta.DataLoadedMsg{
+			TargetApp: meta.INVOICESAPP,
+			Model:     meta.INVOICEMODEL,
+			Data:      items,
+		}
+	}
+}
+
+func (app *invoicesApp) ReloadView() tea.Cmd {
+	app.currentView = app.currentView.Reload()
+
+	return app.currentView.Init()
+}
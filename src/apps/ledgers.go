@@ -23,7 +23,7 @@ type ledgersApp struct {
 func NewLedgersApp(DB *sqlx.DB) meta.App {
 	model := &ledgersApp{DB: DB}
 
-	model.currentView = view.NewListView(model)
+	model.currentView = view.NewLedgersListView(model)
 
 	return model
 }
@@ -50,7 +50,7 @@ func (app *ledgersApp) Update(message tea.Msg) (meta.App, tea.Cmd) {
 
 		switch message.ViewType {
 		case meta.LISTVIEWTYPE:
-			app.currentView = view.NewListView(app)
+			app.currentView = view.NewLedgersListView(app)
 
 		case meta.DETAILVIEWTYPE:
 			ledger := message.Data.(database.Ledger)
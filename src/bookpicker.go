@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"terminaccounting/database"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Small standalone program run before the main one, to choose which book to open
+// when none was given on the command line
+type bookPicker struct {
+	directory string
+	books     []string
+
+	cursor int
+
+	creating  bool
+	nameInput textinput.Model
+
+	err error
+
+	// Path of the chosen book, empty if the user quit without choosing
+	chosen string
+}
+
+func newBookPicker(directory string, books []string) *bookPicker {
+	nameInput := textinput.New()
+	nameInput.Cursor.SetMode(cursor.CursorStatic)
+	nameInput.Placeholder = "book name"
+
+	return &bookPicker{
+		directory: directory,
+		books:     books,
+
+		nameInput: nameInput,
+	}
+}
+
+func (bp *bookPicker) Init() tea.Cmd {
+	return nil
+}
+
+func (bp *bookPicker) Update(message tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := message.(tea.KeyMsg)
+	if !ok {
+		return bp, nil
+	}
+
+	if keyMsg.Type == tea.KeyCtrlC {
+		return bp, tea.Quit
+	}
+
+	if bp.creating {
+		return bp.updateCreating(keyMsg)
+	}
+
+	bp.err = nil
+
+	switch keyMsg.String() {
+	case "q", "esc":
+		return bp, tea.Quit
+
+	case "j", "down":
+		// +1 for the "new book" option
+		bp.cursor = min(bp.cursor+1, len(bp.books))
+
+	case "k", "up":
+		bp.cursor = max(bp.cursor-1, 0)
+
+	case "enter":
+		if bp.cursor == len(bp.books) {
+			bp.creating = true
+			bp.nameInput.Focus()
+
+			return bp, nil
+		}
+
+		bp.chosen = bp.books[bp.cursor]
+		return bp, tea.Quit
+	}
+
+	return bp, nil
+}
+
+func (bp *bookPicker) updateCreating(message tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch message.Type {
+	case tea.KeyEsc:
+		bp.creating = false
+		bp.err = nil
+		bp.nameInput.Blur()
+		bp.nameInput.Reset()
+
+		return bp, nil
+
+	case tea.KeyEnter:
+		path, err := database.NewBookPath(bp.directory, bp.nameInput.Value())
+		if err != nil {
+			bp.err = err
+			return bp, nil
+		}
+
+		// The schemas get set up when connecting
+		bp.chosen = path
+		return bp, tea.Quit
+	}
+
+	var cmd tea.Cmd
+	bp.nameInput, cmd = bp.nameInput.Update(message)
+
+	return bp, cmd
+}
+
+func (bp *bookPicker) View() string {
+	var result strings.Builder
+
+	titleStyle := lipgloss.NewStyle().Bold(true).MarginBottom(1)
+	activeStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#00EAEA"))
+
+	result.WriteString(titleStyle.Render(fmt.Sprintf("Choose a book (%s)", bp.directory)))
+	result.WriteString("\n")
+
+	options := make([]string, 0, len(bp.books)+1)
+	for _, book := range bp.books {
+		options = append(options, database.BookName(book))
+	}
+	options = append(options, "+ New book")
+
+	for i, option := range options {
+		if i == bp.cursor {
+			result.WriteString(activeStyle.Render("> " + option))
+		} else {
+			result.WriteString("  " + option)
+		}
+		result.WriteString("\n")
+	}
+
+	if bp.creating {
+		result.WriteString("\nName: ")
+		result.WriteString(bp.nameInput.View())
+		result.WriteString("\n")
+	}
+
+	if bp.err != nil {
+		result.WriteString("\n")
+		result.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Render(bp.err.Error()))
+		result.WriteString("\n")
+	}
+
+	return result.String()
+}
+
+// Runs the book picker, returning the path of the chosen book or an empty string
+// if the user quit
+func pickBook(directory string) (string, error) {
+	books, err := database.AvailableBooks(directory)
+	if err != nil {
+		return "", err
+	}
+
+	finalModel, err := tea.NewProgram(newBookPicker(directory, books)).Run()
+	if err != nil {
+		return "", err
+	}
+
+	return finalModel.(*bookPicker).chosen, nil
+}
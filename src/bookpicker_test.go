@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	tat "terminaccounting/tat"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBookPicker_ChooseExisting(t *testing.T) {
+	directory := t.TempDir()
+	books := []string{filepath.Join(directory, "household.db"), filepath.Join(directory, "side business.db")}
+
+	tw := tat.NewTestWrapperGeneric(newBookPicker(directory, books))
+
+	tw.AssertViewContains(t, "household")
+	tw.AssertViewContains(t, "side business")
+	tw.AssertViewContains(t, "+ New book")
+
+	tw.SendText("jjk").Send(tea.KeyMsg{Type: tea.KeyEnter})
+
+	tw.Execute(t, func(bp *bookPicker) {
+		assert.Equal(t, books[1], bp.chosen)
+	})
+}
+
+func TestBookPicker_CreateNew(t *testing.T) {
+	directory := t.TempDir()
+	existing := filepath.Join(directory, "existing.db")
+	assert.NoError(t, os.WriteFile(existing, nil, 0o644))
+
+	tw := tat.NewTestWrapperGeneric(newBookPicker(directory, []string{existing}))
+
+	t.Run("existing name", func(t *testing.T) {
+		tw.SendText("j").Send(tea.KeyMsg{Type: tea.KeyEnter}).SendText("existing").Send(tea.KeyMsg{Type: tea.KeyEnter})
+
+		tw.AssertViewContains(t, `book "existing" already exists`)
+		tw.Execute(t, func(bp *bookPicker) {
+			assert.Equal(t, "", bp.chosen)
+		})
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		tw.Send(tea.KeyMsg{Type: tea.KeyEsc})
+
+		tw.Execute(t, func(bp *bookPicker) {
+			assert.False(t, bp.creating)
+			assert.Equal(t, "", bp.nameInput.Value())
+		})
+	})
+
+	t.Run("new name", func(t *testing.T) {
+		tw.Send(tea.KeyMsg{Type: tea.KeyEnter}).SendText("2025").Send(tea.KeyMsg{Type: tea.KeyEnter})
+
+		tw.Execute(t, func(bp *bookPicker) {
+			assert.Equal(t, filepath.Join(directory, "2025.db"), bp.chosen)
+		})
+	})
+}
@@ -93,6 +93,17 @@ func (m *Model) SetItems(items []Item) {
 	m.updateShownItems()
 }
 
+// Like SetItems, but keeps the cursor where it was, for when items changed in place
+func (m *Model) UpdateItems(items []Item) {
+	active := m.activeItem
+
+	m.SetItems(items)
+
+	m.activeItem = max(min(active, len(m.shownItems)-1), 0)
+	m.updateViewportContent()
+	m.scrollViewport()
+}
+
 func (m *Model) Items() []Item {
 	return m.items
 }
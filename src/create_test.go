@@ -327,7 +327,11 @@ func TestCreate_Entries(t *testing.T) {
 		tw.Send(tea.KeyMsg{Type: tea.KeyTab}).
 			SendText("100")
 
-		tw.Send(tea.KeyMsg{Type: tea.KeyTab}, tea.KeyMsg{Type: tea.KeyTab}, tea.KeyMsg{Type: tea.KeyTab}, tea.KeyMsg{Type: tea.KeyTab}, tea.KeyMsg{Type: tea.KeyTab}).
+		// Past credit and the tax code
+		tw.Send(tea.KeyMsg{Type: tea.KeyTab}, tea.KeyMsg{Type: tea.KeyTab}, tea.KeyMsg{Type: tea.KeyTab}).
+			SendText("project:x italy")
+
+		tw.Send(tea.KeyMsg{Type: tea.KeyTab}, tea.KeyMsg{Type: tea.KeyTab}, tea.KeyMsg{Type: tea.KeyTab}, tea.KeyMsg{Type: tea.KeyTab}).
 			SendText("Row 2").
 			AssertViewContains(t, "Row 2")
 
@@ -373,6 +377,7 @@ func TestCreate_Entries(t *testing.T) {
 		assert.Equal(t, database.CurrencyValue(10000), rows[0].Value)
 		assert.Equal(t, l1, rows[0].Ledger)
 		assert.Equal(t, "Row 1", rows[0].Description)
+		assert.Equal(t, database.Tags("italy project:x"), rows[0].Tags)
 
 		assert.Equal(t, database.CurrencyValue(-10000), rows[1].Value)
 		assert.Equal(t, l1, rows[1].Ledger)
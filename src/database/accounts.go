@@ -61,6 +61,8 @@ type Account struct {
 	Type        AccountType `db:"type"`
 	BankNumbers meta.Notes  `db:"banknumbers"`
 	Notes       meta.Notes  `db:"notes"`
+	// Overrides the currency of the ledger for rows with this account
+	Currency string `db:"currency"`
 }
 
 func (a Account) FilterValue() string {
@@ -142,30 +144,9 @@ func MakeLoadAccountsRowsCmd(DB *sqlx.DB, modelId int) tea.Cmd {
 	}
 }
 
-func setupSchemaAccounts(DB *sqlx.DB) (bool, error) {
-	isSetUp, err := DatabaseTableIsSetUp(DB, "accounts")
-	if err != nil {
-		return false, err
-	}
-	if isSetUp {
-		return false, nil
-	}
-
-	schema := `CREATE TABLE IF NOT EXISTS accounts(
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL,
-		type INTEGER NOT NULL,
-		banknumbers TEXT,
-		notes TEXT
-	) STRICT;`
-
-	_, err = DB.Exec(schema)
-	return true, err
-}
-
 func (a Account) Insert(DB *sqlx.DB) (int, error) {
 	result, err := DB.NamedExec(
-		`INSERT INTO accounts (name, type, banknumbers, notes) VALUES (:name, :type, :banknumbers, :notes)`,
+		`INSERT INTO accounts (name, type, banknumbers, notes, currency) VALUES (:name, :type, :banknumbers, :notes, :currency)`,
 		a,
 	)
 	if err != nil {
@@ -190,7 +171,8 @@ func (a Account) Update(DB *sqlx.DB) error {
 	name = :name,
 	type = :type,
 	banknumbers = :banknumbers,
-	notes = :notes
+	notes = :notes,
+	currency = :currency
 	WHERE id = :id;`
 
 	_, err := DB.NamedExec(query, a)
@@ -238,8 +220,32 @@ func SelectAccount(DB *sqlx.DB, id int) (Account, error) {
 	return result, err
 }
 
+// Moves the account to the trash, from where it can be restored
 func DeleteAccount(DB *sqlx.DB, accountId int) error {
-	_, err := DB.Exec(`DELETE FROM accounts WHERE id = $1;`, accountId)
+	tx := DB.MustBegin()
+	defer tx.Rollback()
+
+	var account Account
+	err := tx.Get(&account, `SELECT * FROM accounts WHERE id = $1;`, accountId)
+	if err != nil {
+		return err
+	}
+
+	var budgets int
+	err = tx.Get(&budgets, `SELECT COUNT(*) FROM budgets WHERE account = $1;`, accountId)
+	if err != nil {
+		return err
+	}
+	if budgets != 0 {
+		return fmt.Errorf("Account has budgets, can't delete")
+	}
+
+	err = moveToTrash(tx, meta.ACCOUNTMODEL, account.Id, account.String(), account)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM accounts WHERE id = $1;`, accountId)
 	if err != nil {
 		if err.Error() == "FOREIGN KEY constraint failed" {
 			return fmt.Errorf("Account has entries, can't delete")
@@ -248,6 +254,11 @@ func DeleteAccount(DB *sqlx.DB, accountId int) error {
 		return err
 	}
 
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
 	return UpdateAccountsCache(DB)
 }
 
@@ -0,0 +1,199 @@
+package database
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"terminaccounting/meta"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmoiron/sqlx"
+)
+
+// The age ranges of outstanding amounts, in days since the row's date or due date.
+// Amounts not due yet count as 0-30.
+var AgingBuckets = [4]string{"0-30", "31-60", "61-90", "90+"}
+
+func agingBucket(days int) int {
+	switch {
+	case days <= 30:
+		return 0
+	case days <= 60:
+		return 1
+	case days <= 90:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// The unreconciled balance of one account, split by age
+type AgingLine struct {
+	Account Account
+	Buckets [4]CurrencyValue
+}
+
+func (al AgingLine) Total() CurrencyValue {
+	var result CurrencyValue
+	for _, amount := range al.Buckets {
+		result = result.Add(amount)
+	}
+
+	return result
+}
+
+func (al AgingLine) FilterValue() string {
+	return al.Account.Name + string(al.Account.Type)
+}
+
+func (al AgingLine) Render(isActive bool) string {
+	style := lipgloss.NewStyle()
+	if isActive {
+		style = style.Foreground(meta.ACCOUNTSCOLOUR)
+	}
+
+	return style.Render(renderAgingColumns(fmt.Sprintf("%s (%s)", al.Account.Name, strings.ToLower(string(al.Account.Type))), al.Buckets, al.Total()))
+}
+
+func renderAgingColumns(name string, buckets [4]CurrencyValue, total CurrencyValue) string {
+	values := make([]any, 0, 6)
+	values = append(values, name)
+	for _, amount := range buckets {
+		values = append(values, amount.String())
+	}
+	values = append(values, total.String())
+
+	return fmt.Sprintf("%-28.28s %12s %12s %12s %12s %12s", values...)
+}
+
+func AgingHeader() string {
+	values := []any{"Account"}
+	for _, bucket := range AgingBuckets {
+		values = append(values, bucket)
+	}
+	values = append(values, "Total")
+
+	return fmt.Sprintf("%-28.28s %12s %12s %12s %12s %12s", values...)
+}
+
+// What's outstanding on the accounts ledger at a date, and how it adds up to the ledger's balance:
+// the lines plus the reconciled rows and the rows without an account make up the balance.
+type Aging struct {
+	Date      Date
+	ByDueDate bool
+
+	Lines  []AgingLine
+	Totals [4]CurrencyValue
+
+	LedgerBalance  CurrencyValue
+	Reconciled     CurrencyValue
+	WithoutAccount CurrencyValue
+}
+
+func (a Aging) Total() CurrencyValue {
+	return AgingLine{Buckets: a.Totals}.Total()
+}
+
+// Buckets the unreconciled rows on the accounts ledger up to the date per account.
+// By due date, rows booking an invoice age from its due date, other rows still from their own date.
+func AgingReport(DB *sqlx.DB, date Date, byDueDate bool) (Aging, error) {
+	result := Aging{Date: date, ByDueDate: byDueDate}
+
+	accountsLedger := GetAccountsLedger()
+	if accountsLedger == nil {
+		return Aging{}, errors.New("the aging report shows what's outstanding on the accounts ledger, but none is set, mark one in the ledgers tab")
+	}
+
+	rows := []EntryRow{}
+	err := DB.Select(&rows, `SELECT * FROM entryrows WHERE ledger = $1 AND date <= $2;`, accountsLedger.Id, date)
+	if err != nil {
+		return Aging{}, fmt.Errorf("FAILED TO SELECT ROWS OF ACCOUNTS LEDGER: %v", err)
+	}
+
+	dueDates := make(map[[2]int]Date)
+	if byDueDate {
+		invoices := []Invoice{}
+		err = DB.Select(&invoices, `SELECT * FROM invoices WHERE entry IS NOT NULL;`)
+		if err != nil {
+			return Aging{}, fmt.Errorf("FAILED TO SELECT POSTED INVOICES: %v", err)
+		}
+
+		for _, invoice := range invoices {
+			dueDates[[2]int{*invoice.Entry, invoice.Account}] = invoice.DueDate
+		}
+	}
+
+	byAccount := make(map[int]*AgingLine)
+	for _, row := range rows {
+		result.LedgerBalance = result.LedgerBalance.Add(row.Value)
+
+		switch {
+		case row.Reconciled:
+			result.Reconciled = result.Reconciled.Add(row.Value)
+			continue
+
+		case row.Account == nil:
+			result.WithoutAccount = result.WithoutAccount.Add(row.Value)
+			continue
+		}
+
+		line, ok := byAccount[*row.Account]
+		if !ok {
+			account, err := SelectAccount(DB, *row.Account)
+			if err != nil {
+				return Aging{}, fmt.Errorf("FAILED TO GET ACCOUNT %d: %v", *row.Account, err)
+			}
+
+			line = &AgingLine{Account: account}
+			byAccount[*row.Account] = line
+		}
+
+		since := row.Date
+		if dueDate, ok := dueDates[[2]int{row.Entry, *row.Account}]; ok {
+			since = dueDate
+		}
+
+		days := int(time.Time(date).Sub(time.Time(since)).Hours() / 24)
+		bucket := agingBucket(days)
+
+		line.Buckets[bucket] = line.Buckets[bucket].Add(row.Value)
+		result.Totals[bucket] = result.Totals[bucket].Add(row.Value)
+	}
+
+	for _, line := range byAccount {
+		if line.Buckets != [4]CurrencyValue{} {
+			result.Lines = append(result.Lines, *line)
+		}
+	}
+
+	// Debtors before creditors
+	slices.SortFunc(result.Lines, func(a, b AgingLine) int {
+		return cmp.Or(
+			cmp.Compare(a.Account.Type.CompareId(), b.Account.Type.CompareId()),
+			strings.Compare(a.Account.Name, b.Account.Name),
+			cmp.Compare(a.Account.Id, b.Account.Id),
+		)
+	})
+
+	return result, nil
+}
+
+// The totals and how they reconcile to the balance of the accounts ledger
+func (a Aging) RenderFooter() []string {
+	result := []string{
+		renderAgingColumns("Total outstanding", a.Totals, a.Total()),
+		"",
+		fmt.Sprintf("%-28s %12s", "Reconciled rows", a.Reconciled),
+	}
+
+	if a.WithoutAccount != 0 {
+		result = append(result, fmt.Sprintf("%-28s %12s", "Rows without an account", a.WithoutAccount))
+	}
+
+	result = append(result, fmt.Sprintf("%-28s %12s", "Accounts ledger balance", a.LedgerBalance))
+
+	return result
+}
@@ -0,0 +1,88 @@
+package database_test
+
+import (
+	"terminaccounting/database"
+	"terminaccounting/tat"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgingReport(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	_, err := database.AgingReport(DB, mustDate(t, "24-04-30"), false)
+	assert.EqualError(t, err, "the aging report shows what's outstanding on the accounts ledger, but none is set, mark one in the ledgers tab")
+
+	accounts, err := (&database.Ledger{Name: "Accounts", Type: database.ASSETLEDGER, IsAccounts: true}).Insert(DB)
+	require.NoError(t, err)
+	sales, err := (&database.Ledger{Name: "Sales", Type: database.INCOMELEDGER}).Insert(DB)
+	require.NoError(t, err)
+
+	customer, err := database.Account{Name: "Customer", Type: database.DEBTOR}.Insert(DB)
+	require.NoError(t, err)
+	supplier, err := database.Account{Name: "Supplier", Type: database.CREDITOR}.Insert(DB)
+	require.NoError(t, err)
+
+	journal := insertTestJournal(t, DB)
+	book := func(date string, account int, value database.CurrencyValue, reconciled bool) {
+		_, err := database.Entry{Journal: journal.Id}.Insert(DB, []database.EntryRow{
+			{Date: mustDate(t, date), Ledger: accounts, Account: &account, Value: value, Reconciled: reconciled},
+			{Date: mustDate(t, date), Ledger: sales, Value: -value},
+		})
+		require.NoError(t, err)
+	}
+
+	book("24-01-01", customer, 10000, false)
+	book("24-01-10", customer, 7000, true)
+	book("24-01-20", customer, -7000, true)
+	book("24-03-15", customer, 5000, false)
+	book("24-04-20", customer, -3000, false)
+	book("24-02-01", supplier, -20000, false)
+	// After the date of the report
+	book("24-05-10", customer, 99900, false)
+
+	aging, err := database.AgingReport(DB, mustDate(t, "24-04-30"), false)
+	require.NoError(t, err)
+	require.Len(t, aging.Lines, 2)
+
+	assert.Equal(t, "Customer", aging.Lines[0].Account.Name)
+	assert.Equal(t, [4]database.CurrencyValue{-3000, 5000, 0, 10000}, aging.Lines[0].Buckets)
+	assert.Equal(t, "Supplier", aging.Lines[1].Account.Name)
+	assert.Equal(t, [4]database.CurrencyValue{0, 0, -20000, 0}, aging.Lines[1].Buckets)
+
+	// The outstanding amounts and the reconciled rows add up to the ledger's balance
+	assert.Equal(t, database.CurrencyValue(-8000), aging.Total())
+	assert.Equal(t, database.CurrencyValue(0), aging.Reconciled)
+	assert.Equal(t, aging.LedgerBalance, aging.Total()+aging.Reconciled+aging.WithoutAccount)
+
+	footer := aging.RenderFooter()
+	assert.Equal(t, "Total outstanding                  -30.00        50.00      -200.00       100.00       -80.00", footer[0])
+	assert.Equal(t, "Accounts ledger balance            -80.00", footer[len(footer)-1])
+
+	// Invoices age from their due date when asked to
+	income := database.Journal{Name: "Invoices", Type: database.INCOMEJOURNAL}
+	income.Id, err = income.Insert(DB)
+	require.NoError(t, err)
+
+	invoiceId, err := database.Invoice{
+		Account: customer,
+		Journal: income.Id,
+		Ledger:  sales,
+		Date:    mustDate(t, "24-03-01"),
+		DueDate: mustDate(t, "24-04-15"),
+		Lines:   database.InvoiceLines{{Description: "Work", Quantity: 1, UnitPrice: 1000}},
+	}.Insert(DB)
+	require.NoError(t, err)
+	_, err = database.PostInvoice(DB, invoiceId)
+	require.NoError(t, err)
+
+	aging, err = database.AgingReport(DB, mustDate(t, "24-04-30"), false)
+	require.NoError(t, err)
+	assert.Equal(t, [4]database.CurrencyValue{-3000, 6000, 0, 10000}, aging.Lines[0].Buckets)
+
+	aging, err = database.AgingReport(DB, mustDate(t, "24-04-30"), true)
+	require.NoError(t, err)
+	assert.Equal(t, [4]database.CurrencyValue{-2000, 5000, 0, 10000}, aging.Lines[0].Buckets)
+}
@@ -0,0 +1,208 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"terminaccounting/meta"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmoiron/sqlx"
+)
+
+// A file, like a receipt or an invoice, attached to an entry or one of its rows.
+// The contents are stored in the book itself, content-addressed by their SHA-256 hash.
+type Attachment struct {
+	Id    int `db:"id"`
+	Entry int `db:"entry"`
+	// nil when attached to the entry as a whole
+	Row     *int   `db:"entryrow"`
+	Name    string `db:"name"`
+	Hash    string `db:"hash"`
+	Size    int64  `db:"size"`
+	AddedAt string `db:"added_at"`
+}
+
+func (a Attachment) FilterValue() string {
+	return a.Name
+}
+
+func (a Attachment) Render(isActive bool) string {
+	attachedToStyle := lipgloss.NewStyle().Width(len("Row 99999") + 2)
+	if !isActive {
+		attachedToStyle = attachedToStyle.Foreground(meta.ENTRIESCOLOUR)
+	}
+
+	attachedTo := "Entry"
+	if a.Row != nil {
+		attachedTo = fmt.Sprintf("Row %d", *a.Row)
+	}
+
+	return fmt.Sprintf("%s  %s%s (%s)", a.AddedAt, attachedToStyle.Render(attachedTo), a.Name, formatSize(a.Size))
+}
+
+func formatSize(size int64) string {
+	switch {
+	case size < 1024:
+		return fmt.Sprintf("%d B", size)
+
+	case size < 1024*1024:
+		return fmt.Sprintf("%.1f KiB", float64(size)/1024)
+
+	default:
+		return fmt.Sprintf("%.1f MiB", float64(size)/1024/1024)
+	}
+}
+
+func hashContents(data []byte) string {
+	hash := sha256.Sum256(data)
+
+	return hex.EncodeToString(hash[:])
+}
+
+// Stores the file at path in the book and attaches it to the entry, or to one of its rows if row isn't nil.
+// Files with the same contents are only stored once.
+func AttachFile(DB *sqlx.DB, entry int, row *int, path string) (Attachment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("couldn't read %s: %v", path, err)
+	}
+
+	tx := DB.MustBegin()
+	defer tx.Rollback()
+
+	var count int
+	err = tx.Get(&count, `SELECT COUNT(*) FROM entries WHERE id = $1;`, entry)
+	if err != nil {
+		return Attachment{}, err
+	}
+	if count == 0 {
+		return Attachment{}, fmt.Errorf("FAILED TO ATTACH FILE: ENTRY %d DOESN'T EXIST", entry)
+	}
+
+	if row != nil {
+		err = tx.Get(&count, `SELECT COUNT(*) FROM entryrows WHERE id = $1 AND entry = $2;`, *row, entry)
+		if err != nil {
+			return Attachment{}, err
+		}
+		if count == 0 {
+			return Attachment{}, fmt.Errorf("FAILED TO ATTACH FILE: ROW %d ISN'T PART OF ENTRY %d", *row, entry)
+		}
+	}
+
+	result := Attachment{
+		Entry:   entry,
+		Row:     row,
+		Name:    filepath.Base(path),
+		Hash:    hashContents(data),
+		Size:    int64(len(data)),
+		AddedAt: time.Now().Format(TIMESTAMP_FORMAT),
+	}
+
+	_, err = tx.Exec(`INSERT INTO attachment_contents (hash, data) VALUES ($1, $2) ON CONFLICT (hash) DO NOTHING;`,
+		result.Hash, data)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("FAILED TO STORE ATTACHMENT CONTENTS: %v", err)
+	}
+
+	res, err := tx.NamedExec(`INSERT INTO attachments (entry, entryrow, name, hash, size, added_at)
+		VALUES (:entry, :entryrow, :name, :hash, :size, :added_at);`, result)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("FAILED TO ATTACH FILE: %v", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Attachment{}, err
+	}
+	result.Id = int(id)
+
+	return result, tx.Commit()
+}
+
+// The attachments of an entry, those of the entry as a whole first, then per row
+func SelectAttachments(DB *sqlx.DB, entry int) ([]Attachment, error) {
+	result := []Attachment{}
+
+	err := DB.Select(&result, `SELECT * FROM attachments WHERE entry = $1
+		ORDER BY entryrow IS NOT NULL, entryrow, id;`, entry)
+
+	return result, err
+}
+
+// Returns the contents of the attachment, after checking they still match its hash
+func ReadAttachment(DB *sqlx.DB, attachment Attachment) ([]byte, error) {
+	var data []byte
+	err := DB.Get(&data, `SELECT data FROM attachment_contents WHERE hash = $1;`, attachment.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("FAILED TO READ ATTACHMENT %d: %v", attachment.Id, err)
+	}
+
+	if hashContents(data) != attachment.Hash {
+		return nil, fmt.Errorf("attachment %s is corrupted, its contents don't match its hash", attachment.Name)
+	}
+
+	return data, nil
+}
+
+// Writes the attachment to a file in the temporary directory, for handing it to other programs.
+// The file keeps the attachment's name, so those programs can tell its type.
+func ExtractAttachment(DB *sqlx.DB, attachment Attachment) (string, error) {
+	data, err := ReadAttachment(DB, attachment)
+	if err != nil {
+		return "", err
+	}
+
+	directory := filepath.Join(os.TempDir(), "terminaccounting", attachment.Hash)
+	err = os.MkdirAll(directory, 0o700)
+	if err != nil {
+		return "", fmt.Errorf("couldn't extract %s: %v", attachment.Name, err)
+	}
+
+	path := filepath.Join(directory, attachment.Name)
+	err = os.WriteFile(path, data, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("couldn't extract %s: %v", attachment.Name, err)
+	}
+
+	return path, nil
+}
+
+// Removes the attachment, and its contents if nothing else has them attached
+func DetachFile(DB *sqlx.DB, id int) error {
+	tx := DB.MustBegin()
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM attachments WHERE id = $1;`, id)
+	if err != nil {
+		return fmt.Errorf("FAILED TO DETACH ATTACHMENT %d: %v", id, err)
+	}
+
+	removed, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return errors.New("that attachment was already removed")
+	}
+
+	err = deleteUnusedAttachmentContents(tx)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func deleteUnusedAttachmentContents(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`DELETE FROM attachment_contents WHERE hash NOT IN (SELECT hash FROM attachments);`)
+	if err != nil {
+		return fmt.Errorf("FAILED TO DELETE UNUSED ATTACHMENT CONTENTS: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package database_test
+
+import (
+	"os"
+	"path/filepath"
+	"terminaccounting/database"
+	"terminaccounting/tat"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFile(t *testing.T, name, contents string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+
+	return path
+}
+
+func TestAttachFile(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	journal := insertTestJournal(t, DB)
+	ledger := insertTestLedger(t, DB)
+	entry := insertTestEntry(t, DB, journal.Id, ledger.Id)
+
+	rows, err := database.SelectRowsByEntry(DB, entry.Id)
+	require.NoError(t, err)
+
+	receipt := writeTestFile(t, "receipt.pdf", "%PDF receipt")
+	copied := writeTestFile(t, "receipt copy.pdf", "%PDF receipt")
+
+	entryAttachment, err := database.AttachFile(DB, entry.Id, nil, receipt)
+	require.NoError(t, err)
+	rowAttachment, err := database.AttachFile(DB, entry.Id, &rows[0].Id, copied)
+	require.NoError(t, err)
+
+	assert.Equal(t, "receipt.pdf", entryAttachment.Name)
+	assert.Equal(t, int64(len("%PDF receipt")), entryAttachment.Size)
+	assert.Equal(t, entryAttachment.Hash, rowAttachment.Hash)
+
+	var storedContents int
+	require.NoError(t, DB.Get(&storedContents, `SELECT COUNT(*) FROM attachment_contents;`))
+	assert.Equal(t, 1, storedContents, "identical files are stored once")
+
+	attachments, err := database.SelectAttachments(DB, entry.Id)
+	require.NoError(t, err)
+	assert.Equal(t, []database.Attachment{entryAttachment, rowAttachment}, attachments)
+
+	data, err := database.ReadAttachment(DB, rowAttachment)
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF receipt", string(data))
+
+	path, err := database.ExtractAttachment(DB, rowAttachment)
+	require.NoError(t, err)
+	assert.Equal(t, "receipt copy.pdf", filepath.Base(path))
+
+	_, err = database.AttachFile(DB, entry.Id, &[]int{99}[0], receipt)
+	assert.EqualError(t, err, "FAILED TO ATTACH FILE: ROW 99 ISN'T PART OF ENTRY 1")
+
+	require.NoError(t, database.DetachFile(DB, entryAttachment.Id))
+	require.NoError(t, DB.Get(&storedContents, `SELECT COUNT(*) FROM attachment_contents;`))
+	assert.Equal(t, 1, storedContents, "still attached to the row")
+
+	require.NoError(t, database.DetachFile(DB, rowAttachment.Id))
+	require.NoError(t, DB.Get(&storedContents, `SELECT COUNT(*) FROM attachment_contents;`))
+	assert.Equal(t, 0, storedContents)
+}
+
+func TestAttachFile_Corrupted(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	journal := insertTestJournal(t, DB)
+	ledger := insertTestLedger(t, DB)
+	entry := insertTestEntry(t, DB, journal.Id, ledger.Id)
+
+	attachment, err := database.AttachFile(DB, entry.Id, nil, writeTestFile(t, "invoice.pdf", "%PDF invoice"))
+	require.NoError(t, err)
+
+	_, err = DB.Exec(`UPDATE attachment_contents SET data = X'00';`)
+	require.NoError(t, err)
+
+	_, err = database.ReadAttachment(DB, attachment)
+	assert.EqualError(t, err, "attachment invoice.pdf is corrupted, its contents don't match its hash")
+
+	problems, err := database.CheckIntegrity(DB)
+	require.NoError(t, err)
+	// The test entry is an unfinished suspense entry as well
+	require.Len(t, problems, 2)
+	assert.Equal(t, database.CORRUPTATTACHMENT, problems[1].Kind)
+	assert.Equal(t, &entry.Id, problems[1].Entry)
+}
+
+func TestAttachFile_FollowsEntry(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	journal := insertTestJournal(t, DB)
+	ledger := insertTestLedger(t, DB)
+	entry := insertTestEntry(t, DB, journal.Id, ledger.Id)
+
+	rows, err := database.SelectRowsByEntry(DB, entry.Id)
+	require.NoError(t, err)
+
+	attachment, err := database.AttachFile(DB, entry.Id, &rows[0].Id, writeTestFile(t, "receipt.pdf", "%PDF receipt"))
+	require.NoError(t, err)
+
+	t.Run("removing its row moves it to the entry", func(t *testing.T) {
+		replacement := rows[0]
+		replacement.Id = 0
+		require.NoError(t, entry.Update(DB, []database.EntryRow{replacement}))
+
+		attachments, err := database.SelectAttachments(DB, entry.Id)
+		require.NoError(t, err)
+		require.Len(t, attachments, 1)
+		assert.Nil(t, attachments[0].Row)
+	})
+
+	t.Run("kept while in the trash", func(t *testing.T) {
+		require.NoError(t, database.DeleteEntry(DB, entry.Id))
+
+		attachments, err := database.SelectAttachments(DB, entry.Id)
+		require.NoError(t, err)
+		assert.Len(t, attachments, 1)
+
+		_, err = database.ReadAttachment(DB, attachment)
+		require.NoError(t, err)
+	})
+
+	t.Run("purged with the entry", func(t *testing.T) {
+		_, err := database.PurgeTrash(DB, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+
+		attachments, err := database.SelectAttachments(DB, entry.Id)
+		require.NoError(t, err)
+		assert.Empty(t, attachments)
+
+		var storedContents int
+		require.NoError(t, DB.Get(&storedContents, `SELECT COUNT(*) FROM attachment_contents;`))
+		assert.Equal(t, 0, storedContents)
+	})
+}
@@ -0,0 +1,277 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AuditAction string
+
+const (
+	INSERTACTION    AuditAction = "INSERT"
+	UPDATEACTION    AuditAction = "UPDATE"
+	DELETEACTION    AuditAction = "DELETE"
+	RECONCILEACTION AuditAction = "RECONCILE"
+	RESTOREACTION   AuditAction = "RESTORE"
+)
+
+// The state of an entry and its rows at one point in its history
+type EntrySnapshot struct {
+	Entry Entry      `json:"entry"`
+	Rows  []EntryRow `json:"rows"`
+}
+
+// One line of the audit log: who changed an entry when, and what it looked like afterwards.
+// For deletions the snapshot is the entry as it was right before it was deleted.
+type EntryVersion struct {
+	Id        int           `db:"id"`
+	Entry     int           `db:"entry"`
+	Action    AuditAction   `db:"action"`
+	Author    string        `db:"author"`
+	ChangedAt string        `db:"changed_at"`
+	Snapshot  EntrySnapshot `db:"snapshot"`
+}
+
+func (ev EntryVersion) String() string {
+	return fmt.Sprintf("%s %s by %s", ev.ChangedAt, ev.Action, ev.Author)
+}
+
+// The OS user running the program, recorded as the author of every change
+var auditAuthor = sync.OnceValue(func() string {
+	current, err := user.Current()
+	if err == nil && current.Username != "" {
+		return current.Username
+	}
+
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+
+	return "unknown"
+})
+
+// Appends the current state of the entry to the audit log, as part of the transaction that changed it
+func recordEntryVersion(tx *sqlx.Tx, entryId int, action AuditAction) error {
+	var snapshot EntrySnapshot
+
+	err := tx.Get(&snapshot.Entry, `SELECT * FROM entries WHERE id = $1;`, entryId)
+	if err != nil {
+		return fmt.Errorf("FAILED TO SNAPSHOT ENTRY %d: %v", entryId, err)
+	}
+
+	snapshot.Rows = []EntryRow{}
+	err = tx.Select(&snapshot.Rows, `SELECT * FROM entryrows WHERE entry = $1 ORDER BY id;`, entryId)
+	if err != nil {
+		return fmt.Errorf("FAILED TO SNAPSHOT ROWS OF ENTRY %d: %v", entryId, err)
+	}
+
+	_, err = tx.Exec(`INSERT INTO audit_log (entry, action, author, changed_at, snapshot) VALUES ($1, $2, $3, $4, $5);`,
+		entryId, action, auditAuthor(), time.Now().Format(TIMESTAMP_FORMAT), snapshot)
+	if err != nil {
+		return fmt.Errorf("FAILED TO RECORD ENTRY %d IN AUDIT LOG: %v", entryId, err)
+	}
+
+	return nil
+}
+
+// All recorded versions of an entry, oldest first
+func SelectEntryHistory(DB *sqlx.DB, entryId int) ([]EntryVersion, error) {
+	result := []EntryVersion{}
+
+	err := DB.Select(&result, `SELECT * FROM audit_log WHERE entry = $1 ORDER BY id;`, entryId)
+
+	return result, err
+}
+
+// A field whose value differs between two versions, both rendered for display
+type FieldChange struct {
+	Field string
+	From  string
+	To    string
+}
+
+// Rows are matched on their id between versions.
+// From is nil for a row that was added, To is nil for a row that was removed.
+type RowChange struct {
+	Row    int
+	From   *EntryRow
+	To     *EntryRow
+	Fields []FieldChange
+}
+
+type EntryDiff struct {
+	Fields []FieldChange
+	Rows   []RowChange
+}
+
+func (ed EntryDiff) IsEmpty() bool {
+	return len(ed.Fields) == 0 && len(ed.Rows) == 0
+}
+
+// What changed going from one snapshot to the other. Reads the caches to render names.
+func DiffEntrySnapshots(from, to EntrySnapshot) EntryDiff {
+	var result EntryDiff
+
+	result.Fields = diffFields(
+		[]string{"Journal", "Notes", "Suspense"},
+		entryFieldValues(from.Entry),
+		entryFieldValues(to.Entry),
+	)
+
+	for _, oldRow := range from.Rows {
+		index := slices.IndexFunc(to.Rows, func(newRow EntryRow) bool { return newRow.Id == oldRow.Id })
+		if index == -1 {
+			result.Rows = append(result.Rows, RowChange{Row: oldRow.Id, From: &oldRow})
+			continue
+		}
+
+		newRow := to.Rows[index]
+		fields := diffFields(rowFieldNames, rowFieldValues(oldRow), rowFieldValues(newRow))
+		if len(fields) != 0 {
+			result.Rows = append(result.Rows, RowChange{Row: oldRow.Id, From: &oldRow, To: &newRow, Fields: fields})
+		}
+	}
+
+	for _, newRow := range to.Rows {
+		if !slices.ContainsFunc(from.Rows, func(oldRow EntryRow) bool { return oldRow.Id == newRow.Id }) {
+			result.Rows = append(result.Rows, RowChange{Row: newRow.Id, To: &newRow})
+		}
+	}
+
+	return result
+}
+
+func diffFields(names, from, to []string) []FieldChange {
+	var result []FieldChange
+
+	for i, name := range names {
+		if from[i] != to[i] {
+			result = append(result, FieldChange{Field: name, From: from[i], To: to[i]})
+		}
+	}
+
+	return result
+}
+
+func entryFieldValues(entry Entry) []string {
+	return []string{
+		journalName(entry.Journal),
+		entry.Notes.Collapse("; "),
+		strconv.FormatBool(entry.Suspense),
+	}
+}
+
+var rowFieldNames = []string{"Date", "Ledger", "Account", "Description", "Document", "Value", "Currency", "Amount", "Reconciled", "Group", "Tags", "Tax code"}
+
+func rowFieldValues(row EntryRow) []string {
+	account := "none"
+	if row.Account != nil {
+		account = accountName(*row.Account)
+	}
+
+	document := "none"
+	if row.Document != nil {
+		document = *row.Document
+	}
+
+	currency := row.Currency
+	if currency == HOMECURRENCY {
+		currency = "home"
+	}
+
+	amount := "none"
+	if row.ForeignValue != nil {
+		amount = row.ForeignValue.String()
+	}
+
+	group := "none"
+	if row.Reconciliation != nil {
+		group = strconv.Itoa(*row.Reconciliation)
+	}
+
+	taxCode := "none"
+	if row.TaxCode != nil {
+		taxCode = taxCodeName(*row.TaxCode)
+	}
+
+	return []string{
+		row.Date.String(),
+		ledgerName(row.Ledger),
+		account,
+		row.Description,
+		document,
+		row.Value.String(),
+		currency,
+		amount,
+		strconv.FormatBool(row.Reconciled),
+		group,
+		string(row.Tags),
+		taxCode,
+	}
+}
+
+func taxCodeName(id int) string {
+	taxCode, err := taxCodeById(id)
+	if err != nil {
+		return fmt.Sprintf("<removed tax code %d>", id)
+	}
+
+	return taxCode.Code
+}
+
+func journalName(id int) string {
+	journals := AvailableJournals()
+
+	index := slices.IndexFunc(journals, func(journal Journal) bool { return journal.Id == id })
+	if index == -1 {
+		return fmt.Sprintf("<deleted journal %d>", id)
+	}
+
+	return journals[index].String()
+}
+
+func ledgerName(id int) string {
+	ledgers := AvailableLedgers()
+
+	index := slices.IndexFunc(ledgers, func(ledger Ledger) bool { return ledger.Id == id })
+	if index == -1 {
+		return fmt.Sprintf("<deleted ledger %d>", id)
+	}
+
+	return ledgers[index].String()
+}
+
+func accountName(id int) string {
+	accounts := AvailableAccounts()
+
+	index := slices.IndexFunc(accounts, func(account Account) bool { return account.Id == id })
+	if index == -1 {
+		return fmt.Sprintf("<deleted account %d>", id)
+	}
+
+	return accounts[index].String()
+}
+
+// A one-line description of a row for showing added and removed rows. Reads the caches.
+func SummariseRow(row EntryRow) string {
+	values := rowFieldValues(row)
+
+	result := fmt.Sprintf("%s %s", row.Date, values[1])
+	if row.Account != nil {
+		result += " / " + values[2]
+	}
+	result += fmt.Sprintf(" %s %q", row.Value, row.Description)
+
+	if row.ForeignValue != nil {
+		result += fmt.Sprintf(" (%s %s)", row.Currency, row.ForeignValue)
+	}
+
+	return result
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+func (aa *AuditAction) Scan(value any) error {
+	switch value {
+	case int64(0):
+		*aa = INSERTACTION
+	case int64(1):
+		*aa = UPDATEACTION
+	case int64(2):
+		*aa = DELETEACTION
+	case int64(3):
+		*aa = RECONCILEACTION
+	case int64(4):
+		*aa = RESTOREACTION
+
+	default:
+		return fmt.Errorf("UNMARSHALLING INVALID AUDIT ACTION: %v", value)
+	}
+
+	return nil
+}
+
+func (aa AuditAction) Value() (driver.Value, error) {
+	switch aa {
+	case INSERTACTION:
+		return int64(0), nil
+	case UPDATEACTION:
+		return int64(1), nil
+	case DELETEACTION:
+		return int64(2), nil
+	case RECONCILEACTION:
+		return int64(3), nil
+	case RESTOREACTION:
+		return int64(4), nil
+	}
+
+	return nil, fmt.Errorf("MARSHALLING INVALID AUDIT ACTION: %v", aa)
+}
+
+func (es *EntrySnapshot) Scan(value any) error {
+	converted, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("UNMARSHALLING INVALID ENTRY SNAPSHOT: %v", value)
+	}
+
+	return json.Unmarshal([]byte(converted), es)
+}
+
+func (es EntrySnapshot) Value() (driver.Value, error) {
+	binary, err := json.Marshal(es)
+	if err != nil {
+		return nil, fmt.Errorf("MARSHALLING INVALID ENTRY SNAPSHOT: %v", err)
+	}
+
+	return string(binary), nil
+}
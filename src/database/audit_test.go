@@ -0,0 +1,126 @@
+package database_test
+
+import (
+	"terminaccounting/database"
+	"terminaccounting/meta"
+	"terminaccounting/tat"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntryHistory(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	journal := insertTestJournal(t, DB)
+	ledger := insertTestLedger(t, DB)
+
+	date, err := database.ToDate("24-01-01")
+	require.NoError(t, err)
+
+	entry := database.Entry{Journal: journal.Id, Notes: meta.Notes{}}
+	entry.Id, err = entry.Insert(DB, []database.EntryRow{
+		{Date: date, Ledger: ledger.Id, Description: "first", Value: 1000},
+		{Date: date, Ledger: ledger.Id, Description: "second", Value: -1000},
+	})
+	require.NoError(t, err)
+
+	rows, err := database.SelectRowsByEntry(DB, entry.Id)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	first, second := rows[0], rows[1]
+
+	// Edits the first row, drops the second and adds a third
+	first.Value = 1500
+	entry.Notes = meta.Notes{"corrected"}
+	err = entry.Update(DB, []database.EntryRow{
+		first,
+		{Date: date, Ledger: ledger.Id, Description: "third", Value: -1500},
+	})
+	require.NoError(t, err)
+
+	rows, err = database.SelectRowsByEntry(DB, entry.Id)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, first.Id, rows[0].Id, "edited rows keep their id")
+	third := rows[1]
+
+	first.Reconciled = true
+	third.Reconciled = true
+	_, err = database.SetReconciled(DB, []*database.EntryRow{&first, &third})
+	require.NoError(t, err)
+
+	// Matched rows have to be unmatched before their entry can be deleted
+	assert.ErrorContains(t, database.DeleteEntry(DB, entry.Id), ":unmatch it")
+	_, err = database.UnmatchGroup(DB, *third.Reconciliation)
+	require.NoError(t, err)
+	for _, row := range []*database.EntryRow{&first, &third} {
+		row.Reconciled = false
+		row.Reconciliation = nil
+	}
+
+	require.NoError(t, database.DeleteEntry(DB, entry.Id))
+
+	history, err := database.SelectEntryHistory(DB, entry.Id)
+	require.NoError(t, err)
+	require.Len(t, history, 5)
+
+	var actions []database.AuditAction
+	for _, version := range history {
+		actions = append(actions, version.Action)
+		assert.NotEmpty(t, version.Author)
+		assert.NotEmpty(t, version.ChangedAt)
+		assert.Equal(t, entry.Id, version.Snapshot.Entry.Id)
+	}
+	assert.Equal(t, []database.AuditAction{
+		database.INSERTACTION, database.UPDATEACTION, database.RECONCILEACTION, database.RECONCILEACTION, database.DELETEACTION,
+	}, actions)
+
+	assert.Equal(t, []database.EntryRow{first, third}, history[4].Snapshot.Rows, "deletion keeps the last state")
+
+	t.Run("diff", func(t *testing.T) {
+		diff := database.DiffEntrySnapshots(history[0].Snapshot, history[1].Snapshot)
+
+		assert.Equal(t, []database.FieldChange{{Field: "Notes", From: "", To: "corrected"}}, diff.Fields)
+		require.Len(t, diff.Rows, 3)
+
+		assert.Equal(t, first.Id, diff.Rows[0].Row)
+		assert.Equal(t, []database.FieldChange{{Field: "Value", From: "10.00", To: "15.00"}}, diff.Rows[0].Fields)
+
+		assert.Equal(t, second.Id, diff.Rows[1].Row)
+		assert.Nil(t, diff.Rows[1].To, "removed")
+
+		assert.Equal(t, third.Id, diff.Rows[2].Row)
+		assert.Nil(t, diff.Rows[2].From, "added")
+
+		assert.True(t, database.DiffEntrySnapshots(history[3].Snapshot, history[4].Snapshot).IsEmpty())
+	})
+
+	t.Run("append-only", func(t *testing.T) {
+		_, err := DB.Exec(`UPDATE audit_log SET author = 'someone else';`)
+		assert.ErrorContains(t, err, "the audit log is append-only")
+
+		_, err = DB.Exec(`DELETE FROM audit_log;`)
+		assert.ErrorContains(t, err, "the audit log is append-only")
+	})
+}
+
+func TestUpdateEntry_ForeignRow(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	journal := insertTestJournal(t, DB)
+	ledger := insertTestLedger(t, DB)
+	entry := insertTestEntry(t, DB, journal.Id, ledger.Id)
+	other := insertTestEntry(t, DB, journal.Id, ledger.Id)
+
+	otherRows, err := database.SelectRowsByEntry(DB, other.Id)
+	require.NoError(t, err)
+
+	err = entry.Update(DB, otherRows)
+	assert.ErrorContains(t, err, "ISN'T PART OF IT")
+
+	history, err := database.SelectEntryHistory(DB, entry.Id)
+	require.NoError(t, err)
+	assert.Len(t, history, 1, "failed updates aren't logged")
+}
@@ -0,0 +1,214 @@
+package database
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// A set of unreconciled rows that net to 0, proposed to be reconciled together
+type MatchProposal struct {
+	Rows []EntryRow
+	// Higher is a more likely match, see ProposeMatches
+	Score float64
+}
+
+func (mp MatchProposal) FilterValue() string {
+	var result strings.Builder
+
+	for _, row := range mp.Rows {
+		result.WriteString(row.Date.String())
+		result.WriteString(row.Description)
+		result.WriteString(row.Value.String())
+	}
+
+	return result.String()
+}
+
+func (mp MatchProposal) String() string {
+	var rows []string
+	for _, row := range mp.Rows {
+		rows = append(rows, fmt.Sprintf("%s %s (%s)", row.Date, row.Description, row.Value))
+	}
+
+	return strings.Join(rows, " + ")
+}
+
+// How many of the candidates for a row are tried when looking for two or three rows settling it.
+// Keeps an account with hundreds of open rows from taking ages.
+const autoMatchCandidates = 20
+
+// Proposes sets of the unreconciled rows that net to 0, per ledger, or per account on the accounts ledger.
+// First pairs of equal amounts, then a row settled by two or three others, like a payment covering several invoices.
+// Proposals are ranked by equal amounts, how close the dates are and how alike the descriptions are.
+// Every row is in at most one proposal. Partially matched rows are left alone, they're matched with their group.
+func ProposeMatches(rows []EntryRow) []MatchProposal {
+	var keys []reconciliationKey
+	byKey := make(map[reconciliationKey][]EntryRow)
+	for _, row := range rows {
+		if row.Reconciled || row.Reconciliation != nil {
+			continue
+		}
+
+		key := reconciliationKeyOf(row)
+		if _, ok := byKey[key]; !ok {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], row)
+	}
+
+	var result []MatchProposal
+	for _, key := range keys {
+		result = append(result, proposeMatchesOf(byKey[key])...)
+	}
+
+	slices.SortStableFunc(result, func(a, b MatchProposal) int {
+		return cmp.Or(
+			cmp.Compare(b.Score, a.Score),
+			cmp.Compare(time.Time(a.Rows[0].Date).Unix(), time.Time(b.Rows[0].Date).Unix()),
+		)
+	})
+
+	return result
+}
+
+// The proposals for rows that can settle each other
+func proposeMatchesOf(rows []EntryRow) []MatchProposal {
+	used := make(map[int]bool)
+	isFree := func(proposal MatchProposal) bool {
+		return !slices.ContainsFunc(proposal.Rows, func(row EntryRow) bool { return used[row.Id] })
+	}
+	take := func(candidates []MatchProposal) []MatchProposal {
+		slices.SortStableFunc(candidates, func(a, b MatchProposal) int { return cmp.Compare(b.Score, a.Score) })
+
+		var result []MatchProposal
+		for _, candidate := range candidates {
+			if !isFree(candidate) {
+				continue
+			}
+
+			for _, row := range candidate.Rows {
+				used[row.Id] = true
+			}
+			result = append(result, candidate)
+		}
+
+		return result
+	}
+
+	var pairs []MatchProposal
+	for i, row := range rows {
+		for _, other := range rows[i+1:] {
+			if row.Value.Add(other.Value) == 0 {
+				pairs = append(pairs, newMatchProposal(row, []EntryRow{other}))
+			}
+		}
+	}
+	result := take(pairs)
+
+	var combinations []MatchProposal
+	for _, row := range rows {
+		if used[row.Id] {
+			continue
+		}
+
+		// The rows of the other side and smaller than this one, the closest in date first
+		var candidates []EntryRow
+		for _, other := range rows {
+			if !used[other.Id] && (other.Value > 0) != (row.Value > 0) && other.Value.Abs() < row.Value.Abs() {
+				candidates = append(candidates, other)
+			}
+		}
+		slices.SortStableFunc(candidates, func(a, b EntryRow) int {
+			return cmp.Compare(daysBetween(row.Date, a.Date), daysBetween(row.Date, b.Date))
+		})
+		candidates = candidates[:min(len(candidates), autoMatchCandidates)]
+
+		var best *MatchProposal
+		consider := func(others ...EntryRow) {
+			total := row.Value
+			for _, other := range others {
+				total = total.Add(other.Value)
+			}
+
+			if total != 0 {
+				return
+			}
+
+			proposal := newMatchProposal(row, others)
+			if best == nil || proposal.Score > best.Score {
+				best = &proposal
+			}
+		}
+
+		for i := range candidates {
+			for j := i + 1; j < len(candidates); j++ {
+				consider(candidates[i], candidates[j])
+
+				for k := j + 1; k < len(candidates); k++ {
+					consider(candidates[i], candidates[j], candidates[k])
+				}
+			}
+		}
+
+		if best != nil {
+			combinations = append(combinations, *best)
+		}
+	}
+
+	return append(result, take(combinations)...)
+}
+
+// Each of equal amounts, dates and descriptions adds up to 1 to the score
+func newMatchProposal(row EntryRow, others []EntryRow) MatchProposal {
+	var score float64
+
+	if len(others) == 1 {
+		score++
+	}
+
+	for _, other := range others {
+		// A week apart is half as likely as on the same day
+		score += 1 / (1 + daysBetween(row.Date, other.Date)/7) / float64(len(others))
+		score += descriptionSimilarity(row.Description, other.Description) / float64(len(others))
+	}
+
+	rows := slices.Concat([]EntryRow{row}, others)
+	slices.SortStableFunc(rows, func(a, b EntryRow) int {
+		return cmp.Compare(time.Time(a.Date).Unix(), time.Time(b.Date).Unix())
+	})
+
+	return MatchProposal{Rows: rows, Score: score}
+}
+
+func daysBetween(a, b Date) float64 {
+	return math.Abs(time.Time(a).Sub(time.Time(b)).Hours() / 24)
+}
+
+// The share of words the descriptions have in common, ignoring case
+func descriptionSimilarity(a, b string) float64 {
+	wordsA := strings.Fields(strings.ToLower(a))
+	wordsB := strings.Fields(strings.ToLower(b))
+
+	var union []string
+	shared := 0
+	for _, word := range slices.Concat(wordsA, wordsB) {
+		if slices.Contains(union, word) {
+			continue
+		}
+		union = append(union, word)
+
+		if slices.Contains(wordsA, word) && slices.Contains(wordsB, word) {
+			shared++
+		}
+	}
+
+	if len(union) == 0 {
+		return 0
+	}
+
+	return float64(shared) / float64(len(union))
+}
@@ -0,0 +1,45 @@
+package database_test
+
+import (
+	"terminaccounting/database"
+	"terminaccounting/tat"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProposeMatches(t *testing.T) {
+	tat.SetupTestEnv(t)
+
+	group := 1
+	row := func(id int, date string, description string, value database.CurrencyValue) database.EntryRow {
+		return database.EntryRow{Id: id, Date: mustDate(t, date), Ledger: 1, Description: description, Value: value}
+	}
+
+	invoice := row(1, "24-01-01", "Invoice 12 Acme", 10000)
+	payment := row(2, "24-01-03", "Payment invoice 12 Acme", -10000)
+	first := row(3, "24-02-01", "Invoice 13", 6000)
+	second := row(4, "24-02-01", "Invoice 14", 4000)
+	combined := row(5, "24-02-10", "Payment 13 14", -10000)
+
+	reconciled := row(6, "24-03-01", "Fee", 500)
+	reconciled.Reconciled = true
+	grouped := row(7, "24-03-01", "Fee refund", -500)
+	grouped.Reconciliation = &group
+	unsettled := row(8, "24-03-02", "Fee refund", -500)
+
+	proposals := database.ProposeMatches([]database.EntryRow{
+		invoice, first, second, combined, payment, reconciled, grouped, unsettled,
+	})
+	require.Len(t, proposals, 2)
+
+	// The payment of the same invoice beats the later one of the same amount
+	assert.Equal(t, []database.EntryRow{invoice, payment}, proposals[0].Rows)
+	assert.Equal(t, []database.EntryRow{first, second, combined}, proposals[1].Rows)
+	assert.Greater(t, proposals[0].Score, proposals[1].Score)
+
+	assert.Equal(t, "24-02-01 Invoice 13 (60.00) + 24-02-01 Invoice 14 (40.00) + 24-02-10 Payment 13 14 (-100.00)", proposals[1].String())
+
+	assert.Empty(t, database.ProposeMatches([]database.EntryRow{reconciled, grouped, unsettled}))
+}
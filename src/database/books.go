@@ -0,0 +1,87 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const BOOK_EXTENSION = ".db"
+
+// The directory where books are kept when no explicit path is given,
+// following the XDG base directory spec
+func DataDirectory() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+
+	// Spec says relative paths are invalid and should be ignored
+	if dataHome == "" || !filepath.IsAbs(dataHome) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("couldn't determine data directory: %v", err)
+		}
+
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+
+	return filepath.Join(dataHome, "terminaccounting"), nil
+}
+
+// Returns the paths of all books in the given directory, sorted by name.
+// A directory that doesn't exist yet simply has no books.
+func AvailableBooks(directory string) ([]string, error) {
+	dirEntries, err := os.ReadDir(directory)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("couldn't list books: %v", err)
+	}
+
+	var result []string
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || filepath.Ext(dirEntry.Name()) != BOOK_EXTENSION {
+			continue
+		}
+
+		result = append(result, filepath.Join(directory, dirEntry.Name()))
+	}
+
+	slices.Sort(result)
+
+	return result, nil
+}
+
+// The name of a book as shown to the user
+func BookName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), BOOK_EXTENSION)
+}
+
+// Returns the path for a new book with the given name in directory.
+// Errors if the name is unusable or the book already exists.
+func NewBookPath(directory, name string) (string, error) {
+	name = strings.TrimSpace(name)
+
+	if name == "" {
+		return "", errors.New("book name can't be empty")
+	}
+
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid book name %q", name)
+	}
+
+	path := filepath.Join(directory, name+BOOK_EXTENSION)
+
+	_, err := os.Stat(path)
+	if err == nil {
+		return "", fmt.Errorf("book %q already exists", name)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("couldn't check for existing book: %v", err)
+	}
+
+	return path, nil
+}
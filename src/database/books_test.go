@@ -0,0 +1,89 @@
+package database_test
+
+import (
+	"os"
+	"path/filepath"
+	"terminaccounting/database"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataDirectory(t *testing.T) {
+	t.Run("XDG_DATA_HOME set", func(t *testing.T) {
+		t.Setenv("XDG_DATA_HOME", "/some/data")
+
+		result, err := database.DataDirectory()
+		require.NoError(t, err)
+		assert.Equal(t, "/some/data/terminaccounting", result)
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		t.Setenv("XDG_DATA_HOME", "")
+		t.Setenv("HOME", "/home/someone")
+
+		result, err := database.DataDirectory()
+		require.NoError(t, err)
+		assert.Equal(t, "/home/someone/.local/share/terminaccounting", result)
+	})
+
+	t.Run("relative XDG_DATA_HOME ignored", func(t *testing.T) {
+		t.Setenv("XDG_DATA_HOME", "relative/data")
+		t.Setenv("HOME", "/home/someone")
+
+		result, err := database.DataDirectory()
+		require.NoError(t, err)
+		assert.Equal(t, "/home/someone/.local/share/terminaccounting", result)
+	})
+}
+
+func TestAvailableBooks(t *testing.T) {
+	directory := t.TempDir()
+
+	for _, name := range []string{"b.db", "a.db", "debug.log"} {
+		require.NoError(t, os.WriteFile(filepath.Join(directory, name), nil, 0o644))
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(directory, "dir.db"), 0o755))
+
+	result, err := database.AvailableBooks(directory)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(directory, "a.db"), filepath.Join(directory, "b.db")}, result)
+
+	result, err = database.AvailableBooks(filepath.Join(directory, "nonexistent"))
+	require.NoError(t, err)
+	assert.Empty(t, result)
+}
+
+func TestNewBookPath(t *testing.T) {
+	directory := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(directory, "existing.db"), nil, 0o644))
+
+	result, err := database.NewBookPath(directory, " household ")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(directory, "household.db"), result)
+
+	_, err = database.NewBookPath(directory, "")
+	assert.EqualError(t, err, "book name can't be empty")
+
+	_, err = database.NewBookPath(directory, "../escape")
+	assert.EqualError(t, err, `invalid book name "../escape"`)
+
+	_, err = database.NewBookPath(directory, "existing")
+	assert.EqualError(t, err, `book "existing" already exists`)
+}
+
+func TestConnect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "new book?.db")
+
+	DB, err := database.Connect(path)
+	require.NoError(t, err)
+	defer DB.Close()
+
+	isSetUp, err := database.DatabaseTableIsSetUp(DB, "entryrows")
+	require.NoError(t, err)
+	assert.True(t, isSetUp)
+
+	_, err = os.Stat(path)
+	assert.NoError(t, err)
+}
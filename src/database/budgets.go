@@ -0,0 +1,311 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"terminaccounting/meta"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jmoiron/sqlx"
+)
+
+// An amount planned to be spent, or earned, on an income or expense ledger in every month or every year
+type Budget struct {
+	Id     int `db:"id"`
+	Ledger int `db:"ledger"`
+	// Only counts the rows with this account, nil for all of them
+	Account *int `db:"account"`
+	// Only counts the rows with this tag or dimension, empty for all of them
+	Tag string `db:"tag"`
+	// MONTHPERIOD or YEARPERIOD
+	Period PeriodType    `db:"period"`
+	Amount CurrencyValue `db:"amount"`
+}
+
+func (b Budget) String() string {
+	result := ledgerName(b.Ledger)
+
+	if b.Account != nil {
+		result += " " + accountName(*b.Account)
+	}
+
+	if b.Tag != "" {
+		result += " " + b.Tag
+	}
+
+	return result
+}
+
+// Parses the arguments of :budget into a budget of the ledger.
+// The scope is a single tag, or an account like #3, and empty for the whole ledger.
+// An amount of none is for removing the budget, the amount is left at 0 then.
+func ParseBudget(ledger int, period, amount, scope string) (Budget, error) {
+	result := Budget{Ledger: ledger}
+
+	switch strings.ToLower(period) {
+	case "monthly":
+		result.Period = MONTHPERIOD
+	case "yearly":
+		result.Period = YEARPERIOD
+	default:
+		return Budget{}, fmt.Errorf("invalid budget period %q, use monthly or yearly", period)
+	}
+
+	if accountId, isAccount := strings.CutPrefix(scope, "#"); isAccount {
+		id, err := strconv.Atoi(accountId)
+		if err != nil {
+			return Budget{}, fmt.Errorf("invalid account %q, use its id like #3", scope)
+		}
+		result.Account = &id
+	} else if scope != "" {
+		tags, err := ParseTags(scope)
+		if err != nil {
+			return Budget{}, err
+		}
+		if len(tags.List()) != 1 {
+			return Budget{}, fmt.Errorf("a budget covers a single tag, not %q", scope)
+		}
+		result.Tag = string(tags)
+	}
+
+	if amount != "none" {
+		parsed, err := ParseNonNegativeValue(amount)
+		if err != nil {
+			return Budget{}, fmt.Errorf("invalid budget amount %q", amount)
+		}
+		result.Amount = parsed
+	}
+
+	return result, nil
+}
+
+// What the budget is restricted to within its ledger, empty if it covers the whole ledger
+func (b Budget) Scope() string {
+	switch {
+	case b.Account != nil:
+		return accountName(*b.Account)
+
+	case b.Tag != "":
+		return b.Tag
+
+	default:
+		return ""
+	}
+}
+
+// ledgers are the budget's ledger and its sub-ledgers, whose rows count towards it as well
+func (b Budget) matches(row *EntryRow, ledgers []int) bool {
+	if !slices.Contains(ledgers, row.Ledger) {
+		return false
+	}
+
+	if b.Account != nil && (row.Account == nil || *row.Account != *b.Account) {
+		return false
+	}
+
+	if b.Tag != "" && !slices.Contains(row.Tags.List(), b.Tag) {
+		return false
+	}
+
+	return true
+}
+
+// What was actually spent, or earned for income ledgers, in the period, counting the rows the budget covers.
+// A budget on a parent ledger covers the rows of its sub-ledgers too.
+func (b Budget) Actual(rows []*EntryRow, period Period) CurrencyValue {
+	var result CurrencyValue
+
+	ledgers := AvailableLedgers()
+	subtree := LedgerSubtree(ledgers, b.Ledger)
+
+	for _, row := range rows {
+		if b.matches(row, subtree) && period.Contains(row.Date) {
+			result = result.Add(row.Value)
+		}
+	}
+
+	// Income is credited, so negative
+	index := slices.IndexFunc(ledgers, func(ledger Ledger) bool { return ledger.Id == b.Ledger })
+	if index != -1 && ledgers[index].Type == INCOMELEDGER {
+		return -result
+	}
+
+	return result
+}
+
+// The month or year that date falls in
+func PeriodOf(periodType PeriodType, date Date) Period {
+	t := time.Time(date)
+
+	switch periodType {
+	case MONTHPERIOD:
+		start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return Period{Type: MONTHPERIOD, Start: Date(start), End: Date(start.AddDate(0, 1, -1))}
+
+	case YEARPERIOD:
+		start := time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		return Period{Type: YEARPERIOD, Start: Date(start), End: Date(start.AddDate(1, 0, -1))}
+
+	default:
+		panic(fmt.Sprintf("unexpected database.PeriodType: %#v", periodType))
+	}
+}
+
+// Sets the amount of the budget, replacing the amount of a budget for the same ledger, account, tag and period type
+func SetBudget(DB *sqlx.DB, budget Budget) error {
+	ledgers := AvailableLedgers()
+	index := slices.IndexFunc(ledgers, func(ledger Ledger) bool { return ledger.Id == budget.Ledger })
+	if index == -1 {
+		return fmt.Errorf("FAILED TO SET BUDGET: LEDGER %d DOESN'T EXIST", budget.Ledger)
+	}
+	if ledgers[index].Type != INCOMELEDGER && ledgers[index].Type != EXPENSELEDGER {
+		return fmt.Errorf("budgets are for income and expense ledgers, not for %s ledgers like %s", strings.ToLower(string(ledgers[index].Type)), ledgers[index].Name)
+	}
+
+	if budget.Period != MONTHPERIOD && budget.Period != YEARPERIOD {
+		return fmt.Errorf("budgets are per month or per year, not per %s", budget.Period)
+	}
+
+	if budget.Amount < 0 {
+		return fmt.Errorf("invalid budget %s, budgets can't be negative", budget.Amount)
+	}
+
+	tx := DB.MustBegin()
+	defer tx.Rollback()
+
+	err := deleteBudget(tx, budget)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.NamedExec(`INSERT INTO budgets (ledger, account, tag, period, amount)
+		VALUES (:ledger, :account, :tag, :period, :amount);`, budget)
+	if err != nil {
+		return fmt.Errorf("FAILED TO INSERT BUDGET: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+// Removes the budget for the same ledger, account, tag and period type as budget
+func RemoveBudget(DB *sqlx.DB, budget Budget) error {
+	tx := DB.MustBegin()
+	defer tx.Rollback()
+
+	err := deleteBudget(tx, budget)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func deleteBudget(tx *sqlx.Tx, budget Budget) error {
+	_, err := tx.NamedExec(`DELETE FROM budgets
+		WHERE ledger = :ledger AND account IS :account AND tag = :tag AND period = :period;`, budget)
+	if err != nil {
+		return fmt.Errorf("FAILED TO DELETE BUDGET: %v", err)
+	}
+
+	return nil
+}
+
+func SelectBudgets(DB *sqlx.DB) ([]Budget, error) {
+	result := []Budget{}
+
+	err := DB.Select(&result, `SELECT * FROM budgets ORDER BY ledger, period, account, tag;`)
+
+	return result, err
+}
+
+func SelectBudgetsByLedger(DB *sqlx.DB, ledgerId int) ([]Budget, error) {
+	result := []Budget{}
+
+	err := DB.Select(&result, `SELECT * FROM budgets WHERE ledger = $1 ORDER BY period, account, tag;`, ledgerId)
+
+	return result, err
+}
+
+func MakeLoadLedgerBudgetsCmd(DB *sqlx.DB, ledgerId int) tea.Cmd {
+	return func() tea.Msg {
+		budgets, err := SelectBudgetsByLedger(DB, ledgerId)
+		if err != nil {
+			return fmt.Errorf("FAILED TO LOAD BUDGETS OF LEDGER %d: %v", ledgerId, err)
+		}
+
+		return meta.DataLoadedMsg{
+			TargetApp: meta.LEDGERSAPP,
+			Model:     meta.BUDGETMODEL,
+			Data:      budgets,
+		}
+	}
+}
+
+// One period of a budget, with what was actually spent or earned in it
+type BudgetLine struct {
+	Budget Budget
+	Period Period
+	Actual CurrencyValue
+}
+
+func (bl BudgetLine) Remaining() CurrencyValue {
+	return bl.Budget.Amount.Subtract(bl.Actual)
+}
+
+// Every budget for every month or for the whole year, whichever it was set for
+func BudgetReport(DB *sqlx.DB, year int) ([]BudgetLine, error) {
+	budgets, err := SelectBudgets(DB)
+	if err != nil {
+		return nil, fmt.Errorf("FAILED TO SELECT BUDGETS: %v", err)
+	}
+
+	var rows []*EntryRow
+	err = DB.Select(&rows, `SELECT * FROM entryrows WHERE date BETWEEN $1 AND $2;`,
+		fmt.Sprintf("%d-01-01", year), fmt.Sprintf("%d-12-31", year))
+	if err != nil {
+		return nil, fmt.Errorf("FAILED TO SELECT ROWS FOR BUDGETS: %v", err)
+	}
+
+	yearStart := Date(time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+	var result []BudgetLine
+	for _, budget := range budgets {
+		switch budget.Period {
+		case YEARPERIOD:
+			period := PeriodOf(YEARPERIOD, yearStart)
+			result = append(result, BudgetLine{Budget: budget, Period: period, Actual: budget.Actual(rows, period)})
+
+		case MONTHPERIOD:
+			for month := range 12 {
+				period := PeriodOf(MONTHPERIOD, Date(time.Time(yearStart).AddDate(0, month, 0)))
+				result = append(result, BudgetLine{Budget: budget, Period: period, Actual: budget.Actual(rows, period)})
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Renders the budget report as a table per budget
+func RenderBudgetReport(year int, lines []BudgetLine) []string {
+	title := "Budgets for " + strconv.Itoa(year)
+
+	if len(lines) == 0 {
+		return []string{title, "", "No budgets yet, set one with :budget from a ledger's detail view"}
+	}
+
+	result := []string{title}
+	for i, line := range lines {
+		if i == 0 || line.Budget.Id != lines[i-1].Budget.Id {
+			result = append(result, "", line.Budget.String())
+			result = append(result, fmt.Sprintf("  %-10s %14s %14s %14s", "Period", "Budget", "Actual", "Remaining"))
+		}
+
+		result = append(result, fmt.Sprintf("  %-10s %14s %14s %14s", line.Period, line.Budget.Amount, line.Actual, line.Remaining()))
+	}
+
+	return result
+}
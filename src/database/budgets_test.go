@@ -0,0 +1,114 @@
+package database_test
+
+import (
+	"terminaccounting/database"
+	"terminaccounting/tat"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudgets(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	groceries := database.Ledger{Name: "Groceries", Type: database.EXPENSELEDGER}
+	groceriesId, err := groceries.Insert(DB)
+	require.NoError(t, err)
+	sales := database.Ledger{Name: "Sales", Type: database.INCOMELEDGER}
+	salesId, err := sales.Insert(DB)
+	require.NoError(t, err)
+	bank := database.Ledger{Name: "Bank", Type: database.ASSETLEDGER}
+	bankId, err := bank.Insert(DB)
+	require.NoError(t, err)
+	require.NoError(t, database.UpdateCache(DB))
+
+	journal := insertTestJournal(t, DB)
+
+	insert := func(date string, ledger int, value database.CurrencyValue, tags database.Tags) {
+		entry := database.Entry{Journal: journal.Id}
+		_, err := entry.Insert(DB, []database.EntryRow{
+			{Date: mustDate(t, date), Ledger: ledger, Value: value, Tags: tags},
+			{Date: mustDate(t, date), Ledger: bankId, Value: -value},
+		})
+		require.NoError(t, err)
+	}
+	insert("24-01-05", groceriesId, 12000, "organic")
+	insert("24-01-20", groceriesId, 5000, "")
+	insert("24-02-03", groceriesId, 8000, "")
+	insert("24-03-01", salesId, -250000, "")
+
+	require.NoError(t, database.SetBudget(DB, database.Budget{Ledger: groceriesId, Period: database.MONTHPERIOD, Amount: 15000}))
+	require.NoError(t, database.SetBudget(DB, database.Budget{Ledger: groceriesId, Tag: "organic", Period: database.MONTHPERIOD, Amount: 10000}))
+	require.NoError(t, database.SetBudget(DB, database.Budget{Ledger: salesId, Period: database.YEARPERIOD, Amount: 1000000}))
+
+	// Setting it again replaces the amount
+	require.NoError(t, database.SetBudget(DB, database.Budget{Ledger: salesId, Period: database.YEARPERIOD, Amount: 2000000}))
+
+	err = database.SetBudget(DB, database.Budget{Ledger: bankId, Period: database.MONTHPERIOD, Amount: 100})
+	assert.EqualError(t, err, "budgets are for income and expense ledgers, not for asset ledgers like Bank")
+
+	budgets, err := database.SelectBudgets(DB)
+	require.NoError(t, err)
+	require.Len(t, budgets, 3)
+
+	lines, err := database.BudgetReport(DB, 2024)
+	require.NoError(t, err)
+	require.Len(t, lines, 12+12+1)
+
+	january := lines[0]
+	assert.Equal(t, "2024-01", january.Period.String())
+	assert.Equal(t, database.CurrencyValue(17000), january.Actual)
+	assert.Equal(t, database.CurrencyValue(-2000), january.Remaining(), "over budget")
+
+	organic := lines[12]
+	assert.Equal(t, "organic", organic.Budget.Tag)
+	assert.Equal(t, database.CurrencyValue(12000), organic.Actual)
+
+	salesYear := lines[24]
+	assert.Equal(t, "2024", salesYear.Period.String())
+	assert.Equal(t, database.CurrencyValue(250000), salesYear.Actual, "income counts as positive")
+	assert.Equal(t, database.CurrencyValue(1750000), salesYear.Remaining())
+
+	report := database.RenderBudgetReport(2024, lines)
+	assert.Equal(t, "Budgets for 2024", report[0])
+	assert.Contains(t, report, "  2024-01            150.00         170.00         -20.00")
+
+	require.NoError(t, database.RemoveBudget(DB, database.Budget{Ledger: groceriesId, Tag: "organic", Period: database.MONTHPERIOD}))
+	budgets, err = database.SelectBudgetsByLedger(DB, groceriesId)
+	require.NoError(t, err)
+	require.Len(t, budgets, 1)
+	assert.Equal(t, "", budgets[0].Tag)
+
+	// A budget would be left pointing at nothing
+	customer := insertTestAccount(t, DB)
+	require.NoError(t, database.SetBudget(DB, database.Budget{Ledger: salesId, Account: &customer.Id, Period: database.YEARPERIOD, Amount: 500000}))
+
+	err = database.DeleteLedger(DB, groceriesId)
+	assert.EqualError(t, err, "Ledger has budgets, can't delete")
+	err = database.DeleteAccount(DB, customer.Id)
+	assert.EqualError(t, err, "Account has budgets, can't delete")
+}
+
+func TestParseBudget(t *testing.T) {
+	budget, err := database.ParseBudget(2, "Monthly", "500", "#3")
+	require.NoError(t, err)
+	account := 3
+	assert.Equal(t, database.Budget{Ledger: 2, Account: &account, Period: database.MONTHPERIOD, Amount: 50000}, budget)
+
+	budget, err = database.ParseBudget(2, "yearly", "none", "organic")
+	require.NoError(t, err)
+	assert.Equal(t, database.Budget{Ledger: 2, Tag: "organic", Period: database.YEARPERIOD}, budget)
+
+	_, err = database.ParseBudget(2, "weekly", "500", "")
+	assert.EqualError(t, err, `invalid budget period "weekly", use monthly or yearly`)
+
+	_, err = database.ParseBudget(2, "monthly", "500", "#three")
+	assert.EqualError(t, err, `invalid account "#three", use its id like #3`)
+
+	_, err = database.ParseBudget(2, "monthly", "500", "organic,local")
+	assert.EqualError(t, err, `a budget covers a single tag, not "organic,local"`)
+
+	_, err = database.ParseBudget(2, "monthly", "-500", "")
+	assert.EqualError(t, err, `invalid budget amount "-500"`)
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// The ids of the ledger and all ledgers under it, at any depth
+func LedgerSubtree(ledgers []Ledger, ledgerId int) []int {
+	result := []int{ledgerId}
+
+	// Breadth-first, result doubles as the queue
+	for i := 0; i < len(result); i++ {
+		for _, ledger := range ledgers {
+			if ledger.Parent != nil && *ledger.Parent == result[i] {
+				result = append(result, ledger.Id)
+			}
+		}
+	}
+
+	return result
+}
+
+// A ledger in the chart of accounts, with where it sits in the tree
+type LedgerNode struct {
+	Ledger Ledger
+	// 0 for top-level ledgers
+	Depth       int
+	HasChildren bool
+}
+
+// The ledgers as a tree, flattened depth-first so every ledger directly follows its parent.
+// Siblings are ordered by code, ledgers without a code go after the ones with one.
+func LedgerTree(ledgers []Ledger) []LedgerNode {
+	children := make(map[int][]Ledger)
+	var roots []Ledger
+
+	for _, ledger := range ledgers {
+		// Parents missing from ledgers leave their children at the top level
+		if ledger.Parent != nil && slices.ContainsFunc(ledgers, func(other Ledger) bool { return other.Id == *ledger.Parent }) {
+			children[*ledger.Parent] = append(children[*ledger.Parent], ledger)
+		} else {
+			roots = append(roots, ledger)
+		}
+	}
+
+	compareLedgers := func(a, b Ledger) int {
+		if (a.Code == "") != (b.Code == "") {
+			if a.Code == "" {
+				return 1
+			}
+
+			return -1
+		}
+
+		return cmp.Or(strings.Compare(a.Code, b.Code), cmp.Compare(a.Id, b.Id))
+	}
+
+	var result []LedgerNode
+
+	var visit func(siblings []Ledger, depth int)
+	visit = func(siblings []Ledger, depth int) {
+		slices.SortFunc(siblings, compareLedgers)
+
+		for _, ledger := range siblings {
+			result = append(result, LedgerNode{Ledger: ledger, Depth: depth, HasChildren: len(children[ledger.Id]) != 0})
+			visit(children[ledger.Id], depth+1)
+		}
+	}
+	visit(roots, 0)
+
+	return result
+}
+
+// A ledger's balance in the chart of accounts, Total includes the balances of its sub-ledgers
+type ChartLine struct {
+	Node    LedgerNode
+	Balance CurrencyValue
+	Total   CurrencyValue
+}
+
+// Every ledger in tree order, with its own and its rolled-up balance in the home currency
+func ChartOfAccounts(DB *sqlx.DB) ([]ChartLine, error) {
+	var balances []struct {
+		Ledger  int           `db:"ledger"`
+		Balance CurrencyValue `db:"balance"`
+	}
+	err := DB.Select(&balances, `SELECT ledger, SUM(value) AS balance FROM entryrows GROUP BY ledger;`)
+	if err != nil {
+		return nil, fmt.Errorf("FAILED TO SELECT LEDGER BALANCES: %v", err)
+	}
+
+	balanceOf := make(map[int]CurrencyValue)
+	for _, balance := range balances {
+		balanceOf[balance.Ledger] = balance.Balance
+	}
+
+	ledgers := AvailableLedgers()
+
+	var result []ChartLine
+	for _, node := range LedgerTree(ledgers) {
+		line := ChartLine{Node: node, Balance: balanceOf[node.Ledger.Id]}
+
+		for _, id := range LedgerSubtree(ledgers, node.Ledger.Id) {
+			line.Total = line.Total.Add(balanceOf[id])
+		}
+
+		result = append(result, line)
+	}
+
+	return result, nil
+}
+
+// For `:chart`, parents show their own balance as well as the total of everything under them
+func RenderChartOfAccounts(lines []ChartLine) []string {
+	if len(lines) == 0 {
+		return []string{"Chart of accounts", "", "No ledgers yet"}
+	}
+
+	result := []string{"Chart of accounts", "", fmt.Sprintf("%-40s %14s %14s", "Ledger", "Balance", "Total")}
+	for _, line := range lines {
+		name := strings.Repeat("  ", line.Node.Depth) + line.Node.Ledger.Title()
+
+		total := ""
+		if line.Node.HasChildren {
+			total = line.Total.String()
+		}
+
+		result = append(result, fmt.Sprintf("%-40s %14s %14s", name, line.Balance, total))
+	}
+
+	return result
+}
@@ -0,0 +1,96 @@
+package database_test
+
+import (
+	"terminaccounting/database"
+	"terminaccounting/tat"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLedgerHierarchy(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	insert := func(ledger database.Ledger) int {
+		id, err := ledger.Insert(DB)
+		require.NoError(t, err)
+
+		return id
+	}
+
+	expenses := insert(database.Ledger{Name: "Expenses", Type: database.EXPENSELEDGER, Code: "4000"})
+	housing := insert(database.Ledger{Name: "Housing", Type: database.EXPENSELEDGER, Code: "4200", Parent: &expenses})
+	rent := insert(database.Ledger{Name: "Rent", Type: database.EXPENSELEDGER, Code: "4210", Parent: &housing})
+	food := insert(database.Ledger{Name: "Food", Type: database.EXPENSELEDGER, Code: "4100", Parent: &expenses})
+	bank := insert(database.Ledger{Name: "Bank", Type: database.ASSETLEDGER})
+
+	_, err := (&database.Ledger{Name: "Savings", Type: database.ASSETLEDGER, Parent: &expenses}).Insert(DB)
+	assert.EqualError(t, err, "ledger Savings is ASSET, so it can't be under Expenses, which is EXPENSE")
+
+	missing := 99
+	_, err = (&database.Ledger{Name: "Savings", Type: database.ASSETLEDGER, Parent: &missing}).Insert(DB)
+	assert.EqualError(t, err, "parent ledger 99 doesn't exist")
+
+	_, err = (&database.Ledger{Name: "Groceries", Type: database.EXPENSELEDGER, Code: "4100"}).Insert(DB)
+	assert.EqualError(t, err, "there's already a ledger with code 4100")
+
+	err = database.Ledger{Id: expenses, Name: "Expenses", Type: database.EXPENSELEDGER, Code: "4000", Parent: &rent}.Update(DB)
+	assert.EqualError(t, err, "ledger Expenses can't be placed under itself or one of its sub-ledgers")
+
+	// Changing the type of a parent would leave its sub-ledgers of another type
+	err = database.Ledger{Id: housing, Name: "Housing", Type: database.INCOMELEDGER, Code: "4200"}.Update(DB)
+	assert.EqualError(t, err, "ledger Housing has sub-ledger Rent, which is EXPENSE, so it can't be INCOME")
+
+	assert.ElementsMatch(t, []int{expenses, housing, food, rent}, database.LedgerSubtree(database.AvailableLedgers(), expenses))
+	assert.Equal(t, []int{rent}, database.LedgerSubtree(database.AvailableLedgers(), rent))
+
+	// Ordered by code, with children right after their parent
+	var order []string
+	var depths []int
+	for _, node := range database.LedgerTree(database.AvailableLedgers()) {
+		order = append(order, node.Ledger.Name)
+		depths = append(depths, node.Depth)
+	}
+	assert.Equal(t, []string{"Expenses", "Food", "Housing", "Rent", "Bank"}, order)
+	assert.Equal(t, []int{0, 1, 1, 2, 0}, depths)
+
+	err = database.DeleteLedger(DB, housing)
+	assert.EqualError(t, err, "Ledger has sub-ledgers, can't delete")
+
+	journal := insertTestJournal(t, DB)
+	for _, row := range []struct {
+		ledger int
+		value  database.CurrencyValue
+	}{{rent, 90000}, {food, 25000}, {housing, 5000}} {
+		entry := database.Entry{Journal: journal.Id}
+		_, err := entry.Insert(DB, []database.EntryRow{
+			{Date: mustDate(t, "24-01-01"), Ledger: row.ledger, Value: row.value},
+			{Date: mustDate(t, "24-01-01"), Ledger: bank, Value: -row.value},
+		})
+		require.NoError(t, err)
+	}
+
+	lines, err := database.ChartOfAccounts(DB)
+	require.NoError(t, err)
+	require.Len(t, lines, 5)
+
+	assert.Equal(t, database.CurrencyValue(0), lines[0].Balance)
+	assert.Equal(t, database.CurrencyValue(120000), lines[0].Total)
+	assert.Equal(t, database.CurrencyValue(5000), lines[2].Balance)
+	assert.Equal(t, database.CurrencyValue(95000), lines[2].Total)
+	assert.Equal(t, database.CurrencyValue(-120000), lines[4].Total)
+
+	rendered := database.RenderChartOfAccounts(lines)
+	assert.Contains(t, rendered, "    4210 Rent                                    900.00               ")
+
+	// A budget on a parent counts the rows of its sub-ledgers
+	budget := database.Budget{Ledger: housing, Period: database.MONTHPERIOD, Amount: 100000}
+	rows, err := database.SelectRows(DB)
+	require.NoError(t, err)
+	rowPointers := make([]*database.EntryRow, len(rows))
+	for i := range rows {
+		rowPointers[i] = &rows[i]
+	}
+	assert.Equal(t, database.CurrencyValue(95000), budget.Actual(rowPointers, database.PeriodOf(database.MONTHPERIOD, mustDate(t, "24-01-15"))))
+}
@@ -0,0 +1,240 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"terminaccounting/meta"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmoiron/sqlx"
+)
+
+// A ready-made set of ledgers and journals to start a new book with
+type ChartTemplate struct {
+	Name        string
+	Description string
+
+	// Parents come before their sub-ledgers
+	Ledgers  []ChartTemplateLedger
+	Journals []ChartTemplateJournal
+}
+
+type ChartTemplateLedger struct {
+	Code       string
+	Name       string
+	Type       LedgerType
+	IsAccounts bool
+	// Code of the parent ledger, empty for top-level ledgers
+	Parent string
+}
+
+type ChartTemplateJournal struct {
+	Name string
+	Type JournalType
+}
+
+func (ct ChartTemplate) FilterValue() string {
+	return ct.Name + ct.Description
+}
+
+func (ct ChartTemplate) Render(isActive bool) string {
+	countStyle := lipgloss.NewStyle()
+	if !isActive {
+		countStyle = countStyle.Foreground(meta.LEDGERSCOLOUR)
+	}
+
+	counts := fmt.Sprintf("(%d ledgers, %d journals)", len(ct.Ledgers), len(ct.Journals))
+
+	return fmt.Sprintf("%s  %s %s", ct.Name, ct.Description, countStyle.Render(counts))
+}
+
+// Whether the book has no ledgers and no journals yet, like a freshly created one
+func IsEmptyBook(DB *sqlx.DB) (bool, error) {
+	var count int
+	err := DB.Get(&count, `SELECT (SELECT COUNT(*) FROM ledgers) + (SELECT COUNT(*) FROM journals);`)
+	if err != nil {
+		return false, fmt.Errorf("FAILED TO COUNT LEDGERS AND JOURNALS: %v", err)
+	}
+
+	return count == 0, nil
+}
+
+// Creates the template's ledgers and journals, all of them or none.
+// Only for empty books, so codes and the accounts ledger can't clash with what's already there.
+func (ct ChartTemplate) Apply(DB *sqlx.DB) error {
+	isEmpty, err := IsEmptyBook(DB)
+	if err != nil {
+		return err
+	}
+	if !isEmpty {
+		return errors.New("chart templates are for empty books, this one already has ledgers or journals")
+	}
+
+	tx := DB.MustBegin()
+	defer tx.Rollback()
+
+	idOfCode := make(map[string]int)
+	for _, templateLedger := range ct.Ledgers {
+		ledger := Ledger{
+			Name:       templateLedger.Name,
+			Type:       templateLedger.Type,
+			Notes:      meta.Notes{},
+			IsAccounts: templateLedger.IsAccounts,
+			Currency:   HOMECURRENCY,
+			Code:       templateLedger.Code,
+		}
+
+		if templateLedger.Parent != "" {
+			parentId, ok := idOfCode[templateLedger.Parent]
+			if !ok {
+				return fmt.Errorf("FAILED TO APPLY CHART TEMPLATE %s: PARENT %s OF %s ISN'T CREATED YET", ct.Name, templateLedger.Parent, templateLedger.Code)
+			}
+			ledger.Parent = &parentId
+		}
+
+		res, err := tx.NamedExec(`INSERT INTO ledgers (name, type, notes, is_accounts, currency, parent, code)
+			VALUES (:name, :type, :notes, :is_accounts, :currency, :parent, :code);`, ledger)
+		if err != nil {
+			return fmt.Errorf("FAILED TO INSERT LEDGER %s: %v", ledger.Name, err)
+		}
+
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		idOfCode[templateLedger.Code] = int(id)
+	}
+
+	for _, templateJournal := range ct.Journals {
+		journal := Journal{Name: templateJournal.Name, Type: templateJournal.Type, Notes: meta.Notes{}}
+
+		_, err := tx.NamedExec(`INSERT INTO journals (name, type, notes) VALUES (:name, :type, :notes);`, journal)
+		if err != nil {
+			return fmt.Errorf("FAILED TO INSERT JOURNAL %s: %v", journal.Name, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return UpdateCache(DB)
+}
+
+// The templates offered when opening an empty book, and with `:setup`
+var ChartTemplates = []ChartTemplate{
+	{
+		Name:        "Personal",
+		Description: "minimal personal finance",
+		Ledgers: []ChartTemplateLedger{
+			{Code: "1000", Name: "Checking account", Type: ASSETLEDGER},
+			{Code: "1100", Name: "Savings account", Type: ASSETLEDGER},
+			{Code: "1200", Name: "Cash", Type: ASSETLEDGER},
+			{Code: "2000", Name: "Credit card", Type: LIABILITYLEDGER},
+			{Code: "3000", Name: "Opening balances", Type: EQUITYLEDGER},
+			{Code: "4000", Name: "Income", Type: INCOMELEDGER},
+			{Code: "4100", Name: "Salary", Type: INCOMELEDGER, Parent: "4000"},
+			{Code: "4200", Name: "Interest", Type: INCOMELEDGER, Parent: "4000"},
+			{Code: "4900", Name: "Other income", Type: INCOMELEDGER, Parent: "4000"},
+			{Code: "6000", Name: "Expenses", Type: EXPENSELEDGER},
+			{Code: "6100", Name: "Housing", Type: EXPENSELEDGER, Parent: "6000"},
+			{Code: "6200", Name: "Groceries", Type: EXPENSELEDGER, Parent: "6000"},
+			{Code: "6300", Name: "Transport", Type: EXPENSELEDGER, Parent: "6000"},
+			{Code: "6400", Name: "Insurance", Type: EXPENSELEDGER, Parent: "6000"},
+			{Code: "6500", Name: "Leisure", Type: EXPENSELEDGER, Parent: "6000"},
+			{Code: "6900", Name: "Other expenses", Type: EXPENSELEDGER, Parent: "6000"},
+		},
+		Journals: []ChartTemplateJournal{
+			{Name: "Bank", Type: CASHFLOWJOURNAL},
+			{Name: "General", Type: GENERALJOURNAL},
+		},
+	},
+	{
+		Name:        "Business",
+		Description: "small business with debtors and creditors",
+		Ledgers: []ChartTemplateLedger{
+			{Code: "0100", Name: "Fixed assets", Type: ASSETLEDGER},
+			{Code: "0110", Name: "Equipment", Type: ASSETLEDGER, Parent: "0100"},
+			{Code: "0190", Name: "Accumulated depreciation", Type: ASSETLEDGER, Parent: "0100"},
+			{Code: "1000", Name: "Current assets", Type: ASSETLEDGER},
+			{Code: "1010", Name: "Bank", Type: ASSETLEDGER, Parent: "1000"},
+			{Code: "1020", Name: "Cash", Type: ASSETLEDGER, Parent: "1000"},
+			{Code: "1300", Name: "Debtors", Type: ASSETLEDGER, IsAccounts: true, Parent: "1000"},
+			{Code: "1600", Name: "Current liabilities", Type: LIABILITYLEDGER},
+			{Code: "1610", Name: "Creditors", Type: LIABILITYLEDGER, Parent: "1600"},
+			{Code: "1620", Name: "VAT payable", Type: LIABILITYLEDGER, Parent: "1600"},
+			{Code: "1630", Name: "Taxes payable", Type: LIABILITYLEDGER, Parent: "1600"},
+			{Code: "0500", Name: "Equity", Type: EQUITYLEDGER},
+			{Code: "0510", Name: "Capital", Type: EQUITYLEDGER, Parent: "0500"},
+			{Code: "0520", Name: "Retained earnings", Type: EQUITYLEDGER, Parent: "0500"},
+			{Code: "8000", Name: "Revenue", Type: INCOMELEDGER},
+			{Code: "8010", Name: "Sales", Type: INCOMELEDGER, Parent: "8000"},
+			{Code: "8090", Name: "Other revenue", Type: INCOMELEDGER, Parent: "8000"},
+			{Code: "7000", Name: "Cost of sales", Type: EXPENSELEDGER},
+			{Code: "4000", Name: "Operating expenses", Type: EXPENSELEDGER},
+			{Code: "4010", Name: "Wages", Type: EXPENSELEDGER, Parent: "4000"},
+			{Code: "4100", Name: "Rent", Type: EXPENSELEDGER, Parent: "4000"},
+			{Code: "4200", Name: "Office", Type: EXPENSELEDGER, Parent: "4000"},
+			{Code: "4300", Name: "Travel", Type: EXPENSELEDGER, Parent: "4000"},
+			{Code: "4400", Name: "Marketing", Type: EXPENSELEDGER, Parent: "4000"},
+			{Code: "4800", Name: "Depreciation", Type: EXPENSELEDGER, Parent: "4000"},
+			{Code: "4900", Name: "Bank charges and interest", Type: EXPENSELEDGER, Parent: "4000"},
+		},
+		Journals: []ChartTemplateJournal{
+			{Name: "Sales", Type: INCOMEJOURNAL},
+			{Name: "Purchases", Type: EXPENSEJOURNAL},
+			{Name: "Bank", Type: CASHFLOWJOURNAL},
+			{Name: "Cash", Type: CASHFLOWJOURNAL},
+			{Name: "Memorial", Type: GENERALJOURNAL},
+		},
+	},
+	// The reference codes of the Dutch Referentie Grondslagen Schema, down to its third level,
+	// for the parts a small Dutch business uses
+	{
+		Name:        "RGS",
+		Description: "subset of the Dutch Referentie Grondslagen Schema",
+		Ledgers: []ChartTemplateLedger{
+			{Code: "BMva", Name: "Materiële vaste activa", Type: ASSETLEDGER},
+			{Code: "BMvaBeg", Name: "Bedrijfsgebouwen en -terreinen", Type: ASSETLEDGER, Parent: "BMva"},
+			{Code: "BMvaMei", Name: "Machines en installaties", Type: ASSETLEDGER, Parent: "BMva"},
+			{Code: "BMvaBei", Name: "Andere vaste bedrijfsmiddelen", Type: ASSETLEDGER, Parent: "BMva"},
+			{Code: "BVrd", Name: "Voorraden", Type: ASSETLEDGER},
+			{Code: "BVor", Name: "Vorderingen", Type: ASSETLEDGER},
+			{Code: "BVorDeb", Name: "Handelsdebiteuren", Type: ASSETLEDGER, IsAccounts: true, Parent: "BVor"},
+			{Code: "BVorOvr", Name: "Overige vorderingen", Type: ASSETLEDGER, Parent: "BVor"},
+			{Code: "BLim", Name: "Liquide middelen", Type: ASSETLEDGER},
+			{Code: "BLimKas", Name: "Kasmiddelen", Type: ASSETLEDGER, Parent: "BLim"},
+			{Code: "BLimBan", Name: "Tegoeden op bankgirorekeningen", Type: ASSETLEDGER, Parent: "BLim"},
+			{Code: "BEiv", Name: "Eigen vermogen", Type: EQUITYLEDGER},
+			{Code: "BEivGok", Name: "Gestort en opgevraagd kapitaal", Type: EQUITYLEDGER, Parent: "BEiv"},
+			{Code: "BEivOre", Name: "Overige reserves", Type: EQUITYLEDGER, Parent: "BEiv"},
+			{Code: "BEivOnr", Name: "Onverdeelde winst", Type: EQUITYLEDGER, Parent: "BEiv"},
+			{Code: "BLas", Name: "Langlopende schulden", Type: LIABILITYLEDGER},
+			{Code: "BSch", Name: "Kortlopende schulden", Type: LIABILITYLEDGER},
+			{Code: "BSchCre", Name: "Handelscrediteuren", Type: LIABILITYLEDGER, Parent: "BSch"},
+			{Code: "BSchBep", Name: "Belastingen en premies sociale verzekeringen", Type: LIABILITYLEDGER, Parent: "BSch"},
+			{Code: "BSchOvs", Name: "Overige schulden", Type: LIABILITYLEDGER, Parent: "BSch"},
+			{Code: "WOmz", Name: "Netto-omzet", Type: INCOMELEDGER},
+			{Code: "WOmzNop", Name: "Netto-omzet opbrengsten", Type: INCOMELEDGER, Parent: "WOmz"},
+			{Code: "WOvb", Name: "Overige bedrijfsopbrengsten", Type: INCOMELEDGER},
+			{Code: "WKpr", Name: "Kostprijs van de omzet", Type: EXPENSELEDGER},
+			{Code: "WPer", Name: "Lonen en salarissen", Type: EXPENSELEDGER},
+			{Code: "WAfs", Name: "Afschrijvingen", Type: EXPENSELEDGER},
+			{Code: "WBed", Name: "Overige bedrijfskosten", Type: EXPENSELEDGER},
+			{Code: "WBedHui", Name: "Huisvestingskosten", Type: EXPENSELEDGER, Parent: "WBed"},
+			{Code: "WBedVkk", Name: "Verkoopkosten", Type: EXPENSELEDGER, Parent: "WBed"},
+			{Code: "WBedAut", Name: "Autokosten", Type: EXPENSELEDGER, Parent: "WBed"},
+			{Code: "WBedKan", Name: "Kantoorkosten", Type: EXPENSELEDGER, Parent: "WBed"},
+			{Code: "WBedAlk", Name: "Algemene kosten", Type: EXPENSELEDGER, Parent: "WBed"},
+			{Code: "WFbe", Name: "Financiële baten en lasten", Type: EXPENSELEDGER},
+		},
+		Journals: []ChartTemplateJournal{
+			{Name: "Verkoop", Type: INCOMEJOURNAL},
+			{Name: "Inkoop", Type: EXPENSEJOURNAL},
+			{Name: "Bank", Type: CASHFLOWJOURNAL},
+			{Name: "Kas", Type: CASHFLOWJOURNAL},
+			{Name: "Memoriaal", Type: GENERALJOURNAL},
+		},
+	},
+}
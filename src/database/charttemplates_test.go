@@ -0,0 +1,50 @@
+package database_test
+
+import (
+	"terminaccounting/database"
+	"terminaccounting/tat"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChartTemplates(t *testing.T) {
+	for _, template := range database.ChartTemplates {
+		t.Run(template.Name, func(t *testing.T) {
+			DB := tat.SetupTestEnv(t)
+
+			isEmpty, err := database.IsEmptyBook(DB)
+			require.NoError(t, err)
+			assert.True(t, isEmpty)
+
+			require.NoError(t, template.Apply(DB))
+
+			ledgers, err := database.SelectLedgers(DB)
+			require.NoError(t, err)
+			require.Len(t, ledgers, len(template.Ledgers))
+
+			journals, err := database.SelectJournals(DB)
+			require.NoError(t, err)
+			assert.Len(t, journals, len(template.Journals))
+
+			accountsLedgers := 0
+			for _, ledger := range ledgers {
+				if ledger.IsAccounts {
+					accountsLedgers++
+				}
+
+				// Sub-ledgers are of the same type as their parent
+				if ledger.Parent != nil {
+					parent, err := database.SelectLedger(DB, *ledger.Parent)
+					require.NoError(t, err)
+					assert.Equal(t, parent.Type, ledger.Type, "%s under %s", ledger.Name, parent.Name)
+				}
+			}
+			assert.LessOrEqual(t, accountsLedgers, 1)
+
+			err = template.Apply(DB)
+			assert.EqualError(t, err, "chart templates are for empty books, this one already has ledgers or journals")
+		})
+	}
+}
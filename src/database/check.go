@@ -0,0 +1,358 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"terminaccounting/meta"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmoiron/sqlx"
+)
+
+type IntegrityProblemKind string
+
+const (
+	UNBALANCEDENTRY      IntegrityProblemKind = "Unbalanced entry"
+	MISSINGACCOUNT       IntegrityProblemKind = "Missing account"
+	UNBALANCEDRECONCILED IntegrityProblemKind = "Unbalanced reconciliation"
+	EMPTYENTRY           IntegrityProblemKind = "Empty entry"
+	DANGLINGREFERENCE    IntegrityProblemKind = "Deleted reference"
+	UNFINISHEDSUSPENSE   IntegrityProblemKind = "Unfinished suspense entry"
+	CORRUPTATTACHMENT    IntegrityProblemKind = "Corrupt attachment"
+)
+
+// A single finding of CheckIntegrity.
+// Exactly one of Entry, Ledger and Account is set, being what to jump to in order to fix it.
+type IntegrityProblem struct {
+	Kind    IntegrityProblemKind
+	Details string
+
+	Entry   *int
+	Ledger  *int
+	Account *int
+}
+
+func (ip IntegrityProblem) FilterValue() string {
+	return string(ip.Kind) + ip.Details
+}
+
+func (ip IntegrityProblem) Render(isActive bool) string {
+	kindStyle := lipgloss.NewStyle().Foreground(lipgloss.ANSIColor(1)).Width(len(UNFINISHEDSUSPENSE) + 2)
+	if isActive {
+		kindStyle = kindStyle.Foreground(meta.ENTRIESCOLOUR)
+	}
+
+	return kindStyle.Render(string(ip.Kind)) + ip.Details
+}
+
+// Scans the whole book for things that shouldn't be possible (anymore),
+// but might have been saved by older versions or bugs
+func CheckIntegrity(DB *sqlx.DB) ([]IntegrityProblem, error) {
+	var result []IntegrityProblem
+
+	checks := []func(*sqlx.DB) ([]IntegrityProblem, error){
+		checkUnbalancedEntries,
+		checkMissingAccounts,
+		checkReconciledLedgers,
+		checkReconciledAccounts,
+		checkReconciliationGroups,
+		checkStatements,
+		checkEmptyEntries,
+		checkDanglingReferences,
+		checkInvoiceEntries,
+		checkAttachments,
+	}
+
+	for _, check := range checks {
+		problems, err := check(DB)
+		if err != nil {
+			return nil, fmt.Errorf("FAILED TO CHECK INTEGRITY: %v", err)
+		}
+
+		result = append(result, problems...)
+	}
+
+	return result, nil
+}
+
+func checkUnbalancedEntries(DB *sqlx.DB) ([]IntegrityProblem, error) {
+	var unbalanced []struct {
+		Id       int           `db:"id"`
+		Suspense bool          `db:"suspense"`
+		Total    CurrencyValue `db:"total"`
+	}
+	err := DB.Select(&unbalanced, `SELECT entries.id, entries.suspense, SUM(entryrows.value) AS total
+		FROM entries JOIN entryrows ON entryrows.entry = entries.id
+		GROUP BY entries.id HAVING total != 0
+		ORDER BY entries.id;`)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []IntegrityProblem
+	for _, entry := range unbalanced {
+		kind := UNBALANCEDENTRY
+		if entry.Suspense {
+			kind = UNFINISHEDSUSPENSE
+		}
+
+		result = append(result, IntegrityProblem{
+			Kind:    kind,
+			Details: fmt.Sprintf("Entry %d has rows totalling %s", entry.Id, entry.Total),
+			Entry:   &entry.Id,
+		})
+	}
+
+	return result, nil
+}
+
+func checkMissingAccounts(DB *sqlx.DB) ([]IntegrityProblem, error) {
+	var rows []struct {
+		Id    int `db:"id"`
+		Entry int `db:"entry"`
+	}
+	err := DB.Select(&rows, `SELECT entryrows.id, entryrows.entry
+		FROM entryrows JOIN ledgers ON ledgers.id = entryrows.ledger
+		WHERE ledgers.is_accounts AND entryrows.account IS NULL
+		ORDER BY entryrows.entry, entryrows.id;`)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []IntegrityProblem
+	for _, row := range rows {
+		result = append(result, IntegrityProblem{
+			Kind:    MISSINGACCOUNT,
+			Details: fmt.Sprintf("Entry %d has row %d on the accounts ledger without an account", row.Entry, row.Id),
+			Entry:   &row.Entry,
+		})
+	}
+
+	return result, nil
+}
+
+// For the accounts ledger, reconciling happens per account, see checkReconciledAccounts.
+// Rows reconciled against a statement add up to its closing balance instead, see checkStatements,
+// and rows in a reconciliation group are checked per group, see checkReconciliationGroups.
+func checkReconciledLedgers(DB *sqlx.DB) ([]IntegrityProblem, error) {
+	var ledgers []struct {
+		Id    int           `db:"id"`
+		Name  string        `db:"name"`
+		Total CurrencyValue `db:"total"`
+	}
+	err := DB.Select(&ledgers, `SELECT ledgers.id, ledgers.name, SUM(entryrows.value) AS total
+		FROM entryrows JOIN ledgers ON ledgers.id = entryrows.ledger
+		WHERE entryrows.reconciled AND NOT ledgers.is_accounts AND entryrows.statement IS NULL AND entryrows.reconciliation IS NULL
+		GROUP BY ledgers.id HAVING total != 0
+		ORDER BY ledgers.id;`)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []IntegrityProblem
+	for _, ledger := range ledgers {
+		result = append(result, IntegrityProblem{
+			Kind:    UNBALANCEDRECONCILED,
+			Details: fmt.Sprintf("Reconciled rows on ledger %s (%d) total %s", ledger.Name, ledger.Id, ledger.Total),
+			Ledger:  &ledger.Id,
+		})
+	}
+
+	return result, nil
+}
+
+// Rows in a reconciliation group are checked per group, see checkReconciliationGroups
+func checkReconciledAccounts(DB *sqlx.DB) ([]IntegrityProblem, error) {
+	var accounts []struct {
+		Id    int           `db:"id"`
+		Name  string        `db:"name"`
+		Total CurrencyValue `db:"total"`
+	}
+	err := DB.Select(&accounts, `SELECT accounts.id, accounts.name, SUM(entryrows.value) AS total
+		FROM entryrows
+		JOIN ledgers ON ledgers.id = entryrows.ledger
+		JOIN accounts ON accounts.id = entryrows.account
+		WHERE entryrows.reconciled AND ledgers.is_accounts AND entryrows.reconciliation IS NULL
+		GROUP BY accounts.id HAVING total != 0
+		ORDER BY accounts.id;`)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []IntegrityProblem
+	for _, account := range accounts {
+		result = append(result, IntegrityProblem{
+			Kind:    UNBALANCEDRECONCILED,
+			Details: fmt.Sprintf("Reconciled rows of account %s (%d) total %s", account.Name, account.Id, account.Total),
+			Account: &account.Id,
+		})
+	}
+
+	return result, nil
+}
+
+// A reconciled group settles its rows, so they total 0, see MatchRows
+func checkReconciliationGroups(DB *sqlx.DB) ([]IntegrityProblem, error) {
+	var groups []struct {
+		Id     int           `db:"id"`
+		Ledger int           `db:"ledger"`
+		Total  CurrencyValue `db:"total"`
+	}
+	err := DB.Select(&groups, `SELECT reconciliation AS id, MIN(ledger) AS ledger, SUM(value) AS total
+		FROM entryrows
+		WHERE reconciliation IS NOT NULL AND reconciled
+		GROUP BY reconciliation HAVING total != 0
+		ORDER BY reconciliation;`)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []IntegrityProblem
+	for _, group := range groups {
+		result = append(result, IntegrityProblem{
+			Kind:    UNBALANCEDRECONCILED,
+			Details: fmt.Sprintf("Reconciled rows of group %d total %s", group.Id, group.Total),
+			Ledger:  &group.Ledger,
+		})
+	}
+
+	return result, nil
+}
+
+// The reconciled rows of a ledger add up to the closing balance of the last statement it was reconciled against
+func checkStatements(DB *sqlx.DB) ([]IntegrityProblem, error) {
+	var ledgers []struct {
+		Id             int           `db:"id"`
+		Name           string        `db:"name"`
+		Date           Date          `db:"date"`
+		ClosingBalance CurrencyValue `db:"closing_balance"`
+		Total          CurrencyValue `db:"total"`
+	}
+	err := DB.Select(&ledgers, `SELECT ledgers.id, ledgers.name, statements.date, statements.closing_balance,
+			(SELECT COALESCE(SUM(`+inOwnCurrencySQL+`), 0) FROM entryrows WHERE entryrows.ledger = ledgers.id AND entryrows.reconciled) AS total
+		FROM statements JOIN ledgers ON ledgers.id = statements.ledger
+		WHERE statements.id = (SELECT id FROM statements AS last WHERE last.ledger = statements.ledger ORDER BY date DESC, id DESC LIMIT 1)
+			AND total != statements.closing_balance
+		ORDER BY ledgers.id;`)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []IntegrityProblem
+	for _, ledger := range ledgers {
+		result = append(result, IntegrityProblem{
+			Kind:    UNBALANCEDRECONCILED,
+			Details: fmt.Sprintf("Reconciled rows on ledger %s (%d) total %s, but its statement of %s closes at %s", ledger.Name, ledger.Id, ledger.Total, ledger.Date, ledger.ClosingBalance),
+			Ledger:  &ledger.Id,
+		})
+	}
+
+	return result, nil
+}
+
+func checkEmptyEntries(DB *sqlx.DB) ([]IntegrityProblem, error) {
+	var entries []int
+	err := DB.Select(&entries, `SELECT id FROM entries
+		WHERE NOT EXISTS (SELECT 1 FROM entryrows WHERE entryrows.entry = entries.id)
+		ORDER BY id;`)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []IntegrityProblem
+	for _, entry := range entries {
+		result = append(result, IntegrityProblem{
+			Kind:    EMPTYENTRY,
+			Details: fmt.Sprintf("Entry %d has no rows", entry),
+			Entry:   &entry,
+		})
+	}
+
+	return result, nil
+}
+
+// Foreign keys normally prevent these, but they aren't enforced on every connection
+func checkDanglingReferences(DB *sqlx.DB) ([]IntegrityProblem, error) {
+	var references []struct {
+		Entry   int    `db:"entry"`
+		Details string `db:"details"`
+	}
+	err := DB.Select(&references, `
+		SELECT entry, 'Row ' || id || ' references deleted ledger ' || ledger AS details FROM entryrows
+		WHERE ledger NOT IN (SELECT id FROM ledgers)
+		UNION ALL
+		SELECT entry, 'Row ' || id || ' references deleted account ' || account AS details FROM entryrows
+		WHERE account IS NOT NULL AND account NOT IN (SELECT id FROM accounts)
+		UNION ALL
+		SELECT id AS entry, 'Entry references deleted journal ' || journal AS details FROM entries
+		WHERE journal NOT IN (SELECT id FROM journals)
+		ORDER BY entry;`)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []IntegrityProblem
+	for _, reference := range references {
+		result = append(result, IntegrityProblem{
+			Kind:    DANGLINGREFERENCE,
+			Details: fmt.Sprintf("Entry %d: %s", reference.Entry, reference.Details),
+			Entry:   &reference.Entry,
+		})
+	}
+
+	return result, nil
+}
+
+// Posted invoices point to the entry posting them, see PostInvoice
+func checkInvoiceEntries(DB *sqlx.DB) ([]IntegrityProblem, error) {
+	var invoices []Invoice
+	err := DB.Select(&invoices, `SELECT * FROM invoices
+		WHERE status != $1 AND (entry IS NULL OR entry NOT IN (SELECT id FROM entries))
+		ORDER BY id;`, DRAFTINVOICE)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []IntegrityProblem
+	for _, invoice := range invoices {
+		details := fmt.Sprintf("%s is %s, but its entry was deleted", invoice.Name(), strings.ToLower(string(invoice.Status)))
+		if invoice.Entry == nil {
+			details = fmt.Sprintf("%s is %s, but has no entry", invoice.Name(), strings.ToLower(string(invoice.Status)))
+		}
+
+		result = append(result, IntegrityProblem{
+			Kind:    DANGLINGREFERENCE,
+			Details: details,
+			Account: &invoice.Account,
+		})
+	}
+
+	return result, nil
+}
+
+// Hashes every stored file again, so damage to the book shows up before the file is needed
+func checkAttachments(DB *sqlx.DB) ([]IntegrityProblem, error) {
+	var attachments []Attachment
+	err := DB.Select(&attachments, `SELECT * FROM attachments
+		WHERE entry IN (SELECT id FROM entries)
+		ORDER BY entry, id;`)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []IntegrityProblem
+	for _, attachment := range attachments {
+		_, err := ReadAttachment(DB, attachment)
+		if err == nil {
+			continue
+		}
+
+		result = append(result, IntegrityProblem{
+			Kind:    CORRUPTATTACHMENT,
+			Details: fmt.Sprintf("Entry %d: attachment %s is missing or doesn't match its hash", attachment.Entry, attachment.Name),
+			Entry:   &attachment.Entry,
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,71 @@
+package database_test
+
+import (
+	"terminaccounting/database"
+	"terminaccounting/tat"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckIntegrity_NoProblems(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	journal := insertTestJournal(t, DB)
+	ledger := insertTestLedger(t, DB)
+
+	entry := database.Entry{Journal: journal.Id}
+	_, err := entry.Insert(DB, []database.EntryRow{
+		{Ledger: ledger.Id, Value: 1000, Reconciled: true},
+		{Ledger: ledger.Id, Value: -1000, Reconciled: true},
+	})
+	require.NoError(t, err)
+
+	problems, err := database.CheckIntegrity(DB)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	// The validation in Insert prevents most of these, so write them directly like an older version might have
+	_, err := DB.Exec(`
+		INSERT INTO ledgers (name, type, notes, is_accounts) VALUES ('Bank', 2, '[]', 0), ('Debtors', 2, '[]', 1);
+		INSERT INTO accounts (name, type, banknumbers, notes) VALUES ('Customer', 0, '[]', '[]');
+		INSERT INTO journals (name, type, notes) VALUES ('General', 3, '[]');
+		INSERT INTO entries (journal, notes, suspense) VALUES (1, '[]', 0), (1, '[]', 1), (1, '[]', 0), (1, '[]', 0), (9, '[]', 0), (1, '[]', 0);
+		INSERT INTO reconciliation_groups (id) VALUES (1), (2);
+		INSERT INTO entryrows (entry, date, ledger, account, description, document, value, reconciled, reconciliation) VALUES
+			(1, '2024-01-01', 1, NULL, '', NULL, 1000, 1, NULL),
+			(1, '2024-01-01', 2, 1, '', NULL, -500, 1, NULL),
+			(2, '2024-01-01', 1, NULL, '', NULL, 300, 0, NULL),
+			(3, '2024-01-01', 2, NULL, '', NULL, 500, 0, NULL),
+			(3, '2024-01-01', 7, NULL, '', NULL, -500, 0, NULL),
+			(5, '2024-01-01', 1, 8, '', NULL, 0, 0, NULL),
+			(6, '2024-01-01', 1, NULL, '', NULL, 200, 1, 1),
+			(6, '2024-01-01', 1, NULL, '', NULL, -200, 0, NULL),
+			(6, '2024-01-01', 2, 1, '', NULL, 300, 1, 2),
+			(6, '2024-01-01', 2, 1, '', NULL, -300, 0, NULL);`)
+	require.NoError(t, err)
+
+	problems, err := database.CheckIntegrity(DB)
+	require.NoError(t, err)
+
+	one, two, three, four, five := 1, 2, 3, 4, 5
+	assert.Equal(t, []database.IntegrityProblem{
+		{Kind: database.UNBALANCEDENTRY, Details: "Entry 1 has rows totalling 5.00", Entry: &one},
+		{Kind: database.UNFINISHEDSUSPENSE, Details: "Entry 2 has rows totalling 3.00", Entry: &two},
+		{Kind: database.MISSINGACCOUNT, Details: "Entry 3 has row 4 on the accounts ledger without an account", Entry: &three},
+		{Kind: database.UNBALANCEDRECONCILED, Details: "Reconciled rows on ledger Bank (1) total 10.00", Ledger: &one},
+		{Kind: database.UNBALANCEDRECONCILED, Details: "Reconciled rows of account Customer (1) total -5.00", Account: &one},
+		// Grouped rows are only reported per group, not again for their ledger or account
+		{Kind: database.UNBALANCEDRECONCILED, Details: "Reconciled rows of group 1 total 2.00", Ledger: &one},
+		{Kind: database.UNBALANCEDRECONCILED, Details: "Reconciled rows of group 2 total 3.00", Ledger: &two},
+		{Kind: database.EMPTYENTRY, Details: "Entry 4 has no rows", Entry: &four},
+		{Kind: database.DANGLINGREFERENCE, Details: "Entry 3: Row 5 references deleted ledger 7", Entry: &three},
+		{Kind: database.DANGLINGREFERENCE, Details: "Entry 5: Row 6 references deleted account 8", Entry: &five},
+		{Kind: database.DANGLINGREFERENCE, Details: "Entry 5: Entry references deleted journal 9", Entry: &five},
+	}, problems)
+}
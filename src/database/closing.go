@@ -0,0 +1,215 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"terminaccounting/meta"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// The entries that close a year, as computed by ProposeYearEnd.
+// Posting them leaves the cumulative balances of every ledger as they were at the start of the next year,
+// except that the results of INCOME and EXPENSE ledgers have moved to the equity ledger.
+type YearEndClosing struct {
+	Year         int
+	EquityLedger int
+
+	// Balance per LedgerType at the end of the year, before closing.
+	// For INCOME and EXPENSE this is the result that gets closed.
+	Totals map[LedgerType]CurrencyValue
+
+	// Moves the INCOME and EXPENSE balances into the equity ledger, dated the last day of the year
+	Closing []EntryRow
+	// Zeroes the ASSET, LIABILITY and EQUITY balances, dated the last day of the year
+	BalanceClosing []EntryRow
+	// Reverses BalanceClosing on the first day of the next year, these are the opening balances
+	Opening []EntryRow
+}
+
+// Whether closing the year would change anything, e.g. false when it was already closed
+func (yec YearEndClosing) IsEmpty() bool {
+	return len(yec.Closing) == 0 && len(yec.BalanceClosing) == 0
+}
+
+type ledgerBalance struct {
+	Ledger  int           `db:"ledger"`
+	Account *int          `db:"account"`
+	Type    LedgerType    `db:"type"`
+	Total   CurrencyValue `db:"total"`
+}
+
+// Computes the closing of the given year into the equity ledger.
+// Balances include all rows up to the end of the year, so any earlier years that weren't closed are closed along with it.
+func ProposeYearEnd(DB *sqlx.DB, year int, equityLedger Ledger) (YearEndClosing, error) {
+	if equityLedger.Type != EQUITYLEDGER {
+		return YearEndClosing{}, fmt.Errorf("ledger %s isn't an equity ledger", equityLedger)
+	}
+
+	yearEnd := Date(time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC))
+	nextYearStart := Date(time.Date(year+1, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+	var balances []ledgerBalance
+	err := DB.Select(&balances, `SELECT entryrows.ledger, entryrows.account, ledgers.type, SUM(entryrows.value) AS total
+		FROM entryrows JOIN ledgers ON ledgers.id = entryrows.ledger
+		WHERE entryrows.date <= $1
+		GROUP BY entryrows.ledger, entryrows.account
+		HAVING total != 0
+		ORDER BY entryrows.ledger, entryrows.account;`, yearEnd)
+	if err != nil {
+		return YearEndClosing{}, fmt.Errorf("FAILED TO SELECT BALANCES: %v", err)
+	}
+
+	result := YearEndClosing{
+		Year:         year,
+		EquityLedger: equityLedger.Id,
+		Totals:       make(map[LedgerType]CurrencyValue),
+	}
+
+	var yearResult CurrencyValue
+	for _, balance := range balances {
+		result.Totals[balance.Type] = result.Totals[balance.Type].Add(balance.Total)
+
+		if balance.Type != INCOMELEDGER && balance.Type != EXPENSELEDGER {
+			continue
+		}
+
+		yearResult = yearResult.Add(balance.Total)
+		result.Closing = append(result.Closing, EntryRow{
+			Date:        yearEnd,
+			Ledger:      balance.Ledger,
+			Account:     balance.Account,
+			Description: fmt.Sprintf("Close %d result", year),
+			Value:       -balance.Total,
+		})
+	}
+
+	if len(result.Closing) != 0 {
+		result.Closing = append(result.Closing, EntryRow{
+			Date:        yearEnd,
+			Ledger:      equityLedger.Id,
+			Description: fmt.Sprintf("Result %d", year),
+			Value:       yearResult,
+		})
+	}
+
+	var balanceTotal CurrencyValue
+	equityHasBalance := false
+	for _, balance := range balances {
+		if balance.Type == INCOMELEDGER || balance.Type == EXPENSELEDGER {
+			continue
+		}
+
+		total := balance.Total
+		if balance.Ledger == equityLedger.Id && balance.Account == nil {
+			// Already includes the result from the closing entry
+			total = total.Add(yearResult)
+			equityHasBalance = true
+		}
+
+		balanceTotal = balanceTotal.Add(total)
+		result.BalanceClosing = appendBalanceTransfer(result.BalanceClosing, balance, -total, yearEnd, fmt.Sprintf("Closing balance %d", year))
+		result.Opening = appendBalanceTransfer(result.Opening, balance, total, nextYearStart, fmt.Sprintf("Opening balance %d", year+1))
+	}
+
+	// The equity ledger might not have had a balance of its own yet
+	if !equityHasBalance && yearResult != 0 {
+		equityBalance := ledgerBalance{Ledger: equityLedger.Id, Type: EQUITYLEDGER}
+
+		balanceTotal = balanceTotal.Add(yearResult)
+		result.BalanceClosing = appendBalanceTransfer(result.BalanceClosing, equityBalance, -yearResult, yearEnd, fmt.Sprintf("Closing balance %d", year))
+		result.Opening = appendBalanceTransfer(result.Opening, equityBalance, yearResult, nextYearStart, fmt.Sprintf("Opening balance %d", year+1))
+	}
+
+	if balanceTotal != 0 {
+		return YearEndClosing{}, fmt.Errorf("the books don't balance at the end of %d, they total %s; use :check to find out why", year, balanceTotal)
+	}
+
+	return result, nil
+}
+
+// The balance closing and opening rows cancel each other out, so they're reconciled right away.
+// PostYearEnd puts each pair in a reconciliation group of its own.
+func appendBalanceTransfer(rows []EntryRow, balance ledgerBalance, value CurrencyValue, date Date, description string) []EntryRow {
+	if value == 0 {
+		return rows
+	}
+
+	return append(rows, EntryRow{
+		Date:        date,
+		Ledger:      balance.Ledger,
+		Account:     balance.Account,
+		Description: description,
+		Value:       value,
+		Reconciled:  true,
+	})
+}
+
+// Posts the closing to the given GENERAL journal in a single transaction.
+// Returns the ids of the created entries, skipping any that had no rows.
+func PostYearEnd(DB *sqlx.DB, closing YearEndClosing, journal Journal) ([]int, error) {
+	if journal.Type != GENERALJOURNAL {
+		return nil, fmt.Errorf("journal %s isn't a general journal", journal)
+	}
+
+	if closing.IsEmpty() {
+		return nil, errors.New("nothing to close")
+	}
+
+	transaction, err := DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer transaction.Rollback()
+
+	entries := []struct {
+		note string
+		rows []EntryRow
+	}{
+		{fmt.Sprintf("Year-end closing %d: result to equity", closing.Year), closing.Closing},
+		{fmt.Sprintf("Year-end closing %d: balance sheet", closing.Year), closing.BalanceClosing},
+		{fmt.Sprintf("Opening balances %d", closing.Year+1), closing.Opening},
+	}
+
+	var result []int
+	// The rows as inserted, per entry
+	var posted [][]EntryRow
+	for _, toPost := range entries {
+		if len(toPost.rows) == 0 {
+			posted = append(posted, nil)
+			continue
+		}
+
+		entry := Entry{Journal: journal.Id, Notes: meta.Notes{toPost.note}}
+		id, err := entry.insert(transaction, toPost.rows)
+		if err != nil {
+			return nil, err
+		}
+
+		var rows []EntryRow
+		err = transaction.Select(&rows, `SELECT * FROM entryrows WHERE entry = $1 ORDER BY id;`, id)
+		if err != nil {
+			return nil, fmt.Errorf("FAILED TO SELECT ROWS OF ENTRY %d: %v", id, err)
+		}
+
+		posted = append(posted, rows)
+		result = append(result, id)
+	}
+
+	// Reconciled rows settle each other in a group, the closing and opening rows of a balance are one
+	balanceClosing, opening := posted[1], posted[2]
+	for i := range balanceClosing {
+		_, _, err := assignReconciliationGroups(transaction, []*EntryRow{&balanceClosing[i], &opening[i]})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,160 @@
+package database_test
+
+import (
+	"terminaccounting/database"
+	"terminaccounting/meta"
+	"terminaccounting/tat"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestYearEnd(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	insertLedger := func(name string, ledgerType database.LedgerType) database.Ledger {
+		ledger := database.Ledger{Name: name, Type: ledgerType, Notes: meta.Notes{}}
+		id, err := ledger.Insert(DB)
+		require.NoError(t, err)
+		ledger.Id = id
+
+		return ledger
+	}
+	sales := insertLedger("Sales", database.INCOMELEDGER)
+	costs := insertLedger("Costs", database.EXPENSELEDGER)
+	bank := insertLedger("Bank", database.ASSETLEDGER)
+	loan := insertLedger("Loan", database.LIABILITYLEDGER)
+	equity := insertLedger("Equity", database.EQUITYLEDGER)
+
+	journal := database.Journal{Name: "General", Type: database.GENERALJOURNAL, Notes: meta.Notes{}}
+	journalId, err := journal.Insert(DB)
+	require.NoError(t, err)
+	journal.Id = journalId
+
+	date := func(input string) database.Date {
+		result, err := database.ToDate(input)
+		require.NoError(t, err)
+
+		return result
+	}
+	insertEntry := func(rows ...database.EntryRow) {
+		_, err := database.Entry{Journal: journal.Id}.Insert(DB, rows)
+		require.NoError(t, err)
+	}
+
+	insertEntry(
+		database.EntryRow{Date: date("24-01-10"), Ledger: bank.Id, Value: 50000},
+		database.EntryRow{Date: date("24-01-10"), Ledger: loan.Id, Value: -50000},
+	)
+	insertEntry(
+		database.EntryRow{Date: date("24-06-01"), Ledger: bank.Id, Value: 10000},
+		database.EntryRow{Date: date("24-06-01"), Ledger: sales.Id, Value: -10000},
+	)
+	insertEntry(
+		database.EntryRow{Date: date("24-07-01"), Ledger: costs.Id, Value: 4000},
+		database.EntryRow{Date: date("24-07-01"), Ledger: bank.Id, Value: -4000},
+	)
+	// Next year, mustn't be closed
+	insertEntry(
+		database.EntryRow{Date: date("25-02-01"), Ledger: bank.Id, Value: 700},
+		database.EntryRow{Date: date("25-02-01"), Ledger: sales.Id, Value: -700},
+	)
+
+	closing, err := database.ProposeYearEnd(DB, 2024, equity)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[database.LedgerType]database.CurrencyValue{
+		database.INCOMELEDGER:    -10000,
+		database.EXPENSELEDGER:   4000,
+		database.ASSETLEDGER:     56000,
+		database.LIABILITYLEDGER: -50000,
+	}, closing.Totals)
+
+	yearEnd, nextYear := date("24-12-31"), date("25-01-01")
+	assert.Equal(t, []database.EntryRow{
+		{Date: yearEnd, Ledger: sales.Id, Description: "Close 2024 result", Value: 10000},
+		{Date: yearEnd, Ledger: costs.Id, Description: "Close 2024 result", Value: -4000},
+		{Date: yearEnd, Ledger: equity.Id, Description: "Result 2024", Value: -6000},
+	}, closing.Closing)
+	assert.Equal(t, []database.EntryRow{
+		{Date: yearEnd, Ledger: bank.Id, Description: "Closing balance 2024", Value: -56000, Reconciled: true},
+		{Date: yearEnd, Ledger: loan.Id, Description: "Closing balance 2024", Value: 50000, Reconciled: true},
+		{Date: yearEnd, Ledger: equity.Id, Description: "Closing balance 2024", Value: 6000, Reconciled: true},
+	}, closing.BalanceClosing)
+	assert.Equal(t, []database.EntryRow{
+		{Date: nextYear, Ledger: bank.Id, Description: "Opening balance 2025", Value: 56000, Reconciled: true},
+		{Date: nextYear, Ledger: loan.Id, Description: "Opening balance 2025", Value: -50000, Reconciled: true},
+		{Date: nextYear, Ledger: equity.Id, Description: "Opening balance 2025", Value: -6000, Reconciled: true},
+	}, closing.Opening)
+
+	ids, err := database.PostYearEnd(DB, closing, journal)
+	require.NoError(t, err)
+	assert.Len(t, ids, 3)
+
+	t.Run("balances carry over", func(t *testing.T) {
+		balance := func(ledger database.Ledger) database.CurrencyValue {
+			rows, err := database.SelectRowsByLedger(DB, ledger.Id)
+			require.NoError(t, err)
+
+			var total database.CurrencyValue
+			for _, row := range rows {
+				total = total.Add(row.Value)
+			}
+
+			return total
+		}
+
+		assert.Equal(t, database.CurrencyValue(-700), balance(sales))
+		assert.Equal(t, database.CurrencyValue(0), balance(costs))
+		assert.Equal(t, database.CurrencyValue(56700), balance(bank))
+		assert.Equal(t, database.CurrencyValue(-50000), balance(loan))
+		assert.Equal(t, database.CurrencyValue(-6000), balance(equity))
+	})
+
+	t.Run("closing and opening balances are matched", func(t *testing.T) {
+		closingRows, err := database.SelectRowsByEntry(DB, ids[1])
+		require.NoError(t, err)
+		openingRows, err := database.SelectRowsByEntry(DB, ids[2])
+		require.NoError(t, err)
+		require.Len(t, openingRows, len(closingRows))
+
+		for i := range closingRows {
+			assert.True(t, closingRows[i].Reconciled)
+			require.NotNil(t, closingRows[i].Reconciliation)
+			assert.Equal(t, closingRows[i].Reconciliation, openingRows[i].Reconciliation)
+		}
+
+		problems, err := database.CheckIntegrity(DB)
+		require.NoError(t, err)
+		assert.Empty(t, problems)
+	})
+
+	t.Run("closing twice does nothing", func(t *testing.T) {
+		closing, err := database.ProposeYearEnd(DB, 2024, equity)
+		require.NoError(t, err)
+		assert.True(t, closing.IsEmpty())
+
+		_, err = database.PostYearEnd(DB, closing, journal)
+		assert.EqualError(t, err, "nothing to close")
+	})
+}
+
+func TestYearEnd_Invalid(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	ledger := insertTestLedger(t, DB)
+	journal := insertTestJournal(t, DB)
+	// Suspense entry leaves the books unbalanced
+	insertTestEntry(t, DB, journal.Id, ledger.Id)
+
+	_, err := database.ProposeYearEnd(DB, 2024, ledger)
+	assert.EqualError(t, err, "ledger test ledger (1) isn't an equity ledger")
+
+	equity := database.Ledger{Name: "Equity", Type: database.EQUITYLEDGER}
+	_, err = database.ProposeYearEnd(DB, 2024, equity)
+	assert.EqualError(t, err, "the books don't balance at the end of 2024, they total 10.00; use :check to find out why")
+
+	_, err = database.PostYearEnd(DB, database.YearEndClosing{}, database.Journal{Name: "Sales", Type: database.INCOMEJOURNAL})
+	assert.ErrorContains(t, err, "isn't a general journal")
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Globally accessible list of exchange rates, sorted by currency and then date
+// Atomic for parallel tests
+var exchangeRatesCache atomic.Pointer[[]ExchangeRate]
+
+func AvailableExchangeRates() []ExchangeRate {
+	return *exchangeRatesCache.Load()
+}
+
+// The empty currency is the home currency, in which every row's Value is booked.
+// Anything else is a three letter code like "USD".
+const HOMECURRENCY = ""
+
+var currencyRegex = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Accepts three letter codes in any case, or an empty string for the home currency
+func ParseCurrency(input string) (string, error) {
+	input = strings.ToUpper(strings.TrimSpace(input))
+
+	if input == HOMECURRENCY {
+		return HOMECURRENCY, nil
+	}
+
+	if !currencyRegex.MatchString(input) {
+		return "", fmt.Errorf("invalid currency %q, expected a three letter code like USD", input)
+	}
+
+	return input, nil
+}
+
+// The currency amounts on a row are in: the account's if it has one, the ledger's otherwise
+func CurrencyOf(ledger Ledger, account *Account) string {
+	if account != nil && account.Currency != HOMECURRENCY {
+		return account.Currency
+	}
+
+	return ledger.Currency
+}
+
+// How much one unit of Currency was worth in the home currency, from Date until the next rate
+type ExchangeRate struct {
+	Id       int     `db:"id"`
+	Currency string  `db:"currency"`
+	Date     Date    `db:"date"`
+	Rate     float64 `db:"rate"`
+}
+
+func (er ExchangeRate) String() string {
+	return fmt.Sprintf("%s %s at %s", er.Currency, er.Date, strconv.FormatFloat(er.Rate, 'f', -1, 64))
+}
+
+// Converts a foreign amount to the home currency, rounded to the nearest cent
+func (er ExchangeRate) ToHome(value CurrencyValue) CurrencyValue {
+	return CurrencyValue(math.Round(float64(value) * er.Rate))
+}
+
+// Sets the rate of a currency from a date on, replacing any rate already set on that date
+func SetExchangeRate(DB *sqlx.DB, rate ExchangeRate) error {
+	if rate.Currency == HOMECURRENCY {
+		return fmt.Errorf("the home currency has no exchange rate")
+	}
+
+	if rate.Rate <= 0 || math.IsInf(rate.Rate, 0) || math.IsNaN(rate.Rate) {
+		return fmt.Errorf("invalid exchange rate %v, must be positive", rate.Rate)
+	}
+
+	_, err := DB.NamedExec(`INSERT INTO exchange_rates (currency, date, rate)
+		VALUES (:currency, :date, :rate)
+		ON CONFLICT (currency, date) DO UPDATE SET rate = excluded.rate;`, rate)
+	if err != nil {
+		return fmt.Errorf("FAILED TO SET EXCHANGE RATE: %v", err)
+	}
+
+	return UpdateExchangeRatesCache(DB)
+}
+
+func SelectExchangeRates(DB *sqlx.DB) ([]ExchangeRate, error) {
+	result := []ExchangeRate{}
+
+	err := DB.Select(&result, `SELECT * FROM exchange_rates ORDER BY currency, date;`)
+
+	return result, err
+}
+
+func UpdateExchangeRatesCache(DB *sqlx.DB) error {
+	rates, err := SelectExchangeRates(DB)
+	if err != nil {
+		return err
+	}
+
+	exchangeRatesCache.Swap(&rates)
+
+	return nil
+}
+
+// The rate of the currency on the date, i.e. the last one set on or before it.
+// Reads the cache.
+func ExchangeRateOn(currency string, date Date) (ExchangeRate, error) {
+	return exchangeRateOn(AvailableExchangeRates(), currency, date)
+}
+
+// Expects rates sorted by currency and date
+func exchangeRateOn(rates []ExchangeRate, currency string, date Date) (ExchangeRate, error) {
+	var result *ExchangeRate
+	for _, rate := range rates {
+		if rate.Currency == currency && !time.Time(rate.Date).After(time.Time(date)) {
+			result = &rate
+		}
+	}
+
+	if result != nil {
+		return *result, nil
+	}
+
+	return ExchangeRate{}, fmt.Errorf("no %s exchange rate on or before %s, add one with :rate %s %s <rate>", currency, date, currency, date)
+}
+
+// The balance of the rows in one currency
+type CurrencyTotal struct {
+	Currency string
+	// Sum of the amounts in the currency itself
+	Foreign CurrencyValue
+	// Sum of the values booked in the home currency, includes revaluations
+	Booked CurrencyValue
+}
+
+// The amount of the row in its own currency. Revaluations only adjust the value in the home currency, so they're 0 in it.
+func (er EntryRow) InOwnCurrency() CurrencyValue {
+	if er.Currency == HOMECURRENCY {
+		return er.Value
+	}
+
+	if er.ForeignValue == nil {
+		return 0
+	}
+
+	return *er.ForeignValue
+}
+
+// The SQL counterpart of EntryRow.InOwnCurrency
+const inOwnCurrencySQL = `CASE WHEN currency = '' THEN value ELSE COALESCE(foreign_value, 0) END`
+
+// Totals of the rows per foreign currency, sorted by currency. Home currency rows are left out.
+func CalculateCurrencyTotals(rows []*EntryRow) []CurrencyTotal {
+	totals := make(map[string]CurrencyTotal)
+
+	for _, row := range rows {
+		if row.Currency == HOMECURRENCY {
+			continue
+		}
+
+		total := totals[row.Currency]
+		total.Currency = row.Currency
+		if row.ForeignValue != nil {
+			total.Foreign = total.Foreign.Add(*row.ForeignValue)
+		}
+		total.Booked = total.Booked.Add(row.Value)
+		totals[row.Currency] = total
+	}
+
+	var result []CurrencyTotal
+	for _, total := range totals {
+		result = append(result, total)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Currency < result[j].Currency
+	})
+
+	return result
+}
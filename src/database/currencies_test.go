@@ -0,0 +1,195 @@
+package database_test
+
+import (
+	"terminaccounting/database"
+	"terminaccounting/meta"
+	"terminaccounting/tat"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCurrency(t *testing.T) {
+	for input, expected := range map[string]string{"": database.HOMECURRENCY, "usd": "USD", " GBP ": "GBP"} {
+		result, err := database.ParseCurrency(input)
+		require.NoError(t, err, "input: %q", input)
+		assert.Equal(t, expected, result, "input: %q", input)
+	}
+
+	for _, input := range []string{"US", "USDT", "U$D", "dollar"} {
+		_, err := database.ParseCurrency(input)
+		assert.Error(t, err, "input: %q", input)
+	}
+}
+
+func TestExchangeRateOn(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	date := func(input string) database.Date {
+		result, err := database.ToDate(input)
+		require.NoError(t, err)
+
+		return result
+	}
+
+	require.NoError(t, database.SetExchangeRate(DB, database.ExchangeRate{Currency: "USD", Date: date("24-01-01"), Rate: 0.9}))
+	require.NoError(t, database.SetExchangeRate(DB, database.ExchangeRate{Currency: "USD", Date: date("24-03-01"), Rate: 0.95}))
+	require.NoError(t, database.SetExchangeRate(DB, database.ExchangeRate{Currency: "GBP", Date: date("24-02-01"), Rate: 1.15}))
+	// Replaces the rate on the same date
+	require.NoError(t, database.SetExchangeRate(DB, database.ExchangeRate{Currency: "USD", Date: date("24-03-01"), Rate: 0.92}))
+
+	rate, err := database.ExchangeRateOn("USD", date("24-02-15"))
+	require.NoError(t, err)
+	assert.Equal(t, 0.9, rate.Rate)
+
+	rate, err = database.ExchangeRateOn("USD", date("24-03-01"))
+	require.NoError(t, err)
+	assert.Equal(t, 0.92, rate.Rate)
+	assert.Equal(t, database.CurrencyValue(9200), rate.ToHome(10000))
+
+	_, err = database.ExchangeRateOn("GBP", date("24-01-31"))
+	assert.EqualError(t, err, "no GBP exchange rate on or before 24-01-31, add one with :rate GBP 24-01-31 <rate>")
+
+	err = database.SetExchangeRate(DB, database.ExchangeRate{Currency: "USD", Date: date("24-01-01"), Rate: 0})
+	assert.EqualError(t, err, "invalid exchange rate 0, must be positive")
+}
+
+func TestCalculateCurrencyTotals(t *testing.T) {
+	amount := func(value database.CurrencyValue) *database.CurrencyValue {
+		return &value
+	}
+
+	rows := []*database.EntryRow{
+		{Value: 500},
+		{Value: 900, Currency: "USD", ForeignValue: amount(1000)},
+		{Value: -450, Currency: "USD", ForeignValue: amount(-500)},
+		{Value: 20, Currency: "USD"},
+		{Value: 1150, Currency: "GBP", ForeignValue: amount(1000)},
+	}
+
+	assert.Equal(t, []database.CurrencyTotal{
+		{Currency: "GBP", Foreign: 1000, Booked: 1150},
+		{Currency: "USD", Foreign: 500, Booked: 470},
+	}, database.CalculateCurrencyTotals(rows))
+}
+
+func TestRevaluation(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	insertLedger := func(name string, ledgerType database.LedgerType, currency string) database.Ledger {
+		ledger := database.Ledger{Name: name, Type: ledgerType, Notes: meta.Notes{}, Currency: currency}
+		id, err := ledger.Insert(DB)
+		require.NoError(t, err)
+		ledger.Id = id
+
+		return ledger
+	}
+	paypal := insertLedger("PayPal", database.ASSETLEDGER, "USD")
+	sales := insertLedger("Sales", database.INCOMELEDGER, database.HOMECURRENCY)
+	fxResult := insertLedger("Exchange rate differences", database.EXPENSELEDGER, database.HOMECURRENCY)
+
+	journal := database.Journal{Name: "General", Type: database.GENERALJOURNAL, Notes: meta.Notes{}}
+	journalId, err := journal.Insert(DB)
+	require.NoError(t, err)
+	journal.Id = journalId
+
+	date := func(input string) database.Date {
+		result, err := database.ToDate(input)
+		require.NoError(t, err)
+
+		return result
+	}
+
+	foreign := database.CurrencyValue(10000)
+	_, err = database.Entry{Journal: journal.Id}.Insert(DB, []database.EntryRow{
+		{Date: date("24-01-10"), Ledger: paypal.Id, Value: 9000, Currency: "USD", ForeignValue: &foreign},
+		{Date: date("24-01-10"), Ledger: sales.Id, Value: -9000},
+	})
+	require.NoError(t, err)
+
+	rows, err := database.SelectRowsByLedger(DB, paypal.Id)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "USD", rows[0].Currency)
+	assert.Equal(t, &foreign, rows[0].ForeignValue)
+
+	_, err = database.ProposeRevaluation(DB, date("24-12-31"), paypal)
+	assert.EqualError(t, err, "ledger PayPal (1) isn't an income or expense ledger")
+
+	_, err = database.ProposeRevaluation(DB, date("24-12-31"), fxResult)
+	assert.EqualError(t, err, "no USD exchange rate on or before 24-12-31, add one with :rate USD 24-12-31 <rate>")
+
+	require.NoError(t, database.SetExchangeRate(DB, database.ExchangeRate{Currency: "USD", Date: date("24-12-31"), Rate: 0.95}))
+
+	revaluation, err := database.ProposeRevaluation(DB, date("24-12-31"), fxResult)
+	require.NoError(t, err)
+	assert.Equal(t, []database.EntryRow{
+		{Date: date("24-12-31"), Ledger: paypal.Id, Description: "Revaluation USD 24-12-31 at 0.95", Value: 500, Currency: "USD"},
+		{Date: date("24-12-31"), Ledger: fxResult.Id, Description: "Exchange rate differences 24-12-31", Value: -500},
+	}, revaluation.Rows)
+
+	_, err = database.PostRevaluation(DB, revaluation, journal)
+	require.NoError(t, err)
+
+	rows, err = database.SelectRowsByLedger(DB, paypal.Id)
+	require.NoError(t, err)
+	rowPointers := []*database.EntryRow{&rows[0], &rows[1]}
+	assert.Equal(t, []database.CurrencyTotal{
+		{Currency: "USD", Foreign: 10000, Booked: 9500},
+	}, database.CalculateCurrencyTotals(rowPointers))
+
+	t.Run("revaluing twice does nothing", func(t *testing.T) {
+		revaluation, err := database.ProposeRevaluation(DB, date("24-12-31"), fxResult)
+		require.NoError(t, err)
+		assert.True(t, revaluation.IsEmpty())
+
+		_, err = database.PostRevaluation(DB, revaluation, journal)
+		assert.EqualError(t, err, "nothing to revalue")
+	})
+}
+
+func TestRevaluation_SkipsIncomeAndExpenses(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	insertLedger := func(name string, ledgerType database.LedgerType, currency string) database.Ledger {
+		ledger := database.Ledger{Name: name, Type: ledgerType, Notes: meta.Notes{}, Currency: currency}
+		id, err := ledger.Insert(DB)
+		require.NoError(t, err)
+		ledger.Id = id
+
+		return ledger
+	}
+	paypal := insertLedger("PayPal", database.ASSETLEDGER, "USD")
+	hosting := insertLedger("Hosting", database.EXPENSELEDGER, "USD")
+	fxResult := insertLedger("Exchange rate differences", database.EXPENSELEDGER, database.HOMECURRENCY)
+
+	journal := database.Journal{Name: "General", Type: database.GENERALJOURNAL, Notes: meta.Notes{}}
+	journalId, err := journal.Insert(DB)
+	require.NoError(t, err)
+
+	date := func(input string) database.Date {
+		result, err := database.ToDate(input)
+		require.NoError(t, err)
+
+		return result
+	}
+
+	// Paying the hosting bill in dollars from PayPal
+	bill := database.CurrencyValue(2000)
+	paid := database.CurrencyValue(-2000)
+	_, err = database.Entry{Journal: journalId}.Insert(DB, []database.EntryRow{
+		{Date: date("24-01-10"), Ledger: hosting.Id, Value: 1800, Currency: "USD", ForeignValue: &bill},
+		{Date: date("24-01-10"), Ledger: paypal.Id, Value: -1800, Currency: "USD", ForeignValue: &paid},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, database.SetExchangeRate(DB, database.ExchangeRate{Currency: "USD", Date: date("24-12-31"), Rate: 0.95}))
+
+	revaluation, err := database.ProposeRevaluation(DB, date("24-12-31"), fxResult)
+	require.NoError(t, err)
+	assert.Equal(t, []database.EntryRow{
+		{Date: date("24-12-31"), Ledger: paypal.Id, Description: "Revaluation USD 24-12-31 at 0.95", Value: -100, Currency: "USD"},
+		{Date: date("24-12-31"), Ledger: fxResult.Id, Description: "Exchange rate differences 24-12-31", Value: 100},
+	}, revaluation.Rows, "the expense stays at the rate it was booked at")
+}
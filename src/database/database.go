@@ -2,13 +2,17 @@ package database
 
 import (
 	"fmt"
-	"log/slog"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 )
 
-func Connect() (*sqlx.DB, error) {
-	DB, err := sqlx.Connect("sqlite3", "file:test.db?cache=shared&mode=rwc&_foreign_keys=on")
+// Opens (or creates) the book at path and makes sure its schemas are set up
+func Connect(path string) (*sqlx.DB, error) {
+	// Characters with special meaning in sqlite URIs have to be escaped
+	escaper := strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23")
+
+	DB, err := sqlx.Connect("sqlite3", fmt.Sprintf("file:%s?cache=shared&mode=rwc&_foreign_keys=on", escaper.Replace(path)))
 	if err != nil {
 		return DB, err
 	}
@@ -34,62 +38,43 @@ func DatabaseTableIsSetUp(DB *sqlx.DB, name string) (bool, error) {
 	return false, nil
 }
 
+// Brings the database schemas up to date, see migrations.go
 func InitSchemas(DB *sqlx.DB) error {
-	changed, err := setupSchemaLedgers(DB)
+	err := MigrateTo(DB, LatestSchemaVersion())
 	if err != nil {
 		return err
 	}
-	if changed {
-		slog.Info("Set up `ledgers` schema")
-	}
 
-	changed, err = setupSchemaAccounts(DB)
-	if err != nil {
-		return err
-	}
-	if changed {
-		slog.Info("Set up `accounts` schema")
-	}
+	return setupSearchIndex(DB)
+}
 
-	changed, err = setupSchemaJournals(DB)
+func UpdateCache(DB *sqlx.DB) error {
+	err := UpdateLedgersCache(DB)
 	if err != nil {
 		return err
 	}
-	if changed {
-		slog.Info("Set up `journals` schema")
-	}
 
-	changed, err = setupSchemaEntries(DB)
+	err = UpdateAccountsCache(DB)
 	if err != nil {
 		return err
 	}
-	if changed {
-		slog.Info("Set up `entries` schema")
-	}
 
-	changed, err = setupSchemaEntryRows(DB)
+	err = UpdateJournalsCache(DB)
 	if err != nil {
 		return err
 	}
-	if changed {
-		slog.Info("Set up `entryrows` schema")
-	}
-
-	return nil
-}
 
-func UpdateCache(DB *sqlx.DB) error {
-	err := UpdateLedgersCache(DB)
+	err = UpdatePeriodsCache(DB)
 	if err != nil {
 		return err
 	}
 
-	err = UpdateAccountsCache(DB)
+	err = UpdateExchangeRatesCache(DB)
 	if err != nil {
 		return err
 	}
 
-	err = UpdateJournalsCache(DB)
+	err = UpdateTaxCodesCache(DB)
 	if err != nil {
 		return err
 	}
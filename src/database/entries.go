@@ -18,6 +18,35 @@ type Entry struct {
 	Id      int        `db:"id"`
 	Journal int        `db:"journal"`
 	Notes   meta.Notes `db:"notes"`
+	// Allows saving the entry while its rows don't sum to zero, for unfinished work
+	Suspense bool `db:"suspense"`
+}
+
+// Returned when saving an entry whose rows don't sum to zero, if it isn't marked as suspense
+type UnbalancedEntryError struct {
+	Total CurrencyValue
+}
+
+func (err UnbalancedEntryError) Error() string {
+	return fmt.Sprintf("entry is unbalanced, its rows total %s instead of 0.00", err.Total)
+}
+
+func (e Entry) checkBalanced(rows []EntryRow) error {
+	if e.Suspense {
+		return nil
+	}
+
+	pointers := make([]*EntryRow, len(rows))
+	for i := range rows {
+		pointers[i] = &rows[i]
+	}
+
+	total := CalculateTotal(pointers)
+	if total != 0 {
+		return UnbalancedEntryError{Total: total}
+	}
+
+	return nil
 }
 
 func (e Entry) FilterValue() string {
@@ -56,6 +85,10 @@ func (e Entry) Title() string {
 }
 
 func (e Entry) Description() string {
+	if e.Suspense {
+		return "SUSPENSE; " + strings.Join(e.Notes, "; ")
+	}
+
 	return strings.Join(e.Notes, "; ")
 }
 
@@ -119,35 +152,39 @@ func CalculateSize(rows []*EntryRow) CurrencyValue {
 	return sum
 }
 
-func setupSchemaEntries(DB *sqlx.DB) (bool, error) {
-	isSetUp, err := DatabaseTableIsSetUp(DB, "entries")
+func (e Entry) Insert(DB *sqlx.DB, rows []EntryRow) (int, error) {
+	transaction, err := DB.Beginx()
 	if err != nil {
-		return false, err
+		return 0, err
 	}
-	if isSetUp {
-		return false, nil
+	defer transaction.Rollback()
+
+	id, err := e.insert(transaction, rows)
+	if err != nil {
+		return 0, err
 	}
 
-	schema := `CREATE TABLE IF NOT EXISTS entries(
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		journal INTEGER NOT NULL,
-		notes TEXT,
-		FOREIGN KEY (journal) REFERENCES journals(id) ON DELETE RESTRICT
-	) STRICT;`
+	err = transaction.Commit()
+	if err != nil {
+		return id, err
+	}
 
-	_, err = DB.Exec(schema)
-	return true, err
+	return id, nil
 }
 
-func (e Entry) Insert(DB *sqlx.DB, rows []EntryRow) (int, error) {
-	transaction, err := DB.Beginx()
-	defer transaction.Rollback()
+// Inserts the entry as part of a larger transaction, for when multiple entries must be created together
+func (e Entry) insert(transaction *sqlx.Tx, rows []EntryRow) (int, error) {
+	err := e.checkBalanced(rows)
+	if err != nil {
+		return 0, err
+	}
 
+	err = checkPeriodsOpen(transaction, rows)
 	if err != nil {
 		return 0, err
 	}
 
-	res, err := transaction.NamedExec(`INSERT INTO entries (journal, notes) VALUES (:journal, :notes)`, e)
+	res, err := transaction.NamedExec(`INSERT INTO entries (journal, notes, suspense) VALUES (:journal, :notes, :suspense)`, e)
 	if err != nil {
 		return 0, err
 	}
@@ -167,58 +204,126 @@ func (e Entry) Insert(DB *sqlx.DB, rows []EntryRow) (int, error) {
 		return 0, err
 	}
 
-	err = transaction.Commit()
+	err = recordEntryVersion(transaction, int(id), INSERTACTION)
 	if err != nil {
-		return int(id), err
+		return 0, err
 	}
 
 	return int(id), nil
 }
 
 func (e Entry) Update(DB *sqlx.DB, rows []EntryRow) error {
+	err := e.checkBalanced(rows)
+	if err != nil {
+		return err
+	}
+
 	query := `UPDATE entries SET
 	journal = :journal,
-	notes = :notes
+	notes = :notes,
+	suspense = :suspense
 	WHERE id = :id;`
 
 	tx := DB.MustBegin()
 	defer tx.Rollback()
 
-	totalChanged := 0
+	// The invoice would stay posted for amounts that aren't booked anymore
+	err = checkNotPostedByInvoice(tx, e.Id, "changed")
+	if err != nil {
+		return err
+	}
 
-	res, err := tx.NamedExec(query, e)
+	// Both moving rows out of and into a locked period count as changing it
+	var oldRows []EntryRow
+	err = tx.Select(&oldRows, `SELECT * FROM entryrows WHERE entry = $1;`, e.Id)
 	if err != nil {
 		return err
 	}
-	changedMain, err := res.RowsAffected()
+	err = checkPeriodsOpen(tx, slices.Concat(oldRows, rows))
 	if err != nil {
 		return err
 	}
-	totalChanged += int(changedMain)
 
-	// Delete all rows and then create new ones
-	// Brute force, but robust way to handle deleting and creating rows
-	res, err = tx.Exec(`DELETE FROM entryrows WHERE entry = $1;`, e.Id)
+	totalChanged := 0
+
+	res, err := tx.NamedExec(query, e)
 	if err != nil {
 		return err
 	}
-	changedDelete, err := res.RowsAffected()
+	changedMain, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
-	totalChanged += int(changedDelete)
+	totalChanged += int(changedMain)
 
-	// Inject the ID's into the new rows
+	// Rows keep their id when edited, so the audit log can follow them across versions.
+	// Rows without an id are new, old rows missing from rows were removed.
+	var newRows []EntryRow
+	keptIds := make(map[int]struct{})
 	for i := range rows {
 		rows[i].Entry = e.Id
+
+		if rows[i].Id == 0 {
+			newRows = append(newRows, rows[i])
+			continue
+		}
+
+		oldIndex := slices.IndexFunc(oldRows, func(old EntryRow) bool { return old.Id == rows[i].Id })
+		if oldIndex == -1 {
+			return fmt.Errorf("FAILED TO UPDATE ENTRY %d: ROW %d ISN'T PART OF IT", e.Id, rows[i].Id)
+		}
+		keptIds[rows[i].Id] = struct{}{}
+
+		err = checkReconciliationKept(oldRows[oldIndex], &rows[i])
+		if err != nil {
+			return err
+		}
+
+		changedUpdate, err := updateRow(tx, rows[i])
+		if err != nil {
+			return err
+		}
+		totalChanged += changedUpdate
+	}
+
+	for _, old := range oldRows {
+		if _, ok := keptIds[old.Id]; ok {
+			continue
+		}
+
+		err = checkReconciliationKept(old, nil)
+		if err != nil {
+			return err
+		}
+
+		res, err = tx.Exec(`DELETE FROM entryrows WHERE id = $1;`, old.Id)
+		if err != nil {
+			return err
+		}
+		changedDelete, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		totalChanged += int(changedDelete)
+
+		// Files attached to the row still document the entry
+		_, err = tx.Exec(`UPDATE attachments SET entryrow = NULL WHERE entryrow = $1;`, old.Id)
+		if err != nil {
+			return err
+		}
 	}
 
-	changedInsert, err := insertRows(tx, rows)
+	changedInsert, err := insertRows(tx, newRows)
 	if err != nil {
 		return err
 	}
 	totalChanged += changedInsert
 
+	err = recordEntryVersion(tx, e.Id, UPDATEACTION)
+	if err != nil {
+		return err
+	}
+
 	tx.Commit()
 
 	slog.Debug("Updated entry", "id", e.Id, "changed", totalChanged)
@@ -226,6 +331,31 @@ func (e Entry) Update(DB *sqlx.DB, rows []EntryRow) error {
 	return nil
 }
 
+// Editing an entry leaves the reconciliation of its rows as it was, that's changed by SetReconciled, MatchRows and SaveStatement.
+// A row matched in a group or reconciled against a statement keeps its amount, ledger and account,
+// otherwise its group or statement wouldn't add up anymore. A nil row means the row is removed.
+func checkReconciliationKept(old EntryRow, row *EntryRow) error {
+	if row != nil {
+		row.Reconciled = old.Reconciled
+		row.Reconciliation = old.Reconciliation
+		row.Statement = old.Statement
+
+		sameAccount := (old.Account == nil) == (row.Account == nil) && (old.Account == nil || *old.Account == *row.Account)
+		if old.Value == row.Value && old.Ledger == row.Ledger && sameAccount {
+			return nil
+		}
+	}
+
+	if old.Reconciliation != nil {
+		return fmt.Errorf("row %d is matched in group %d, :unmatch it before changing its amount, ledger or account or removing it", old.Id, *old.Reconciliation)
+	}
+	if old.Statement != nil {
+		return fmt.Errorf("row %d was reconciled against statement %d, its amount, ledger or account can't change and it can't be removed", old.Id, *old.Statement)
+	}
+
+	return nil
+}
+
 type CurrencyValue int64
 
 func ParseCurrencyValue(input string) (CurrencyValue, error) {
@@ -276,6 +406,16 @@ func ParseCurrencyValue(input string) (CurrencyValue, error) {
 	}
 }
 
+// Parses an amount typed as a command argument, which can't be negative
+func ParseNonNegativeValue(input string) (CurrencyValue, error) {
+	// ParseCurrencyValue panics on negative values
+	if strings.HasPrefix(input, "-") {
+		return 0, fmt.Errorf("%q is negative", input)
+	}
+
+	return ParseCurrencyValue(input)
+}
+
 func (cv CurrencyValue) String() string {
 	whole := cv / 100
 	decimal := cv % 100
@@ -334,6 +474,25 @@ type EntryRow struct {
 	Document    *string       `db:"document"`
 	Value       CurrencyValue `db:"value"`
 	Reconciled  bool          `db:"reconciled"`
+	Tags        Tags          `db:"tags"`
+
+	// The group of rows settling each other, like an invoice and its payments, nil if the row isn't matched.
+	// A group is reconciled once it totals 0, until then it's a partial match.
+	Reconciliation *int `db:"reconciliation"`
+	// The bank statement the row was reconciled against, see SaveStatement
+	Statement *int `db:"statement"`
+	// The id the bank gave the transaction the row was imported from, like an OFX FITID, nil for rows that weren't imported
+	ExternalId *string `db:"external_id"`
+
+	// The currency of the ledger or account, HOMECURRENCY for most rows
+	Currency string `db:"currency"`
+	// The amount in Currency that Value was converted from, Value itself is always in the home currency.
+	// Nil for home currency rows, and for rows that only adjust the booked value like revaluations.
+	ForeignValue *CurrencyValue `db:"foreign_value"`
+
+	// The tax code the row was booked with, nil for rows without tax
+	TaxCode *int    `db:"tax_code"`
+	TaxRole TaxRole `db:"tax_role"`
 }
 
 func (er EntryRow) FilterValue() string {
@@ -367,10 +526,17 @@ func (er EntryRow) FilterValue() string {
 
 	result.WriteString(er.Value.String())
 
+	if er.ForeignValue != nil {
+		result.WriteString(er.Currency)
+		result.WriteString(er.ForeignValue.String())
+	}
+
 	if er.Reconciled {
 		result.WriteString("reconciled")
 	}
 
+	result.WriteString(string(er.Tags))
+
 	return result.String()
 }
 
@@ -389,54 +555,20 @@ func (er EntryRow) Render(isActive bool) string {
 	return style.Render(er.String())
 }
 
-func setupSchemaEntryRows(DB *sqlx.DB) (bool, error) {
-	isSetUp, err := DatabaseTableIsSetUp(DB, "entryrows")
-	if err != nil {
-		return false, err
-	}
-	if isSetUp {
-		return false, nil
-	}
-
-	schema := `CREATE TABLE IF NOT EXISTS entryrows(
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		entry INTEGER NOT NULL,
-		date TEXT NOT NULL,
-		ledger INTEGER NOT NULL,
-		account INTEGER,
-		description TEXT,
-		document TEXT,
-		value INTEGER NOT NULL,
-		reconciled INTEGER NOT NULL,
-		FOREIGN KEY (entry) REFERENCES entries(id) ON DELETE CASCADE,
-		FOREIGN KEY (ledger) REFERENCES ledgers(id) ON DELETE RESTRICT,
-		FOREIGN KEY (account) REFERENCES accounts(id) ON DELETE RESTRICT
-	) STRICT;`
-
-	_, err = DB.Exec(schema)
-	return true, err
-}
-
 func insertRows(transaction *sqlx.Tx, rows []EntryRow) (int, error) {
 	if len(rows) == 0 {
 		return 0, nil
 	}
 
-	accountLedger := GetAccountsLedger()
-
-	// If accountLedger is set, ensure all rows on it have an account set
-	if accountLedger != nil {
-		for i, row := range rows {
-			if row.Ledger == accountLedger.Id && row.Account == nil {
-				return 0, fmt.Errorf("Row %d is on accounts ledger but has no account set", i)
-			}
-		}
+	err := checkRowAccounts(rows)
+	if err != nil {
+		return 0, err
 	}
 
 	query := `INSERT INTO entryrows
-	(entry, date, ledger, account, description, document, value, reconciled)
+	(entry, date, ledger, account, description, document, value, reconciled, tags, currency, foreign_value, tax_code, tax_role, reconciliation, statement, external_id)
 	VALUES
-	(:entry, :date, :ledger, :account, :description, :document, :value, :reconciled);`
+	(:entry, :date, :ledger, :account, :description, :document, :value, :reconciled, :tags, :currency, :foreign_value, :tax_code, :tax_role, :reconciliation, :statement, :external_id);`
 
 	result, err := transaction.NamedExec(query, rows)
 	if err != nil {
@@ -450,6 +582,73 @@ func insertRows(transaction *sqlx.Tx, rows []EntryRow) (int, error) {
 	return int(changed), err
 }
 
+func updateRow(transaction *sqlx.Tx, row EntryRow) (int, error) {
+	err := checkRowAccounts([]EntryRow{row})
+	if err != nil {
+		return 0, err
+	}
+
+	query := `UPDATE entryrows SET
+	date = :date,
+	ledger = :ledger,
+	account = :account,
+	description = :description,
+	document = :document,
+	value = :value,
+	reconciled = :reconciled,
+	tags = :tags,
+	currency = :currency,
+	foreign_value = :foreign_value,
+	tax_code = :tax_code,
+	tax_role = :tax_role,
+	reconciliation = :reconciliation,
+	statement = :statement,
+	external_id = :external_id
+	WHERE id = :id;`
+
+	result, err := transaction.NamedExec(query, row)
+	if err != nil {
+		return 0, err
+	}
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(changed), nil
+}
+
+// If the accounts ledger is set, ensures all rows on it have an account set
+func checkRowAccounts(rows []EntryRow) error {
+	accountLedger := GetAccountsLedger()
+	if accountLedger == nil {
+		return nil
+	}
+
+	for i, row := range rows {
+		if row.Ledger == accountLedger.Id && row.Account == nil {
+			return fmt.Errorf("Row %d is on accounts ledger but has no account set", i)
+		}
+	}
+
+	return nil
+}
+
+// An entry posted by an invoice has to stay as it was posted, change is what would be done to it, like "deleted"
+func checkNotPostedByInvoice(tx *sqlx.Tx, entryId int, change string) error {
+	var invoices []Invoice
+	err := tx.Select(&invoices, `SELECT * FROM invoices WHERE entry = $1;`, entryId)
+	if err != nil {
+		return fmt.Errorf("FAILED TO SELECT INVOICES OF ENTRY %d: %v", entryId, err)
+	}
+
+	if len(invoices) != 0 {
+		return fmt.Errorf("entry %d was posted by %s, it can't be %s", entryId, invoices[0].Name(), change)
+	}
+
+	return nil
+}
+
 func SelectEntries(DB *sqlx.DB) ([]Entry, error) {
 	result := []Entry{}
 
@@ -474,10 +673,64 @@ func SelectEntriesByJournal(DB *sqlx.DB, journalId int) ([]Entry, error) {
 	return result, err
 }
 
+// Moves the entry and its rows to the trash, from where they can be restored
 func DeleteEntry(DB *sqlx.DB, id int) error {
-	_, err := DB.Exec(`DELETE FROM entries WHERE id = $1;`, id)
+	tx := DB.MustBegin()
+	defer tx.Rollback()
+
+	var rows []EntryRow
+	err := tx.Select(&rows, `SELECT * FROM entryrows WHERE entry = $1;`, id)
+	if err != nil {
+		return err
+	}
+
+	err = checkPeriodsOpen(tx, rows)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		err = checkReconciliationKept(row, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The invoice would be left posted without its entry
+	err = checkNotPostedByInvoice(tx, id, "deleted")
+	if err != nil {
+		return err
+	}
+
+	// Logged before deleting, the snapshot is the last state of the entry
+	err = recordEntryVersion(tx, id, DELETEACTION)
+	if err != nil {
+		return err
+	}
+
+	var entry Entry
+	err = tx.Get(&entry, `SELECT * FROM entries WHERE id = $1;`, id)
+	if err != nil {
+		return err
+	}
+
+	err = moveToTrash(tx, meta.ENTRYMODEL, id, entry.String(), EntrySnapshot{Entry: entry, Rows: rows})
+	if err != nil {
+		return err
+	}
+
+	// Rows are deleted explicitly rather than relying on the cascade, which needs foreign keys to be on
+	_, err = tx.Exec(`DELETE FROM entryrows WHERE entry = $1;`, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM entries WHERE id = $1;`, id)
+	if err != nil {
+		return err
+	}
 
-	return err
+	return tx.Commit()
 }
 
 func SelectRows(DB *sqlx.DB) ([]EntryRow, error) {
@@ -496,6 +749,31 @@ func SelectRowsByLedger(DB *sqlx.DB, id int) ([]EntryRow, error) {
 	return result, err
 }
 
+func SelectRowsByLedgers(DB *sqlx.DB, ids []int) ([]EntryRow, error) {
+	result := []EntryRow{}
+
+	query, args, err := sqlx.In(`SELECT * FROM entryrows WHERE ledger IN (?);`, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	err = DB.Select(&result, DB.Rebind(query), args...)
+
+	return result, err
+}
+
+// The external ids of the rows imported onto the ledger, see EntryRow.ExternalId
+func SelectExternalIds(DB *sqlx.DB, ledger int) ([]string, error) {
+	result := []string{}
+
+	err := DB.Select(&result, `SELECT external_id FROM entryrows WHERE ledger = $1 AND external_id IS NOT NULL;`, ledger)
+	if err != nil {
+		return nil, fmt.Errorf("FAILED TO SELECT EXTERNAL IDS OF LEDGER %d: %v", ledger, err)
+	}
+
+	return result, nil
+}
+
 func SelectRowsByAccount(DB *sqlx.DB, id int) ([]EntryRow, error) {
 	result := []EntryRow{}
 
@@ -528,30 +806,119 @@ func SelectRowsByEntry(DB *sqlx.DB, id int) ([]EntryRow, error) {
 }
 
 func SetReconciled(DB *sqlx.DB, rows []*EntryRow) (int, error) {
+	return SetReconciledGroups(DB, [][]*EntryRow{rows})
+}
+
+// Sets the reconciled status of every group of rows, the newly reconciled rows of each group settle each other.
+// All groups go through in one transaction, so either all of them are reconciled or none of them are.
+func SetReconciledGroups(DB *sqlx.DB, groups [][]*EntryRow) (int, error) {
 	// Transaction to ensure all reconciling goes through.
 	// Otherwise db in insane state, where reconciled rows don't add to 0
 	tx := DB.MustBegin()
 	defer tx.Rollback()
 
+	totalChanged := 0
+	// The group each newly reconciled row got, only set on the rows once committed
+	assigned := make(map[*EntryRow]int)
+
+	for _, rows := range groups {
+		changed, groupAssigned, err := setReconciled(tx, rows)
+		if err != nil {
+			return 0, err
+		}
+
+		totalChanged += changed
+		for row, group := range groupAssigned {
+			assigned[row] = group
+		}
+	}
+
+	err := tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("FAILED TO COMMIT RECONCILING ROWS: %v", err)
+	}
+
+	for row, group := range assigned {
+		row.Reconciliation = &group
+	}
+
+	return totalChanged, nil
+}
+
+// Sets the reconciled status of the rows within the transaction, returns the group of each newly reconciled row
+func setReconciled(tx *sqlx.Tx, rows []*EntryRow) (int, map[*EntryRow]int, error) {
 	query := `UPDATE entryrows SET reconciled = :reconciled WHERE id = :id;`
 	totalChanged := 0
+	// The entries that had a row toggled, in order, each gets one version in the audit log
+	var changedEntries []int
+	// Reconciled together, so they settle each other
+	var newlyReconciled []*EntryRow
 
 	for _, row := range rows {
+		// Only rows whose status actually changes are affected by locked periods
+		var stored EntryRow
+		err := tx.Get(&stored, `SELECT * FROM entryrows WHERE id = $1;`, row.Id)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if stored.Reconciled && !row.Reconciled && stored.Reconciliation != nil {
+			return 0, nil, fmt.Errorf("row %d is matched in group %d, unmatch the group to unreconcile it", row.Id, *stored.Reconciliation)
+		}
+		if stored.Reconciled && !row.Reconciled && stored.Statement != nil {
+			return 0, nil, fmt.Errorf("row %d was reconciled against statement %d, unreconcile it against a new statement", row.Id, *stored.Statement)
+		}
+		if !stored.Reconciled && row.Reconciled {
+			newlyReconciled = append(newlyReconciled, row)
+		}
+
+		if stored.Reconciled != row.Reconciled {
+			err = checkPeriodsOpen(tx, []EntryRow{stored})
+			if err != nil {
+				return 0, nil, err
+			}
+		}
+
 		res, err := tx.NamedExec(query, row)
 		if err != nil {
-			return 0, err
+			return 0, nil, err
 		}
 
 		changed, err := res.RowsAffected()
 		if err != nil {
-			return 0, err
+			return 0, nil, err
 		}
 		totalChanged += int(changed)
+
+		if stored.Reconciled != row.Reconciled && !slices.Contains(changedEntries, stored.Entry) {
+			changedEntries = append(changedEntries, stored.Entry)
+		}
 	}
 
-	tx.Commit()
+	// A group that doesn't total 0 isn't settled, that's what MatchRows is for
+	err := CheckSettled(newlyReconciled)
+	if err != nil {
+		return 0, nil, err
+	}
 
-	return totalChanged, nil
+	assigned, _, err := assignReconciliationGroups(tx, newlyReconciled)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, entryId := range changedEntries {
+		err := recordEntryVersion(tx, entryId, RECONCILEACTION)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	result := make(map[*EntryRow]int)
+	for _, row := range newlyReconciled {
+		result[row] = assigned[row.Id]
+	}
+
+	return totalChanged, result, nil
 }
 
 func MakeSelectEntryCmd(DB *sqlx.DB, entryId int) tea.Cmd {
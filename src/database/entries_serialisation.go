@@ -2,17 +2,28 @@ package database
 
 import (
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"time"
 )
 
 // Don't ask me why we have to use this specific date as string format in Go
-const DATE_FORMAT = "06-01-02"
+const (
+	// How dates are shown on screen and typed in by the user
+	DATE_FORMAT = "06-01-02"
+
+	// How dates are stored in the database. Kept separate from DATE_FORMAT,
+	// four-digit years sort correctly and are readable from other SQLite tools.
+	DATE_STORAGE_FORMAT = "2006-01-02"
+
+	// When something happened, for the audit log and the trash. Sorts correctly as a string.
+	TIMESTAMP_FORMAT = "2006-01-02 15:04:05"
+)
 
 func (d *Date) Scan(value any) error {
 	switch value := value.(type) {
 	case string:
-		parsed, err := time.Parse(DATE_FORMAT, value)
+		parsed, err := time.Parse(DATE_STORAGE_FORMAT, value)
 		if err != nil {
 			return err
 		}
@@ -27,5 +38,20 @@ func (d *Date) Scan(value any) error {
 }
 
 func (d Date) Value() (driver.Value, error) {
-	return time.Time(d).Format(DATE_FORMAT), nil
+	return time.Time(d).Format(DATE_STORAGE_FORMAT), nil
+}
+
+// Dates in audit log snapshots use the storage format as well
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Time(d).Format(DATE_STORAGE_FORMAT))
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var value string
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+
+	return d.Scan(value)
 }
@@ -45,7 +45,7 @@ func TestInsertEntry(t *testing.T) {
 			Ledger:     ledger.Id,
 			Account:    nil,
 			Document:   nil,
-			Value:      5,
+			Value:      -5,
 			Reconciled: false,
 		},
 	}
@@ -126,9 +126,10 @@ func TestUpdateEntry(t *testing.T) {
 	entry := insertTestEntry(t, DB, journal.Id, ledger.Id)
 
 	updatedEntry := database.Entry{
-		Id:      entry.Id,
-		Journal: journal.Id,
-		Notes:   meta.Notes{"updated note"},
+		Id:       entry.Id,
+		Journal:  journal.Id,
+		Notes:    meta.Notes{"updated note"},
+		Suspense: true,
 	}
 	newDate, err := database.ToDate("25-06-15")
 	require.NoError(t, err)
@@ -209,6 +210,18 @@ func TestParseCurrencyValue(t *testing.T) {
 	}
 }
 
+func TestParseNonNegativeValue(t *testing.T) {
+	result, err := database.ParseNonNegativeValue("12.50")
+	require.NoError(t, err)
+	assert.Equal(t, database.CurrencyValue(1250), result)
+
+	_, err = database.ParseNonNegativeValue("-12.50")
+	assert.EqualError(t, err, `"-12.50" is negative`)
+
+	_, err = database.ParseNonNegativeValue("abc")
+	assert.Error(t, err)
+}
+
 func TestCurrencyValueString(t *testing.T) {
 	tests := []struct {
 		value    database.CurrencyValue
@@ -329,15 +342,15 @@ func TestSetReconciled(t *testing.T) {
 	require.Len(t, rows, 1)
 	assert.False(t, rows[0].Reconciled)
 
+	// A row on its own doesn't settle anything
 	rows[0].Reconciled = true
-	changed, err := database.SetReconciled(DB, []*database.EntryRow{&rows[0]})
-	require.NoError(t, err)
-	assert.Equal(t, 1, changed)
+	_, err = database.SetReconciled(DB, []*database.EntryRow{&rows[0]})
+	assert.EqualError(t, err, "the rows on test ledger (1) total 10.00 instead of 0.00")
 
 	updatedRows, err := database.SelectRows(DB)
 	require.NoError(t, err)
 	require.Len(t, updatedRows, 1)
-	assert.True(t, updatedRows[0].Reconciled)
+	assert.False(t, updatedRows[0].Reconciled)
 }
 
 func TestSetReconciledMultipleRows(t *testing.T) {
@@ -383,18 +396,21 @@ func TestSetReconciledReturnsChangedCount(t *testing.T) {
 	_, err = entry.Insert(DB, []database.EntryRow{
 		{Date: date, Ledger: ledger.Id, Value: 500},
 		{Date: date, Ledger: ledger.Id, Value: -500},
+		{Date: date, Ledger: ledger.Id, Value: 300},
+		{Date: date, Ledger: ledger.Id, Value: -300},
 	})
 	require.NoError(t, err)
 
 	rows, err := database.SelectRows(DB)
 	require.NoError(t, err)
-	require.Len(t, rows, 2)
+	require.Len(t, rows, 4)
 
-	// Only reconcile one of two rows
+	// Only reconcile two of four rows
 	rows[0].Reconciled = true
-	changed, err := database.SetReconciled(DB, []*database.EntryRow{&rows[0]})
+	rows[1].Reconciled = true
+	changed, err := database.SetReconciled(DB, []*database.EntryRow{&rows[0], &rows[1]})
 	require.NoError(t, err)
-	assert.Equal(t, 1, changed)
+	assert.Equal(t, 2, changed)
 }
 
 func TestSelectRowsByAccount(t *testing.T) {
@@ -416,16 +432,11 @@ func TestSelectRowsByAccount(t *testing.T) {
 	date, err := database.ToDate("24-01-01")
 	require.NoError(t, err)
 
-	entryWithAccount := database.Entry{Journal: journal.Id, Notes: meta.Notes{}}
-	_, err = entryWithAccount.Insert(DB, []database.EntryRow{
-		{Date: date, Ledger: accountsLedgerId, Account: &account.Id, Value: 500},
-	})
-	require.NoError(t, err)
-
 	// Row on a non-accounts ledger should not appear
-	entryWithoutAccountsLedger := database.Entry{Journal: journal.Id, Notes: meta.Notes{}}
-	_, err = entryWithoutAccountsLedger.Insert(DB, []database.EntryRow{
-		{Date: date, Ledger: regularLedger.Id, Account: &account.Id, Value: 300},
+	entry := database.Entry{Journal: journal.Id, Notes: meta.Notes{}}
+	_, err = entry.Insert(DB, []database.EntryRow{
+		{Date: date, Ledger: accountsLedgerId, Account: &account.Id, Value: 500},
+		{Date: date, Ledger: regularLedger.Id, Account: &account.Id, Value: -500},
 	})
 	require.NoError(t, err)
 
@@ -435,3 +446,110 @@ func TestSelectRowsByAccount(t *testing.T) {
 	assert.Equal(t, accountsLedgerId, rows[0].Ledger)
 	assert.Equal(t, &account.Id, rows[0].Account)
 }
+
+func TestInsertEntry_Unbalanced(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	journal := insertTestJournal(t, DB)
+	ledger := insertTestLedger(t, DB)
+
+	date, err := database.ToDate("24-01-01")
+	require.NoError(t, err)
+
+	rows := []database.EntryRow{
+		{Date: date, Ledger: ledger.Id, Value: 1000},
+		{Date: date, Ledger: ledger.Id, Value: -750},
+	}
+
+	entry := database.Entry{Journal: journal.Id, Notes: meta.Notes{}}
+	_, err = entry.Insert(DB, rows)
+
+	var unbalancedErr database.UnbalancedEntryError
+	require.ErrorAs(t, err, &unbalancedErr)
+	assert.Equal(t, database.CurrencyValue(250), unbalancedErr.Total)
+
+	entries, err := database.SelectEntries(DB)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	entry.Suspense = true
+	id, err := entry.Insert(DB, rows)
+	require.NoError(t, err)
+
+	result, err := database.SelectEntry(DB, id)
+	require.NoError(t, err)
+	assert.True(t, result.Suspense)
+}
+
+func TestUpdateEntry_Unbalanced(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	journal := insertTestJournal(t, DB)
+	ledger := insertTestLedger(t, DB)
+	entry := insertTestEntry(t, DB, journal.Id, ledger.Id)
+
+	entry.Suspense = false
+	rows, err := database.SelectRowsByEntry(DB, entry.Id)
+	require.NoError(t, err)
+
+	err = entry.Update(DB, rows)
+
+	var unbalancedErr database.UnbalancedEntryError
+	require.ErrorAs(t, err, &unbalancedErr)
+	assert.Equal(t, database.CurrencyValue(1000), unbalancedErr.Total)
+
+	// Nothing changed
+	result, err := database.SelectEntry(DB, entry.Id)
+	require.NoError(t, err)
+	assert.True(t, result.Suspense)
+
+	// Balancing it lets it go through
+	rows = append(rows, database.EntryRow{Date: rows[0].Date, Ledger: ledger.Id, Value: -1000})
+	require.NoError(t, entry.Update(DB, rows))
+
+	result, err = database.SelectEntry(DB, entry.Id)
+	require.NoError(t, err)
+	assert.False(t, result.Suspense)
+}
+
+func TestDate_StorageFormat(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	journal := insertTestJournal(t, DB)
+	ledger := insertTestLedger(t, DB)
+	insertTestEntry(t, DB, journal.Id, ledger.Id)
+
+	var stored string
+	require.NoError(t, DB.Get(&stored, `SELECT date FROM entryrows;`))
+	assert.Equal(t, "2024-01-01", stored)
+
+	rows, err := database.SelectRows(DB)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "24-01-01", rows[0].Date.String())
+}
+
+func TestSelectExternalIds(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	bank, err := (&database.Ledger{Name: "Bank", Type: database.ASSETLEDGER}).Insert(DB)
+	require.NoError(t, err)
+	sales, err := (&database.Ledger{Name: "Sales", Type: database.INCOMELEDGER}).Insert(DB)
+	require.NoError(t, err)
+	journal := insertTestJournal(t, DB)
+
+	fitId := "T1"
+	_, err = database.Entry{Journal: journal.Id}.Insert(DB, []database.EntryRow{
+		{Date: mustDate(t, "24-01-05"), Ledger: bank, Value: 10000, ExternalId: &fitId},
+		{Date: mustDate(t, "24-01-05"), Ledger: sales, Value: -10000},
+	})
+	require.NoError(t, err)
+
+	imported, err := database.SelectExternalIds(DB, bank)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"T1"}, imported)
+
+	imported, err = database.SelectExternalIds(DB, sales)
+	require.NoError(t, err)
+	assert.Empty(t, imported)
+}
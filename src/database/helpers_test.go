@@ -59,9 +59,11 @@ func insertTestJournal(t *testing.T, DB *sqlx.DB) database.Journal {
 func insertTestEntry(t *testing.T, DB *sqlx.DB, journalId int, ledgerId int) database.Entry {
 	t.Helper()
 
+	// Single row, so has to be a suspense entry
 	entry := database.Entry{
-		Journal: journalId,
-		Notes:   meta.Notes{},
+		Journal:  journalId,
+		Notes:    meta.Notes{},
+		Suspense: true,
 	}
 	date, err := database.ToDate("24-01-01")
 	require.NoError(t, err)
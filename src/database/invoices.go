@@ -0,0 +1,713 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"terminaccounting/meta"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jmoiron/sqlx"
+)
+
+type InvoiceStatus string
+
+const (
+	// Still being written, can be changed and has no number yet
+	DRAFTINVOICE InvoiceStatus = "DRAFT"
+	// Numbered and booked, waiting to be paid
+	POSTEDINVOICE InvoiceStatus = "POSTED"
+	PAIDINVOICE   InvoiceStatus = "PAID"
+)
+
+// Days after its date that an invoice is due, if no due date is given
+const DEFAULT_PAYMENT_TERM = 30
+
+// A line of an invoice, like 3 hours of work at 75.00 each
+type InvoiceLine struct {
+	Description string        `json:"description"`
+	Quantity    float64       `json:"quantity"`
+	UnitPrice   CurrencyValue `json:"unit_price"`
+}
+
+func (il InvoiceLine) Amount() CurrencyValue {
+	return CurrencyValue(math.Round(il.Quantity * float64(il.UnitPrice)))
+}
+
+// How the line is typed into the invoice create view
+func (il InvoiceLine) String() string {
+	return fmt.Sprintf("%s x %s %s", strconv.FormatFloat(il.Quantity, 'f', -1, 64), il.UnitPrice, il.Description)
+}
+
+type InvoiceLines []InvoiceLine
+
+// Parses one line per row of input, like "3 x 75.00 Consulting", skipping empty rows
+func ParseInvoiceLines(input string) (InvoiceLines, error) {
+	result := InvoiceLines{}
+
+	for i, row := range strings.Split(input, "\n") {
+		fields := strings.Fields(row)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if len(fields) < 4 || fields[1] != "x" {
+			return nil, fmt.Errorf("invoice line %d should look like \"3 x 75.00 Consulting\", not %q", i+1, row)
+		}
+
+		quantity, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil || quantity <= 0 || math.IsInf(quantity, 0) {
+			return nil, fmt.Errorf("invalid quantity %q on invoice line %d", fields[0], i+1)
+		}
+
+		// ParseCurrencyValue panics on negative values
+		if strings.HasPrefix(fields[2], "-") {
+			return nil, fmt.Errorf("invalid price %q on invoice line %d, prices can't be negative", fields[2], i+1)
+		}
+		price, err := ParseCurrencyValue(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid price %q on invoice line %d", fields[2], i+1)
+		}
+
+		result = append(result, InvoiceLine{
+			Description: strings.Join(fields[3:], " "),
+			Quantity:    quantity,
+			UnitPrice:   price,
+		})
+	}
+
+	return result, nil
+}
+
+func (il InvoiceLines) String() string {
+	rows := make([]string, len(il))
+	for i, line := range il {
+		rows[i] = line.String()
+	}
+
+	return strings.Join(rows, "\n")
+}
+
+// An invoice to a DEBTOR account. Posting it books the lines on Ledger in Journal, and the total on the account.
+type Invoice struct {
+	Id int `db:"id"`
+	// Like 2024-0001, given when the invoice is posted so that the numbers of posted invoices have no gaps
+	Number  string `db:"number"`
+	Account int    `db:"account"`
+	// An INCOME journal
+	Journal int `db:"journal"`
+	// The income ledger the lines are booked on
+	Ledger  int  `db:"ledger"`
+	TaxCode *int `db:"tax_code"`
+
+	Date    Date          `db:"date"`
+	DueDate Date          `db:"due_date"`
+	Status  InvoiceStatus `db:"status"`
+
+	Lines InvoiceLines `db:"lines"`
+	Notes meta.Notes   `db:"notes"`
+
+	// The entry created by posting, nil for drafts
+	Entry *int `db:"entry"`
+}
+
+func (i Invoice) FilterValue() string {
+	var result strings.Builder
+
+	result.WriteString(strconv.Itoa(i.Id))
+	result.WriteString(i.Number)
+	result.WriteString(accountName(i.Account))
+	result.WriteString(string(i.Status))
+	result.WriteString(i.Date.String())
+	result.WriteString(i.Notes.Collapse())
+
+	for _, line := range i.Lines {
+		result.WriteString(line.Description)
+	}
+
+	return result.String()
+}
+
+func (i Invoice) Title() string {
+	return i.Name() + " " + accountName(i.Account)
+}
+
+func (i Invoice) Description() string {
+	total := "error"
+	if subtotal, tax, err := i.Amounts(); err == nil {
+		total = subtotal.Add(tax).String()
+	}
+
+	return fmt.Sprintf("%s, due %s, %s", total, i.DueDate, i.StatusOn(*Today()))
+}
+
+// The number of the invoice, or which draft it is
+func (i Invoice) Name() string {
+	if i.Number == "" {
+		return fmt.Sprintf("Draft %d", i.Id)
+	}
+
+	return "Invoice " + i.Number
+}
+
+func (i Invoice) String() string {
+	return i.Name()
+}
+
+// The status, where posted invoices past their due date are OVERDUE
+func (i Invoice) StatusOn(date Date) string {
+	if i.Status == POSTEDINVOICE && time.Time(i.DueDate).Before(time.Time(date)) {
+		return "OVERDUE"
+	}
+
+	return string(i.Status)
+}
+
+// The total of the lines and the tax on them, tax being calculated per line like the rows posting books
+func (i Invoice) Amounts() (subtotal, tax CurrencyValue, err error) {
+	for _, line := range i.Lines {
+		amount := line.Amount()
+		subtotal = subtotal.Add(amount)
+
+		if i.TaxCode != nil {
+			lineTax, err := PendingTax(*i.TaxCode, amount)
+			if err != nil {
+				return 0, 0, err
+			}
+
+			tax = tax.Add(lineTax)
+		}
+	}
+
+	return subtotal, tax, nil
+}
+
+// Checks that the invoice refers to a debtor, an income journal and ledger, and a sales tax code
+func (i Invoice) check() error {
+	accounts := AvailableAccounts()
+	accountIndex := slices.IndexFunc(accounts, func(account Account) bool { return account.Id == i.Account })
+	if accountIndex == -1 {
+		return fmt.Errorf("account %d doesn't exist", i.Account)
+	}
+	if accounts[accountIndex].Type != DEBTOR {
+		return fmt.Errorf("invoices are for debtors, and %s is a %s", accounts[accountIndex].Name, strings.ToLower(string(accounts[accountIndex].Type)))
+	}
+
+	journals := AvailableJournals()
+	journalIndex := slices.IndexFunc(journals, func(journal Journal) bool { return journal.Id == i.Journal })
+	if journalIndex == -1 {
+		return fmt.Errorf("journal %d doesn't exist", i.Journal)
+	}
+	if journals[journalIndex].Type != INCOMEJOURNAL {
+		return fmt.Errorf("invoices are posted in income journals, and %s is a %s journal", journals[journalIndex].Name, strings.ToLower(string(journals[journalIndex].Type)))
+	}
+
+	ledgers := AvailableLedgers()
+	ledgerIndex := slices.IndexFunc(ledgers, func(ledger Ledger) bool { return ledger.Id == i.Ledger })
+	if ledgerIndex == -1 {
+		return fmt.Errorf("ledger %d doesn't exist", i.Ledger)
+	}
+	if ledgers[ledgerIndex].Type != INCOMELEDGER {
+		return fmt.Errorf("invoice lines are booked on an income ledger, and %s is %s", ledgers[ledgerIndex].Name, ledgers[ledgerIndex].Type)
+	}
+
+	if i.TaxCode != nil {
+		taxCode, err := taxCodeById(*i.TaxCode)
+		if err != nil {
+			return err
+		}
+		if taxCode.Type != SALESTAX {
+			return fmt.Errorf("tax code %s is for purchases, invoices need a sales tax code", taxCode.Code)
+		}
+	}
+
+	if time.Time(i.DueDate).Before(time.Time(i.Date)) {
+		return fmt.Errorf("the due date %s is before the invoice date %s", i.DueDate, i.Date)
+	}
+
+	return nil
+}
+
+// Inserts the invoice as a draft
+func (i Invoice) Insert(DB *sqlx.DB) (int, error) {
+	i.Status = DRAFTINVOICE
+	i.Number = ""
+	i.Entry = nil
+
+	err := i.check()
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := DB.NamedExec(`INSERT INTO invoices (number, account, journal, ledger, tax_code, date, due_date, status, lines, notes, entry)
+		VALUES (:number, :account, :journal, :ledger, :tax_code, :date, :due_date, :status, :lines, :notes, :entry);`, i)
+	if err != nil {
+		return 0, fmt.Errorf("FAILED TO INSERT INVOICE: %v", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// Updates a draft, posted invoices can't be changed anymore
+func (i Invoice) Update(DB *sqlx.DB) error {
+	current, err := SelectInvoice(DB, i.Id)
+	if err != nil {
+		return fmt.Errorf("FAILED TO GET INVOICE %d: %v", i.Id, err)
+	}
+	if current.Status != DRAFTINVOICE {
+		return fmt.Errorf("%s is posted, so it can't be changed anymore", current.Name())
+	}
+
+	err = i.check()
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.NamedExec(`UPDATE invoices SET
+		account = :account,
+		journal = :journal,
+		ledger = :ledger,
+		tax_code = :tax_code,
+		date = :date,
+		due_date = :due_date,
+		lines = :lines,
+		notes = :notes
+		WHERE id = :id;`, i)
+	if err != nil {
+		return fmt.Errorf("FAILED TO UPDATE INVOICE %d: %v", i.Id, err)
+	}
+
+	return nil
+}
+
+// Deletes a draft, posted invoices have to stay for the numbering to have no gaps
+func DeleteInvoice(DB *sqlx.DB, id int) error {
+	invoice, err := SelectInvoice(DB, id)
+	if err != nil {
+		return fmt.Errorf("FAILED TO GET INVOICE %d: %v", id, err)
+	}
+	if invoice.Status != DRAFTINVOICE {
+		return fmt.Errorf("%s is posted, so it can't be deleted", invoice.Name())
+	}
+
+	_, err = DB.Exec(`DELETE FROM invoices WHERE id = $1;`, id)
+	if err != nil {
+		return fmt.Errorf("FAILED TO DELETE INVOICE %d: %v", id, err)
+	}
+
+	return nil
+}
+
+// Newest first, drafts before the rest
+func SelectInvoices(DB *sqlx.DB) ([]Invoice, error) {
+	result := []Invoice{}
+
+	err := DB.Select(&result, `SELECT * FROM invoices ORDER BY number != '', date DESC, id DESC;`)
+
+	return result, err
+}
+
+func SelectInvoice(DB *sqlx.DB, id int) (Invoice, error) {
+	var result Invoice
+
+	err := DB.Get(&result, `SELECT * FROM invoices WHERE id = $1;`, id)
+
+	return result, err
+}
+
+func MakeLoadInvoicesDetailCmd(DB *sqlx.DB, id int) tea.Cmd {
+	return func() tea.Msg {
+		invoice, err := SelectInvoice(DB, id)
+		if err != nil {
+			return fmt.Errorf("FAILED TO LOAD INVOICE WITH ID %d: %#v", id, err)
+		}
+
+		return meta.DataLoadedMsg{
+			TargetApp: meta.INVOICESAPP,
+			Model:     meta.INVOICEMODEL,
+			Data:      invoice,
+		}
+	}
+}
+
+// Numbers the draft and books it: the total on the account in the accounts ledger, each line on the invoice's ledger.
+// The tax of each line is generated like for rows with a tax code typed in the entry create view.
+func PostInvoice(DB *sqlx.DB, id int) (Invoice, error) {
+	tx := DB.MustBegin()
+	defer tx.Rollback()
+
+	var invoice Invoice
+	err := tx.Get(&invoice, `SELECT * FROM invoices WHERE id = $1;`, id)
+	if err != nil {
+		return Invoice{}, fmt.Errorf("FAILED TO GET INVOICE %d: %v", id, err)
+	}
+	if invoice.Status != DRAFTINVOICE {
+		return Invoice{}, fmt.Errorf("%s is already posted", invoice.Name())
+	}
+
+	err = invoice.check()
+	if err != nil {
+		return Invoice{}, err
+	}
+
+	subtotal, tax, err := invoice.Amounts()
+	if err != nil {
+		return Invoice{}, err
+	}
+	if subtotal == 0 {
+		return Invoice{}, errors.New("an invoice without an amount to pay can't be posted")
+	}
+
+	accountsLedger := GetAccountsLedger()
+	if accountsLedger == nil {
+		return Invoice{}, errors.New("posting an invoice books it on the accounts ledger, but none is set, mark one in the ledgers tab")
+	}
+
+	var account Account
+	err = tx.Get(&account, `SELECT * FROM accounts WHERE id = $1;`, invoice.Account)
+	if err != nil {
+		return Invoice{}, fmt.Errorf("FAILED TO GET ACCOUNT %d: %v", invoice.Account, err)
+	}
+	ledger := AvailableLedgers()[slices.IndexFunc(AvailableLedgers(), func(ledger Ledger) bool { return ledger.Id == invoice.Ledger })]
+	for _, currency := range []string{CurrencyOf(*accountsLedger, &account), ledger.Currency} {
+		if currency != HOMECURRENCY {
+			return Invoice{}, fmt.Errorf("invoices are in the home currency, but %s or %s is in %s", account.Name, ledger.Name, currency)
+		}
+	}
+
+	invoice.Number, err = nextInvoiceNumber(tx, time.Time(invoice.Date).Year())
+	if err != nil {
+		return Invoice{}, err
+	}
+
+	document := invoice.Number
+	rows := []EntryRow{{
+		Date:        invoice.Date,
+		Ledger:      accountsLedger.Id,
+		Account:     &invoice.Account,
+		Description: invoice.Name(),
+		Document:    &document,
+		Value:       subtotal.Add(tax),
+	}}
+	for _, line := range invoice.Lines {
+		if line.Amount() == 0 {
+			continue
+		}
+
+		rows = append(rows, EntryRow{
+			Date:        invoice.Date,
+			Ledger:      invoice.Ledger,
+			Description: line.Description,
+			Document:    &document,
+			Value:       -line.Amount(),
+			TaxCode:     invoice.TaxCode,
+		})
+	}
+
+	rows, err = GenerateTaxRows(rows)
+	if err != nil {
+		return Invoice{}, err
+	}
+	for i := range rows {
+		rows[i].Document = &document
+	}
+
+	entry := Entry{Journal: invoice.Journal, Notes: meta.Notes{invoice.Name() + " to " + account.Name}}
+	entryId, err := entry.insert(tx, rows)
+	if err != nil {
+		return Invoice{}, err
+	}
+
+	invoice.Status = POSTEDINVOICE
+	invoice.Entry = &entryId
+	_, err = tx.NamedExec(`UPDATE invoices SET number = :number, status = :status, entry = :entry WHERE id = :id;`, invoice)
+	if err != nil {
+		return Invoice{}, fmt.Errorf("FAILED TO POST INVOICE %d: %v", id, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Invoice{}, err
+	}
+
+	return invoice, nil
+}
+
+// The number after the highest one of the year, like 2024-0001 for the first
+func nextInvoiceNumber(tx *sqlx.Tx, year int) (string, error) {
+	var numbers []string
+	err := tx.Select(&numbers, `SELECT number FROM invoices WHERE number LIKE $1;`, fmt.Sprintf("%d-%%", year))
+	if err != nil {
+		return "", fmt.Errorf("FAILED TO SELECT INVOICE NUMBERS: %v", err)
+	}
+
+	highest := 0
+	for _, number := range numbers {
+		sequence, err := strconv.Atoi(strings.TrimPrefix(number, fmt.Sprintf("%d-", year)))
+		if err != nil {
+			return "", fmt.Errorf("FAILED TO PARSE INVOICE NUMBER %q: %v", number, err)
+		}
+
+		highest = max(highest, sequence)
+	}
+
+	return fmt.Sprintf("%d-%04d", year, highest+1), nil
+}
+
+// Marks a posted invoice as paid, or as unpaid again
+func SetInvoicePaid(DB *sqlx.DB, id int, paid bool) (Invoice, error) {
+	invoice, err := SelectInvoice(DB, id)
+	if err != nil {
+		return Invoice{}, fmt.Errorf("FAILED TO GET INVOICE %d: %v", id, err)
+	}
+
+	switch {
+	case invoice.Status == DRAFTINVOICE:
+		return Invoice{}, fmt.Errorf("%s isn't posted yet, post it with :post first", invoice.Name())
+
+	case paid:
+		invoice.Status = PAIDINVOICE
+
+	default:
+		invoice.Status = POSTEDINVOICE
+	}
+
+	_, err = DB.NamedExec(`UPDATE invoices SET status = :status WHERE id = :id;`, invoice)
+	if err != nil {
+		return Invoice{}, fmt.Errorf("FAILED TO UPDATE STATUS OF INVOICE %d: %v", id, err)
+	}
+
+	return invoice, nil
+}
+
+type InvoiceFormat string
+
+const (
+	TEXTINVOICE InvoiceFormat = "text"
+	HTMLINVOICE InvoiceFormat = "html"
+)
+
+func ParseInvoiceFormat(input string) (InvoiceFormat, error) {
+	switch InvoiceFormat(strings.ToLower(input)) {
+	case TEXTINVOICE:
+		return TEXTINVOICE, nil
+	case HTMLINVOICE:
+		return HTMLINVOICE, nil
+	}
+
+	return "", fmt.Errorf("unknown invoice format %q, expected text or html", input)
+}
+
+func (ifo InvoiceFormat) Extension() string {
+	if ifo == HTMLINVOICE {
+		return "html"
+	}
+
+	return "txt"
+}
+
+// Everything a rendered invoice shows, with the names and amounts looked up
+type invoiceDocument struct {
+	Title    string
+	Date     string
+	DueDate  string
+	Customer string
+	Lines    []invoiceDocumentLine
+	Subtotal string
+	TaxLabel string
+	Tax      string
+	Total    string
+	Notes    []string
+}
+
+type invoiceDocumentLine struct {
+	Description string
+	Quantity    string
+	UnitPrice   string
+	Amount      string
+}
+
+func newInvoiceDocument(invoice Invoice) (invoiceDocument, error) {
+	subtotal, tax, err := invoice.Amounts()
+	if err != nil {
+		return invoiceDocument{}, err
+	}
+
+	result := invoiceDocument{
+		Title:    invoice.Name(),
+		Date:     invoice.Date.String(),
+		DueDate:  invoice.DueDate.String(),
+		Customer: customerName(invoice.Account),
+		Subtotal: subtotal.String(),
+		Tax:      tax.String(),
+		Total:    subtotal.Add(tax).String(),
+		Notes:    invoice.Notes,
+	}
+
+	result.TaxLabel = "Tax"
+	if invoice.TaxCode != nil {
+		taxCode, err := taxCodeById(*invoice.TaxCode)
+		if err != nil {
+			return invoiceDocument{}, err
+		}
+
+		result.TaxLabel = fmt.Sprintf("Tax %s%%", strconv.FormatFloat(taxCode.Rate, 'f', -1, 64))
+	}
+
+	for _, line := range invoice.Lines {
+		result.Lines = append(result.Lines, invoiceDocumentLine{
+			Description: line.Description,
+			Quantity:    strconv.FormatFloat(line.Quantity, 'f', -1, 64),
+			UnitPrice:   line.UnitPrice.String(),
+			Amount:      line.Amount().String(),
+		})
+	}
+
+	return result, nil
+}
+
+// The name of the account alone, without the id accountName adds
+func customerName(id int) string {
+	accounts := AvailableAccounts()
+
+	index := slices.IndexFunc(accounts, func(account Account) bool { return account.Id == id })
+	if index == -1 {
+		return accountName(id)
+	}
+
+	return accounts[index].Name
+}
+
+// The invoice as plain text, lines aligned in columns. Reads the caches.
+func RenderInvoiceText(invoice Invoice) (string, error) {
+	document, err := newInvoiceDocument(invoice)
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+
+	fmt.Fprintf(&result, "%s\n\n", document.Title)
+	fmt.Fprintf(&result, "%-10s %s\n", "To", document.Customer)
+	fmt.Fprintf(&result, "%-10s %s\n", "Date", document.Date)
+	fmt.Fprintf(&result, "%-10s %s\n\n", "Due", document.DueDate)
+
+	fmt.Fprintf(&result, "%-32s %10s %12s %12s\n", "Description", "Quantity", "Price", "Amount")
+	for _, line := range document.Lines {
+		fmt.Fprintf(&result, "%-32s %10s %12s %12s\n", line.Description, line.Quantity, line.UnitPrice, line.Amount)
+	}
+	result.WriteString("\n")
+
+	fmt.Fprintf(&result, "%-32s %36s\n", "Subtotal", document.Subtotal)
+	fmt.Fprintf(&result, "%-32s %36s\n", document.TaxLabel, document.Tax)
+	fmt.Fprintf(&result, "%-32s %36s\n", "Total", document.Total)
+
+	if len(document.Notes) != 0 {
+		result.WriteString("\n" + strings.Join(document.Notes, "\n") + "\n")
+	}
+
+	return result.String(), nil
+}
+
+// Self-contained so it can be mailed or printed as is, without any other files
+var invoiceHTMLTemplate = template.Must(template.New("invoice").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 3em; color: #222; }
+h1 { margin-bottom: 1em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { padding: 0.3em 0.6em; text-align: left; }
+.lines th { border-bottom: 1px solid #222; }
+.number { text-align: right; }
+.total td { border-top: 1px solid #222; font-weight: bold; }
+.notes { margin-top: 2em; white-space: pre-line; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<table>
+<tr><th>To</th><td>{{.Customer}}</td></tr>
+<tr><th>Date</th><td>{{.Date}}</td></tr>
+<tr><th>Due</th><td>{{.DueDate}}</td></tr>
+</table>
+<br>
+<table class="lines">
+<tr><th>Description</th><th class="number">Quantity</th><th class="number">Price</th><th class="number">Amount</th></tr>
+{{- range .Lines}}
+<tr><td>{{.Description}}</td><td class="number">{{.Quantity}}</td><td class="number">{{.UnitPrice}}</td><td class="number">{{.Amount}}</td></tr>
+{{- end}}
+<tr><td colspan="3">Subtotal</td><td class="number">{{.Subtotal}}</td></tr>
+<tr><td colspan="3">{{.TaxLabel}}</td><td class="number">{{.Tax}}</td></tr>
+<tr class="total"><td colspan="3">Total</td><td class="number">{{.Total}}</td></tr>
+</table>
+{{- if .Notes}}
+<div class="notes">{{range .Notes}}{{.}}
+{{end}}</div>
+{{- end}}
+</body>
+</html>
+`))
+
+// The invoice as a single HTML page with inline styling. Reads the caches.
+func RenderInvoiceHTML(invoice Invoice) (string, error) {
+	document, err := newInvoiceDocument(invoice)
+	if err != nil {
+		return "", err
+	}
+
+	var result bytes.Buffer
+	err = invoiceHTMLTemplate.Execute(&result, document)
+	if err != nil {
+		return "", fmt.Errorf("FAILED TO RENDER INVOICE %d: %v", invoice.Id, err)
+	}
+
+	return result.String(), nil
+}
+
+// Writes the rendered invoice to path, or to invoice-<number> in the working directory if path is empty
+func ExportInvoice(invoice Invoice, format InvoiceFormat, path string) (string, error) {
+	var rendered string
+	var err error
+	switch format {
+	case TEXTINVOICE:
+		rendered, err = RenderInvoiceText(invoice)
+	case HTMLINVOICE:
+		rendered, err = RenderInvoiceHTML(invoice)
+	default:
+		panic(fmt.Sprintf("unexpected database.InvoiceFormat: %#v", format))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if path == "" {
+		name := invoice.Number
+		if name == "" {
+			name = fmt.Sprintf("draft-%d", invoice.Id)
+		}
+
+		path = fmt.Sprintf("invoice-%s.%s", name, format.Extension())
+	}
+
+	err = os.WriteFile(path, []byte(rendered), 0o600)
+	if err != nil {
+		return "", fmt.Errorf("couldn't export %s: %v", invoice.Name(), err)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+func (is *InvoiceStatus) Scan(value any) error {
+	switch value {
+	case int64(0):
+		*is = DRAFTINVOICE
+	case int64(1):
+		*is = POSTEDINVOICE
+	case int64(2):
+		*is = PAIDINVOICE
+
+	default:
+		return fmt.Errorf("UNMARSHALLING INVALID INVOICE STATUS: %v", value)
+	}
+
+	return nil
+}
+
+func (is InvoiceStatus) Value() (driver.Value, error) {
+	switch is {
+	case DRAFTINVOICE:
+		return int64(0), nil
+	case POSTEDINVOICE:
+		return int64(1), nil
+	case PAIDINVOICE:
+		return int64(2), nil
+	}
+
+	return nil, fmt.Errorf("MARSHALLING INVALID INVOICE STATUS: %v", is)
+}
+
+func (il *InvoiceLines) Scan(value any) error {
+	converted, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("UNMARSHALLING INVALID INVOICE LINES: %v", value)
+	}
+
+	return json.Unmarshal([]byte(converted), il)
+}
+
+func (il InvoiceLines) Value() (driver.Value, error) {
+	binary, err := json.Marshal(il)
+	if err != nil {
+		return nil, fmt.Errorf("MARSHALLING INVALID INVOICE LINES: %v", err)
+	}
+
+	return string(binary), nil
+}
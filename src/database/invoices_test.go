@@ -0,0 +1,189 @@
+package database_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"terminaccounting/database"
+	"terminaccounting/meta"
+	"terminaccounting/tat"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInvoiceLines(t *testing.T) {
+	lines, err := database.ParseInvoiceLines("2 x 50.00 Consulting work\n\n0.5 x 20 Travel\n")
+	require.NoError(t, err)
+	assert.Equal(t, database.InvoiceLines{
+		{Description: "Consulting work", Quantity: 2, UnitPrice: 5000},
+		{Description: "Travel", Quantity: 0.5, UnitPrice: 2000},
+	}, lines)
+	assert.Equal(t, "2 x 50.00 Consulting work\n0.5 x 20.00 Travel", lines.String())
+
+	_, err = database.ParseInvoiceLines("Consulting 50.00")
+	assert.EqualError(t, err, `invoice line 1 should look like "3 x 75.00 Consulting", not "Consulting 50.00"`)
+
+	_, err = database.ParseInvoiceLines("1 x -5.00 Refund")
+	assert.EqualError(t, err, `invalid price "-5.00" on invoice line 1, prices can't be negative`)
+}
+
+func TestPostInvoice(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	insert := func(ledger database.Ledger) int {
+		id, err := ledger.Insert(DB)
+		require.NoError(t, err)
+
+		return id
+	}
+
+	debtors := insert(database.Ledger{Name: "Debtors", Type: database.ASSETLEDGER, IsAccounts: true})
+	sales := insert(database.Ledger{Name: "Sales", Type: database.INCOMELEDGER})
+	vat := insert(database.Ledger{Name: "VAT", Type: database.LIABILITYLEDGER})
+
+	err := database.SetTaxCode(DB, database.TaxCode{Code: "H21", Type: database.SALESTAX, Rate: 21, Ledger: vat, TurnoverBox: "1a", TaxBox: "1a"})
+	require.NoError(t, err)
+	taxCode := database.AvailableTaxCodes()[0].Id
+
+	customer := insertTestAccount(t, DB)
+	journal := database.Journal{Name: "Sales", Type: database.INCOMEJOURNAL}
+	journal.Id, err = journal.Insert(DB)
+	require.NoError(t, err)
+
+	invoice := database.Invoice{
+		Account: customer.Id,
+		Journal: journal.Id,
+		Ledger:  sales,
+		TaxCode: &taxCode,
+		Date:    mustDate(t, "24-03-01"),
+		DueDate: mustDate(t, "24-03-31"),
+		Lines: database.InvoiceLines{
+			{Description: "Consulting", Quantity: 2, UnitPrice: 5000},
+			{Description: "Travel", Quantity: 1, UnitPrice: 2000},
+		},
+		Notes: meta.Notes{},
+	}
+
+	general := insertTestJournal(t, DB)
+	wrongJournal := invoice
+	wrongJournal.Journal = general.Id
+	_, err = wrongJournal.Insert(DB)
+	assert.EqualError(t, err, "invoices are posted in income journals, and test journal is a general journal")
+
+	invoice.Id, err = invoice.Insert(DB)
+	require.NoError(t, err)
+
+	subtotal, tax, err := invoice.Amounts()
+	require.NoError(t, err)
+	assert.Equal(t, database.CurrencyValue(12000), subtotal)
+	assert.Equal(t, database.CurrencyValue(2520), tax)
+
+	posted, err := database.PostInvoice(DB, invoice.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-0001", posted.Number)
+	assert.Equal(t, database.POSTEDINVOICE, posted.Status)
+	require.NotNil(t, posted.Entry)
+
+	rows, err := database.SelectRowsByEntry(DB, *posted.Entry)
+	require.NoError(t, err)
+	require.Len(t, rows, 5)
+	assert.Equal(t, debtors, rows[0].Ledger)
+	assert.Equal(t, &customer.Id, rows[0].Account)
+	assert.Equal(t, database.CurrencyValue(14520), rows[0].Value)
+	assert.Equal(t, database.CurrencyValue(-10000), rows[1].Value)
+	assert.Equal(t, database.CurrencyValue(-2000), rows[2].Value)
+	assert.Equal(t, database.PAYABLETAXROLE, rows[3].TaxRole)
+	assert.Equal(t, database.CurrencyValue(-2100), rows[3].Value)
+	assert.Equal(t, database.CurrencyValue(-420), rows[4].Value)
+	assert.Equal(t, "2024-0001", *rows[4].Document)
+
+	_, err = database.PostInvoice(DB, invoice.Id)
+	assert.EqualError(t, err, "Invoice 2024-0001 is already posted")
+
+	err = posted.Update(DB)
+	assert.EqualError(t, err, "Invoice 2024-0001 is posted, so it can't be changed anymore")
+
+	err = database.DeleteInvoice(DB, invoice.Id)
+	assert.EqualError(t, err, "Invoice 2024-0001 is posted, so it can't be deleted")
+
+	assert.Equal(t, "OVERDUE", posted.StatusOn(mustDate(t, "24-04-01")))
+
+	// Numbering continues within the year
+	second := invoice
+	second.Id, err = second.Insert(DB)
+	require.NoError(t, err)
+
+	second, err = database.PostInvoice(DB, second.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-0002", second.Number)
+
+	paid, err := database.SetInvoicePaid(DB, invoice.Id, true)
+	require.NoError(t, err)
+	assert.Equal(t, database.PAIDINVOICE, paid.Status)
+	assert.Equal(t, "PAID", paid.StatusOn(mustDate(t, "24-04-01")))
+
+	err = database.DeleteEntry(DB, *posted.Entry)
+	assert.EqualError(t, err, fmt.Sprintf("entry %d was posted by Invoice 2024-0001, it can't be deleted", *posted.Entry))
+
+	err = database.Entry{Id: *posted.Entry, Journal: journal.Id}.Update(DB, rows)
+	assert.EqualError(t, err, fmt.Sprintf("entry %d was posted by Invoice 2024-0001, it can't be changed", *posted.Entry))
+
+	t.Run("entry deleted by an older version", func(t *testing.T) {
+		_, err := DB.Exec(`DELETE FROM entryrows WHERE entry = $1;`, *second.Entry)
+		require.NoError(t, err)
+		_, err = DB.Exec(`DELETE FROM entries WHERE id = $1;`, *second.Entry)
+		require.NoError(t, err)
+
+		problems, err := database.CheckIntegrity(DB)
+		require.NoError(t, err)
+		assert.Equal(t, []database.IntegrityProblem{{
+			Kind:    database.DANGLINGREFERENCE,
+			Details: "Invoice 2024-0002 is posted, but its entry was deleted",
+			Account: &customer.Id,
+		}}, problems)
+	})
+}
+
+func TestExportInvoice(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	sales := insertTestLedger(t, DB)
+	customer := insertTestAccount(t, DB)
+	journal := database.Journal{Name: "Sales", Type: database.INCOMEJOURNAL}
+	journalId, err := journal.Insert(DB)
+	require.NoError(t, err)
+
+	invoice := database.Invoice{
+		Account: customer.Id,
+		Journal: journalId,
+		Ledger:  sales.Id,
+		Date:    mustDate(t, "24-03-01"),
+		DueDate: mustDate(t, "24-03-31"),
+		Lines:   database.InvoiceLines{{Description: "Fish & <chips>", Quantity: 3, UnitPrice: 1250}},
+		Notes:   meta.Notes{"Thanks!"},
+	}
+	invoice.Id, err = invoice.Insert(DB)
+	require.NoError(t, err)
+
+	text, err := database.RenderInvoiceText(invoice)
+	require.NoError(t, err)
+	assert.Contains(t, text, "To         test account\n")
+	assert.Contains(t, text, "Fish & <chips>                            3        12.50        37.50")
+	assert.Contains(t, text, "Total                                                           37.50")
+
+	path := filepath.Join(t.TempDir(), "invoice.html")
+	written, err := database.ExportInvoice(invoice, database.HTMLINVOICE, path)
+	require.NoError(t, err)
+	assert.Equal(t, path, written)
+
+	html, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(html), "<title>Draft 1</title>")
+	assert.Contains(t, string(html), "Fish &amp; &lt;chips&gt;")
+	assert.NotContains(t, string(html), "<link")
+
+	_, err = database.ParseInvoiceFormat("pdf")
+	assert.EqualError(t, err, `unknown invoice format "pdf", expected text or html`)
+}
@@ -119,26 +119,6 @@ func MakeLoadJournalsDetailCmd(DB *sqlx.DB, id int) tea.Cmd {
 	}
 }
 
-func setupSchemaJournals(DB *sqlx.DB) (bool, error) {
-	isSetUp, err := DatabaseTableIsSetUp(DB, "journals")
-	if err != nil {
-		return false, err
-	}
-	if isSetUp {
-		return false, nil
-	}
-
-	schema := `CREATE TABLE IF NOT EXISTS journals(
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL,
-		type INTEGER NOT NULL,
-		notes TEXT
-	) STRICT;`
-
-	_, err = DB.Exec(schema)
-	return true, err
-}
-
 func (j *Journal) Insert(DB *sqlx.DB) (int, error) {
 	_, err := DB.NamedExec(`INSERT INTO journals (name, type, notes) VALUES (:name, :type, :notes)`, j)
 	if err != nil {
@@ -203,8 +183,23 @@ func SelectJournal(DB *sqlx.DB, id int) (Journal, error) {
 	return result, err
 }
 
+// Moves the journal to the trash, from where it can be restored
 func DeleteJournal(DB *sqlx.DB, id int) error {
-	_, err := DB.Exec(`DELETE FROM journals WHERE id = $1;`, id)
+	tx := DB.MustBegin()
+	defer tx.Rollback()
+
+	var journal Journal
+	err := tx.Get(&journal, `SELECT * FROM journals WHERE id = $1;`, id)
+	if err != nil {
+		return err
+	}
+
+	err = moveToTrash(tx, meta.JOURNALMODEL, journal.Id, journal.String(), journal)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM journals WHERE id = $1;`, id)
 	if err != nil {
 		if err.Error() == "FOREIGN KEY constraint failed" {
 			return fmt.Errorf("Journal has entries, can't delete")
@@ -213,6 +208,11 @@ func DeleteJournal(DB *sqlx.DB, id int) error {
 		return err
 	}
 
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
 	return UpdateJournalsCache(DB)
 }
 
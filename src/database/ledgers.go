@@ -70,15 +70,25 @@ type Ledger struct {
 	Type       LedgerType `db:"type"`
 	Notes      meta.Notes `db:"notes"`
 	IsAccounts bool       `db:"is_accounts"`
+	// The currency amounts on this ledger are in, HOMECURRENCY for most ledgers
+	Currency string `db:"currency"`
+	// The ledger this one rolls up into, nil for top-level ledgers
+	Parent *int `db:"parent"`
+	// Like 4100, empty for ledgers without one
+	Code string `db:"code"`
+	// The account number of the bank account the ledger books, empty for ledgers that aren't one
+	BankNumber string `db:"bank_number"`
 }
 
 func (l Ledger) FilterValue() string {
 	var result strings.Builder
 
 	result.WriteString(fmt.Sprintf("%d", l.Id))
+	result.WriteString(l.Code)
 	result.WriteString(l.Name)
 	result.WriteString(string(l.Type))
 	result.WriteString(l.Notes.Collapse())
+	result.WriteString(l.BankNumber)
 
 	if l.IsAccounts {
 		result.WriteString("isAccounts")
@@ -88,7 +98,11 @@ func (l Ledger) FilterValue() string {
 }
 
 func (l Ledger) Title() string {
-	return l.Name
+	if l.Code == "" {
+		return l.Name
+	}
+
+	return l.Code + " " + l.Name
 }
 
 func (l Ledger) Description() string {
@@ -131,7 +145,8 @@ func MakeLoadLedgersDetailCmd(DB *sqlx.DB, id int) tea.Cmd {
 func MakeLoadLedgersRowsCmd(DB *sqlx.DB, ledgerId int) tea.Cmd {
 	// Aren't closures just great
 	return func() tea.Msg {
-		rows, err := SelectRowsByLedger(DB, ledgerId)
+		// A parent ledger shows the rows of its sub-ledgers as well
+		rows, err := SelectRowsByLedgers(DB, LedgerSubtree(AvailableLedgers(), ledgerId))
 		if err != nil {
 			return fmt.Errorf("FAILED TO LOAD LEDGER ROWS: %v", err)
 		}
@@ -144,34 +159,18 @@ func MakeLoadLedgersRowsCmd(DB *sqlx.DB, ledgerId int) tea.Cmd {
 	}
 }
 
-func setupSchemaLedgers(DB *sqlx.DB) (bool, error) {
-	isSetUp, err := DatabaseTableIsSetUp(DB, "ledgers")
+func (l *Ledger) Insert(DB *sqlx.DB) (int, error) {
+	err := l.checkHierarchy(AvailableLedgers())
 	if err != nil {
-		return false, err
-	}
-	if isSetUp {
-		return false, nil
+		return 0, err
 	}
 
-	schema := `CREATE TABLE IF NOT EXISTS ledgers(
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL,
-		type INTEGER NOT NULL,
-		notes TEXT,
-		is_accounts INTEGER NOT NULL
-	) STRICT;`
-
-	_, err = DB.Exec(schema)
-	return true, err
-}
-
-func (l *Ledger) Insert(DB *sqlx.DB) (int, error) {
 	result, err := DB.NamedExec(
-		`INSERT INTO ledgers (name, type, notes, is_accounts)
-		VALUES (:name, :type, :notes, :is_accounts);`,
+		`INSERT INTO ledgers (name, type, notes, is_accounts, currency, parent, code, bank_number)
+		VALUES (:name, :type, :notes, :is_accounts, :currency, :parent, :code, :bank_number);`,
 		l)
 	if err != nil {
-		return 0, err
+		return 0, l.describeUniqueConflict(err)
 	}
 
 	id, err := result.LastInsertId()
@@ -188,16 +187,25 @@ func (l *Ledger) Insert(DB *sqlx.DB) (int, error) {
 }
 
 func (l Ledger) Update(DB *sqlx.DB) error {
+	err := l.checkHierarchy(AvailableLedgers())
+	if err != nil {
+		return err
+	}
+
 	query := `UPDATE ledgers SET
 	name = :name,
 	type = :type,
 	notes = :notes,
-	is_accounts = :is_accounts
+	is_accounts = :is_accounts,
+	currency = :currency,
+	parent = :parent,
+	code = :code,
+	bank_number = :bank_number
 	WHERE id = :id;`
 
-	_, err := DB.NamedExec(query, l)
+	_, err = DB.NamedExec(query, l)
 	if err != nil {
-		return err
+		return l.describeUniqueConflict(err)
 	}
 
 	return UpdateLedgersCache(DB)
@@ -230,8 +238,41 @@ func SelectLedger(DB *sqlx.DB, ledgerId int) (Ledger, error) {
 	return result, err
 }
 
+// Moves the ledger to the trash, from where it can be restored
 func DeleteLedger(DB *sqlx.DB, ledgerId int) error {
-	_, err := DB.Exec(`DELETE FROM ledgers WHERE id = $1;`, ledgerId)
+	tx := DB.MustBegin()
+	defer tx.Rollback()
+
+	var ledger Ledger
+	err := tx.Get(&ledger, `SELECT * FROM ledgers WHERE id = $1;`, ledgerId)
+	if err != nil {
+		return err
+	}
+
+	var children int
+	err = tx.Get(&children, `SELECT COUNT(*) FROM ledgers WHERE parent = $1;`, ledgerId)
+	if err != nil {
+		return err
+	}
+	if children != 0 {
+		return fmt.Errorf("Ledger has sub-ledgers, can't delete")
+	}
+
+	var budgets int
+	err = tx.Get(&budgets, `SELECT COUNT(*) FROM budgets WHERE ledger = $1;`, ledgerId)
+	if err != nil {
+		return err
+	}
+	if budgets != 0 {
+		return fmt.Errorf("Ledger has budgets, can't delete")
+	}
+
+	err = moveToTrash(tx, meta.LEDGERMODEL, ledger.Id, ledger.String(), ledger)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM ledgers WHERE id = $1;`, ledgerId)
 	if err != nil {
 		if err.Error() == "FOREIGN KEY constraint failed" {
 			return fmt.Errorf("Ledger has entries, can't delete")
@@ -240,6 +281,11 @@ func DeleteLedger(DB *sqlx.DB, ledgerId int) error {
 		return err
 	}
 
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
 	return UpdateLedgersCache(DB)
 }
 
@@ -258,6 +304,24 @@ func MakeSelectLedgersCmd(DB *sqlx.DB, targetApp meta.AppType) tea.Cmd {
 	}
 }
 
+// The ledger booking the bank account with the number, nil if there's none
+func GetLedgerByBankNumber(bankNumber string) *Ledger {
+	if bankNumber == "" {
+		return nil
+	}
+
+	availableLedgers := AvailableLedgers()
+	idx := slices.IndexFunc(availableLedgers, func(ledger Ledger) bool {
+		return ledger.BankNumber == bankNumber
+	})
+
+	if idx == -1 {
+		return nil
+	}
+
+	return &availableLedgers[idx]
+}
+
 func GetAccountsLedger() *Ledger {
 	availableLedgers := AvailableLedgers()
 	idx := slices.IndexFunc(availableLedgers, func(ledger Ledger) bool {
@@ -270,3 +334,54 @@ func GetAccountsLedger() *Ledger {
 
 	return &availableLedgers[idx]
 }
+
+// A ledger's parent has to exist and be of the same type, and a ledger can't end up under itself.
+// Its sub-ledgers have to keep being of its type as well.
+func (l Ledger) checkHierarchy(ledgers []Ledger) error {
+	for _, child := range ledgers {
+		if child.Parent != nil && *child.Parent == l.Id && child.Type != l.Type {
+			return fmt.Errorf("ledger %s has sub-ledger %s, which is %s, so it can't be %s", l.Name, child.Name, child.Type, l.Type)
+		}
+	}
+
+	if l.Parent == nil {
+		return nil
+	}
+
+	index := slices.IndexFunc(ledgers, func(ledger Ledger) bool { return ledger.Id == *l.Parent })
+	if index == -1 {
+		return fmt.Errorf("parent ledger %d doesn't exist", *l.Parent)
+	}
+	if ledgers[index].Type != l.Type {
+		return fmt.Errorf("ledger %s is %s, so it can't be under %s, which is %s", l.Name, l.Type, ledgers[index].Name, ledgers[index].Type)
+	}
+
+	ancestor := ledgers[index]
+	for {
+		if ancestor.Id == l.Id {
+			return fmt.Errorf("ledger %s can't be placed under itself or one of its sub-ledgers", l.Name)
+		}
+		if ancestor.Parent == nil {
+			break
+		}
+
+		index = slices.IndexFunc(ledgers, func(ledger Ledger) bool { return ledger.Id == *ancestor.Parent })
+		if index == -1 {
+			break
+		}
+		ancestor = ledgers[index]
+	}
+
+	return nil
+}
+
+func (l Ledger) describeUniqueConflict(err error) error {
+	if strings.HasPrefix(err.Error(), "UNIQUE constraint failed: ledgers.code") {
+		return fmt.Errorf("there's already a ledger with code %s", l.Code)
+	}
+	if strings.HasPrefix(err.Error(), "UNIQUE constraint failed: ledgers.bank_number") {
+		return fmt.Errorf("there's already a ledger with bank number %s", l.BankNumber)
+	}
+
+	return err
+}
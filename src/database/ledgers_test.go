@@ -128,3 +128,22 @@ func TestLedgerTypeSerialization(t *testing.T) {
 		})
 	}
 }
+
+func TestLedgerBankNumber(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	bank := database.Ledger{Name: "Bank", Type: database.ASSETLEDGER, BankNumber: "NL91ABNA0417164300"}
+	id, err := bank.Insert(DB)
+	require.NoError(t, err)
+	_, err = (&database.Ledger{Name: "Cash", Type: database.ASSETLEDGER}).Insert(DB)
+	require.NoError(t, err)
+
+	found := database.GetLedgerByBankNumber("NL91ABNA0417164300")
+	require.NotNil(t, found)
+	assert.Equal(t, id, found.Id)
+	assert.Nil(t, database.GetLedgerByBankNumber("NL01ABCD0000000001"))
+	assert.Nil(t, database.GetLedgerByBankNumber(""), "ledgers without a bank number don't match")
+
+	_, err = (&database.Ledger{Name: "Savings", Type: database.ASSETLEDGER, BankNumber: "NL91ABNA0417164300"}).Insert(DB)
+	assert.EqualError(t, err, "there's already a ledger with bank number NL91ABNA0417164300")
+}
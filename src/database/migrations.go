@@ -0,0 +1,592 @@
+package database
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type migration struct {
+	description string
+	up          func(*sqlx.Tx) error
+}
+
+// Ordered list of migrations, migrations[i] takes the schema from version i to version i+1.
+// Only ever append to this, a book in the wild may be at any of these versions.
+var migrations = []migration{
+	{"create ledgers", migrateCreateLedgers},
+	{"create accounts", migrateCreateAccounts},
+	{"create journals", migrateCreateJournals},
+	{"create entries", migrateCreateEntries},
+	{"create entryrows", migrateCreateEntryRows},
+	{"index entries and entryrows foreign keys", migrateIndexForeignKeys},
+	{"add suspense flag to entries", migrateAddEntrySuspense},
+	{"store entryrow dates as four-digit ISO dates", migrateIsoDates},
+	{"create periods", migrateCreatePeriods},
+	{"add currencies and exchange rates", migrateAddCurrencies},
+	{"create audit log", migrateCreateAuditLog},
+	{"create trash", migrateCreateTrash},
+	{"create attachments", migrateCreateAttachments},
+	{"add tags to entryrows", migrateAddEntryRowTags},
+	{"create recurring entries", migrateCreateRecurringEntries},
+	{"create entry templates", migrateCreateEntryTemplates},
+	{"create budgets", migrateCreateBudgets},
+	{"add parents and codes to ledgers", migrateAddLedgerHierarchy},
+	{"add tax codes", migrateAddTaxCodes},
+	{"create invoices", migrateCreateInvoices},
+	{"add reconciliation groups to entryrows", migrateAddReconciliationGroups},
+	{"create statements", migrateCreateStatements},
+	{"add bank numbers and external ids", migrateAddBankNumbers},
+	{"create reconciliation groups", migrateCreateReconciliationGroups},
+	{"add foreign keys to budgets", migrateAddBudgetForeignKeys},
+}
+
+func LatestSchemaVersion() int {
+	return len(migrations)
+}
+
+func setupSchemaVersion(DB *sqlx.DB) error {
+	schema := `CREATE TABLE IF NOT EXISTS schema_version(
+		version INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at TEXT NOT NULL
+	) STRICT;`
+
+	_, err := DB.Exec(schema)
+	return err
+}
+
+func SchemaVersion(DB *sqlx.DB) (int, error) {
+	isSetUp, err := DatabaseTableIsSetUp(DB, "schema_version")
+	if err != nil {
+		return 0, err
+	}
+	if !isSetUp {
+		return 0, nil
+	}
+
+	var version int
+	err = DB.Get(&version, `SELECT COALESCE(MAX(version), 0) FROM schema_version;`)
+	if err != nil {
+		return 0, fmt.Errorf("FAILED TO GET SCHEMA VERSION: %v", err)
+	}
+
+	return version, nil
+}
+
+// Applies all migrations needed to get the database to the target version.
+// If the database already contained data, a backup is made first.
+func MigrateTo(DB *sqlx.DB, target int) error {
+	if target < 0 || target > LatestSchemaVersion() {
+		return fmt.Errorf("invalid schema version %d", target)
+	}
+
+	current, err := SchemaVersion(DB)
+	if err != nil {
+		return err
+	}
+
+	if current > LatestSchemaVersion() {
+		return fmt.Errorf("database has schema version %d, which is newer than this program supports (%d)", current, LatestSchemaVersion())
+	}
+
+	if current >= target {
+		return nil
+	}
+
+	hasData, err := hasUserTables(DB)
+	if err != nil {
+		return err
+	}
+	if hasData {
+		err = backupDatabase(DB, current)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = setupSchemaVersion(DB)
+	if err != nil {
+		return err
+	}
+
+	for version := current; version < target; version++ {
+		err = applyMigration(DB, version+1, migrations[version])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func applyMigration(DB *sqlx.DB, version int, migration migration) error {
+	tx, err := DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = migration.up(tx)
+	if err != nil {
+		return fmt.Errorf("FAILED TO MIGRATE TO VERSION %d (%s): %v", version, migration.description, err)
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO schema_version (version, description, applied_at) VALUES ($1, $2, $3);`,
+		version, migration.description, time.Now().Format(time.RFC3339),
+	)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Applied migration", "version", version, "description", migration.description)
+
+	return nil
+}
+
+// Whether any table other than the bookkeeping ones exists,
+// this is also true for books from before schema versioning existed
+func hasUserTables(DB *sqlx.DB) (bool, error) {
+	var count int
+	err := DB.Get(&count, `SELECT COUNT(*) FROM sqlite_master
+		WHERE type = 'table' AND name NOT IN ('schema_version', 'sqlite_sequence');`)
+	if err != nil {
+		return false, fmt.Errorf("FAILED TO CHECK IF DATABASE IS NEW: %v", err)
+	}
+
+	return count > 0, nil
+}
+
+// Copies the database file to a timestamped backup next to it.
+// In-memory databases don't have a file, those are skipped.
+func backupDatabase(DB *sqlx.DB, version int) error {
+	var path string
+	err := DB.Get(&path, `SELECT file FROM pragma_database_list WHERE name = 'main';`)
+	if err != nil {
+		return fmt.Errorf("FAILED TO FIND DATABASE FILE: %v", err)
+	}
+
+	if path == "" {
+		return nil
+	}
+
+	backupPath := fmt.Sprintf("%s.v%d-%s.bak", path, version, time.Now().Format("20060102-150405"))
+
+	_, err = DB.Exec(`VACUUM INTO $1;`, backupPath)
+	if err != nil {
+		return fmt.Errorf("FAILED TO BACK UP DATABASE BEFORE MIGRATING: %v", err)
+	}
+
+	slog.Info("Backed up database before migrating", "path", backupPath)
+
+	return nil
+}
+
+// Note: the create migrations use IF NOT EXISTS,
+// because books from before schema versioning already have these tables
+
+func migrateCreateLedgers(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS ledgers(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		type INTEGER NOT NULL,
+		notes TEXT,
+		is_accounts INTEGER NOT NULL
+	) STRICT;`)
+
+	return err
+}
+
+func migrateCreateAccounts(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS accounts(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		type INTEGER NOT NULL,
+		banknumbers TEXT,
+		notes TEXT
+	) STRICT;`)
+
+	return err
+}
+
+func migrateCreateJournals(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS journals(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		type INTEGER NOT NULL,
+		notes TEXT
+	) STRICT;`)
+
+	return err
+}
+
+func migrateCreateEntries(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS entries(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		journal INTEGER NOT NULL,
+		notes TEXT,
+		FOREIGN KEY (journal) REFERENCES journals(id) ON DELETE RESTRICT
+	) STRICT;`)
+
+	return err
+}
+
+func migrateCreateEntryRows(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS entryrows(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		entry INTEGER NOT NULL,
+		date TEXT NOT NULL,
+		ledger INTEGER NOT NULL,
+		account INTEGER,
+		description TEXT,
+		document TEXT,
+		value INTEGER NOT NULL,
+		reconciled INTEGER NOT NULL,
+		FOREIGN KEY (entry) REFERENCES entries(id) ON DELETE CASCADE,
+		FOREIGN KEY (ledger) REFERENCES ledgers(id) ON DELETE RESTRICT,
+		FOREIGN KEY (account) REFERENCES accounts(id) ON DELETE RESTRICT
+	) STRICT;`)
+
+	return err
+}
+
+func migrateIndexForeignKeys(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`
+		CREATE INDEX IF NOT EXISTS entries_journal ON entries(journal);
+		CREATE INDEX IF NOT EXISTS entryrows_entry ON entryrows(entry);
+		CREATE INDEX IF NOT EXISTS entryrows_ledger ON entryrows(ledger);
+		CREATE INDEX IF NOT EXISTS entryrows_account ON entryrows(account);
+	`)
+
+	return err
+}
+
+func migrateAddEntrySuspense(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE entries ADD COLUMN suspense INTEGER NOT NULL DEFAULT 0;`)
+
+	return err
+}
+
+// Rewrites the "06-01-02" dates to "2006-01-02".
+// Picks the century the same way time.Parse did when reading them: 69-99 is the 1900s, 00-68 the 2000s.
+func migrateIsoDates(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`UPDATE entryrows
+		SET date = (CASE WHEN CAST(substr(date, 1, 2) AS INTEGER) >= 69 THEN '19' ELSE '20' END) || date
+		WHERE date GLOB '[0-9][0-9]-[0-9][0-9]-[0-9][0-9]';`)
+
+	return err
+}
+
+func migrateCreatePeriods(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE periods(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		type INTEGER NOT NULL,
+		start_date TEXT NOT NULL,
+		end_date TEXT NOT NULL,
+		locked INTEGER NOT NULL,
+		UNIQUE (type, start_date)
+	) STRICT;`)
+
+	return err
+}
+
+// Existing ledgers, accounts and rows are all in the home currency, which is the empty string
+func migrateAddCurrencies(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`
+		ALTER TABLE ledgers ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+		ALTER TABLE accounts ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+		ALTER TABLE entryrows ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+		ALTER TABLE entryrows ADD COLUMN foreign_value INTEGER;
+
+		CREATE TABLE exchange_rates(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			currency TEXT NOT NULL,
+			date TEXT NOT NULL,
+			rate REAL NOT NULL,
+			UNIQUE (currency, date)
+		) STRICT;
+	`)
+
+	return err
+}
+
+// Append-only: the triggers refuse to change or remove anything once it's logged.
+// No foreign key on entry, the log outlives the entries it describes.
+func migrateCreateAuditLog(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE audit_log(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			entry INTEGER NOT NULL,
+			action INTEGER NOT NULL,
+			author TEXT NOT NULL,
+			changed_at TEXT NOT NULL,
+			snapshot TEXT NOT NULL
+		) STRICT;
+
+		CREATE INDEX audit_log_entry ON audit_log(entry);
+
+		CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+		BEGIN
+			SELECT RAISE(ABORT, 'the audit log is append-only');
+		END;
+
+		CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log
+		BEGIN
+			SELECT RAISE(ABORT, 'the audit log is append-only');
+		END;
+	`)
+
+	return err
+}
+
+// Deleted objects are moved here as JSON, see TrashItem
+func migrateCreateTrash(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE trash(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		model TEXT NOT NULL,
+		object INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		deleted_at TEXT NOT NULL,
+		data TEXT NOT NULL
+	) STRICT;`)
+
+	return err
+}
+
+// Contents are stored once per hash, however often they're attached.
+// No foreign keys on entry and entryrow, attachments stay put while their entry is in the trash.
+func migrateCreateAttachments(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE attachment_contents(
+			hash TEXT PRIMARY KEY,
+			data BLOB NOT NULL
+		) STRICT;
+
+		CREATE TABLE attachments(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			entry INTEGER NOT NULL,
+			entryrow INTEGER,
+			name TEXT NOT NULL,
+			hash TEXT NOT NULL REFERENCES attachment_contents(hash),
+			size INTEGER NOT NULL,
+			added_at TEXT NOT NULL
+		) STRICT;
+
+		CREATE INDEX attachments_entry ON attachments(entry);
+	`)
+
+	return err
+}
+
+// Space-separated, see Tags
+func migrateAddEntryRowTags(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE entryrows ADD COLUMN tags TEXT NOT NULL DEFAULT '';`)
+
+	return err
+}
+
+// The template is an EntrySnapshot as JSON, like in the audit log
+func migrateCreateRecurringEntries(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE recurring_entries(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			frequency INTEGER NOT NULL,
+			start_date TEXT NOT NULL,
+			end_date TEXT,
+			count INTEGER,
+			handled INTEGER NOT NULL,
+			template TEXT NOT NULL
+		) STRICT;
+	`)
+
+	return err
+}
+
+// The rows are TemplateRows as JSON
+func migrateCreateEntryTemplates(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE entry_templates(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			journal INTEGER NOT NULL,
+			notes TEXT,
+			rows TEXT NOT NULL
+		) STRICT;
+	`)
+
+	return err
+}
+
+// A ledger has at most one budget per account or tag and period type
+func migrateCreateBudgets(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE budgets(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			ledger INTEGER NOT NULL,
+			account INTEGER,
+			tag TEXT NOT NULL,
+			period INTEGER NOT NULL,
+			amount INTEGER NOT NULL
+		) STRICT;
+
+		CREATE UNIQUE INDEX budgets_scope ON budgets(ledger, COALESCE(account, 0), tag, period);
+	`)
+
+	return err
+}
+
+// Ledgers without a code have the empty string, so only actual codes have to be unique
+func migrateAddLedgerHierarchy(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`
+		ALTER TABLE ledgers ADD COLUMN parent INTEGER REFERENCES ledgers(id) ON DELETE RESTRICT;
+		ALTER TABLE ledgers ADD COLUMN code TEXT NOT NULL DEFAULT '';
+
+		CREATE UNIQUE INDEX ledgers_code ON ledgers(code) WHERE code != '';
+	`)
+
+	return err
+}
+
+// Rows with a tax code are either the amount the tax is charged on or the generated tax, see TaxRole
+func migrateAddTaxCodes(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE tax_codes(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			code TEXT NOT NULL UNIQUE,
+			type INTEGER NOT NULL,
+			rate REAL NOT NULL,
+			reverse_charge INTEGER NOT NULL,
+			ledger INTEGER NOT NULL REFERENCES ledgers(id) ON DELETE RESTRICT,
+			turnover_box TEXT NOT NULL,
+			tax_box TEXT NOT NULL,
+			deductible_box TEXT NOT NULL
+		) STRICT;
+
+		ALTER TABLE entryrows ADD COLUMN tax_code INTEGER REFERENCES tax_codes(id) ON DELETE RESTRICT;
+		ALTER TABLE entryrows ADD COLUMN tax_role INTEGER NOT NULL DEFAULT 0;
+
+		CREATE INDEX entryrows_tax_code ON entryrows(tax_code);
+	`)
+
+	return err
+}
+
+// Drafts have the empty string as number, so only the numbers of posted invoices have to be unique.
+// The entry isn't a foreign key, so deleting the entry of a posted invoice doesn't lose the invoice.
+func migrateCreateInvoices(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE invoices(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			number TEXT NOT NULL,
+			account INTEGER NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+			journal INTEGER NOT NULL REFERENCES journals(id) ON DELETE RESTRICT,
+			ledger INTEGER NOT NULL REFERENCES ledgers(id) ON DELETE RESTRICT,
+			tax_code INTEGER REFERENCES tax_codes(id) ON DELETE RESTRICT,
+			date TEXT NOT NULL,
+			due_date TEXT NOT NULL,
+			status INTEGER NOT NULL,
+			lines TEXT NOT NULL,
+			notes TEXT,
+			entry INTEGER
+		) STRICT;
+
+		CREATE UNIQUE INDEX invoices_number ON invoices(number) WHERE number != '';
+		CREATE INDEX invoices_account ON invoices(account);
+	`)
+
+	return err
+}
+
+// Rows matched with each other share a reconciliation group, see MatchRows
+func migrateAddReconciliationGroups(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`
+		ALTER TABLE entryrows ADD COLUMN reconciliation INTEGER;
+
+		CREATE INDEX entryrows_reconciliation ON entryrows(reconciliation);
+	`)
+
+	return err
+}
+
+// Rows reconciled against a bank statement point to it, and add up to its closing balance instead of to 0
+func migrateCreateStatements(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE statements(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			ledger INTEGER NOT NULL REFERENCES ledgers(id) ON DELETE RESTRICT,
+			date TEXT NOT NULL,
+			closing_balance INTEGER NOT NULL,
+			reconciled_on TEXT NOT NULL
+		) STRICT;
+
+		ALTER TABLE entryrows ADD COLUMN statement INTEGER REFERENCES statements(id) ON DELETE SET NULL;
+
+		CREATE INDEX statements_ledger ON statements(ledger);
+		CREATE INDEX entryrows_statement ON entryrows(statement);
+	`)
+
+	return err
+}
+
+// A bank ledger knows the account number of its bank account, so a bank file can pick its ledger.
+// Imported rows keep the id the bank gave the transaction, so importing the same transaction twice can be avoided.
+func migrateAddBankNumbers(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`
+		ALTER TABLE ledgers ADD COLUMN bank_number TEXT NOT NULL DEFAULT '';
+		CREATE UNIQUE INDEX ledgers_bank_number ON ledgers(bank_number) WHERE bank_number != '';
+
+		ALTER TABLE entryrows ADD COLUMN external_id TEXT;
+		CREATE INDEX entryrows_external_id ON entryrows(ledger, external_id);
+	`)
+
+	return err
+}
+
+// Group ids are handed out by AUTOINCREMENT, which never reuses the id of an unmatched group
+func migrateCreateReconciliationGroups(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE reconciliation_groups(
+			id INTEGER PRIMARY KEY AUTOINCREMENT
+		) STRICT;
+
+		INSERT INTO reconciliation_groups (id)
+			SELECT DISTINCT reconciliation FROM entryrows WHERE reconciliation IS NOT NULL ORDER BY reconciliation;
+	`)
+
+	return err
+}
+
+// SQLite can't add a foreign key to an existing table, so the table is built again.
+// Budgets of ledgers or accounts that were deleted already don't count towards anything anymore, and are dropped.
+func migrateAddBudgetForeignKeys(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE budgets_new(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			ledger INTEGER NOT NULL REFERENCES ledgers(id) ON DELETE RESTRICT,
+			account INTEGER REFERENCES accounts(id) ON DELETE RESTRICT,
+			tag TEXT NOT NULL,
+			period INTEGER NOT NULL,
+			amount INTEGER NOT NULL
+		) STRICT;
+
+		INSERT INTO budgets_new (id, ledger, account, tag, period, amount)
+			SELECT id, ledger, account, tag, period, amount FROM budgets
+			WHERE ledger IN (SELECT id FROM ledgers) AND (account IS NULL OR account IN (SELECT id FROM accounts));
+
+		DROP TABLE budgets;
+		ALTER TABLE budgets_new RENAME TO budgets;
+
+		CREATE UNIQUE INDEX budgets_scope ON budgets(ledger, COALESCE(account, 0), tag, period);
+	`)
+
+	return err
+}
@@ -0,0 +1,592 @@
+package database_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"terminaccounting/database"
+	"terminaccounting/meta"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupEmptyTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	DB := sqlx.MustConnect("sqlite3", fmt.Sprintf("file:migrations_%d?mode=memory&cache=shared", time.Now().UnixNano()))
+	t.Cleanup(func() { DB.Close() })
+
+	return DB
+}
+
+// The schemas as they were before schema versioning was introduced
+const legacySchema = `
+CREATE TABLE ledgers(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	type INTEGER NOT NULL,
+	notes TEXT,
+	is_accounts INTEGER NOT NULL
+) STRICT;
+CREATE TABLE accounts(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	type INTEGER NOT NULL,
+	banknumbers TEXT,
+	notes TEXT
+) STRICT;
+CREATE TABLE journals(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	type INTEGER NOT NULL,
+	notes TEXT
+) STRICT;
+CREATE TABLE entries(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	journal INTEGER NOT NULL,
+	notes TEXT,
+	FOREIGN KEY (journal) REFERENCES journals(id) ON DELETE RESTRICT
+) STRICT;
+CREATE TABLE entryrows(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	entry INTEGER NOT NULL,
+	date TEXT NOT NULL,
+	ledger INTEGER NOT NULL,
+	account INTEGER,
+	description TEXT,
+	document TEXT,
+	value INTEGER NOT NULL,
+	reconciled INTEGER NOT NULL,
+	FOREIGN KEY (entry) REFERENCES entries(id) ON DELETE CASCADE,
+	FOREIGN KEY (ledger) REFERENCES ledgers(id) ON DELETE RESTRICT,
+	FOREIGN KEY (account) REFERENCES accounts(id) ON DELETE RESTRICT
+) STRICT;`
+
+// Inserts data into every table that exists at the given schema version,
+// using only the columns that existed at that version
+func insertFixtureData(t *testing.T, DB *sqlx.DB, version int) {
+	t.Helper()
+
+	// Dates were stored with two-digit years until version 8
+	date := "24-01-31"
+	if version >= 8 {
+		date = "2024-01-31"
+	}
+
+	fixtures := []struct {
+		sinceVersion int
+		query        string
+	}{
+		{1, `INSERT INTO ledgers (name, type, notes, is_accounts) VALUES ('Bank', 2, '["main account"]', 0), ('Debtors', 2, '[]', 1);`},
+		{2, `INSERT INTO accounts (name, type, banknumbers, notes) VALUES ('Customer', 0, '["NL00BANK0123456789"]', '[]');`},
+		{3, `INSERT INTO journals (name, type, notes) VALUES ('Sales', 0, '[]');`},
+		{4, `INSERT INTO entries (journal, notes) VALUES (1, '["invoice 1"]');`},
+		{5, fmt.Sprintf(`INSERT INTO entryrows (entry, date, ledger, account, description, document, value, reconciled)
+			VALUES (1, '%[1]s', 1, NULL, 'payment', NULL, 1000, 0), (1, '%[1]s', 2, 1, 'payment', 'invoice.pdf', -1000, 1);`, date)},
+		{9, `INSERT INTO periods (type, start_date, end_date, locked) VALUES (0, '2023-01-01', '2023-12-31', 1);`},
+		{10, `INSERT INTO exchange_rates (currency, date, rate) VALUES ('USD', '2024-01-31', 0.9);`},
+		{11, `INSERT INTO audit_log (entry, action, author, changed_at, snapshot)
+			VALUES (1, 0, 'alice', '2024-01-31 12:00:00', '{"entry":{"Id":1,"Journal":1,"Notes":["invoice 1"],"Suspense":false},"rows":[]}');`},
+		{12, `INSERT INTO trash (model, object, name, deleted_at, data)
+			VALUES ('JOURNAL', 2, 'Purchases', '2024-01-31 12:00:00', '{"Id":2,"Name":"Purchases","Type":"EXPENSE","Notes":[]}');`},
+		{13, `INSERT INTO attachment_contents (hash, data) VALUES ('6b86b273ff34fce19d6b804eff5a3f5747ada4eaa22f1d49c01e52ddb7875b4b', X'31');
+			INSERT INTO attachments (entry, entryrow, name, hash, size, added_at)
+			VALUES (1, 2, 'invoice.pdf', '6b86b273ff34fce19d6b804eff5a3f5747ada4eaa22f1d49c01e52ddb7875b4b', 1, '2024-01-31 12:00:00');`},
+		{14, `UPDATE entryrows SET tags = 'client:acme invoice' WHERE id = 2;`},
+		{15, `INSERT INTO recurring_entries (name, frequency, start_date, end_date, count, handled, template)
+			VALUES ('Rent', 2, '2024-01-31', NULL, 12, 1, '{"entry":{"Id":1,"Journal":1,"Notes":["invoice 1"],"Suspense":false},"rows":[]}');`},
+		{16, `INSERT INTO entry_templates (name, journal, notes, rows)
+			VALUES ('rent', 1, '[]', '[{"ledger":1,"account":null,"description":"Rent","tags":"","value":50000,"percentage":null,"tax_code":null}]');`},
+		{17, `INSERT INTO budgets (ledger, account, tag, period, amount) VALUES (1, NULL, '', 2, 100000);`},
+		{18, `UPDATE ledgers SET code = '1000' WHERE id = 1;
+			UPDATE ledgers SET parent = 1, code = '1300' WHERE id = 2;`},
+		{19, `INSERT INTO tax_codes (code, type, rate, reverse_charge, ledger, turnover_box, tax_box, deductible_box)
+			VALUES ('H21', 0, 21, 0, 1, '1a', '1a', '');
+			UPDATE entryrows SET tax_code = 1, tax_role = 1 WHERE id = 1;`},
+		{20, `INSERT INTO invoices (number, account, journal, ledger, tax_code, date, due_date, status, lines, notes, entry)
+			VALUES ('2024-0001', 1, 1, 2, NULL, '2024-01-31', '2024-02-29', 1, '[{"description":"Consulting","quantity":2,"unit_price":500}]', '[]', 1);`},
+		{21, `UPDATE entryrows SET reconciliation = 1 WHERE id = 2;`},
+		{22, `INSERT INTO statements (ledger, date, closing_balance, reconciled_on) VALUES (1, '2024-01-31', 1000, '2024-02-01');
+			UPDATE entryrows SET statement = 1, reconciled = 1 WHERE id = 1;`},
+		{23, `UPDATE ledgers SET bank_number = 'NL00BANK0123456789' WHERE id = 1;
+			UPDATE entryrows SET external_id = '20240131001' WHERE id = 1;`},
+		{24, `INSERT INTO reconciliation_groups DEFAULT VALUES;`},
+	}
+
+	for _, fixture := range fixtures {
+		if version < fixture.sinceVersion {
+			continue
+		}
+
+		_, err := DB.Exec(fixture.query)
+		require.NoError(t, err)
+	}
+
+	// Until budgets got their foreign keys, they outlived their ledger or account
+	if version >= 17 && version < 25 {
+		_, err := DB.Exec(`INSERT INTO budgets (ledger, account, tag, period, amount) VALUES (99, NULL, '', 2, 100), (1, 99, '', 2, 100);`)
+		require.NoError(t, err)
+	}
+}
+
+// Asserts that the data from insertFixtureData made it through the migrations intact
+func assertFixtureData(t *testing.T, DB *sqlx.DB, version int) {
+	t.Helper()
+
+	ledgers, err := database.SelectLedgers(DB)
+	require.NoError(t, err)
+	accounts, err := database.SelectAccounts(DB)
+	require.NoError(t, err)
+	journals, err := database.SelectJournals(DB)
+	require.NoError(t, err)
+	entries, err := database.SelectEntries(DB)
+	require.NoError(t, err)
+	rows, err := database.SelectRows(DB)
+	require.NoError(t, err)
+	periods, err := database.SelectPeriods(DB)
+	require.NoError(t, err)
+	rates, err := database.SelectExchangeRates(DB)
+	require.NoError(t, err)
+	history, err := database.SelectEntryHistory(DB, 1)
+	require.NoError(t, err)
+	trash, err := database.SelectTrash(DB)
+	require.NoError(t, err)
+	attachments, err := database.SelectAttachments(DB, 1)
+	require.NoError(t, err)
+	recurringEntries, err := database.SelectRecurringEntries(DB)
+	require.NoError(t, err)
+	templates, err := database.SelectTemplates(DB)
+	require.NoError(t, err)
+	budgets, err := database.SelectBudgets(DB)
+	require.NoError(t, err)
+	taxCodes, err := database.SelectTaxCodes(DB)
+	require.NoError(t, err)
+	invoices, err := database.SelectInvoices(DB)
+	require.NoError(t, err)
+	statements, err := database.SelectStatements(DB, 1)
+	require.NoError(t, err)
+	groups := []int{}
+	require.NoError(t, DB.Select(&groups, `SELECT id FROM reconciliation_groups ORDER BY id;`))
+
+	if version >= 1 {
+		expected := []database.Ledger{
+			{Id: 1, Name: "Bank", Type: database.ASSETLEDGER, Notes: meta.Notes{"main account"}, IsAccounts: false},
+			{Id: 2, Name: "Debtors", Type: database.ASSETLEDGER, Notes: meta.Notes{}, IsAccounts: true},
+		}
+		if version >= 18 {
+			parent := 1
+			expected[0].Code = "1000"
+			expected[1].Parent = &parent
+			expected[1].Code = "1300"
+		}
+		if version >= 23 {
+			expected[0].BankNumber = "NL00BANK0123456789"
+		}
+		assert.Equal(t, expected, ledgers)
+
+		// Ledgers from before a column was added get its default
+		for _, ledger := range ledgers {
+			if version < 18 {
+				assert.Nil(t, ledger.Parent)
+				assert.Equal(t, "", ledger.Code)
+			}
+			if version < 23 {
+				assert.Equal(t, "", ledger.BankNumber)
+			}
+		}
+	} else {
+		assert.Empty(t, ledgers)
+	}
+
+	if version >= 2 {
+		assert.Equal(t, []database.Account{
+			{Id: 1, Name: "Customer", Type: database.DEBTOR, BankNumbers: meta.Notes{"NL00BANK0123456789"}, Notes: meta.Notes{}},
+		}, accounts)
+	} else {
+		assert.Empty(t, accounts)
+	}
+
+	if version >= 3 {
+		assert.Equal(t, []database.Journal{
+			{Id: 1, Name: "Sales", Type: database.INCOMEJOURNAL, Notes: meta.Notes{}},
+		}, journals)
+	} else {
+		assert.Empty(t, journals)
+	}
+
+	if version >= 4 {
+		assert.Equal(t, []database.Entry{
+			{Id: 1, Journal: 1, Notes: meta.Notes{"invoice 1"}},
+		}, entries)
+	} else {
+		assert.Empty(t, entries)
+	}
+
+	if version >= 5 {
+		date, err := database.ToDate("24-01-31")
+		require.NoError(t, err)
+		account := 1
+		document := "invoice.pdf"
+
+		expected := []database.EntryRow{
+			{Id: 1, Entry: 1, Date: date, Ledger: 1, Account: nil, Description: "payment", Document: nil, Value: 1000, Reconciled: false},
+			{Id: 2, Entry: 1, Date: date, Ledger: 2, Account: &account, Description: "payment", Document: &document, Value: -1000, Reconciled: true},
+		}
+		if version >= 14 {
+			expected[1].Tags = "client:acme invoice"
+		}
+		if version >= 19 {
+			taxCode := 1
+			expected[0].TaxCode = &taxCode
+			expected[0].TaxRole = database.TAXBASEROLE
+		}
+		if version >= 21 {
+			group := 1
+			expected[1].Reconciliation = &group
+		}
+		if version >= 22 {
+			statement := 1
+			expected[0].Statement = &statement
+			expected[0].Reconciled = true
+		}
+		if version >= 23 {
+			externalId := "20240131001"
+			expected[0].ExternalId = &externalId
+		}
+		assert.Equal(t, expected, rows)
+
+		// Rows from before a column was added get its default
+		for _, row := range rows {
+			if version < 14 {
+				assert.Equal(t, database.Tags(""), row.Tags)
+			}
+			if version < 19 {
+				assert.Nil(t, row.TaxCode)
+				assert.Equal(t, database.NOTAXROLE, row.TaxRole)
+			}
+			if version < 21 {
+				assert.Nil(t, row.Reconciliation)
+			}
+			if version < 22 {
+				assert.Nil(t, row.Statement)
+			}
+			if version < 23 {
+				assert.Nil(t, row.ExternalId)
+			}
+		}
+	} else {
+		assert.Empty(t, rows)
+	}
+
+	if version >= 9 {
+		start, err := database.ToDate("23-01-01")
+		require.NoError(t, err)
+		end, err := database.ToDate("23-12-31")
+		require.NoError(t, err)
+
+		assert.Equal(t, []database.Period{
+			{Id: 1, Type: database.YEARPERIOD, Start: start, End: end, Locked: true},
+		}, periods)
+	} else {
+		assert.Empty(t, periods)
+	}
+
+	if version >= 10 {
+		date, err := database.ToDate("24-01-31")
+		require.NoError(t, err)
+
+		assert.Equal(t, []database.ExchangeRate{
+			{Id: 1, Currency: "USD", Date: date, Rate: 0.9},
+		}, rates)
+	} else {
+		assert.Empty(t, rates)
+	}
+
+	if version >= 11 {
+		assert.Equal(t, []database.EntryVersion{
+			{
+				Id:        1,
+				Entry:     1,
+				Action:    database.INSERTACTION,
+				Author:    "alice",
+				ChangedAt: "2024-01-31 12:00:00",
+				Snapshot: database.EntrySnapshot{
+					Entry: database.Entry{Id: 1, Journal: 1, Notes: meta.Notes{"invoice 1"}},
+					Rows:  []database.EntryRow{},
+				},
+			},
+		}, history)
+	} else {
+		assert.Empty(t, history)
+	}
+
+	if version >= 12 {
+		assert.Equal(t, []database.TrashItem{
+			{
+				Id:        1,
+				Model:     meta.JOURNALMODEL,
+				Object:    2,
+				Name:      "Purchases",
+				DeletedAt: "2024-01-31 12:00:00",
+				Data:      `{"Id":2,"Name":"Purchases","Type":"EXPENSE","Notes":[]}`,
+			},
+		}, trash)
+	} else {
+		assert.Empty(t, trash)
+	}
+
+	if version >= 13 {
+		row := 2
+		require.Equal(t, []database.Attachment{
+			{
+				Id:      1,
+				Entry:   1,
+				Row:     &row,
+				Name:    "invoice.pdf",
+				Hash:    "6b86b273ff34fce19d6b804eff5a3f5747ada4eaa22f1d49c01e52ddb7875b4b",
+				Size:    1,
+				AddedAt: "2024-01-31 12:00:00",
+			},
+		}, attachments)
+
+		data, err := database.ReadAttachment(DB, attachments[0])
+		require.NoError(t, err)
+		assert.Equal(t, "1", string(data))
+	} else {
+		assert.Empty(t, attachments)
+	}
+
+	if version >= 15 {
+		start, err := database.ToDate("24-01-31")
+		require.NoError(t, err)
+		count := 12
+
+		assert.Equal(t, []database.RecurringEntry{
+			{
+				Id:        1,
+				Name:      "Rent",
+				Frequency: database.MONTHLYFREQUENCY,
+				StartDate: start,
+				Count:     &count,
+				Handled:   1,
+				Template: database.EntrySnapshot{
+					Entry: database.Entry{Id: 1, Journal: 1, Notes: meta.Notes{"invoice 1"}},
+					Rows:  []database.EntryRow{},
+				},
+			},
+		}, recurringEntries)
+	} else {
+		assert.Empty(t, recurringEntries)
+	}
+
+	if version >= 16 {
+		rent := database.CurrencyValue(50000)
+
+		assert.Equal(t, []database.EntryTemplate{
+			{
+				Id:      1,
+				Name:    "rent",
+				Journal: 1,
+				Notes:   meta.Notes{},
+				Rows:    database.TemplateRows{{Ledger: 1, Description: "Rent", Value: &rent}},
+			},
+		}, templates)
+	} else {
+		assert.Empty(t, templates)
+	}
+
+	// Only the budgets of ledgers and accounts that still exist are kept
+	if version >= 17 {
+		assert.Equal(t, []database.Budget{
+			{Id: 1, Ledger: 1, Account: nil, Tag: "", Period: database.MONTHPERIOD, Amount: 100000},
+		}, budgets)
+	} else {
+		assert.Empty(t, budgets)
+	}
+
+	if version >= 19 {
+		assert.Equal(t, []database.TaxCode{
+			{Id: 1, Code: "H21", Type: database.SALESTAX, Rate: 21, Ledger: 1, TurnoverBox: "1a", TaxBox: "1a"},
+		}, taxCodes)
+	} else {
+		assert.Empty(t, taxCodes)
+	}
+
+	if version >= 20 {
+		date, err := database.ToDate("24-01-31")
+		require.NoError(t, err)
+		dueDate, err := database.ToDate("24-02-29")
+		require.NoError(t, err)
+		entry := 1
+
+		assert.Equal(t, []database.Invoice{
+			{
+				Id:      1,
+				Number:  "2024-0001",
+				Account: 1,
+				Journal: 1,
+				Ledger:  2,
+				Date:    date,
+				DueDate: dueDate,
+				Status:  database.POSTEDINVOICE,
+				Lines:   database.InvoiceLines{{Description: "Consulting", Quantity: 2, UnitPrice: 500}},
+				Notes:   meta.Notes{},
+				Entry:   &entry,
+			},
+		}, invoices)
+	} else {
+		assert.Empty(t, invoices)
+	}
+
+	if version >= 22 {
+		date, err := database.ToDate("24-01-31")
+		require.NoError(t, err)
+		reconciledOn, err := database.ToDate("24-02-01")
+		require.NoError(t, err)
+
+		assert.Equal(t, []database.Statement{
+			{Id: 1, Ledger: 1, Date: date, ClosingBalance: 1000, ReconciledOn: reconciledOn, Rows: 1},
+		}, statements)
+	} else {
+		assert.Empty(t, statements)
+	}
+
+	// Groups from before they had their own table are taken over, so their ids aren't given out again
+	if version >= 21 {
+		assert.Equal(t, []int{1}, groups)
+	} else {
+		assert.Empty(t, groups)
+	}
+}
+
+func TestMigrate_FromEveryVersion(t *testing.T) {
+	for version := 0; version < database.LatestSchemaVersion(); version++ {
+		t.Run(fmt.Sprintf("from version %d", version), func(t *testing.T) {
+			DB := setupEmptyTestDB(t)
+
+			require.NoError(t, database.MigrateTo(DB, version))
+			insertFixtureData(t, DB, version)
+
+			require.NoError(t, database.InitSchemas(DB))
+
+			result, err := database.SchemaVersion(DB)
+			require.NoError(t, err)
+			assert.Equal(t, database.LatestSchemaVersion(), result)
+
+			assertFixtureData(t, DB, version)
+		})
+	}
+}
+
+func TestMigrate_FromLegacy(t *testing.T) {
+	DB := setupEmptyTestDB(t)
+
+	_, err := DB.Exec(legacySchema)
+	require.NoError(t, err)
+	// The legacy schema contained all tables of the first five versions
+	insertFixtureData(t, DB, 5)
+
+	version, err := database.SchemaVersion(DB)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+
+	require.NoError(t, database.InitSchemas(DB))
+
+	version, err = database.SchemaVersion(DB)
+	require.NoError(t, err)
+	assert.Equal(t, database.LatestSchemaVersion(), version)
+
+	assertFixtureData(t, DB, 5)
+}
+
+func TestMigrate_Idempotent(t *testing.T) {
+	DB := setupEmptyTestDB(t)
+
+	require.NoError(t, database.InitSchemas(DB))
+	insertFixtureData(t, DB, database.LatestSchemaVersion())
+	require.NoError(t, database.InitSchemas(DB))
+
+	var count int
+	require.NoError(t, DB.Get(&count, `SELECT COUNT(*) FROM schema_version;`))
+	assert.Equal(t, database.LatestSchemaVersion(), count)
+
+	assertFixtureData(t, DB, database.LatestSchemaVersion())
+}
+
+func TestMigrate_TooNew(t *testing.T) {
+	DB := setupEmptyTestDB(t)
+
+	require.NoError(t, database.InitSchemas(DB))
+	_, err := DB.Exec(`INSERT INTO schema_version (version, description, applied_at) VALUES ($1, 'from the future', '')`, database.LatestSchemaVersion()+1)
+	require.NoError(t, err)
+
+	err = database.InitSchemas(DB)
+	assert.ErrorContains(t, err, "newer than this program supports")
+}
+
+func TestMigrate_Backup(t *testing.T) {
+	directory := t.TempDir()
+	path := filepath.Join(directory, "book.db")
+
+	DB := sqlx.MustConnect("sqlite3", fmt.Sprintf("file:%s?mode=rwc", path))
+	defer DB.Close()
+
+	t.Run("no backup of new database", func(t *testing.T) {
+		require.NoError(t, database.MigrateTo(DB, 1))
+
+		backups, err := filepath.Glob(path + ".*.bak")
+		require.NoError(t, err)
+		assert.Empty(t, backups)
+	})
+
+	t.Run("backup before migrating existing data", func(t *testing.T) {
+		insertFixtureData(t, DB, 1)
+		require.NoError(t, database.InitSchemas(DB))
+
+		backups, err := filepath.Glob(path + ".v1-*.bak")
+		require.NoError(t, err)
+		require.Len(t, backups, 1)
+
+		backup := sqlx.MustConnect("sqlite3", fmt.Sprintf("file:%s?mode=ro", backups[0]))
+		defer backup.Close()
+
+		version, err := database.SchemaVersion(backup)
+		require.NoError(t, err)
+		assert.Equal(t, 1, version)
+
+		var count int
+		require.NoError(t, backup.Get(&count, `SELECT COUNT(*) FROM ledgers;`))
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("no backup when up to date", func(t *testing.T) {
+		require.NoError(t, database.InitSchemas(DB))
+
+		backups, err := filepath.Glob(path + ".*.bak")
+		require.NoError(t, err)
+		assert.Len(t, backups, 1)
+	})
+
+	_, err := os.Stat(path)
+	assert.NoError(t, err)
+}
+
+func TestMigrate_IsoDates(t *testing.T) {
+	DB := setupEmptyTestDB(t)
+
+	require.NoError(t, database.MigrateTo(DB, 7))
+	insertFixtureData(t, DB, 7)
+	_, err := DB.Exec(`INSERT INTO entryrows (entry, date, ledger, account, description, document, value, reconciled)
+		VALUES (1, '68-12-31', 1, NULL, '', NULL, 0, 0), (1, '69-01-01', 1, NULL, '', NULL, 0, 0);`)
+	require.NoError(t, err)
+
+	require.NoError(t, database.InitSchemas(DB))
+
+	var dates []string
+	require.NoError(t, DB.Select(&dates, `SELECT date FROM entryrows ORDER BY date;`))
+	assert.Equal(t, []string{"1969-01-01", "2024-01-31", "2024-01-31", "2068-12-31"}, dates)
+}
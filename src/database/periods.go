@@ -0,0 +1,196 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Globally accessible list of known periods, locked or not
+// Atomic for parallel tests
+var periodsCache atomic.Pointer[[]Period]
+
+func AvailablePeriods() []Period {
+	return *periodsCache.Load()
+}
+
+type PeriodType string
+
+const (
+	YEARPERIOD    PeriodType = "YEAR"
+	QUARTERPERIOD PeriodType = "QUARTER"
+	MONTHPERIOD   PeriodType = "MONTH"
+)
+
+// A fiscal period. Periods are only stored once they have been locked,
+// a locked period refuses any change to rows dated inside it.
+type Period struct {
+	Id    int        `db:"id"`
+	Type  PeriodType `db:"type"`
+	Start Date       `db:"start_date"`
+	// Inclusive
+	End    Date `db:"end_date"`
+	Locked bool `db:"locked"`
+}
+
+// Formats the period the same way ParsePeriod takes it
+func (p Period) String() string {
+	start := time.Time(p.Start)
+
+	switch p.Type {
+	case YEARPERIOD:
+		return start.Format("2006")
+	case QUARTERPERIOD:
+		return fmt.Sprintf("%d-Q%d", start.Year(), (int(start.Month())-1)/3+1)
+	case MONTHPERIOD:
+		return start.Format("2006-01")
+	default:
+		panic(fmt.Sprintf("unexpected database.PeriodType: %#v", p.Type))
+	}
+}
+
+func (p Period) Contains(date Date) bool {
+	return !time.Time(date).Before(time.Time(p.Start)) && !time.Time(date).After(time.Time(p.End))
+}
+
+var periodPattern = regexp.MustCompile(`^(\d{4})(?:-(?:[qQ]([1-4])|(\d{2})))?$`)
+
+// Parses "2024" as a year, "2024-Q1" as a quarter and "2024-03" as a month
+func ParsePeriod(input string) (Period, error) {
+	matches := periodPattern.FindStringSubmatch(input)
+	if matches == nil {
+		return Period{}, fmt.Errorf("invalid period %q, use e.g. 2024, 2024-Q1 or 2024-03", input)
+	}
+
+	year, _ := strconv.Atoi(matches[1])
+
+	var result Period
+	var start time.Time
+	switch {
+	case matches[2] != "":
+		quarter, _ := strconv.Atoi(matches[2])
+
+		result.Type = QUARTERPERIOD
+		start = time.Date(year, time.Month((quarter-1)*3+1), 1, 0, 0, 0, 0, time.UTC)
+		result.End = Date(start.AddDate(0, 3, -1))
+
+	case matches[3] != "":
+		month, _ := strconv.Atoi(matches[3])
+		if month < 1 || month > 12 {
+			return Period{}, fmt.Errorf("invalid month in period %q", input)
+		}
+
+		result.Type = MONTHPERIOD
+		start = time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+		result.End = Date(start.AddDate(0, 1, -1))
+
+	default:
+		result.Type = YEARPERIOD
+		start = time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		result.End = Date(start.AddDate(1, 0, -1))
+	}
+	result.Start = Date(start)
+
+	return result, nil
+}
+
+// Returned when a change would touch a row dated inside a locked period
+type LockedPeriodError struct {
+	Period Period
+	Date   Date
+}
+
+func (err LockedPeriodError) Error() string {
+	return fmt.Sprintf("row dated %s is in locked period %s, unlock it first with :unlock %s", err.Date, err.Period, err.Period)
+}
+
+func LockPeriod(DB *sqlx.DB, period Period) error {
+	period.Locked = true
+
+	_, err := DB.NamedExec(`INSERT INTO periods (type, start_date, end_date, locked)
+		VALUES (:type, :start_date, :end_date, :locked)
+		ON CONFLICT (type, start_date) DO UPDATE SET locked = excluded.locked;`, period)
+	if err != nil {
+		return err
+	}
+
+	return UpdatePeriodsCache(DB)
+}
+
+func UnlockPeriod(DB *sqlx.DB, period Period) error {
+	result, err := DB.NamedExec(`UPDATE periods SET locked = 0
+		WHERE type = :type AND start_date = :start_date AND locked;`, period)
+	if err != nil {
+		return err
+	}
+
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if changed == 0 {
+		return fmt.Errorf("period %s isn't locked", period)
+	}
+
+	return UpdatePeriodsCache(DB)
+}
+
+func SelectPeriods(DB *sqlx.DB) ([]Period, error) {
+	result := []Period{}
+
+	err := DB.Select(&result, `SELECT * FROM periods ORDER BY start_date, type;`)
+
+	return result, err
+}
+
+func UpdatePeriodsCache(DB *sqlx.DB) error {
+	periods, err := SelectPeriods(DB)
+	if err != nil {
+		return err
+	}
+
+	periodsCache.Store(&periods)
+
+	return nil
+}
+
+// Finds a locked period containing the date in the cache, nil if there is none
+func LockedPeriodOf(date Date) *Period {
+	periods := AvailablePeriods()
+
+	index := slices.IndexFunc(periods, func(period Period) bool {
+		return period.Locked && period.Contains(date)
+	})
+	if index == -1 {
+		return nil
+	}
+
+	return &periods[index]
+}
+
+// Checks the database rather than the cache, so this can't be bypassed by a stale cache
+func checkPeriodsOpen(queryer sqlx.Queryer, rows []EntryRow) error {
+	for _, row := range rows {
+		var period Period
+		err := sqlx.Get(queryer, &period, `SELECT * FROM periods
+			WHERE locked AND start_date <= $1 AND end_date >= $1
+			ORDER BY start_date LIMIT 1;`, row.Date)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("FAILED TO CHECK LOCKED PERIODS: %v", err)
+		}
+
+		return LockedPeriodError{Period: period, Date: row.Date}
+	}
+
+	return nil
+}
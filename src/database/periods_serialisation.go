@@ -0,0 +1,35 @@
+package database
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+func (pt *PeriodType) Scan(value any) error {
+	switch value {
+	case int64(0):
+		*pt = YEARPERIOD
+	case int64(1):
+		*pt = QUARTERPERIOD
+	case int64(2):
+		*pt = MONTHPERIOD
+
+	default:
+		return fmt.Errorf("UNMARSHALLING INVALID PERIOD TYPE: %v", value)
+	}
+
+	return nil
+}
+
+func (pt PeriodType) Value() (driver.Value, error) {
+	switch pt {
+	case YEARPERIOD:
+		return int64(0), nil
+	case QUARTERPERIOD:
+		return int64(1), nil
+	case MONTHPERIOD:
+		return int64(2), nil
+	}
+
+	return nil, fmt.Errorf("MARSHALLING INVALID PERIOD TYPE: %v", pt)
+}
@@ -0,0 +1,138 @@
+package database_test
+
+import (
+	"terminaccounting/database"
+	"terminaccounting/tat"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePeriod(t *testing.T) {
+	tests := []struct {
+		input     string
+		kind      database.PeriodType
+		start     string
+		end       string
+		formatted string
+	}{
+		{"2024", database.YEARPERIOD, "24-01-01", "24-12-31", "2024"},
+		{"2024-Q1", database.QUARTERPERIOD, "24-01-01", "24-03-31", "2024-Q1"},
+		{"2024-q4", database.QUARTERPERIOD, "24-10-01", "24-12-31", "2024-Q4"},
+		{"2024-02", database.MONTHPERIOD, "24-02-01", "24-02-29", "2024-02"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			period, err := database.ParsePeriod(test.input)
+			require.NoError(t, err)
+
+			start, err := database.ToDate(test.start)
+			require.NoError(t, err)
+			end, err := database.ToDate(test.end)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.kind, period.Type)
+			assert.Equal(t, start, period.Start)
+			assert.Equal(t, end, period.End)
+			assert.Equal(t, test.formatted, period.String())
+		})
+	}
+
+	for _, input := range []string{"", "24", "2024-Q5", "2024-13", "2024-1", "last year"} {
+		_, err := database.ParsePeriod(input)
+		assert.Error(t, err, "input: %q", input)
+	}
+}
+
+func TestLockPeriod(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	journal := insertTestJournal(t, DB)
+	ledger := insertTestLedger(t, DB)
+	// Dated 24-01-01
+	entry := insertTestEntry(t, DB, journal.Id, ledger.Id)
+	rows, err := database.SelectRowsByEntry(DB, entry.Id)
+	require.NoError(t, err)
+
+	period, err := database.ParsePeriod("2024-Q1")
+	require.NoError(t, err)
+	require.NoError(t, database.LockPeriod(DB, period))
+
+	lockedPeriod := database.LockedPeriodOf(rows[0].Date)
+	require.NotNil(t, lockedPeriod)
+	assert.Equal(t, "2024-Q1", lockedPeriod.String())
+
+	var lockedErr database.LockedPeriodError
+
+	t.Run("insert", func(t *testing.T) {
+		_, err := entry.Insert(DB, rows)
+		require.ErrorAs(t, err, &lockedErr)
+		assert.Equal(t, "row dated 24-01-01 is in locked period 2024-Q1, unlock it first with :unlock 2024-Q1", err.Error())
+	})
+
+	t.Run("update", func(t *testing.T) {
+		movedOut := rows[0]
+		movedOut.Date, err = database.ToDate("24-05-01")
+		require.NoError(t, err)
+
+		err := entry.Update(DB, []database.EntryRow{movedOut})
+		assert.ErrorAs(t, err, &lockedErr)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		err := database.DeleteEntry(DB, entry.Id)
+		assert.ErrorAs(t, err, &lockedErr)
+	})
+
+	t.Run("reconcile", func(t *testing.T) {
+		reconciled := rows[0]
+		reconciled.Reconciled = true
+
+		_, err := database.SetReconciled(DB, []*database.EntryRow{&reconciled})
+		assert.ErrorAs(t, err, &lockedErr)
+
+		// Unchanged rows are fine though
+		_, err = database.SetReconciled(DB, []*database.EntryRow{&rows[0]})
+		assert.NoError(t, err)
+	})
+
+	stored, err := database.SelectRowsByEntry(DB, entry.Id)
+	require.NoError(t, err)
+	assert.Equal(t, rows, stored)
+
+	t.Run("other periods are unaffected", func(t *testing.T) {
+		other := rows[0]
+		other.Date, err = database.ToDate("24-04-01")
+		require.NoError(t, err)
+
+		_, err := entry.Insert(DB, []database.EntryRow{other})
+		assert.NoError(t, err)
+	})
+}
+
+func TestUnlockPeriod(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	journal := insertTestJournal(t, DB)
+	ledger := insertTestLedger(t, DB)
+	entry := insertTestEntry(t, DB, journal.Id, ledger.Id)
+
+	period, err := database.ParsePeriod("2024")
+	require.NoError(t, err)
+
+	assert.EqualError(t, database.UnlockPeriod(DB, period), "period 2024 isn't locked")
+
+	require.NoError(t, database.LockPeriod(DB, period))
+	// Locking twice is fine
+	require.NoError(t, database.LockPeriod(DB, period))
+	require.NoError(t, database.UnlockPeriod(DB, period))
+
+	periods, err := database.SelectPeriods(DB)
+	require.NoError(t, err)
+	require.Len(t, periods, 1)
+	assert.False(t, periods[0].Locked)
+
+	assert.NoError(t, database.DeleteEntry(DB, entry.Id))
+}
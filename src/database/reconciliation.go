@@ -0,0 +1,259 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Rows that were matched with each other, see MatchRows
+type ReconciliationGroup struct {
+	Id   int
+	Rows int
+	// What's still open, 0 once the group is reconciled
+	Total CurrencyValue
+}
+
+func (rg ReconciliationGroup) String() string {
+	if rg.Total == 0 {
+		return fmt.Sprintf("group %d of %d rows is reconciled", rg.Id, rg.Rows)
+	}
+
+	return fmt.Sprintf("group %d of %d rows is partially matched, %s is still open", rg.Id, rg.Rows, rg.Total)
+}
+
+// Matches the rows into reconciliation groups, one per ledger, or per account on the accounts ledger.
+// A group that totals 0 is reconciled, otherwise it's a partial match, like an invoice that's only paid in part,
+// and its rows stay unreconciled until they're matched again with the rest of the payments.
+// Rows of an existing group have to be matched together with all other rows of that group.
+func MatchRows(DB *sqlx.DB, rows []*EntryRow) ([]ReconciliationGroup, error) {
+	tx := DB.MustBegin()
+	defer tx.Rollback()
+
+	assigned, groups, err := assignReconciliationGroups(tx, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	reconciled := make(map[int]bool)
+	// The entries that had a row matched, in order, each gets one version in the audit log
+	var changedEntries []int
+
+	for _, row := range rows {
+		group := groups[slices.IndexFunc(groups, func(group ReconciliationGroup) bool { return group.Id == assigned[row.Id] })]
+		reconciled[row.Id] = group.Total == 0
+
+		var stored EntryRow
+		err := tx.Get(&stored, `SELECT * FROM entryrows WHERE id = $1;`, row.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		// Only rows whose status actually changes are affected by locked periods
+		if stored.Reconciled != reconciled[row.Id] {
+			err = checkPeriodsOpen(tx, []EntryRow{stored})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		_, err = tx.Exec(`UPDATE entryrows SET reconciled = $1 WHERE id = $2;`, reconciled[row.Id], row.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		if !slices.Contains(changedEntries, stored.Entry) {
+			changedEntries = append(changedEntries, stored.Entry)
+		}
+	}
+
+	for _, entryId := range changedEntries {
+		err := recordEntryVersion(tx, entryId, RECONCILEACTION)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("FAILED TO COMMIT MATCHING ROWS: %v", err)
+	}
+
+	for _, row := range rows {
+		group := assigned[row.Id]
+		row.Reconciliation = &group
+		row.Reconciled = reconciled[row.Id]
+	}
+
+	return groups, nil
+}
+
+// Splits up a reconciliation group, its rows are unreconciled and can be matched again
+func UnmatchGroup(DB *sqlx.DB, group int) (int, error) {
+	tx := DB.MustBegin()
+	defer tx.Rollback()
+
+	rows := []EntryRow{}
+	err := tx.Select(&rows, `SELECT * FROM entryrows WHERE reconciliation = $1;`, group)
+	if err != nil {
+		return 0, fmt.Errorf("FAILED TO SELECT ROWS OF RECONCILIATION GROUP %d: %v", group, err)
+	}
+
+	if len(rows) == 0 {
+		return 0, fmt.Errorf("there is no reconciliation group %d", group)
+	}
+
+	var reconciledRows []EntryRow
+	var changedEntries []int
+	for _, row := range rows {
+		if row.Reconciled {
+			reconciledRows = append(reconciledRows, row)
+		}
+
+		if !slices.Contains(changedEntries, row.Entry) {
+			changedEntries = append(changedEntries, row.Entry)
+		}
+	}
+
+	err = checkPeriodsOpen(tx, reconciledRows)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`UPDATE entryrows SET reconciliation = NULL, reconciled = 0 WHERE reconciliation = $1;`, group)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, entryId := range changedEntries {
+		err := recordEntryVersion(tx, entryId, RECONCILEACTION)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("FAILED TO COMMIT UNMATCHING ROWS: %v", err)
+	}
+
+	return len(rows), nil
+}
+
+// Rows on the accounts ledger settle each other per account, other rows per ledger
+type reconciliationKey struct {
+	ledger  int
+	account int
+}
+
+func reconciliationKeyOf(row EntryRow) reconciliationKey {
+	result := reconciliationKey{ledger: row.Ledger}
+
+	accountsLedger := GetAccountsLedger()
+	if accountsLedger != nil && row.Ledger == accountsLedger.Id && row.Account != nil {
+		result.account = *row.Account
+	}
+
+	return result
+}
+
+// The keys in the order they first appear in, and the rows per key
+func splitByReconciliationKey(rows []*EntryRow) ([]reconciliationKey, map[reconciliationKey][]*EntryRow) {
+	var keys []reconciliationKey
+	byKey := make(map[reconciliationKey][]*EntryRow)
+	for _, row := range rows {
+		key := reconciliationKeyOf(*row)
+
+		if _, ok := byKey[key]; !ok {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], row)
+	}
+
+	return keys, byKey
+}
+
+// Checks that the rows settle each other, per ledger and per account on the accounts ledger,
+// as each of those gets a reconciliation group of its own when they're reconciled
+func CheckSettled(rows []*EntryRow) error {
+	keys, byKey := splitByReconciliationKey(rows)
+
+	for _, key := range keys {
+		total := CalculateTotal(byKey[key])
+		if total == 0 {
+			continue
+		}
+
+		if key.account != 0 {
+			return fmt.Errorf("the rows of %s on %s total %s instead of 0.00", accountName(key.account), ledgerName(key.ledger), total)
+		}
+
+		return fmt.Errorf("the rows on %s total %s instead of 0.00", ledgerName(key.ledger), total)
+	}
+
+	return nil
+}
+
+// Gives the rows new reconciliation groups, replacing the groups they were in.
+// Returns the group per row id, the rows passed in are left untouched until the transaction is committed.
+func assignReconciliationGroups(tx *sqlx.Tx, rows []*EntryRow) (map[int]int, []ReconciliationGroup, error) {
+	isGiven := func(id int) bool {
+		return slices.ContainsFunc(rows, func(row *EntryRow) bool { return row.Id == id })
+	}
+
+	// A group with some of its rows left out wouldn't settle anything anymore
+	for _, row := range rows {
+		var stored *int
+		err := tx.Get(&stored, `SELECT reconciliation FROM entryrows WHERE id = $1;`, row.Id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("FAILED TO GET RECONCILIATION GROUP OF ROW %d: %v", row.Id, err)
+		}
+
+		if stored == nil {
+			continue
+		}
+
+		var others []int
+		err = tx.Select(&others, `SELECT id FROM entryrows WHERE reconciliation = $1;`, *stored)
+		if err != nil {
+			return nil, nil, fmt.Errorf("FAILED TO SELECT ROWS OF RECONCILIATION GROUP %d: %v", *stored, err)
+		}
+
+		for _, other := range others {
+			if !isGiven(other) {
+				return nil, nil, fmt.Errorf("row %d is matched with row %d in group %d, they have to be matched together", row.Id, other, *stored)
+			}
+		}
+	}
+
+	keys, byKey := splitByReconciliationKey(rows)
+
+	assigned := make(map[int]int)
+	var groups []ReconciliationGroup
+	for _, key := range keys {
+		// Ids come from their own sequence, so an id isn't given out again after its group is unmatched
+		res, err := tx.Exec(`INSERT INTO reconciliation_groups DEFAULT VALUES;`)
+		if err != nil {
+			return nil, nil, fmt.Errorf("FAILED TO CREATE RECONCILIATION GROUP: %v", err)
+		}
+		id64, err := res.LastInsertId()
+		if err != nil {
+			return nil, nil, err
+		}
+		id := int(id64)
+
+		for _, row := range byKey[key] {
+			_, err = tx.Exec(`UPDATE entryrows SET reconciliation = $1 WHERE id = $2;`, id, row.Id)
+			if err != nil {
+				return nil, nil, fmt.Errorf("FAILED TO SET RECONCILIATION GROUP OF ROW %d: %v", row.Id, err)
+			}
+
+			assigned[row.Id] = id
+		}
+
+		groups = append(groups, ReconciliationGroup{Id: id, Rows: len(byKey[key]), Total: CalculateTotal(byKey[key])})
+	}
+
+	return assigned, groups, nil
+}
@@ -0,0 +1,282 @@
+package database_test
+
+import (
+	"fmt"
+	"terminaccounting/database"
+	"terminaccounting/tat"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchRows(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	accounts, err := (&database.Ledger{Name: "Accounts", Type: database.ASSETLEDGER, IsAccounts: true}).Insert(DB)
+	require.NoError(t, err)
+	sales, err := (&database.Ledger{Name: "Sales", Type: database.INCOMELEDGER}).Insert(DB)
+	require.NoError(t, err)
+	customer, err := database.Account{Name: "Customer", Type: database.DEBTOR}.Insert(DB)
+	require.NoError(t, err)
+
+	journal := insertTestJournal(t, DB)
+	book := func(value database.CurrencyValue) {
+		_, err := database.Entry{Journal: journal.Id}.Insert(DB, []database.EntryRow{
+			{Date: mustDate(t, "24-01-01"), Ledger: accounts, Account: &customer, Value: value},
+			{Date: mustDate(t, "24-01-01"), Ledger: sales, Value: -value},
+		})
+		require.NoError(t, err)
+	}
+
+	// Two invoices, paid by a partial payment and then one payment settling the rest of both
+	book(10000)
+	book(5000)
+	book(-6000)
+	book(-9000)
+
+	rows, err := database.SelectRowsByAccount(DB, customer)
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	firstInvoice, secondInvoice, partial, rest := &rows[0], &rows[1], &rows[2], &rows[3]
+
+	groups, err := database.MatchRows(DB, []*database.EntryRow{firstInvoice, partial})
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, database.ReconciliationGroup{Id: 1, Rows: 2, Total: 4000}, groups[0])
+	assert.Equal(t, "group 1 of 2 rows is partially matched, 40.00 is still open", groups[0].String())
+	require.NotNil(t, firstInvoice.Reconciliation)
+	assert.Equal(t, 1, *firstInvoice.Reconciliation)
+	assert.False(t, firstInvoice.Reconciled, "a partial match isn't reconciled yet")
+
+	_, err = database.MatchRows(DB, []*database.EntryRow{partial, secondInvoice, rest})
+	assert.EqualError(t, err, "row 5 is matched with row 1 in group 1, they have to be matched together")
+
+	groups, err = database.MatchRows(DB, []*database.EntryRow{firstInvoice, partial, secondInvoice, rest})
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, "group 2 of 4 rows is reconciled", groups[0].String())
+
+	stored, err := database.SelectRowsByAccount(DB, customer)
+	require.NoError(t, err)
+	for _, row := range stored {
+		assert.True(t, row.Reconciled)
+		require.NotNil(t, row.Reconciliation)
+		assert.Equal(t, 2, *row.Reconciliation)
+	}
+
+	// Rows of a group can only be unreconciled together
+	rest.Reconciled = false
+	_, err = database.SetReconciled(DB, []*database.EntryRow{rest})
+	assert.EqualError(t, err, "row 7 is matched in group 2, unmatch the group to unreconcile it")
+
+	changed, err := database.UnmatchGroup(DB, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 4, changed)
+
+	stored, err = database.SelectRowsByAccount(DB, customer)
+	require.NoError(t, err)
+	for _, row := range stored {
+		assert.False(t, row.Reconciled)
+		assert.Nil(t, row.Reconciliation)
+	}
+
+	_, err = database.UnmatchGroup(DB, 2)
+	assert.EqualError(t, err, "there is no reconciliation group 2")
+}
+
+func TestSetReconciledGroupsRows(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+	journal := insertTestJournal(t, DB)
+	ledger := insertTestLedger(t, DB)
+
+	date := mustDate(t, "24-01-01")
+	_, err := database.Entry{Journal: journal.Id}.Insert(DB, []database.EntryRow{
+		{Date: date, Ledger: ledger.Id, Value: 1000},
+		{Date: date, Ledger: ledger.Id, Value: -1000},
+	})
+	require.NoError(t, err)
+
+	rows, err := database.SelectRows(DB)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+
+	rows[0].Reconciled = true
+	rows[1].Reconciled = true
+	_, err = database.SetReconciled(DB, []*database.EntryRow{&rows[0], &rows[1]})
+	require.NoError(t, err)
+
+	require.NotNil(t, rows[0].Reconciliation)
+	assert.Equal(t, rows[0].Reconciliation, rows[1].Reconciliation)
+
+	problems, err := database.CheckIntegrity(DB)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestUpdateEntryKeepsReconciliation(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+	journal := insertTestJournal(t, DB)
+	ledger := insertTestLedger(t, DB)
+
+	date := mustDate(t, "24-01-01")
+	entryId, err := database.Entry{Journal: journal.Id}.Insert(DB, []database.EntryRow{
+		{Date: date, Ledger: ledger.Id, Value: 1000},
+		{Date: date, Ledger: ledger.Id, Value: -1000},
+	})
+	require.NoError(t, err)
+
+	rows, err := database.SelectRowsByEntry(DB, entryId)
+	require.NoError(t, err)
+	_, err = database.MatchRows(DB, []*database.EntryRow{&rows[0], &rows[1]})
+	require.NoError(t, err)
+
+	// Edited like the update view does, which doesn't know about reconciliation
+	edited := func() []database.EntryRow {
+		return []database.EntryRow{
+			{Id: rows[0].Id, Date: date, Ledger: ledger.Id, Description: "Settled", Value: 1000},
+			{Id: rows[1].Id, Date: date, Ledger: ledger.Id, Description: "Settled", Value: -1000},
+		}
+	}
+
+	entry := database.Entry{Id: entryId, Journal: journal.Id}
+	require.NoError(t, entry.Update(DB, edited()))
+
+	stored, err := database.SelectRowsByEntry(DB, entryId)
+	require.NoError(t, err)
+	for _, row := range stored {
+		assert.Equal(t, "Settled", row.Description)
+		assert.True(t, row.Reconciled)
+		require.NotNil(t, row.Reconciliation)
+		assert.Equal(t, *rows[0].Reconciliation, *row.Reconciliation)
+	}
+
+	changed := edited()
+	changed[0].Value = 1500
+	changed = append(changed, database.EntryRow{Date: date, Ledger: ledger.Id, Value: -500})
+	err = entry.Update(DB, changed)
+	assert.EqualError(t, err, fmt.Sprintf("row %d is matched in group 1, :unmatch it before changing its amount, ledger or account or removing it", rows[0].Id))
+
+	err = entry.Update(DB, []database.EntryRow{
+		{Id: rows[0].Id, Date: date, Ledger: ledger.Id, Value: 1000},
+		{Date: date, Ledger: ledger.Id, Value: -1000},
+	})
+	assert.EqualError(t, err, fmt.Sprintf("row %d is matched in group 1, :unmatch it before changing its amount, ledger or account or removing it", rows[1].Id))
+}
+
+func TestReconciliationGroupIdsArentReused(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+	journal := insertTestJournal(t, DB)
+	ledger := insertTestLedger(t, DB)
+
+	date := mustDate(t, "24-01-01")
+	entryId, err := database.Entry{Journal: journal.Id}.Insert(DB, []database.EntryRow{
+		{Date: date, Ledger: ledger.Id, Value: 1000},
+		{Date: date, Ledger: ledger.Id, Value: -1000},
+	})
+	require.NoError(t, err)
+
+	rows, err := database.SelectRowsByEntry(DB, entryId)
+	require.NoError(t, err)
+	groups, err := database.MatchRows(DB, []*database.EntryRow{&rows[0], &rows[1]})
+	require.NoError(t, err)
+	assert.Equal(t, 1, groups[0].Id)
+
+	err = database.DeleteEntry(DB, entryId)
+	assert.EqualError(t, err, fmt.Sprintf("row %d is matched in group 1, :unmatch it before changing its amount, ledger or account or removing it", rows[0].Id))
+
+	_, err = database.UnmatchGroup(DB, 1)
+	require.NoError(t, err)
+
+	groups, err = database.MatchRows(DB, []*database.EntryRow{&rows[0], &rows[1]})
+	require.NoError(t, err)
+	assert.Equal(t, 2, groups[0].Id, "the id of an unmatched group isn't given out again")
+}
+
+func TestSetReconciledGroups(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+	journal := insertTestJournal(t, DB)
+	ledger := insertTestLedger(t, DB)
+
+	date := mustDate(t, "24-01-01")
+	for range 2 {
+		_, err := database.Entry{Journal: journal.Id}.Insert(DB, []database.EntryRow{
+			{Date: date, Ledger: ledger.Id, Value: 1000},
+			{Date: date, Ledger: ledger.Id, Value: -1000},
+		})
+		require.NoError(t, err)
+	}
+
+	rows, err := database.SelectRows(DB)
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	for i := range rows {
+		rows[i].Reconciled = true
+	}
+
+	// The second group can't be unreconciled, so the first one isn't reconciled either
+	_, err = database.MatchRows(DB, []*database.EntryRow{&rows[2], &rows[3]})
+	require.NoError(t, err)
+	rows[2].Reconciled = false
+	_, err = database.SetReconciledGroups(DB, [][]*database.EntryRow{{&rows[0], &rows[1]}, {&rows[2]}})
+	assert.EqualError(t, err, fmt.Sprintf("row %d is matched in group 1, unmatch the group to unreconcile it", rows[2].Id))
+
+	stored, err := database.SelectRowsByEntry(DB, rows[0].Entry)
+	require.NoError(t, err)
+	for _, row := range stored {
+		assert.False(t, row.Reconciled)
+		assert.Nil(t, row.Reconciliation)
+	}
+
+	_, err = database.UnmatchGroup(DB, 1)
+	require.NoError(t, err)
+	rows[2].Reconciled = true
+
+	changed, err := database.SetReconciledGroups(DB, [][]*database.EntryRow{{&rows[0], &rows[1]}, {&rows[2], &rows[3]}})
+	require.NoError(t, err)
+	assert.Equal(t, 4, changed)
+	assert.Equal(t, 2, *rows[0].Reconciliation)
+	assert.Equal(t, 2, *rows[1].Reconciliation)
+	assert.Equal(t, 3, *rows[2].Reconciliation)
+	assert.Equal(t, 3, *rows[3].Reconciliation)
+}
+
+func TestSetReconciled_SettlesPerAccount(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	accounts, err := (&database.Ledger{Name: "Accounts", Type: database.ASSETLEDGER, IsAccounts: true}).Insert(DB)
+	require.NoError(t, err)
+	first, err := database.Account{Name: "First", Type: database.DEBTOR}.Insert(DB)
+	require.NoError(t, err)
+	second, err := database.Account{Name: "Second", Type: database.DEBTOR}.Insert(DB)
+	require.NoError(t, err)
+	require.NoError(t, database.UpdateCache(DB))
+
+	journal := insertTestJournal(t, DB)
+	date := mustDate(t, "24-01-01")
+	entryId, err := database.Entry{Journal: journal.Id}.Insert(DB, []database.EntryRow{
+		{Date: date, Ledger: accounts, Account: &first, Value: 10000},
+		{Date: date, Ledger: accounts, Account: &second, Value: -10000},
+	})
+	require.NoError(t, err)
+
+	rows, err := database.SelectRowsByEntry(DB, entryId)
+	require.NoError(t, err)
+	rows[0].Reconciled = true
+	rows[1].Reconciled = true
+
+	// Together they total 0, but each account would get a group of its own that doesn't
+	_, err = database.SetReconciled(DB, []*database.EntryRow{&rows[0], &rows[1]})
+	assert.EqualError(t, err, fmt.Sprintf("the rows of First (%d) on Accounts (%d) total 100.00 instead of 0.00", first, accounts))
+
+	stored, err := database.SelectRowsByEntry(DB, entryId)
+	require.NoError(t, err)
+	for _, row := range stored {
+		assert.False(t, row.Reconciled)
+		assert.Nil(t, row.Reconciliation)
+	}
+
+	problems, err := database.CheckIntegrity(DB)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
@@ -0,0 +1,349 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"terminaccounting/meta"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmoiron/sqlx"
+)
+
+type Frequency string
+
+const (
+	DAILYFREQUENCY   Frequency = "DAILY"
+	WEEKLYFREQUENCY  Frequency = "WEEKLY"
+	MONTHLYFREQUENCY Frequency = "MONTHLY"
+	YEARLYFREQUENCY  Frequency = "YEARLY"
+)
+
+func ParseFrequency(input string) (Frequency, error) {
+	switch strings.ToLower(input) {
+	case "daily":
+		return DAILYFREQUENCY, nil
+	case "weekly":
+		return WEEKLYFREQUENCY, nil
+	case "monthly":
+		return MONTHLYFREQUENCY, nil
+	case "yearly":
+		return YEARLYFREQUENCY, nil
+	}
+
+	return "", fmt.Errorf("unknown frequency %q, use daily, weekly, monthly or yearly", input)
+}
+
+func (f Frequency) String() string {
+	return strings.ToLower(string(f))
+}
+
+// The date n occurrences after start.
+// Monthly and yearly occurrences stick to the day of the month of start, or the last day of shorter months.
+func (f Frequency) advance(start Date, n int) Date {
+	t := time.Time(start)
+
+	switch f {
+	case DAILYFREQUENCY:
+		return Date(t.AddDate(0, 0, n))
+
+	case WEEKLYFREQUENCY:
+		return Date(t.AddDate(0, 0, 7*n))
+
+	case MONTHLYFREQUENCY:
+		return addMonthsClamped(t, n)
+
+	case YEARLYFREQUENCY:
+		return addMonthsClamped(t, 12*n)
+
+	default:
+		panic(fmt.Sprintf("unexpected database.Frequency: %#v", f))
+	}
+}
+
+// Unlike time.AddDate, doesn't overflow into the next month: Jan 31 plus one month is Feb 28 (or 29)
+func addMonthsClamped(t time.Time, months int) Date {
+	firstOfMonth := time.Date(t.Year(), t.Month()+time.Month(months), 1, 0, 0, 0, 0, t.Location())
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+
+	return Date(firstOfMonth.AddDate(0, 0, min(t.Day(), lastDay)-1))
+}
+
+// An entry that gets posted again every day, week, month or year, like rent or insurance.
+// Occurrences are numbered from 0, which falls on StartDate.
+type RecurringEntry struct {
+	Id        int       `db:"id"`
+	Name      string    `db:"name"`
+	Frequency Frequency `db:"frequency"`
+	StartDate Date      `db:"start_date"`
+	// No occurrences fall after this date, nil for no end date
+	EndDate *Date `db:"end_date"`
+	// The total number of occurrences, nil for no limit
+	Count *int `db:"count"`
+	// How many occurrences have been posted or skipped, the next one to handle is occurrence Handled
+	Handled int `db:"handled"`
+	// The entry and rows every occurrence is a copy of, with all rows dated on the occurrence's date
+	Template EntrySnapshot `db:"template"`
+}
+
+// The date of occurrence n, false if the definition ends before it
+func (re RecurringEntry) Occurrence(n int) (Date, bool) {
+	if re.Count != nil && n >= *re.Count {
+		return Date{}, false
+	}
+
+	date := re.Frequency.advance(re.StartDate, n)
+	if re.EndDate != nil && time.Time(date).After(time.Time(*re.EndDate)) {
+		return Date{}, false
+	}
+
+	return date, true
+}
+
+// One occurrence of a recurring entry, that's due to be posted
+type RecurringOccurrence struct {
+	Recurring RecurringEntry
+	Number    int
+	Date      Date
+}
+
+func (ro RecurringOccurrence) FilterValue() string {
+	return ro.Date.String() + ro.Recurring.Name
+}
+
+func (ro RecurringOccurrence) Render(isActive bool) string {
+	frequencyStyle := lipgloss.NewStyle()
+	if !isActive {
+		frequencyStyle = frequencyStyle.Foreground(meta.ENTRIESCOLOUR)
+	}
+
+	progress := fmt.Sprintf("#%d", ro.Number+1)
+	if ro.Recurring.Count != nil {
+		progress = fmt.Sprintf("%d/%d", ro.Number+1, *ro.Recurring.Count)
+	}
+
+	return fmt.Sprintf("%s  %s %s", ro.Date, ro.Recurring.Name, frequencyStyle.Render(fmt.Sprintf("(%s, %s)", ro.Recurring.Frequency, progress)))
+}
+
+// Makes the entry recur, it counts as the first occurrence.
+// endDate and count limit the occurrences, either can be nil.
+func MakeRecurring(DB *sqlx.DB, entryId int, frequency Frequency, endDate *Date, count *int) (RecurringEntry, error) {
+	var template EntrySnapshot
+
+	err := DB.Get(&template.Entry, `SELECT * FROM entries WHERE id = $1;`, entryId)
+	if err != nil {
+		return RecurringEntry{}, fmt.Errorf("FAILED TO GET ENTRY %d: %v", entryId, err)
+	}
+
+	template.Rows = []EntryRow{}
+	err = DB.Select(&template.Rows, `SELECT * FROM entryrows WHERE entry = $1 ORDER BY id;`, entryId)
+	if err != nil {
+		return RecurringEntry{}, fmt.Errorf("FAILED TO GET ROWS OF ENTRY %d: %v", entryId, err)
+	}
+	if len(template.Rows) == 0 {
+		return RecurringEntry{}, errors.New("an entry without rows can't recur")
+	}
+
+	startDate := slices.MinFunc(template.Rows, func(a, b EntryRow) int {
+		return time.Time(a.Date).Compare(time.Time(b.Date))
+	}).Date
+
+	if count != nil && *count < 1 {
+		return RecurringEntry{}, fmt.Errorf("invalid number of occurrences %d", *count)
+	}
+	if endDate != nil && time.Time(*endDate).Before(time.Time(startDate)) {
+		return RecurringEntry{}, fmt.Errorf("end date %s is before the entry's date %s", *endDate, startDate)
+	}
+
+	name := template.Rows[0].Description
+	if name == "" {
+		name = fmt.Sprintf("Entry %d", entryId)
+	}
+
+	for i := range template.Rows {
+		template.Rows[i].Id = 0
+		template.Rows[i].Entry = 0
+		template.Rows[i].Reconciled = false
+		template.Rows[i].Reconciliation = nil
+		template.Rows[i].Statement = nil
+		template.Rows[i].ExternalId = nil
+	}
+	template.Entry.Id = 0
+
+	result := RecurringEntry{
+		Name:      name,
+		Frequency: frequency,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Count:     count,
+		Handled:   1,
+		Template:  template,
+	}
+
+	res, err := DB.NamedExec(`INSERT INTO recurring_entries (name, frequency, start_date, end_date, count, handled, template)
+		VALUES (:name, :frequency, :start_date, :end_date, :count, :handled, :template);`, result)
+	if err != nil {
+		return RecurringEntry{}, fmt.Errorf("FAILED TO INSERT RECURRING ENTRY: %v", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return RecurringEntry{}, err
+	}
+	result.Id = int(id)
+
+	return result, nil
+}
+
+func SelectRecurringEntries(DB *sqlx.DB) ([]RecurringEntry, error) {
+	result := []RecurringEntry{}
+
+	err := DB.Select(&result, `SELECT * FROM recurring_entries ORDER BY id;`)
+
+	return result, err
+}
+
+// The occurrences falling on or before today that haven't been posted or skipped yet, oldest first
+func DueOccurrences(DB *sqlx.DB, today Date) ([]RecurringOccurrence, error) {
+	recurringEntries, err := SelectRecurringEntries(DB)
+	if err != nil {
+		return nil, fmt.Errorf("FAILED TO SELECT RECURRING ENTRIES: %v", err)
+	}
+
+	result := []RecurringOccurrence{}
+	for _, recurring := range recurringEntries {
+		for n := recurring.Handled; ; n++ {
+			date, ok := recurring.Occurrence(n)
+			if !ok || time.Time(date).After(time.Time(today)) {
+				break
+			}
+
+			result = append(result, RecurringOccurrence{Recurring: recurring, Number: n, Date: date})
+		}
+	}
+
+	slices.SortStableFunc(result, func(a, b RecurringOccurrence) int {
+		return time.Time(a.Date).Compare(time.Time(b.Date))
+	})
+
+	return result, nil
+}
+
+// Posts the occurrences as entries, all of them or none.
+// They have to be in order per recurring entry, as DueOccurrences returns them.
+func PostOccurrences(DB *sqlx.DB, occurrences []RecurringOccurrence) ([]int, error) {
+	tx := DB.MustBegin()
+	defer tx.Rollback()
+
+	var result []int
+	for _, occurrence := range occurrences {
+		err := markOccurrenceHandled(tx, occurrence)
+		if err != nil {
+			return nil, err
+		}
+
+		rows, err := occurrenceRows(occurrence)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't post %s of %s: %v", occurrence.Recurring.Name, occurrence.Date, err)
+		}
+
+		id, err := occurrence.Recurring.Template.Entry.insert(tx, rows)
+		var unbalanced UnbalancedEntryError
+		if errors.As(err, &unbalanced) {
+			return nil, fmt.Errorf("couldn't post %s of %s, at the exchange rates of that day its rows total %s instead of 0.00, post it by hand", occurrence.Recurring.Name, occurrence.Date, unbalanced.Total)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("couldn't post %s of %s: %v", occurrence.Recurring.Name, occurrence.Date, err)
+		}
+		result = append(result, id)
+	}
+
+	return result, tx.Commit()
+}
+
+// The rows of the template, dated on the occurrence.
+// Foreign currency rows are converted at the rate of that date, like rows entered by hand, and their tax is worked out again.
+func occurrenceRows(occurrence RecurringOccurrence) ([]EntryRow, error) {
+	rows := slices.Clone(occurrence.Recurring.Template.Rows)
+	converted := false
+
+	for i := range rows {
+		rows[i].Date = occurrence.Date
+
+		if rows[i].Currency == HOMECURRENCY || rows[i].ForeignValue == nil {
+			continue
+		}
+
+		rate, err := ExchangeRateOn(rows[i].Currency, occurrence.Date)
+		if err != nil {
+			return nil, err
+		}
+
+		rows[i].Value = rate.ToHome(*rows[i].ForeignValue)
+		if rows[i].Value == 0 {
+			return nil, fmt.Errorf("row %d is worth 0.00 in the home currency at %s", i, rate)
+		}
+		converted = true
+	}
+
+	if !converted {
+		return rows, nil
+	}
+
+	return GenerateTaxRows(rows)
+}
+
+// Passes over the occurrence without posting it, it has to be the next one of its recurring entry
+func SkipOccurrence(DB *sqlx.DB, occurrence RecurringOccurrence) error {
+	tx := DB.MustBegin()
+	defer tx.Rollback()
+
+	err := markOccurrenceHandled(tx, occurrence)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func markOccurrenceHandled(tx *sqlx.Tx, occurrence RecurringOccurrence) error {
+	var handled int
+	err := tx.Get(&handled, `SELECT handled FROM recurring_entries WHERE id = $1;`, occurrence.Recurring.Id)
+	if err != nil {
+		return fmt.Errorf("FAILED TO GET RECURRING ENTRY %d: %v", occurrence.Recurring.Id, err)
+	}
+
+	if occurrence.Number < handled {
+		return fmt.Errorf("%s of %s was already posted or skipped", occurrence.Recurring.Name, occurrence.Date)
+	}
+	if occurrence.Number > handled {
+		return fmt.Errorf("post or skip the earlier occurrences of %s first", occurrence.Recurring.Name)
+	}
+
+	_, err = tx.Exec(`UPDATE recurring_entries SET handled = handled + 1 WHERE id = $1;`, occurrence.Recurring.Id)
+	if err != nil {
+		return fmt.Errorf("FAILED TO UPDATE RECURRING ENTRY %d: %v", occurrence.Recurring.Id, err)
+	}
+
+	return nil
+}
+
+// Stops the entry from recurring, the entries already posted stay
+func DeleteRecurringEntry(DB *sqlx.DB, id int) error {
+	res, err := DB.Exec(`DELETE FROM recurring_entries WHERE id = $1;`, id)
+	if err != nil {
+		return fmt.Errorf("FAILED TO DELETE RECURRING ENTRY %d: %v", id, err)
+	}
+
+	removed, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return errors.New("that entry already stopped recurring")
+	}
+
+	return nil
+}
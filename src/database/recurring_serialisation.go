@@ -0,0 +1,39 @@
+package database
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+func (f *Frequency) Scan(value any) error {
+	switch value {
+	case int64(0):
+		*f = DAILYFREQUENCY
+	case int64(1):
+		*f = WEEKLYFREQUENCY
+	case int64(2):
+		*f = MONTHLYFREQUENCY
+	case int64(3):
+		*f = YEARLYFREQUENCY
+
+	default:
+		return fmt.Errorf("UNMARSHALLING INVALID FREQUENCY: %v", value)
+	}
+
+	return nil
+}
+
+func (f Frequency) Value() (driver.Value, error) {
+	switch f {
+	case DAILYFREQUENCY:
+		return int64(0), nil
+	case WEEKLYFREQUENCY:
+		return int64(1), nil
+	case MONTHLYFREQUENCY:
+		return int64(2), nil
+	case YEARLYFREQUENCY:
+		return int64(3), nil
+	}
+
+	return nil, fmt.Errorf("MARSHALLING INVALID FREQUENCY: %v", f)
+}
@@ -0,0 +1,183 @@
+package database_test
+
+import (
+	"terminaccounting/database"
+	"terminaccounting/tat"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func insertRentEntry(t *testing.T, DB *sqlx.DB, date string) int {
+	t.Helper()
+
+	ledger := insertTestLedger(t, DB)
+	journal := insertTestJournal(t, DB)
+
+	parsed, err := database.ToDate(date)
+	require.NoError(t, err)
+
+	entry := database.Entry{Journal: journal.Id}
+	id, err := entry.Insert(DB, []database.EntryRow{
+		{Date: parsed, Ledger: ledger.Id, Description: "Rent", Value: 50000, Reconciled: true},
+		{Date: parsed, Ledger: ledger.Id, Description: "Rent", Value: -50000},
+	})
+	require.NoError(t, err)
+
+	return id
+}
+
+func mustDate(t *testing.T, input string) database.Date {
+	t.Helper()
+
+	date, err := database.ToDate(input)
+	require.NoError(t, err)
+
+	return date
+}
+
+func TestRecurringEntry_Occurrence(t *testing.T) {
+	count := 3
+	endDate := mustDate(t, "24-04-15")
+	recurring := database.RecurringEntry{Frequency: database.MONTHLYFREQUENCY, StartDate: mustDate(t, "24-01-31"), Count: &count}
+
+	date, ok := recurring.Occurrence(1)
+	require.True(t, ok)
+	assert.Equal(t, "24-02-29", date.String(), "sticks to the end of shorter months")
+
+	date, ok = recurring.Occurrence(2)
+	require.True(t, ok)
+	assert.Equal(t, "24-03-31", date.String())
+
+	_, ok = recurring.Occurrence(3)
+	assert.False(t, ok)
+
+	recurring = database.RecurringEntry{Frequency: database.WEEKLYFREQUENCY, StartDate: mustDate(t, "24-04-01"), EndDate: &endDate}
+
+	date, ok = recurring.Occurrence(2)
+	require.True(t, ok)
+	assert.Equal(t, "24-04-15", date.String(), "the end date is inclusive")
+
+	_, ok = recurring.Occurrence(3)
+	assert.False(t, ok)
+}
+
+func TestRecurringEntries(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	entryId := insertRentEntry(t, DB, "24-01-31")
+
+	count := 4
+	recurring, err := database.MakeRecurring(DB, entryId, database.MONTHLYFREQUENCY, nil, &count)
+	require.NoError(t, err)
+	assert.Equal(t, "Rent", recurring.Name)
+	assert.Equal(t, 1, recurring.Handled, "the entry itself is the first occurrence")
+
+	due, err := database.DueOccurrences(DB, mustDate(t, "24-03-31"))
+	require.NoError(t, err)
+	require.Len(t, due, 2)
+	assert.Equal(t, "24-02-29", due[0].Date.String())
+	assert.Equal(t, "24-03-31", due[1].Date.String())
+
+	err = database.SkipOccurrence(DB, due[1])
+	assert.EqualError(t, err, "post or skip the earlier occurrences of Rent first")
+
+	posted, err := database.PostOccurrences(DB, due)
+	require.NoError(t, err)
+	require.Len(t, posted, 2)
+
+	rows, err := database.SelectRowsByEntry(DB, posted[0])
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "24-02-29", rows[0].Date.String())
+	assert.False(t, rows[0].Reconciled, "a new occurrence hasn't been reconciled yet")
+
+	_, err = database.PostOccurrences(DB, due[:1])
+	assert.EqualError(t, err, "Rent of 24-02-29 was already posted or skipped")
+
+	due, err = database.DueOccurrences(DB, mustDate(t, "25-01-01"))
+	require.NoError(t, err)
+	require.Len(t, due, 1, "only 4 occurrences in total")
+	require.NoError(t, database.SkipOccurrence(DB, due[0]))
+
+	due, err = database.DueOccurrences(DB, mustDate(t, "25-01-01"))
+	require.NoError(t, err)
+	assert.Empty(t, due)
+
+	require.NoError(t, database.DeleteRecurringEntry(DB, recurring.Id))
+	assert.EqualError(t, database.DeleteRecurringEntry(DB, recurring.Id), "that entry already stopped recurring")
+}
+
+func TestPostOccurrences_AllOrNothing(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	entryId := insertRentEntry(t, DB, "24-01-01")
+
+	_, err := database.MakeRecurring(DB, entryId, database.DAILYFREQUENCY, nil, nil)
+	require.NoError(t, err)
+
+	period, err := database.ParsePeriod("2024-01")
+	require.NoError(t, err)
+	require.NoError(t, database.LockPeriod(DB, period))
+
+	due, err := database.DueOccurrences(DB, mustDate(t, "24-01-03"))
+	require.NoError(t, err)
+	require.Len(t, due, 2)
+
+	_, err = database.PostOccurrences(DB, due)
+	assert.ErrorContains(t, err, "couldn't post Rent of 24-01-02")
+
+	entries, err := database.SelectEntries(DB)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "nothing got posted")
+}
+
+func TestPostOccurrences_ForeignCurrency(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	ledger := insertTestLedger(t, DB)
+	journal := insertTestJournal(t, DB)
+
+	require.NoError(t, database.SetExchangeRate(DB, database.ExchangeRate{Currency: "USD", Date: mustDate(t, "24-01-01"), Rate: 0.9}))
+	require.NoError(t, database.SetExchangeRate(DB, database.ExchangeRate{Currency: "USD", Date: mustDate(t, "24-02-01"), Rate: 0.95}))
+
+	bill, paid := database.CurrencyValue(2000), database.CurrencyValue(-2000)
+	hosting, err := database.Entry{Journal: journal.Id}.Insert(DB, []database.EntryRow{
+		{Date: mustDate(t, "24-01-10"), Ledger: ledger.Id, Description: "Hosting", Value: 1800, Currency: "USD", ForeignValue: &bill},
+		{Date: mustDate(t, "24-01-10"), Ledger: ledger.Id, Description: "Hosting", Value: -1800, Currency: "USD", ForeignValue: &paid},
+	})
+	require.NoError(t, err)
+	_, err = database.MakeRecurring(DB, hosting, database.MONTHLYFREQUENCY, nil, nil)
+	require.NoError(t, err)
+
+	due, err := database.DueOccurrences(DB, mustDate(t, "24-02-10"))
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+
+	posted, err := database.PostOccurrences(DB, due)
+	require.NoError(t, err)
+	rows, err := database.SelectRowsByEntry(DB, posted[0])
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, database.CurrencyValue(1900), rows[0].Value, "converted at the rate of the occurrence")
+	assert.Equal(t, &bill, rows[0].ForeignValue)
+	assert.Equal(t, database.CurrencyValue(-1900), rows[1].Value)
+
+	// Paid from a home currency ledger, the same dollars are worth more than the euros paid for them now
+	rent, err := database.Entry{Journal: journal.Id}.Insert(DB, []database.EntryRow{
+		{Date: mustDate(t, "24-01-10"), Ledger: ledger.Id, Description: "Rent", Value: 1800, Currency: "USD", ForeignValue: &bill},
+		{Date: mustDate(t, "24-01-10"), Ledger: ledger.Id, Description: "Rent", Value: -1800},
+	})
+	require.NoError(t, err)
+	_, err = database.MakeRecurring(DB, rent, database.MONTHLYFREQUENCY, nil, nil)
+	require.NoError(t, err)
+
+	due, err = database.DueOccurrences(DB, mustDate(t, "24-02-10"))
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+
+	_, err = database.PostOccurrences(DB, due)
+	assert.EqualError(t, err, "couldn't post Rent of 24-02-10, at the exchange rates of that day its rows total 1.00 instead of 0.00, post it by hand")
+}
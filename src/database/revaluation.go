@@ -0,0 +1,112 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"terminaccounting/meta"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// The entry that books unrealised exchange rate differences, as computed by ProposeRevaluation.
+// Posting it makes the booked value of every foreign currency balance match the rate on Date.
+type Revaluation struct {
+	Date         Date
+	ResultLedger int
+
+	// One row per ledger, account and currency whose booked value changes,
+	// followed by the row that books the total difference on the result ledger
+	Rows []EntryRow
+}
+
+func (r Revaluation) IsEmpty() bool {
+	return len(r.Rows) == 0
+}
+
+type currencyBalance struct {
+	Ledger       int           `db:"ledger"`
+	Account      *int          `db:"account"`
+	Currency     string        `db:"currency"`
+	ForeignTotal CurrencyValue `db:"foreign_total"`
+	Total        CurrencyValue `db:"total"`
+}
+
+// Computes the revaluation of all foreign currency balances at the given date,
+// with the differences going to resultLedger
+func ProposeRevaluation(DB *sqlx.DB, date Date, resultLedger Ledger) (Revaluation, error) {
+	if resultLedger.Type != INCOMELEDGER && resultLedger.Type != EXPENSELEDGER {
+		return Revaluation{}, fmt.Errorf("ledger %s isn't an income or expense ledger", resultLedger)
+	}
+
+	// Income and expenses stay at the rate they were booked at, only what's owned and owed is revalued
+	var balances []currencyBalance
+	err := DB.Select(&balances, `SELECT entryrows.ledger, entryrows.account, entryrows.currency,
+			SUM(COALESCE(entryrows.foreign_value, 0)) AS foreign_total, SUM(entryrows.value) AS total
+		FROM entryrows JOIN ledgers ON ledgers.id = entryrows.ledger
+		WHERE entryrows.currency != '' AND entryrows.date <= $1
+			AND (ledgers.type IN ($2, $3) OR ledgers.is_accounts)
+		GROUP BY entryrows.ledger, entryrows.account, entryrows.currency
+		ORDER BY entryrows.ledger, entryrows.account, entryrows.currency;`, date, ASSETLEDGER, LIABILITYLEDGER)
+	if err != nil {
+		return Revaluation{}, fmt.Errorf("FAILED TO SELECT CURRENCY BALANCES: %v", err)
+	}
+
+	rates, err := SelectExchangeRates(DB)
+	if err != nil {
+		return Revaluation{}, fmt.Errorf("FAILED TO SELECT EXCHANGE RATES: %v", err)
+	}
+
+	result := Revaluation{
+		Date:         date,
+		ResultLedger: resultLedger.Id,
+	}
+
+	var totalDifference CurrencyValue
+	for _, balance := range balances {
+		rate, err := exchangeRateOn(rates, balance.Currency, date)
+		if err != nil {
+			return Revaluation{}, err
+		}
+
+		difference := rate.ToHome(balance.ForeignTotal).Subtract(balance.Total)
+		if difference == 0 {
+			continue
+		}
+
+		totalDifference = totalDifference.Add(difference)
+		result.Rows = append(result.Rows, EntryRow{
+			Date:        date,
+			Ledger:      balance.Ledger,
+			Account:     balance.Account,
+			Description: fmt.Sprintf("Revaluation %s", rate),
+			Value:       difference,
+			Currency:    balance.Currency,
+		})
+	}
+
+	if len(result.Rows) != 0 {
+		result.Rows = append(result.Rows, EntryRow{
+			Date:        date,
+			Ledger:      resultLedger.Id,
+			Description: fmt.Sprintf("Exchange rate differences %s", date),
+			Value:       -totalDifference,
+		})
+	}
+
+	return result, nil
+}
+
+// Posts the revaluation as a single entry in the given GENERAL journal, returns its id
+func PostRevaluation(DB *sqlx.DB, revaluation Revaluation, journal Journal) (int, error) {
+	if journal.Type != GENERALJOURNAL {
+		return 0, fmt.Errorf("journal %s isn't a general journal", journal)
+	}
+
+	if revaluation.IsEmpty() {
+		return 0, errors.New("nothing to revalue")
+	}
+
+	entry := Entry{Journal: journal.Id, Notes: meta.Notes{fmt.Sprintf("Revaluation of foreign currencies at %s", revaluation.Date)}}
+
+	return entry.Insert(DB, revaluation.Rows)
+}
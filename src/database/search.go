@@ -0,0 +1,236 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"terminaccounting/meta"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmoiron/sqlx"
+)
+
+// A column whose text is searchable by `:search`
+type searchSource struct {
+	model meta.ModelType
+	table string
+	// The column holding the entry to go to for a match, empty for objects outside entries
+	entry string
+	field string
+}
+
+// The entry of a match in SQL, prefix is "new." inside triggers
+func (ss searchSource) entryExpression(prefix string) string {
+	if ss.entry == "" {
+		return "NULL"
+	}
+
+	return prefix + ss.entry
+}
+
+var searchSources = []searchSource{
+	{meta.ENTRYROWMODEL, "entryrows", "entry", "description"},
+	{meta.ENTRYROWMODEL, "entryrows", "entry", "document"},
+	{meta.ENTRYMODEL, "entries", "id", "notes"},
+	{meta.LEDGERMODEL, "ledgers", "", "notes"},
+	{meta.ACCOUNTMODEL, "accounts", "", "notes"},
+	{meta.JOURNALMODEL, "journals", "", "notes"},
+}
+
+// Selects every searchable text in the book, one row per object and field
+func searchDocumentsQuery() string {
+	var parts []string
+
+	for _, source := range searchSources {
+		parts = append(parts, fmt.Sprintf(
+			`SELECT '%s' AS model, id AS object, %s AS entry, '%s' AS field, %s AS text FROM %s WHERE %s NOT IN ('', '[]')`,
+			source.model, source.entryExpression(""), source.field, source.field, source.table, source.field,
+		))
+	}
+
+	return strings.Join(parts, "\nUNION ALL\n")
+}
+
+// One object that matched a search
+type SearchResult struct {
+	Model  meta.ModelType `db:"model"`
+	Object int            `db:"object"`
+	// For entries and their rows, the entry to go to
+	Entry *int   `db:"entry"`
+	Field string `db:"field"`
+	Text  string `db:"text"`
+}
+
+func (sr SearchResult) FilterValue() string {
+	return sr.Text
+}
+
+func (sr SearchResult) Render(isActive bool) string {
+	modelStyle := lipgloss.NewStyle().Width(len(meta.ENTRYROWMODEL) + 2)
+	if !isActive {
+		modelStyle = modelStyle.Foreground(meta.ENTRIESCOLOUR)
+	}
+
+	return fmt.Sprintf("%s%-12s %s", modelStyle.Render(string(sr.Model)), sr.Field, sr.displayText())
+}
+
+// Notes are stored as JSON lists, shows them the way the views do
+func (sr SearchResult) displayText() string {
+	if sr.Field != "notes" {
+		return sr.Text
+	}
+
+	var notes meta.Notes
+	if json.Unmarshal([]byte(sr.Text), &notes) != nil {
+		return sr.Text
+	}
+
+	return notes.Collapse("; ")
+}
+
+// Whether the SQLite library was built with FTS5, i.e. with the sqlite_fts5 build tag the Makefile sets
+func searchIndexAvailable(DB *sqlx.DB) (bool, error) {
+	var result bool
+
+	err := DB.Get(&result, `SELECT sqlite_compileoption_used('ENABLE_FTS5');`)
+
+	return result, err
+}
+
+// Sets up the FTS5 index and the triggers that keep it in sync.
+// It's derived data, so rather than being a migration it's (re)built here whenever the triggers are missing.
+// Without FTS5 the triggers are dropped, so they can't break writes, and Search falls back to LIKE.
+func setupSearchIndex(DB *sqlx.DB) error {
+	available, err := searchIndexAvailable(DB)
+	if err != nil {
+		return fmt.Errorf("FAILED TO CHECK FOR FTS5: %v", err)
+	}
+
+	var triggerNames []string
+	for _, source := range searchSources {
+		for _, event := range []string{"insert", "update", "delete"} {
+			triggerNames = append(triggerNames, fmt.Sprintf("search_%s_%s_%s", source.table, source.field, event))
+		}
+	}
+
+	tx := DB.MustBegin()
+	defer tx.Rollback()
+
+	if !available {
+		slog.Info("SQLite was built without FTS5, searching without index")
+
+		for _, name := range triggerNames {
+			_, err = tx.Exec(fmt.Sprintf(`DROP TRIGGER IF EXISTS %s;`, name))
+			if err != nil {
+				return fmt.Errorf("FAILED TO DROP SEARCH TRIGGER %s: %v", name, err)
+			}
+		}
+
+		return tx.Commit()
+	}
+
+	var existingTriggers int
+	err = tx.Get(&existingTriggers, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'search\_%' ESCAPE '\';`)
+	if err != nil {
+		return fmt.Errorf("FAILED TO CHECK SEARCH TRIGGERS: %v", err)
+	}
+	if existingTriggers == len(triggerNames) {
+		return nil
+	}
+
+	_, err = tx.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts5(
+		model UNINDEXED,
+		object UNINDEXED,
+		entry UNINDEXED,
+		field UNINDEXED,
+		text
+	);`)
+	if err != nil {
+		return fmt.Errorf("FAILED TO CREATE SEARCH INDEX: %v", err)
+	}
+
+	for _, source := range searchSources {
+		insert := fmt.Sprintf(`INSERT INTO search_index (model, object, entry, field, text)
+			SELECT '%[1]s', new.id, %[2]s, '%[3]s', new.%[3]s WHERE new.%[3]s NOT IN ('', '[]');`,
+			source.model, source.entryExpression("new."), source.field)
+		remove := fmt.Sprintf(`DELETE FROM search_index WHERE model = '%s' AND object = old.id AND field = '%s';`,
+			source.model, source.field)
+
+		name := fmt.Sprintf("search_%s_%s", source.table, source.field)
+		_, err = tx.Exec(fmt.Sprintf(`
+			DROP TRIGGER IF EXISTS %[1]s_insert;
+			DROP TRIGGER IF EXISTS %[1]s_update;
+			DROP TRIGGER IF EXISTS %[1]s_delete;
+			CREATE TRIGGER %[1]s_insert AFTER INSERT ON %[2]s BEGIN %[3]s END;
+			CREATE TRIGGER %[1]s_update AFTER UPDATE OF %[5]s ON %[2]s BEGIN %[4]s %[3]s END;
+			CREATE TRIGGER %[1]s_delete AFTER DELETE ON %[2]s BEGIN %[4]s END;`,
+			name, source.table, insert, remove, source.field))
+		if err != nil {
+			return fmt.Errorf("FAILED TO CREATE SEARCH TRIGGERS FOR %s.%s: %v", source.table, source.field, err)
+		}
+	}
+
+	// The triggers were missing, so the index may have missed changes
+	_, err = tx.Exec(`DELETE FROM search_index;`)
+	if err != nil {
+		return fmt.Errorf("FAILED TO CLEAR SEARCH INDEX: %v", err)
+	}
+	_, err = tx.Exec(`INSERT INTO search_index (model, object, entry, field, text) ` + searchDocumentsQuery() + `;`)
+	if err != nil {
+		return fmt.Errorf("FAILED TO FILL SEARCH INDEX: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+// Searches all descriptions, documents and notes for texts containing every word of the query,
+// words match on prefix. Best matches first if the FTS5 index is available.
+func Search(DB *sqlx.DB, query string) ([]SearchResult, error) {
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return nil, fmt.Errorf("nothing to search for")
+	}
+
+	available, err := searchIndexAvailable(DB)
+	if err != nil {
+		return nil, fmt.Errorf("FAILED TO CHECK FOR FTS5: %v", err)
+	}
+
+	result := []SearchResult{}
+
+	if available {
+		// Quoted, so the user's input isn't interpreted as FTS5 query syntax
+		var terms []string
+		for _, word := range words {
+			terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"*`)
+		}
+
+		err = DB.Select(&result, `SELECT model, object, entry, field, text FROM search_index
+			WHERE search_index MATCH $1
+			ORDER BY rank;`, strings.Join(terms, " "))
+		if err != nil {
+			return nil, fmt.Errorf("FAILED TO SEARCH: %v", err)
+		}
+
+		return result, nil
+	}
+
+	var conditions []string
+	var args []any
+	for _, word := range words {
+		escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(word)
+
+		conditions = append(conditions, `text LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escaped+"%")
+	}
+
+	err = DB.Select(&result, `SELECT * FROM (`+searchDocumentsQuery()+`)
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY entry IS NULL, entry DESC, object DESC;`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("FAILED TO SEARCH: %v", err)
+	}
+
+	return result, nil
+}
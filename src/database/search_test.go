@@ -0,0 +1,79 @@
+package database_test
+
+import (
+	"terminaccounting/database"
+	"terminaccounting/meta"
+	"terminaccounting/tat"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Runs against the FTS5 index when built with -tags sqlite_fts5, and against the LIKE fallback otherwise
+func TestSearch(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	ledger := database.Ledger{Name: "Bank", Type: database.ASSETLEDGER, Notes: meta.Notes{"Main account at the cooperative bank"}}
+	ledgerId, err := ledger.Insert(DB)
+	require.NoError(t, err)
+
+	journal := insertTestJournal(t, DB)
+
+	date, err := database.ToDate("24-01-01")
+	require.NoError(t, err)
+	document := "invoice-2024-001.pdf"
+	entry := database.Entry{Journal: journal.Id, Notes: meta.Notes{"Groceries for the cooperative"}}
+	entryId, err := entry.Insert(DB, []database.EntryRow{
+		{Date: date, Ledger: ledgerId, Description: "Coffee beans", Document: &document, Value: 1000},
+		{Date: date, Ledger: ledgerId, Description: "Tea", Value: -1000},
+	})
+	require.NoError(t, err)
+	entry.Id = entryId
+
+	results, err := database.Search(DB, "cooperative")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []database.SearchResult{
+		{Model: meta.LEDGERMODEL, Object: ledgerId, Field: "notes", Text: `["Main account at the cooperative bank"]`},
+		{Model: meta.ENTRYMODEL, Object: entryId, Entry: &entryId, Field: "notes", Text: `["Groceries for the cooperative"]`},
+	}, results)
+
+	results, err = database.Search(DB, "coff")
+	require.NoError(t, err)
+	require.Len(t, results, 1, "words match on prefix")
+	assert.Equal(t, "Coffee beans", results[0].Text)
+	assert.Equal(t, &entryId, results[0].Entry)
+
+	results, err = database.Search(DB, "invoice 2024")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "document", results[0].Field)
+
+	results, err = database.Search(DB, "cooperative beans")
+	require.NoError(t, err)
+	assert.Empty(t, results, "every word has to match in the same text")
+
+	t.Run("kept in sync", func(t *testing.T) {
+		rows, err := database.SelectRowsByEntry(DB, entryId)
+		require.NoError(t, err)
+		rows[0].Description = "Espresso beans"
+		require.NoError(t, entry.Update(DB, rows))
+
+		results, err := database.Search(DB, "coffee")
+		require.NoError(t, err)
+		assert.Empty(t, results)
+
+		results, err = database.Search(DB, "espresso")
+		require.NoError(t, err)
+		assert.Len(t, results, 1)
+
+		require.NoError(t, database.DeleteEntry(DB, entryId))
+
+		results, err = database.Search(DB, "beans")
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+
+	_, err = database.Search(DB, "  ")
+	assert.EqualError(t, err, "nothing to search for")
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"terminaccounting/meta"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmoiron/sqlx"
+)
+
+// A bank statement a ledger was reconciled against.
+// The reconciled rows of the ledger add up to its closing balance, see SaveStatement.
+// Amounts are in the ledger's currency, like the statement of a foreign bank account is.
+type Statement struct {
+	Id             int           `db:"id"`
+	Ledger         int           `db:"ledger"`
+	Date           Date          `db:"date"`
+	ClosingBalance CurrencyValue `db:"closing_balance"`
+	ReconciledOn   Date          `db:"reconciled_on"`
+
+	// The rows reconciled against it, only filled by SelectStatements
+	Rows int `db:"rows"`
+}
+
+func (s Statement) FilterValue() string {
+	return s.Date.String() + s.ClosingBalance.String() + s.ReconciledOn.String()
+}
+
+func (s Statement) Render(isActive bool) string {
+	style := lipgloss.NewStyle()
+	if isActive {
+		style = style.Foreground(meta.LEDGERSCOLOUR)
+	}
+
+	return style.Render(fmt.Sprintf("%-10s %16s %8d %14s", s.Date, s.ClosingBalance, s.Rows, s.ReconciledOn))
+}
+
+func StatementsHeader() string {
+	return fmt.Sprintf("%-10s %16s %8s %14s", "Date", "Closing balance", "Rows", "Reconciled on")
+}
+
+// How far the reconciled rows are off from the closing balance, reconciling is done once it's 0
+func (s Statement) Difference(rows []*EntryRow) CurrencyValue {
+	var reconciled CurrencyValue
+	for _, row := range rows {
+		if row.Reconciled {
+			reconciled = reconciled.Add(row.InOwnCurrency())
+		}
+	}
+
+	return s.ClosingBalance.Subtract(reconciled)
+}
+
+// Newest first
+func SelectStatements(DB *sqlx.DB, ledger int) ([]Statement, error) {
+	result := []Statement{}
+
+	err := DB.Select(&result, `SELECT statements.*,
+			(SELECT COUNT(*) FROM entryrows WHERE entryrows.statement = statements.id) AS rows
+		FROM statements WHERE ledger = $1
+		ORDER BY date DESC, id DESC;`, ledger)
+	if err != nil {
+		return nil, fmt.Errorf("FAILED TO SELECT STATEMENTS OF LEDGER %d: %v", ledger, err)
+	}
+
+	return result, nil
+}
+
+// Saves the statement and the rows ticked on it. Rows newly reconciled point to the statement,
+// rows no longer reconciled are let go of. Afterwards all reconciled rows of the ledger have to add up to the closing balance.
+// Returns the saved statement and how many rows changed.
+func SaveStatement(DB *sqlx.DB, statement Statement, rows []*EntryRow) (Statement, int, error) {
+	tx := DB.MustBegin()
+	defer tx.Rollback()
+
+	var previous []Statement
+	err := tx.Select(&previous, `SELECT * FROM statements WHERE ledger = $1 ORDER BY date DESC LIMIT 1;`, statement.Ledger)
+	if err != nil {
+		return Statement{}, 0, fmt.Errorf("FAILED TO SELECT LAST STATEMENT: %v", err)
+	}
+	if len(previous) != 0 && time.Time(statement.Date).Before(time.Time(previous[0].Date)) {
+		return Statement{}, 0, fmt.Errorf("the statement of %s is older than the last one reconciled, of %s", statement.Date, previous[0].Date)
+	}
+
+	statement.ReconciledOn = *Today()
+	res, err := tx.NamedExec(`INSERT INTO statements (ledger, date, closing_balance, reconciled_on)
+		VALUES (:ledger, :date, :closing_balance, :reconciled_on);`, statement)
+	if err != nil {
+		return Statement{}, 0, fmt.Errorf("FAILED TO INSERT STATEMENT: %v", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Statement{}, 0, err
+	}
+	statement.Id = int(id)
+
+	var changedRows []*EntryRow
+	// The entries that had a row toggled, in order, each gets one version in the audit log
+	var changedEntries []int
+
+	for _, row := range rows {
+		var stored EntryRow
+		err := tx.Get(&stored, `SELECT * FROM entryrows WHERE id = $1;`, row.Id)
+		if err != nil {
+			return Statement{}, 0, err
+		}
+
+		if stored.Reconciled == row.Reconciled {
+			continue
+		}
+
+		if stored.Ledger != statement.Ledger {
+			return Statement{}, 0, fmt.Errorf("row %d isn't on the ledger of the statement", row.Id)
+		}
+		if stored.Reconciliation != nil {
+			return Statement{}, 0, fmt.Errorf("row %d is matched in group %d, unmatch the group to unreconcile it", row.Id, *stored.Reconciliation)
+		}
+
+		err = checkPeriodsOpen(tx, []EntryRow{stored})
+		if err != nil {
+			return Statement{}, 0, err
+		}
+
+		if row.Reconciled {
+			_, err = tx.Exec(`UPDATE entryrows SET reconciled = 1, statement = $1 WHERE id = $2;`, statement.Id, row.Id)
+		} else {
+			_, err = tx.Exec(`UPDATE entryrows SET reconciled = 0, statement = NULL WHERE id = $1;`, row.Id)
+		}
+		if err != nil {
+			return Statement{}, 0, err
+		}
+
+		changedRows = append(changedRows, row)
+		if !slices.Contains(changedEntries, stored.Entry) {
+			changedEntries = append(changedEntries, stored.Entry)
+		}
+	}
+
+	var balance CurrencyValue
+	err = tx.Get(&balance, `SELECT COALESCE(SUM(`+inOwnCurrencySQL+`), 0) FROM entryrows WHERE ledger = $1 AND reconciled;`, statement.Ledger)
+	if err != nil {
+		return Statement{}, 0, fmt.Errorf("FAILED TO GET RECONCILED BALANCE: %v", err)
+	}
+	if balance != statement.ClosingBalance {
+		return Statement{}, 0, fmt.Errorf("the reconciled rows add up to %s, but the statement closes at %s", balance, statement.ClosingBalance)
+	}
+
+	for _, entryId := range changedEntries {
+		err := recordEntryVersion(tx, entryId, RECONCILEACTION)
+		if err != nil {
+			return Statement{}, 0, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Statement{}, 0, fmt.Errorf("FAILED TO COMMIT STATEMENT: %v", err)
+	}
+
+	for _, row := range changedRows {
+		if row.Reconciled {
+			statementId := statement.Id
+			row.Statement = &statementId
+			statement.Rows++
+		} else {
+			row.Statement = nil
+		}
+	}
+
+	return statement, len(changedRows), nil
+}
@@ -0,0 +1,117 @@
+package database_test
+
+import (
+	"terminaccounting/database"
+	"terminaccounting/tat"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveStatement(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	bank, err := (&database.Ledger{Name: "Bank", Type: database.ASSETLEDGER}).Insert(DB)
+	require.NoError(t, err)
+	sales, err := (&database.Ledger{Name: "Sales", Type: database.INCOMELEDGER}).Insert(DB)
+	require.NoError(t, err)
+
+	journal := insertTestJournal(t, DB)
+	book := func(date string, value database.CurrencyValue) {
+		_, err := database.Entry{Journal: journal.Id}.Insert(DB, []database.EntryRow{
+			{Date: mustDate(t, date), Ledger: bank, Value: value},
+			{Date: mustDate(t, date), Ledger: sales, Value: -value},
+		})
+		require.NoError(t, err)
+	}
+
+	book("24-03-05", 10000)
+	book("24-03-20", -2500)
+	book("24-03-30", 4000)
+
+	rows, err := database.SelectRowsByLedger(DB, bank)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	pointers := []*database.EntryRow{&rows[0], &rows[1], &rows[2]}
+
+	statement := database.Statement{Ledger: bank, Date: mustDate(t, "24-03-31"), ClosingBalance: 7500}
+	assert.Equal(t, database.CurrencyValue(7500), statement.Difference(pointers))
+
+	// The last row only shows up on next month's statement
+	rows[0].Reconciled = true
+	rows[1].Reconciled = true
+	assert.Equal(t, database.CurrencyValue(0), statement.Difference(pointers))
+
+	saved, changed, err := database.SaveStatement(DB, statement, pointers)
+	require.NoError(t, err)
+	assert.Equal(t, 2, changed)
+	assert.Equal(t, 1, saved.Id)
+	require.NotNil(t, rows[0].Statement)
+	assert.Equal(t, 1, *rows[0].Statement)
+	assert.Nil(t, rows[2].Statement)
+
+	statements, err := database.SelectStatements(DB, bank)
+	require.NoError(t, err)
+	require.Len(t, statements, 1)
+	assert.Equal(t, 2, statements[0].Rows)
+	assert.Equal(t, database.CurrencyValue(7500), statements[0].ClosingBalance)
+
+	// Statement rows don't settle each other, so the integrity check compares them to the closing balance
+	problems, err := database.CheckIntegrity(DB)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+
+	_, _, err = database.SaveStatement(DB, database.Statement{Ledger: bank, Date: mustDate(t, "24-02-29"), ClosingBalance: 7500}, nil)
+	assert.EqualError(t, err, "the statement of 24-02-29 is older than the last one reconciled, of 24-03-31")
+
+	_, _, err = database.SaveStatement(DB, database.Statement{Ledger: bank, Date: mustDate(t, "24-04-30"), ClosingBalance: 10000}, pointers)
+	assert.EqualError(t, err, "the reconciled rows add up to 75.00, but the statement closes at 100.00")
+
+	// Statement rows are only unreconciled against a new statement
+	rows[1].Reconciled = false
+	_, err = database.SetReconciled(DB, pointers)
+	assert.EqualError(t, err, "row 3 was reconciled against statement 1, unreconcile it against a new statement")
+}
+
+func TestSaveStatement_ForeignLedger(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	bank, err := (&database.Ledger{Name: "PayPal", Type: database.ASSETLEDGER, Currency: "USD"}).Insert(DB)
+	require.NoError(t, err)
+	sales, err := (&database.Ledger{Name: "Sales", Type: database.INCOMELEDGER}).Insert(DB)
+	require.NoError(t, err)
+
+	journal := insertTestJournal(t, DB)
+	dollars := database.CurrencyValue(1000)
+	_, err = database.Entry{Journal: journal.Id}.Insert(DB, []database.EntryRow{
+		{Date: mustDate(t, "24-03-05"), Ledger: bank, Value: 900, Currency: "USD", ForeignValue: &dollars},
+		{Date: mustDate(t, "24-03-05"), Ledger: sales, Value: -900},
+	})
+	require.NoError(t, err)
+	// A revaluation only changes what the dollars are worth at home
+	_, err = database.Entry{Journal: journal.Id}.Insert(DB, []database.EntryRow{
+		{Date: mustDate(t, "24-03-31"), Ledger: bank, Value: 20, Currency: "USD"},
+		{Date: mustDate(t, "24-03-31"), Ledger: sales, Value: -20},
+	})
+	require.NoError(t, err)
+
+	rows, err := database.SelectRowsByLedger(DB, bank)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	rows[0].Reconciled = true
+	rows[1].Reconciled = true
+	pointers := []*database.EntryRow{&rows[0], &rows[1]}
+
+	// The statement closes in dollars
+	statement := database.Statement{Ledger: bank, Date: mustDate(t, "24-03-31"), ClosingBalance: 1000}
+	assert.Equal(t, database.CurrencyValue(0), statement.Difference(pointers))
+
+	_, changed, err := database.SaveStatement(DB, statement, pointers)
+	require.NoError(t, err)
+	assert.Equal(t, 2, changed)
+
+	problems, err := database.CheckIntegrity(DB)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
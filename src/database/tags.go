@@ -0,0 +1,162 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// The tags and dimensions of an entry row, separated by spaces.
+// Plain tags like `italy-2024` mark a row, `key:value` dimensions like `project:x` give it a value for key.
+// Kept as a normalised string rather than a slice, so EntryRows stay comparable.
+type Tags string
+
+// Parses tags separated by spaces or commas, into sorted tags without duplicates
+func ParseTags(input string) (Tags, error) {
+	fields := strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+
+	var result []string
+	dimensions := make(map[string]struct{})
+	for _, field := range fields {
+		if slices.Contains(result, field) {
+			continue
+		}
+
+		if key, value, isDimension := strings.Cut(field, ":"); isDimension {
+			if key == "" || value == "" {
+				return "", fmt.Errorf("invalid tag %q, dimensions are written as key:value", field)
+			}
+
+			if _, ok := dimensions[key]; ok {
+				return "", fmt.Errorf("dimension %q is given more than one value", key)
+			}
+			dimensions[key] = struct{}{}
+		}
+
+		result = append(result, field)
+	}
+
+	slices.Sort(result)
+
+	return Tags(strings.Join(result, " ")), nil
+}
+
+func (t Tags) List() []string {
+	return strings.Fields(string(t))
+}
+
+// The tags that aren't dimensions
+func (t Tags) Plain() []string {
+	var result []string
+
+	for _, tag := range t.List() {
+		if !strings.Contains(tag, ":") {
+			result = append(result, tag)
+		}
+	}
+
+	return result
+}
+
+// The value of the dimension key, if the row has one
+func (t Tags) Dimension(key string) (string, bool) {
+	for _, tag := range t.List() {
+		if tagKey, value, isDimension := strings.Cut(tag, ":"); isDimension && tagKey == key {
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+// The total of the rows with one value of a dimension (or one tag) on one ledger
+type DimensionTotal struct {
+	Value  string
+	Ledger int
+	Total  CurrencyValue
+}
+
+// Totals the rows per value of the dimension and per ledger, sorted by value and ledger.
+// With an empty dimension it totals per plain tag instead, a row with several tags counts towards each.
+// Rows without the dimension are left out.
+func TotalsByDimension(DB *sqlx.DB, dimension string) ([]DimensionTotal, error) {
+	var rows []EntryRow
+	err := DB.Select(&rows, `SELECT * FROM entryrows WHERE tags != '';`)
+	if err != nil {
+		return nil, fmt.Errorf("FAILED TO SELECT TAGGED ROWS: %v", err)
+	}
+
+	type groupKey struct {
+		value  string
+		ledger int
+	}
+	totals := make(map[groupKey]CurrencyValue)
+
+	for _, row := range rows {
+		var values []string
+		if dimension == "" {
+			values = row.Tags.Plain()
+		} else if value, ok := row.Tags.Dimension(dimension); ok {
+			values = []string{value}
+		}
+
+		for _, value := range values {
+			key := groupKey{value: value, ledger: row.Ledger}
+			totals[key] = totals[key].Add(row.Value)
+		}
+	}
+
+	var result []DimensionTotal
+	for key, total := range totals {
+		result = append(result, DimensionTotal{Value: key.value, Ledger: key.ledger, Total: total})
+	}
+
+	slices.SortFunc(result, func(a, b DimensionTotal) int {
+		if a.Value != b.Value {
+			return strings.Compare(a.Value, b.Value)
+		}
+
+		return a.Ledger - b.Ledger
+	})
+
+	return result, nil
+}
+
+// Renders the totals as a report, each value with its total and then its totals per ledger
+func RenderDimensionTotals(dimension string, totals []DimensionTotal) []string {
+	title := fmt.Sprintf("Totals per %s", dimension)
+	if dimension == "" {
+		title = "Totals per tag, rows with several tags count towards each"
+	}
+
+	if len(totals) == 0 {
+		if dimension == "" {
+			return []string{title, "", "No rows are tagged"}
+		}
+
+		return []string{title, "", fmt.Sprintf("No rows have a %s", dimension)}
+	}
+
+	result := []string{title}
+	for start := 0; start < len(totals); {
+		end := start
+		var valueTotal CurrencyValue
+		for end < len(totals) && totals[end].Value == totals[start].Value {
+			valueTotal = valueTotal.Add(totals[end].Total)
+			end++
+		}
+
+		result = append(result, "", fmt.Sprintf("%-40s %14s", totals[start].Value, valueTotal))
+		for _, total := range totals[start:end] {
+			result = append(result, fmt.Sprintf("  %-38s %14s", ledgerName(total.Ledger), total.Total))
+		}
+
+		start = end
+	}
+
+	return result
+}
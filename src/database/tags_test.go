@@ -0,0 +1,91 @@
+package database_test
+
+import (
+	"terminaccounting/database"
+	"terminaccounting/tat"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTags(t *testing.T) {
+	tags, err := database.ParseTags("project:x, italy\tproject:x  2024")
+	require.NoError(t, err)
+	assert.Equal(t, database.Tags("2024 italy project:x"), tags)
+
+	assert.Equal(t, []string{"2024", "italy"}, tags.Plain())
+
+	value, ok := tags.Dimension("project")
+	assert.True(t, ok)
+	assert.Equal(t, "x", value)
+
+	_, ok = tags.Dimension("region")
+	assert.False(t, ok)
+
+	tags, err = database.ParseTags("  ")
+	require.NoError(t, err)
+	assert.Equal(t, database.Tags(""), tags)
+	assert.Empty(t, tags.List())
+
+	_, err = database.ParseTags("project:")
+	assert.EqualError(t, err, `invalid tag "project:", dimensions are written as key:value`)
+
+	_, err = database.ParseTags("project:x project:y")
+	assert.EqualError(t, err, `dimension "project" is given more than one value`)
+}
+
+func TestTotalsByDimension(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	bank := database.Ledger{Name: "Bank", Type: database.ASSETLEDGER}
+	bankId, err := bank.Insert(DB)
+	require.NoError(t, err)
+
+	costs := database.Ledger{Name: "Costs", Type: database.EXPENSELEDGER}
+	costsId, err := costs.Insert(DB)
+	require.NoError(t, err)
+	require.NoError(t, database.UpdateCache(DB))
+
+	journal := insertTestJournal(t, DB)
+
+	date, err := database.ToDate("24-01-01")
+	require.NoError(t, err)
+	entry := database.Entry{Journal: journal.Id}
+	_, err = entry.Insert(DB, []database.EntryRow{
+		{Date: date, Ledger: costsId, Value: 1000, Tags: "italy project:x"},
+		{Date: date, Ledger: costsId, Value: 500, Tags: "project:y"},
+		{Date: date, Ledger: costsId, Value: 250, Tags: "italy travel"},
+		{Date: date, Ledger: bankId, Value: -1750},
+	})
+	require.NoError(t, err)
+
+	totals, err := database.TotalsByDimension(DB, "project")
+	require.NoError(t, err)
+	assert.Equal(t, []database.DimensionTotal{
+		{Value: "x", Ledger: costsId, Total: 1000},
+		{Value: "y", Ledger: costsId, Total: 500},
+	}, totals)
+
+	totals, err = database.TotalsByDimension(DB, "")
+	require.NoError(t, err)
+	assert.Equal(t, []database.DimensionTotal{
+		{Value: "italy", Ledger: costsId, Total: 1250},
+		{Value: "travel", Ledger: costsId, Total: 250},
+	}, totals, "a row counts towards each of its tags")
+
+	report := database.RenderDimensionTotals("project", []database.DimensionTotal{
+		{Value: "x", Ledger: bankId, Total: -1000},
+		{Value: "x", Ledger: costsId, Total: 1000},
+	})
+	require.Len(t, report, 5)
+	assert.Equal(t, "Totals per project", report[0])
+	assert.Regexp(t, `^x\s+0\.00$`, report[2])
+	assert.Regexp(t, `^  Bank.*\s+-10\.00$`, report[3])
+	assert.Regexp(t, `^  Costs.*\s+10\.00$`, report[4])
+
+	totals, err = database.TotalsByDimension(DB, "region")
+	require.NoError(t, err)
+	assert.Empty(t, totals)
+	assert.Equal(t, []string{"Totals per region", "", "No rows have a region"}, database.RenderDimensionTotals("region", totals))
+}
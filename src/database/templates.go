@@ -0,0 +1,247 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"terminaccounting/meta"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// A named skeleton of an entry, for entries that keep coming back with the same ledgers and accounts
+type EntryTemplate struct {
+	Id      int          `db:"id"`
+	Name    string       `db:"name"`
+	Journal int          `db:"journal"`
+	Notes   meta.Notes   `db:"notes"`
+	Rows    TemplateRows `db:"rows"`
+}
+
+type TemplateRows []TemplateRow
+
+// A row of a template. Its amount is either fixed by Value, a share of the entry's total by Percentage, or left empty.
+// Amounts are in the currency of the row's ledger or account, like when typed into the entry create view.
+type TemplateRow struct {
+	Ledger      int            `json:"ledger"`
+	Account     *int           `json:"account"`
+	Description string         `json:"description"`
+	Tags        Tags           `json:"tags"`
+	Value       *CurrencyValue `json:"value"`
+	// Debits positive and credits negative, like Value
+	Percentage *float64 `json:"percentage"`
+	// The tax rows are generated again from this when the entry is saved, so they aren't part of the template
+	TaxCode *int `json:"tax_code"`
+}
+
+func (et EntryTemplate) String() string {
+	return et.Name
+}
+
+// Saves the entry as a template, with its amounts fixed or, if split is set, as percentages of the money it moves
+func SaveTemplate(DB *sqlx.DB, entryId int, name string, split bool) (EntryTemplate, error) {
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return EntryTemplate{}, fmt.Errorf("invalid template name %q, it has to be a single word", name)
+	}
+
+	var count int
+	err := DB.Get(&count, `SELECT COUNT(*) FROM entry_templates WHERE name = $1;`, name)
+	if err != nil {
+		return EntryTemplate{}, err
+	}
+	if count != 0 {
+		return EntryTemplate{}, fmt.Errorf("there's already a template named %q, remove it first with :deletetemplate", name)
+	}
+
+	entry, err := SelectEntry(DB, entryId)
+	if err != nil {
+		return EntryTemplate{}, fmt.Errorf("FAILED TO GET ENTRY %d: %v", entryId, err)
+	}
+
+	rows, err := SelectRowsByEntry(DB, entryId)
+	if err != nil {
+		return EntryTemplate{}, fmt.Errorf("FAILED TO GET ROWS OF ENTRY %d: %v", entryId, err)
+	}
+	if len(rows) == 0 {
+		return EntryTemplate{}, errors.New("an entry without rows can't be a template")
+	}
+
+	var size CurrencyValue
+	for _, row := range rows {
+		if row.Value > 0 {
+			size = size.Add(row.Value)
+		}
+	}
+	if split && size == 0 {
+		return EntryTemplate{}, fmt.Errorf("entry %d moves no money, so it can't be split", entryId)
+	}
+
+	result := EntryTemplate{
+		Name:    name,
+		Journal: entry.Journal,
+		Notes:   entry.Notes,
+		Rows:    TemplateRows{},
+	}
+
+	for _, row := range rows {
+		if row.TaxRole.IsGenerated() {
+			continue
+		}
+
+		templateRow := TemplateRow{
+			Ledger:      row.Ledger,
+			Account:     row.Account,
+			Description: row.Description,
+			Tags:        row.Tags,
+			TaxCode:     row.TaxCode,
+		}
+
+		if split {
+			percentage := float64(row.Value) / float64(size) * 100
+			templateRow.Percentage = &percentage
+		} else {
+			value := row.Value
+			if row.ForeignValue != nil {
+				value = *row.ForeignValue
+			}
+			templateRow.Value = &value
+		}
+
+		result.Rows = append(result.Rows, templateRow)
+	}
+
+	res, err := DB.NamedExec(`INSERT INTO entry_templates (name, journal, notes, rows) VALUES (:name, :journal, :notes, :rows);`, result)
+	if err != nil {
+		return EntryTemplate{}, fmt.Errorf("FAILED TO INSERT TEMPLATE: %v", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return EntryTemplate{}, err
+	}
+	result.Id = int(id)
+
+	return result, nil
+}
+
+func SelectTemplates(DB *sqlx.DB) ([]EntryTemplate, error) {
+	result := []EntryTemplate{}
+
+	err := DB.Select(&result, `SELECT * FROM entry_templates ORDER BY name;`)
+
+	return result, err
+}
+
+func SelectTemplateByName(DB *sqlx.DB, name string) (EntryTemplate, error) {
+	templates, err := SelectTemplates(DB)
+	if err != nil {
+		return EntryTemplate{}, fmt.Errorf("FAILED TO SELECT TEMPLATES: %v", err)
+	}
+
+	index := slices.IndexFunc(templates, func(template EntryTemplate) bool { return template.Name == name })
+	if index == -1 {
+		if len(templates) == 0 {
+			return EntryTemplate{}, fmt.Errorf("no template named %q, save one from an entry with :savetemplate", name)
+		}
+
+		names := make([]string, len(templates))
+		for i, template := range templates {
+			names[i] = template.Name
+		}
+
+		return EntryTemplate{}, fmt.Errorf("no template named %q, there are: %s", name, strings.Join(names, ", "))
+	}
+
+	return templates[index], nil
+}
+
+func DeleteTemplate(DB *sqlx.DB, name string) error {
+	res, err := DB.Exec(`DELETE FROM entry_templates WHERE name = $1;`, name)
+	if err != nil {
+		return fmt.Errorf("FAILED TO DELETE TEMPLATE %q: %v", name, err)
+	}
+
+	removed, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return fmt.Errorf("no template named %q", name)
+	}
+
+	return nil
+}
+
+// The rows of an entry made from the template, dated on date.
+// Rows with a percentage get that share of total, or are left empty if it's nil.
+// Rounding differences go to the row with the largest share, so splits that balance keep balancing.
+func (et EntryTemplate) Instantiate(date Date, total *CurrencyValue) ([]EntryRow, error) {
+	availableLedgers := AvailableLedgers()
+	availableAccounts := AvailableAccounts()
+
+	result := make([]EntryRow, len(et.Rows))
+
+	var percentageSum float64
+	var splitSum CurrencyValue
+	largestShare := -1
+
+	for i, templateRow := range et.Rows {
+		if !slices.ContainsFunc(availableLedgers, func(ledger Ledger) bool { return ledger.Id == templateRow.Ledger }) {
+			return nil, fmt.Errorf("template %s uses ledger %d, which was deleted", et.Name, templateRow.Ledger)
+		}
+		if templateRow.Account != nil && !slices.ContainsFunc(availableAccounts, func(account Account) bool { return account.Id == *templateRow.Account }) {
+			return nil, fmt.Errorf("template %s uses account %d, which was deleted", et.Name, *templateRow.Account)
+		}
+
+		if templateRow.TaxCode != nil {
+			if _, err := taxCodeById(*templateRow.TaxCode); err != nil {
+				return nil, fmt.Errorf("template %s uses tax code %d, which was removed", et.Name, *templateRow.TaxCode)
+			}
+		}
+
+		result[i] = EntryRow{
+			Date:        date,
+			Ledger:      templateRow.Ledger,
+			Account:     templateRow.Account,
+			Description: templateRow.Description,
+			Tags:        templateRow.Tags,
+			TaxCode:     templateRow.TaxCode,
+		}
+
+		switch {
+		case templateRow.Value != nil:
+			result[i].Value = *templateRow.Value
+
+		case templateRow.Percentage != nil && total != nil:
+			result[i].Value = CurrencyValue(math.Round(float64(*total) * *templateRow.Percentage / 100))
+
+			percentageSum += *templateRow.Percentage
+			splitSum = splitSum.Add(result[i].Value)
+			if largestShare == -1 || math.Abs(*templateRow.Percentage) > math.Abs(*et.Rows[largestShare].Percentage) {
+				largestShare = i
+			}
+		}
+	}
+
+	if largestShare != -1 && math.Abs(percentageSum) < 1e-6 && splitSum != 0 {
+		result[largestShare].Value = result[largestShare].Value.Subtract(splitSum)
+	}
+
+	return result, nil
+}
+
+// For `:template` without a name
+func RenderTemplates(templates []EntryTemplate) []string {
+	if len(templates) == 0 {
+		return []string{"No templates yet", "", "Save an entry as one with :savetemplate <name> [split] from its detail view"}
+	}
+
+	result := []string{"Templates, use one with :template <name> [total]", ""}
+	for _, template := range templates {
+		result = append(result, fmt.Sprintf("%-20s %s, %d row(s)", template.Name, journalName(template.Journal), len(template.Rows)))
+	}
+
+	return result
+}
@@ -0,0 +1,25 @@
+package database
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+func (tr *TemplateRows) Scan(value any) error {
+	converted, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("UNMARSHALLING INVALID TEMPLATE ROWS: %v", value)
+	}
+
+	return json.Unmarshal([]byte(converted), tr)
+}
+
+func (tr TemplateRows) Value() (driver.Value, error) {
+	binary, err := json.Marshal(tr)
+	if err != nil {
+		return nil, fmt.Errorf("MARSHALLING INVALID TEMPLATE ROWS: %v", err)
+	}
+
+	return string(binary), nil
+}
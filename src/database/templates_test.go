@@ -0,0 +1,86 @@
+package database_test
+
+import (
+	"terminaccounting/database"
+	"terminaccounting/meta"
+	"terminaccounting/tat"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplates(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	ledger := insertTestLedger(t, DB)
+	journal := insertTestJournal(t, DB)
+
+	// Groceries with 21% VAT, paid from the bank
+	date := mustDate(t, "24-01-01")
+	entry := database.Entry{Journal: journal.Id, Notes: meta.Notes{"Groceries"}}
+	entryId, err := entry.Insert(DB, []database.EntryRow{
+		{Date: date, Ledger: ledger.Id, Description: "Groceries", Value: 10000, Tags: "food"},
+		{Date: date, Ledger: ledger.Id, Description: "VAT", Value: 2100},
+		{Date: date, Ledger: ledger.Id, Description: "Bank", Value: -12100},
+	})
+	require.NoError(t, err)
+	require.NoError(t, database.UpdateCache(DB))
+
+	fixed, err := database.SaveTemplate(DB, entryId, "groceries", false)
+	require.NoError(t, err)
+	split, err := database.SaveTemplate(DB, entryId, "groceries-split", true)
+	require.NoError(t, err)
+
+	_, err = database.SaveTemplate(DB, entryId, "groceries", false)
+	assert.EqualError(t, err, `there's already a template named "groceries", remove it first with :deletetemplate`)
+	_, err = database.SaveTemplate(DB, entryId, "two words", false)
+	assert.EqualError(t, err, `invalid template name "two words", it has to be a single word`)
+
+	selected, err := database.SelectTemplateByName(DB, "groceries")
+	require.NoError(t, err)
+	assert.Equal(t, fixed, selected)
+	assert.Equal(t, meta.Notes{"Groceries"}, selected.Notes)
+
+	_, err = database.SelectTemplateByName(DB, "rent")
+	assert.EqualError(t, err, `no template named "rent", there are: groceries, groceries-split`)
+
+	today := mustDate(t, "24-06-01")
+
+	rows, err := fixed.Instantiate(today, nil)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, database.EntryRow{Date: today, Ledger: ledger.Id, Description: "Groceries", Value: 10000, Tags: "food"}, rows[0])
+
+	rows, err = split.Instantiate(today, nil)
+	require.NoError(t, err)
+	assert.Equal(t, database.CurrencyValue(0), rows[0].Value, "without a total, split rows are left empty")
+
+	total := database.CurrencyValue(10001)
+	rows, err = split.Instantiate(today, &total)
+	require.NoError(t, err)
+	assert.Equal(t, database.CurrencyValue(8265), rows[0].Value)
+	assert.Equal(t, database.CurrencyValue(1736), rows[1].Value)
+	assert.Equal(t, database.CurrencyValue(-10001), rows[2].Value)
+
+	// Thirds don't add up after rounding, the row with the largest share takes the difference
+	third, whole := 100.0/3, -100.0
+	thirds := database.EntryTemplate{Name: "thirds", Rows: database.TemplateRows{
+		{Ledger: ledger.Id, Percentage: &third},
+		{Ledger: ledger.Id, Percentage: &third},
+		{Ledger: ledger.Id, Percentage: &third},
+		{Ledger: ledger.Id, Percentage: &whole},
+	}}
+	total = database.CurrencyValue(100)
+	rows, err = thirds.Instantiate(today, &total)
+	require.NoError(t, err)
+	assert.Equal(t, []database.CurrencyValue{33, 33, 33, -99}, []database.CurrencyValue{rows[0].Value, rows[1].Value, rows[2].Value, rows[3].Value})
+
+	require.NoError(t, database.DeleteLedger(DB, ledger.Id))
+	require.NoError(t, database.UpdateCache(DB))
+	_, err = fixed.Instantiate(today, nil)
+	assert.ErrorContains(t, err, "which was deleted")
+
+	require.NoError(t, database.DeleteTemplate(DB, "groceries"))
+	assert.EqualError(t, database.DeleteTemplate(DB, "groceries"), `no template named "groceries"`)
+}
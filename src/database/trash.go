@@ -0,0 +1,292 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"terminaccounting/meta"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmoiron/sqlx"
+)
+
+// A deleted ledger, account, journal or entry, kept until it's restored or purged.
+// Deleting moves objects here, so nothing else ever has to skip over deleted data.
+type TrashItem struct {
+	Id int `db:"id"`
+	// Which kind of object Data holds
+	Model meta.ModelType `db:"model"`
+	// The id the object had, it gets that id back when restored
+	Object    int    `db:"object"`
+	Name      string `db:"name"`
+	DeletedAt string `db:"deleted_at"`
+	// The object as JSON, an EntrySnapshot for entries so their rows come along
+	Data string `db:"data"`
+}
+
+func (ti TrashItem) FilterValue() string {
+	return string(ti.Model) + ti.Name
+}
+
+func (ti TrashItem) Render(isActive bool) string {
+	modelStyle := lipgloss.NewStyle().Width(len(meta.JOURNALMODEL) + 2)
+	if !isActive {
+		modelStyle = modelStyle.Foreground(meta.ENTRIESCOLOUR)
+	}
+
+	return fmt.Sprintf("%s  %s%s", ti.DeletedAt, modelStyle.Render(string(ti.Model)), ti.Name)
+}
+
+// Stores the object in the trash, as part of the transaction that deletes it
+func moveToTrash(tx *sqlx.Tx, model meta.ModelType, object int, name string, data any) error {
+	binary, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("FAILED TO MARSHAL %s %d FOR THE TRASH: %v", model, object, err)
+	}
+
+	_, err = tx.Exec(`INSERT INTO trash (model, object, name, deleted_at, data) VALUES ($1, $2, $3, $4, $5);`,
+		model, object, name, time.Now().Format(TIMESTAMP_FORMAT), string(binary))
+	if err != nil {
+		return fmt.Errorf("FAILED TO MOVE %s %d TO THE TRASH: %v", model, object, err)
+	}
+
+	return nil
+}
+
+// Everything in the trash, most recently deleted first
+func SelectTrash(DB *sqlx.DB) ([]TrashItem, error) {
+	result := []TrashItem{}
+
+	err := DB.Select(&result, `SELECT * FROM trash ORDER BY deleted_at DESC, id DESC;`)
+
+	return result, err
+}
+
+// Puts the object back with its original id, entries get their rows back as well.
+// Fails if anything the object refers to is in the trash itself.
+func RestoreFromTrash(DB *sqlx.DB, itemId int) (TrashItem, error) {
+	tx := DB.MustBegin()
+	defer tx.Rollback()
+
+	var item TrashItem
+	err := tx.Get(&item, `SELECT * FROM trash WHERE id = $1;`, itemId)
+	if err != nil {
+		return TrashItem{}, fmt.Errorf("FAILED TO GET TRASH ITEM %d: %v", itemId, err)
+	}
+
+	switch item.Model {
+	case meta.LEDGERMODEL:
+		err = restoreLedger(tx, item)
+
+	case meta.ACCOUNTMODEL:
+		err = restoreAccount(tx, item)
+
+	case meta.JOURNALMODEL:
+		err = restoreJournal(tx, item)
+
+	case meta.ENTRYMODEL:
+		err = restoreEntry(tx, item)
+
+	default:
+		err = fmt.Errorf("UNEXPECTED MODEL IN TRASH: %q", item.Model)
+	}
+	if err != nil {
+		return TrashItem{}, err
+	}
+
+	_, err = tx.Exec(`DELETE FROM trash WHERE id = $1;`, item.Id)
+	if err != nil {
+		return TrashItem{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return TrashItem{}, err
+	}
+
+	return item, UpdateCache(DB)
+}
+
+func restoreLedger(tx *sqlx.Tx, item TrashItem) error {
+	var ledger Ledger
+	err := json.Unmarshal([]byte(item.Data), &ledger)
+	if err != nil {
+		return fmt.Errorf("UNMARSHALLING INVALID LEDGER FROM TRASH: %v", err)
+	}
+
+	if ledger.IsAccounts {
+		var accountsLedgers int
+		err = tx.Get(&accountsLedgers, `SELECT COUNT(*) FROM ledgers WHERE is_accounts;`)
+		if err != nil {
+			return err
+		}
+
+		if accountsLedgers != 0 {
+			return fmt.Errorf("can't restore %s, there already is an accounts ledger", item.Name)
+		}
+	}
+
+	// The parent may have been deleted since, then the ledger comes back at the top level
+	if ledger.Parent != nil {
+		var parents int
+		err = tx.Get(&parents, `SELECT COUNT(*) FROM ledgers WHERE id = $1;`, *ledger.Parent)
+		if err != nil {
+			return err
+		}
+
+		if parents == 0 {
+			ledger.Parent = nil
+		}
+	}
+
+	_, err = tx.NamedExec(`INSERT INTO ledgers (id, name, type, notes, is_accounts, currency, parent, code, bank_number)
+		VALUES (:id, :name, :type, :notes, :is_accounts, :currency, :parent, :code, :bank_number);`, ledger)
+
+	return err
+}
+
+func restoreAccount(tx *sqlx.Tx, item TrashItem) error {
+	var account Account
+	err := json.Unmarshal([]byte(item.Data), &account)
+	if err != nil {
+		return fmt.Errorf("UNMARSHALLING INVALID ACCOUNT FROM TRASH: %v", err)
+	}
+
+	_, err = tx.NamedExec(`INSERT INTO accounts (id, name, type, banknumbers, notes, currency)
+		VALUES (:id, :name, :type, :banknumbers, :notes, :currency);`, account)
+
+	return err
+}
+
+func restoreJournal(tx *sqlx.Tx, item TrashItem) error {
+	var journal Journal
+	err := json.Unmarshal([]byte(item.Data), &journal)
+	if err != nil {
+		return fmt.Errorf("UNMARSHALLING INVALID JOURNAL FROM TRASH: %v", err)
+	}
+
+	_, err = tx.NamedExec(`INSERT INTO journals (id, name, type, notes) VALUES (:id, :name, :type, :notes);`, journal)
+
+	return err
+}
+
+func restoreEntry(tx *sqlx.Tx, item TrashItem) error {
+	var snapshot EntrySnapshot
+	err := json.Unmarshal([]byte(item.Data), &snapshot)
+	if err != nil {
+		return fmt.Errorf("UNMARSHALLING INVALID ENTRY FROM TRASH: %v", err)
+	}
+
+	err = checkRestorable(tx, item, meta.JOURNALMODEL, snapshot.Entry.Journal)
+	if err != nil {
+		return err
+	}
+	for _, row := range snapshot.Rows {
+		err = checkRestorable(tx, item, meta.LEDGERMODEL, row.Ledger)
+		if err != nil {
+			return err
+		}
+
+		if row.Account != nil {
+			err = checkRestorable(tx, item, meta.ACCOUNTMODEL, *row.Account)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Its group or statement may have moved on since, the rows come back unreconciled
+	for i := range snapshot.Rows {
+		snapshot.Rows[i].Reconciled = false
+		snapshot.Rows[i].Reconciliation = nil
+		snapshot.Rows[i].Statement = nil
+	}
+
+	// The tax code may have been removed since, the rows keep their amounts without it
+	for i, row := range snapshot.Rows {
+		if row.TaxCode == nil {
+			continue
+		}
+
+		if _, err := taxCodeById(*row.TaxCode); err != nil {
+			snapshot.Rows[i].TaxCode = nil
+			snapshot.Rows[i].TaxRole = NOTAXROLE
+		}
+	}
+
+	// A period may have been locked since the entry was deleted
+	err = checkPeriodsOpen(tx, snapshot.Rows)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.NamedExec(`INSERT INTO entries (id, journal, notes, suspense) VALUES (:id, :journal, :notes, :suspense);`, snapshot.Entry)
+	if err != nil {
+		return err
+	}
+
+	if len(snapshot.Rows) != 0 {
+		_, err = tx.NamedExec(`INSERT INTO entryrows
+		(id, entry, date, ledger, account, description, document, value, reconciled, tags, currency, foreign_value, tax_code, tax_role, reconciliation, statement, external_id)
+		VALUES
+		(:id, :entry, :date, :ledger, :account, :description, :document, :value, :reconciled, :tags, :currency, :foreign_value, :tax_code, :tax_role, :reconciliation, :statement, :external_id);`, snapshot.Rows)
+		if err != nil {
+			return err
+		}
+	}
+
+	return recordEntryVersion(tx, snapshot.Entry.Id, RESTOREACTION)
+}
+
+// Checks that the object the restored item refers to exists, rather than leaving that to the foreign keys,
+// to tell the user which object to restore first
+func checkRestorable(tx *sqlx.Tx, item TrashItem, model meta.ModelType, id int) error {
+	tables := map[meta.ModelType]string{
+		meta.LEDGERMODEL:  "ledgers",
+		meta.ACCOUNTMODEL: "accounts",
+		meta.JOURNALMODEL: "journals",
+	}
+
+	var count int
+	err := tx.Get(&count, fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE id = $1;`, tables[model]), id)
+	if err != nil {
+		return fmt.Errorf("FAILED TO CHECK %s %d EXISTS: %v", model, id, err)
+	}
+
+	if count == 0 {
+		return fmt.Errorf("can't restore %s, the %s it uses (id %d) was deleted, restore that first", item.Name, strings.ToLower(string(model)), id)
+	}
+
+	return nil
+}
+
+// Permanently removes everything deleted at or before the given time, returns how many items were removed.
+// The files attached to removed entries go with them.
+func PurgeTrash(DB *sqlx.DB, before time.Time) (int, error) {
+	tx := DB.MustBegin()
+	defer tx.Rollback()
+
+	_, err := tx.Exec(`DELETE FROM attachments WHERE entry IN
+		(SELECT object FROM trash WHERE model = $1 AND deleted_at <= $2);`, meta.ENTRYMODEL, before.Format(TIMESTAMP_FORMAT))
+	if err != nil {
+		return 0, fmt.Errorf("FAILED TO PURGE ATTACHMENTS: %v", err)
+	}
+
+	err = deleteUnusedAttachmentContents(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`DELETE FROM trash WHERE deleted_at <= $1;`, before.Format(TIMESTAMP_FORMAT))
+	if err != nil {
+		return 0, fmt.Errorf("FAILED TO PURGE TRASH: %v", err)
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(purged), tx.Commit()
+}
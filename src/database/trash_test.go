@@ -0,0 +1,144 @@
+package database_test
+
+import (
+	"terminaccounting/database"
+	"terminaccounting/meta"
+	"terminaccounting/tat"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrash_RestoreEntry(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	journal := insertTestJournal(t, DB)
+	ledger := insertTestLedger(t, DB)
+	entry := insertTestEntry(t, DB, journal.Id, ledger.Id)
+
+	rows, err := database.SelectRowsByEntry(DB, entry.Id)
+	require.NoError(t, err)
+
+	require.NoError(t, database.DeleteEntry(DB, entry.Id))
+	require.NoError(t, database.DeleteJournal(DB, journal.Id))
+
+	trash, err := database.SelectTrash(DB)
+	require.NoError(t, err)
+	require.Len(t, trash, 2)
+	// Same second, so the most recently deleted is the one with the highest id
+	assert.Equal(t, meta.JOURNALMODEL, trash[0].Model)
+	assert.Equal(t, meta.ENTRYMODEL, trash[1].Model)
+	assert.Equal(t, entry.Id, trash[1].Object)
+
+	_, err = database.RestoreFromTrash(DB, trash[1].Id)
+	assert.EqualError(t, err, "can't restore Entry 1, the journal it uses (id 1) was deleted, restore that first")
+
+	_, err = database.RestoreFromTrash(DB, trash[0].Id)
+	require.NoError(t, err)
+	assert.Len(t, database.AvailableJournals(), 1, "restoring updates the caches")
+
+	restored, err := database.RestoreFromTrash(DB, trash[1].Id)
+	require.NoError(t, err)
+	assert.Equal(t, "Entry 1", restored.Name)
+
+	resultEntry, err := database.SelectEntry(DB, entry.Id)
+	require.NoError(t, err)
+	assert.Equal(t, entry, resultEntry)
+
+	resultRows, err := database.SelectRowsByEntry(DB, entry.Id)
+	require.NoError(t, err)
+	assert.Equal(t, rows, resultRows)
+
+	history, err := database.SelectEntryHistory(DB, entry.Id)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, database.RESTOREACTION, history[2].Action)
+
+	trash, err = database.SelectTrash(DB)
+	require.NoError(t, err)
+	assert.Empty(t, trash)
+}
+
+func TestTrash_RestoreLedgerAndAccount(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	ledger := database.Ledger{Name: "Debtors", Type: database.ASSETLEDGER, Notes: meta.Notes{}, IsAccounts: true}
+	id, err := ledger.Insert(DB)
+	require.NoError(t, err)
+	ledger.Id = id
+	account := insertTestAccount(t, DB)
+
+	require.NoError(t, database.DeleteLedger(DB, ledger.Id))
+	require.NoError(t, database.DeleteAccount(DB, account.Id))
+	assert.Empty(t, database.AvailableLedgers())
+	assert.Empty(t, database.AvailableAccounts())
+
+	other := database.Ledger{Name: "Creditors", Type: database.LIABILITYLEDGER, Notes: meta.Notes{}, IsAccounts: true}
+	otherId, err := other.Insert(DB)
+	require.NoError(t, err)
+
+	trash, err := database.SelectTrash(DB)
+	require.NoError(t, err)
+	require.Len(t, trash, 2)
+
+	_, err = database.RestoreFromTrash(DB, trash[1].Id)
+	assert.EqualError(t, err, "can't restore Debtors (1), there already is an accounts ledger")
+
+	require.NoError(t, database.DeleteLedger(DB, otherId))
+	_, err = database.RestoreFromTrash(DB, trash[1].Id)
+	require.NoError(t, err)
+	_, err = database.RestoreFromTrash(DB, trash[0].Id)
+	require.NoError(t, err)
+
+	assert.Equal(t, []database.Ledger{ledger}, database.AvailableLedgers())
+	assert.Equal(t, []database.Account{account}, database.AvailableAccounts())
+}
+
+func TestPurgeTrash(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	insertTestJournal(t, DB)
+	require.NoError(t, database.DeleteJournal(DB, 1))
+
+	purged, err := database.PurgeTrash(DB, time.Now().AddDate(0, 0, -30))
+	require.NoError(t, err)
+	assert.Equal(t, 0, purged)
+
+	purged, err = database.PurgeTrash(DB, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	trash, err := database.SelectTrash(DB)
+	require.NoError(t, err)
+	assert.Empty(t, trash)
+}
+
+func TestTrash_RestoreEntryUnreconciled(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	journal := insertTestJournal(t, DB)
+	ledger := insertTestLedger(t, DB)
+	entry := insertTestEntry(t, DB, journal.Id, ledger.Id)
+
+	// Reconciled before rows got groups, so the entry can still be deleted
+	_, err := DB.Exec(`UPDATE entryrows SET reconciled = 1 WHERE entry = $1;`, entry.Id)
+	require.NoError(t, err)
+	require.NoError(t, database.DeleteEntry(DB, entry.Id))
+
+	trash, err := database.SelectTrash(DB)
+	require.NoError(t, err)
+	require.Len(t, trash, 1)
+	_, err = database.RestoreFromTrash(DB, trash[0].Id)
+	require.NoError(t, err)
+
+	rows, err := database.SelectRowsByEntry(DB, entry.Id)
+	require.NoError(t, err)
+	require.NotEmpty(t, rows)
+	for _, row := range rows {
+		assert.False(t, row.Reconciled)
+		assert.Nil(t, row.Reconciliation)
+		assert.Nil(t, row.Statement)
+	}
+}
@@ -0,0 +1,511 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"terminaccounting/bubbles/itempicker"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmoiron/sqlx"
+)
+
+// Globally accessible list of available tax codes
+// Atomic for parallel tests
+var taxCodesCache atomic.Pointer[[]TaxCode]
+
+func AvailableTaxCodes() []TaxCode {
+	return *taxCodesCache.Load()
+}
+
+func AvailableTaxCodesAsItempickerItems() []itempicker.Item {
+	var result []itempicker.Item
+
+	result = append(result, (*TaxCode)(nil))
+
+	for _, taxCode := range AvailableTaxCodes() {
+		result = append(result, &taxCode)
+	}
+
+	return result
+}
+
+type TaxType string
+
+const (
+	SALESTAX    TaxType = "SALES"
+	PURCHASETAX TaxType = "PURCHASE"
+)
+
+// What a row with a tax code is to the tax: the amount it's charged on, or the tax itself
+type TaxRole string
+
+const (
+	// Rows without a tax code
+	NOTAXROLE TaxRole = ""
+	// The amount the tax is charged on, typed in by the user
+	TAXBASEROLE TaxRole = "BASE"
+	// Generated tax that has to be paid
+	PAYABLETAXROLE TaxRole = "PAYABLE"
+	// Generated tax that can be deducted from what has to be paid
+	DEDUCTIBLETAXROLE TaxRole = "DEDUCTIBLE"
+)
+
+// Whether the row was generated from a row with a tax code, and so gets generated again instead of edited
+func (tr TaxRole) IsGenerated() bool {
+	return tr == PAYABLETAXROLE || tr == DEDUCTIBLETAXROLE
+}
+
+// A VAT or sales tax rate, which rows can be booked with to have their tax booked on Ledger automatically.
+// The boxes are the numbers of the boxes on the VAT return the amounts are reported in, empty for none.
+type TaxCode struct {
+	Id   int     `db:"id"`
+	Code string  `db:"code"`
+	Type TaxType `db:"type"`
+	// In percent, like 21
+	Rate float64 `db:"rate"`
+	// For sales the customer accounts for the tax, so none is booked.
+	// For purchases the tax is both payable and deductible.
+	ReverseCharge bool `db:"reverse_charge"`
+	// The VAT ledger the tax is booked on
+	Ledger int `db:"ledger"`
+
+	// Where the amounts the tax is charged on are reported
+	TurnoverBox string `db:"turnover_box"`
+	// Where the tax is reported, the payable tax for reverse-charge purchases
+	TaxBox string `db:"tax_box"`
+	// Where the deductible tax of reverse-charge purchases is reported
+	DeductibleBox string `db:"deductible_box"`
+}
+
+// Parses the arguments of :taxcode into a tax code.
+// The ledger is given by its id like #4, the options are reverse and the boxes like turnover:1a.
+func ParseTaxCode(code, taxType, rate, ledger string, options []string) (TaxCode, error) {
+	result := TaxCode{Code: code}
+
+	switch strings.ToLower(taxType) {
+	case "sales":
+		result.Type = SALESTAX
+	case "purchase":
+		result.Type = PURCHASETAX
+	default:
+		return TaxCode{}, fmt.Errorf("invalid tax code type %q, use sales or purchase", taxType)
+	}
+
+	parsedRate, err := strconv.ParseFloat(strings.TrimSuffix(rate, "%"), 64)
+	if err != nil {
+		return TaxCode{}, fmt.Errorf("invalid rate %q", rate)
+	}
+	result.Rate = parsedRate
+
+	ledgerId, isLedger := strings.CutPrefix(ledger, "#")
+	result.Ledger, err = strconv.Atoi(ledgerId)
+	if !isLedger || err != nil {
+		return TaxCode{}, fmt.Errorf("invalid VAT ledger %q, use its id like #4", ledger)
+	}
+
+	for _, option := range options {
+		key, box, hasBox := strings.Cut(option, ":")
+
+		switch {
+		case option == "reverse":
+			result.ReverseCharge = true
+		case hasBox && key == "turnover":
+			result.TurnoverBox = box
+		case hasBox && key == "tax":
+			result.TaxBox = box
+		case hasBox && key == "deductible":
+			result.DeductibleBox = box
+		default:
+			return TaxCode{}, fmt.Errorf("invalid tax code option %q, use reverse, turnover:<box>, tax:<box> or deductible:<box>", option)
+		}
+	}
+
+	return result, nil
+}
+
+// *TaxCode because they're nullable for the sake of the itempicker
+func (tc *TaxCode) String() string {
+	if tc == nil {
+		return lipgloss.NewStyle().Italic(true).Render("None")
+	}
+
+	return tc.Code
+}
+
+func (tc *TaxCode) CompareId() int {
+	// Since sqlite autoincrements from 1, -1 will never be a valid ID
+	if tc == nil {
+		return -1
+	}
+
+	return tc.Id
+}
+
+// Like "H21: sales 21%", with the VAT ledger and boxes
+func (tc TaxCode) Describe() string {
+	result := fmt.Sprintf("%s: %s %s%%", tc.Code, strings.ToLower(string(tc.Type)), strconv.FormatFloat(tc.Rate, 'f', -1, 64))
+	if tc.ReverseCharge {
+		result += " reverse-charge"
+	}
+
+	result += " on " + ledgerName(tc.Ledger)
+
+	var boxes []string
+	if tc.TurnoverBox != "" {
+		boxes = append(boxes, "turnover:"+tc.TurnoverBox)
+	}
+	if tc.TaxBox != "" {
+		boxes = append(boxes, "tax:"+tc.TaxBox)
+	}
+	if tc.DeductibleBox != "" {
+		boxes = append(boxes, "deductible:"+tc.DeductibleBox)
+	}
+	if len(boxes) != 0 {
+		result += ", " + strings.Join(boxes, " ")
+	}
+
+	return result
+}
+
+// The tax on base, rounded to the cent, with the same sign as base
+func (tc TaxCode) TaxOn(base CurrencyValue) CurrencyValue {
+	return CurrencyValue(math.Round(float64(base) * tc.Rate / 100))
+}
+
+// The box the amounts of rows with role are reported in
+func (tc TaxCode) box(role TaxRole) string {
+	switch role {
+	case TAXBASEROLE:
+		return tc.TurnoverBox
+
+	case PAYABLETAXROLE:
+		return tc.TaxBox
+
+	case DEDUCTIBLETAXROLE:
+		if tc.ReverseCharge {
+			return tc.DeductibleBox
+		}
+
+		return tc.TaxBox
+
+	default:
+		panic(fmt.Sprintf("unexpected database.TaxRole: %#v", role))
+	}
+}
+
+func SelectTaxCodes(DB *sqlx.DB) ([]TaxCode, error) {
+	result := []TaxCode{}
+
+	err := DB.Select(&result, `SELECT * FROM tax_codes ORDER BY code;`)
+
+	return result, err
+}
+
+func UpdateTaxCodesCache(DB *sqlx.DB) error {
+	taxCodes, err := SelectTaxCodes(DB)
+	if err != nil {
+		return err
+	}
+
+	taxCodesCache.Store(&taxCodes)
+
+	return nil
+}
+
+// Sets the tax code, replacing the one with the same code
+func SetTaxCode(DB *sqlx.DB, taxCode TaxCode) error {
+	if taxCode.Code == "" {
+		return errors.New("a tax code needs a code, like H21")
+	}
+
+	if taxCode.Type != SALESTAX && taxCode.Type != PURCHASETAX {
+		return fmt.Errorf("FAILED TO SET TAX CODE: INVALID TYPE %q", taxCode.Type)
+	}
+
+	if taxCode.Rate < 0 || taxCode.Rate > 100 || math.IsNaN(taxCode.Rate) {
+		return fmt.Errorf("invalid rate %s%%, rates are between 0 and 100", strconv.FormatFloat(taxCode.Rate, 'f', -1, 64))
+	}
+
+	ledgers := AvailableLedgers()
+	index := slices.IndexFunc(ledgers, func(ledger Ledger) bool { return ledger.Id == taxCode.Ledger })
+	if index == -1 {
+		return fmt.Errorf("ledger %d doesn't exist", taxCode.Ledger)
+	}
+	if ledgers[index].Currency != HOMECURRENCY {
+		return fmt.Errorf("tax is booked in the home currency, so the VAT ledger can't be %s, which is in %s", ledgers[index].Name, ledgers[index].Currency)
+	}
+	if ledgers[index].IsAccounts {
+		return fmt.Errorf("the VAT ledger can't be the accounts ledger %s, its rows need an account", ledgers[index].Name)
+	}
+
+	if taxCode.DeductibleBox != "" && !(taxCode.ReverseCharge && taxCode.Type == PURCHASETAX) {
+		return errors.New("only reverse-charge purchases have a separate box for the deductible tax")
+	}
+
+	_, err := DB.NamedExec(`INSERT INTO tax_codes (code, type, rate, reverse_charge, ledger, turnover_box, tax_box, deductible_box)
+		VALUES (:code, :type, :rate, :reverse_charge, :ledger, :turnover_box, :tax_box, :deductible_box)
+		ON CONFLICT (code) DO UPDATE SET
+		type = excluded.type,
+		rate = excluded.rate,
+		reverse_charge = excluded.reverse_charge,
+		ledger = excluded.ledger,
+		turnover_box = excluded.turnover_box,
+		tax_box = excluded.tax_box,
+		deductible_box = excluded.deductible_box;`, taxCode)
+	if err != nil {
+		return fmt.Errorf("FAILED TO SET TAX CODE: %v", err)
+	}
+
+	return UpdateTaxCodesCache(DB)
+}
+
+// Removes the tax code, as long as no rows are booked with it
+func RemoveTaxCode(DB *sqlx.DB, code string) error {
+	taxCode, err := taxCodeByCode(code)
+	if err != nil {
+		return err
+	}
+
+	var used int
+	err = DB.Get(&used, `SELECT COUNT(*) FROM entryrows WHERE tax_code = $1;`, taxCode.Id)
+	if err != nil {
+		return fmt.Errorf("FAILED TO COUNT ROWS WITH TAX CODE %s: %v", code, err)
+	}
+	if used != 0 {
+		return fmt.Errorf("tax code %s is used by %d row(s), so it can't be removed", code, used)
+	}
+
+	_, err = DB.Exec(`DELETE FROM tax_codes WHERE id = $1;`, taxCode.Id)
+	if err != nil {
+		return fmt.Errorf("FAILED TO DELETE TAX CODE %s: %v", code, err)
+	}
+
+	return UpdateTaxCodesCache(DB)
+}
+
+func taxCodeByCode(code string) (TaxCode, error) {
+	taxCodes := AvailableTaxCodes()
+
+	index := slices.IndexFunc(taxCodes, func(taxCode TaxCode) bool { return strings.EqualFold(taxCode.Code, code) })
+	if index == -1 {
+		return TaxCode{}, fmt.Errorf("no tax code %q, add one with :taxcode", code)
+	}
+
+	return taxCodes[index], nil
+}
+
+func taxCodeById(id int) (TaxCode, error) {
+	taxCodes := AvailableTaxCodes()
+
+	index := slices.IndexFunc(taxCodes, func(taxCode TaxCode) bool { return taxCode.Id == id })
+	if index == -1 {
+		return TaxCode{}, fmt.Errorf("tax code %d doesn't exist", id)
+	}
+
+	return taxCodes[index], nil
+}
+
+// Replaces the generated tax rows among rows by the tax of the rows with a tax code, booked on the code's VAT ledger.
+// Rows with a tax code become TAXBASEROLE rows, rows without one lose their role.
+func GenerateTaxRows(rows []EntryRow) ([]EntryRow, error) {
+	var result []EntryRow
+	var generated []EntryRow
+
+	for _, row := range rows {
+		if row.TaxRole.IsGenerated() {
+			continue
+		}
+
+		if row.TaxCode == nil {
+			row.TaxRole = NOTAXROLE
+			result = append(result, row)
+			continue
+		}
+
+		row.TaxRole = TAXBASEROLE
+		result = append(result, row)
+
+		taxCode, err := taxCodeById(*row.TaxCode)
+		if err != nil {
+			return nil, err
+		}
+
+		tax := taxCode.TaxOn(row.Value)
+		if tax == 0 {
+			continue
+		}
+
+		taxRow := EntryRow{
+			Entry:       row.Entry,
+			Date:        row.Date,
+			Ledger:      taxCode.Ledger,
+			Description: row.Description,
+			TaxCode:     row.TaxCode,
+			Currency:    HOMECURRENCY,
+		}
+
+		switch {
+		case taxCode.Type == SALESTAX && !taxCode.ReverseCharge:
+			// Sales are credited, so is their tax
+			taxRow.Value = tax
+			taxRow.TaxRole = PAYABLETAXROLE
+			generated = append(generated, taxRow)
+
+		case taxCode.Type == PURCHASETAX:
+			taxRow.Value = tax
+			taxRow.TaxRole = DEDUCTIBLETAXROLE
+			generated = append(generated, taxRow)
+
+			// The buyer pays the tax to the tax office instead of to the seller, cancelling out the deduction
+			if taxCode.ReverseCharge {
+				taxRow.Value = -tax
+				taxRow.TaxRole = PAYABLETAXROLE
+				generated = append(generated, taxRow)
+			}
+		}
+	}
+
+	return append(result, generated...), nil
+}
+
+// The tax generated rows with a tax code will get, to show the total of an entry before it's saved
+func PendingTax(taxCodeId int, base CurrencyValue) (CurrencyValue, error) {
+	taxCode, err := taxCodeById(taxCodeId)
+	if err != nil {
+		return 0, err
+	}
+
+	// Reverse-charge purchases generate tax that cancels out, reverse-charge sales none at all
+	if taxCode.ReverseCharge {
+		return 0, nil
+	}
+
+	return taxCode.TaxOn(base), nil
+}
+
+// One box of a VAT return
+type VatBox struct {
+	Box string
+	// The amount tax was charged on, sales positive
+	Turnover CurrencyValue
+	// Tax to pay positive, deductible tax negative
+	Tax CurrencyValue
+}
+
+// The boxes of the VAT return for the period, ordered by box number
+func VatReturn(DB *sqlx.DB, period Period) ([]VatBox, error) {
+	var rows []EntryRow
+	err := DB.Select(&rows, `SELECT * FROM entryrows WHERE tax_code IS NOT NULL AND date BETWEEN $1 AND $2;`,
+		period.Start, period.End)
+	if err != nil {
+		return nil, fmt.Errorf("FAILED TO SELECT ROWS FOR VAT RETURN: %v", err)
+	}
+
+	boxes := make(map[string]*VatBox)
+	for _, row := range rows {
+		taxCode, err := taxCodeById(*row.TaxCode)
+		if err != nil {
+			return nil, err
+		}
+
+		box := taxCode.box(row.TaxRole)
+		if box == "" {
+			continue
+		}
+		if boxes[box] == nil {
+			boxes[box] = &VatBox{Box: box}
+		}
+
+		switch row.TaxRole {
+		case TAXBASEROLE:
+			// Sales are credited, purchases debited
+			if taxCode.Type == SALESTAX {
+				boxes[box].Turnover = boxes[box].Turnover.Subtract(row.Value)
+			} else {
+				boxes[box].Turnover = boxes[box].Turnover.Add(row.Value)
+			}
+
+		case PAYABLETAXROLE, DEDUCTIBLETAXROLE:
+			boxes[box].Tax = boxes[box].Tax.Subtract(row.Value)
+		}
+	}
+
+	var result []VatBox
+	for _, box := range boxes {
+		result = append(result, *box)
+	}
+	slices.SortFunc(result, func(a, b VatBox) int { return compareBoxes(a.Box, b.Box) })
+
+	return result, nil
+}
+
+// Orders 1a, 1b, 2a, ..., 10 by number first and letter second
+func compareBoxes(a, b string) int {
+	numberA, restA := splitBox(a)
+	numberB, restB := splitBox(b)
+
+	if numberA != numberB {
+		return numberA - numberB
+	}
+
+	return strings.Compare(restA, restB)
+}
+
+func splitBox(box string) (int, string) {
+	digits := strings.IndexFunc(box, func(r rune) bool { return r < '0' || r > '9' })
+	if digits == -1 {
+		digits = len(box)
+	}
+
+	number, err := strconv.Atoi(box[:digits])
+	if err != nil {
+		return math.MaxInt, box
+	}
+
+	return number, box[digits:]
+}
+
+// Renders the VAT return as a table of boxes, with the tax to pay or get back
+func RenderVatReturn(period Period, boxes []VatBox) []string {
+	title := "VAT return for " + period.String()
+
+	if len(boxes) == 0 {
+		return []string{title, "", "No rows with a tax code in this period, add codes with :taxcode"}
+	}
+
+	result := []string{title, "", fmt.Sprintf("%-8s %14s %14s", "Box", "Turnover", "Tax")}
+
+	var total CurrencyValue
+	for _, box := range boxes {
+		result = append(result, fmt.Sprintf("%-8s %14s %14s", box.Box, box.Turnover, box.Tax))
+		total = total.Add(box.Tax)
+	}
+
+	result = append(result, "")
+	if total >= 0 {
+		result = append(result, fmt.Sprintf("%-8s %29s", "To pay", total))
+	} else {
+		result = append(result, fmt.Sprintf("%-8s %29s", "To get", -total))
+	}
+
+	return result
+}
+
+// For `:taxcodes`
+func RenderTaxCodes(taxCodes []TaxCode) []string {
+	if len(taxCodes) == 0 {
+		return []string{"No tax codes yet", "", "Add one with :taxcode <code> <sales|purchase> <rate> <#ledger> [reverse] [turnover:box] [tax:box] [deductible:box]"}
+	}
+
+	result := []string{"Tax codes, pick them per row in the Tax column of an entry", ""}
+	for _, taxCode := range taxCodes {
+		result = append(result, taxCode.Describe())
+	}
+
+	return result
+}
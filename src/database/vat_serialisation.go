@@ -0,0 +1,64 @@
+package database
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+func (tt *TaxType) Scan(value any) error {
+	switch value {
+	case int64(0):
+		*tt = SALESTAX
+	case int64(1):
+		*tt = PURCHASETAX
+
+	default:
+		return fmt.Errorf("UNMARSHALLING INVALID TAX TYPE: %v", value)
+	}
+
+	return nil
+}
+
+func (tt TaxType) Value() (driver.Value, error) {
+	switch tt {
+	case SALESTAX:
+		return int64(0), nil
+	case PURCHASETAX:
+		return int64(1), nil
+	}
+
+	return nil, fmt.Errorf("MARSHALLING INVALID TAX TYPE: %v", tt)
+}
+
+func (tr *TaxRole) Scan(value any) error {
+	switch value {
+	case int64(0):
+		*tr = NOTAXROLE
+	case int64(1):
+		*tr = TAXBASEROLE
+	case int64(2):
+		*tr = PAYABLETAXROLE
+	case int64(3):
+		*tr = DEDUCTIBLETAXROLE
+
+	default:
+		return fmt.Errorf("UNMARSHALLING INVALID TAX ROLE: %v", value)
+	}
+
+	return nil
+}
+
+func (tr TaxRole) Value() (driver.Value, error) {
+	switch tr {
+	case NOTAXROLE:
+		return int64(0), nil
+	case TAXBASEROLE:
+		return int64(1), nil
+	case PAYABLETAXROLE:
+		return int64(2), nil
+	case DEDUCTIBLETAXROLE:
+		return int64(3), nil
+	}
+
+	return nil, fmt.Errorf("MARSHALLING INVALID TAX ROLE: %v", tr)
+}
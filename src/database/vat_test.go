@@ -0,0 +1,134 @@
+package database_test
+
+import (
+	"terminaccounting/database"
+	"terminaccounting/tat"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVatReturn(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	insert := func(ledger database.Ledger) int {
+		id, err := ledger.Insert(DB)
+		require.NoError(t, err)
+
+		return id
+	}
+
+	sales := insert(database.Ledger{Name: "Sales", Type: database.INCOMELEDGER})
+	costs := insert(database.Ledger{Name: "Costs", Type: database.EXPENSELEDGER})
+	bank := insert(database.Ledger{Name: "Bank", Type: database.ASSETLEDGER})
+	vat := insert(database.Ledger{Name: "VAT", Type: database.LIABILITYLEDGER})
+
+	err := database.SetTaxCode(DB, database.TaxCode{Code: "H21", Type: database.SALESTAX, Rate: 21, Ledger: vat, TurnoverBox: "1a", TaxBox: "1a"})
+	require.NoError(t, err)
+	err = database.SetTaxCode(DB, database.TaxCode{Code: "V21", Type: database.PURCHASETAX, Rate: 21, Ledger: vat, TaxBox: "5b"})
+	require.NoError(t, err)
+	err = database.SetTaxCode(DB, database.TaxCode{
+		Code: "EU21", Type: database.PURCHASETAX, Rate: 21, ReverseCharge: true, Ledger: vat,
+		TurnoverBox: "4b", TaxBox: "4b", DeductibleBox: "5b",
+	})
+	require.NoError(t, err)
+
+	err = database.SetTaxCode(DB, database.TaxCode{Code: "X", Type: database.SALESTAX, Rate: 9, Ledger: vat, DeductibleBox: "5b"})
+	assert.EqualError(t, err, "only reverse-charge purchases have a separate box for the deductible tax")
+
+	taxCodes := database.AvailableTaxCodes()
+	require.Len(t, taxCodes, 3)
+	assert.Equal(t, "EU21: purchase 21% reverse-charge on VAT (4), turnover:4b tax:4b deductible:5b", taxCodes[0].Describe())
+	h21, v21, eu21 := taxCodes[1].Id, taxCodes[2].Id, taxCodes[0].Id
+
+	journal := insertTestJournal(t, DB)
+	book := func(date string, rows ...database.EntryRow) []database.EntryRow {
+		for i := range rows {
+			rows[i].Date = mustDate(t, date)
+		}
+
+		rows, err := database.GenerateTaxRows(rows)
+		require.NoError(t, err)
+
+		_, err = database.Entry{Journal: journal.Id}.Insert(DB, rows)
+		require.NoError(t, err)
+
+		return rows
+	}
+
+	rows := book("24-01-10",
+		database.EntryRow{Ledger: bank, Value: 12100},
+		database.EntryRow{Ledger: sales, Value: -10000, TaxCode: &h21},
+	)
+	require.Len(t, rows, 3)
+	assert.Equal(t, database.EntryRow{Entry: 1, Date: mustDate(t, "24-01-10"), Ledger: vat, Value: -2100, TaxCode: &h21, TaxRole: database.PAYABLETAXROLE}, rows[2])
+
+	book("24-02-10",
+		database.EntryRow{Ledger: costs, Value: 5000, TaxCode: &v21},
+		database.EntryRow{Ledger: bank, Value: -6050},
+	)
+
+	// The payable and deductible tax cancel out, so the bank pays just the base
+	rows = book("24-03-10",
+		database.EntryRow{Ledger: costs, Value: 20000, TaxCode: &eu21},
+		database.EntryRow{Ledger: bank, Value: -20000},
+	)
+	require.Len(t, rows, 4)
+
+	// Outside of the quarter
+	book("24-04-10",
+		database.EntryRow{Ledger: bank, Value: 12100},
+		database.EntryRow{Ledger: sales, Value: -10000, TaxCode: &h21},
+	)
+
+	period, err := database.ParsePeriod("2024-Q1")
+	require.NoError(t, err)
+
+	boxes, err := database.VatReturn(DB, period)
+	require.NoError(t, err)
+	assert.Equal(t, []database.VatBox{
+		{Box: "1a", Turnover: 10000, Tax: 2100},
+		{Box: "4b", Turnover: 20000, Tax: 4200},
+		{Box: "5b", Tax: -5250},
+	}, boxes)
+
+	rendered := database.RenderVatReturn(period, boxes)
+	assert.Contains(t, rendered, "5b                 0.00         -52.50")
+	assert.Equal(t, "To pay                           10.50", rendered[len(rendered)-1])
+
+	// Generating again replaces the tax rows instead of adding to them
+	again, err := database.GenerateTaxRows(rows)
+	require.NoError(t, err)
+	assert.Equal(t, rows, again)
+
+	err = database.RemoveTaxCode(DB, "V21")
+	assert.EqualError(t, err, "tax code V21 is used by 2 row(s), so it can't be removed")
+}
+
+func TestParseTaxCode(t *testing.T) {
+	taxCode, err := database.ParseTaxCode("EU", "Purchase", "21%", "#4", []string{"reverse", "turnover:4b", "tax:4b", "deductible:5b"})
+	require.NoError(t, err)
+	assert.Equal(t, database.TaxCode{
+		Code:          "EU",
+		Type:          database.PURCHASETAX,
+		Rate:          21,
+		ReverseCharge: true,
+		Ledger:        4,
+		TurnoverBox:   "4b",
+		TaxBox:        "4b",
+		DeductibleBox: "5b",
+	}, taxCode)
+
+	_, err = database.ParseTaxCode("H21", "income", "21", "#4", nil)
+	assert.EqualError(t, err, `invalid tax code type "income", use sales or purchase`)
+
+	_, err = database.ParseTaxCode("H21", "sales", "high", "#4", nil)
+	assert.EqualError(t, err, `invalid rate "high"`)
+
+	_, err = database.ParseTaxCode("H21", "sales", "21", "4", nil)
+	assert.EqualError(t, err, `invalid VAT ledger "4", use its id like #4`)
+
+	_, err = database.ParseTaxCode("H21", "sales", "21", "#4", []string{"box:1a"})
+	assert.EqualError(t, err, `invalid tax code option "box:1a", use reverse, turnover:<box>, tax:<box> or deductible:<box>`)
+}
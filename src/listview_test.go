@@ -169,3 +169,34 @@ func testListView_GoToDetail_NoItems(t *testing.T, appType meta.AppType) {
 	require.Len(t, tw.LastCmdResults, 2)
 	assert.Error(t, tw.LastCmdResults[1].(error))
 }
+
+func TestLedgersListView_Fold(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	parentId, err := (&database.Ledger{Name: "Expenses", Type: database.EXPENSELEDGER, Code: "4000"}).Insert(DB)
+	require.NoError(t, err)
+	_, err = (&database.Ledger{Name: "Groceries", Type: database.EXPENSELEDGER, Code: "4100", Parent: &parentId}).Insert(DB)
+	require.NoError(t, err)
+
+	tw := tat.NewTestWrapperGeneric(newTerminaccounting(DB))
+	tw.GoToTab(meta.LEDGERSAPP)
+
+	tw.AssertViewContains(t, "▾ 4000 Expenses")
+	tw.AssertViewContains(t, "4100 Groceries")
+
+	tw.SendText("za")
+	tw.AssertViewContains(t, "▸ 4000 Expenses")
+	tw.AssertViewNotContains(t, "Groceries")
+
+	tw.SendText("zR")
+	tw.AssertViewContains(t, "4100 Groceries")
+
+	tw.SendText("zM")
+	tw.AssertViewNotContains(t, "Groceries")
+
+	// Goes to the parent, not the tree item showing it
+	tw.SendText("gd")
+	switchMsg, ok := tw.LastCmdResults[1].(meta.SwitchAppViewMsg)
+	require.True(t, ok, "expected SwitchAppViewMsg, got %T", tw.LastCmdResults[1])
+	assert.Equal(t, parentId, switchMsg.Data.(database.Ledger).Id)
+}
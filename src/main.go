@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log/slog"
 	"os"
@@ -11,6 +12,9 @@ import (
 )
 
 func main() {
+	dbPath := flag.String("db", "", "path of the book to open (default: pick one from the data directory)")
+	flag.Parse()
+
 	logFile, err := initSlog()
 	if err != nil {
 		slog.Error("Couldn't create logger:", "error", err)
@@ -18,15 +22,38 @@ func main() {
 	}
 	defer logFile.Close()
 
-	DB, err := database.Connect()
+	if *dbPath == "" {
+		*dbPath, err = chooseBook()
+		if err != nil {
+			slog.Error("Couldn't choose book:", "error", err)
+			fmt.Printf("Couldn't choose book: %v\n", err)
+			os.Exit(1)
+		}
+
+		if *dbPath == "" {
+			slog.Info("No book chosen, exiting")
+			os.Exit(0)
+		}
+	}
+
+	DB, err := database.Connect(*dbPath)
 	if err != nil {
 		slog.Error("Couldn't connect to database:", "error", err)
+		fmt.Printf("Couldn't open %q: %v\n", *dbPath, err)
 		os.Exit(1)
 	}
 	defer DB.Close()
 
+	slog.Info("Opened book", "path", *dbPath)
+
 	ta := newTerminaccounting(DB)
 
+	// A book without ledgers or journals was most likely just created
+	ta.offerChartTemplates, err = database.IsEmptyBook(DB)
+	if err != nil {
+		slog.Error("Couldn't check whether the book is empty:", "error", err)
+	}
+
 	finalModel, err := tea.NewProgram(ta, tea.WithAltScreen()).Run()
 	if err != nil {
 		slog.Error("Bubbletea error", "error", err)
@@ -44,3 +71,17 @@ func main() {
 	slog.Info("Exited gracefully")
 	os.Exit(0)
 }
+
+func chooseBook() (string, error) {
+	directory, err := database.DataDirectory()
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(directory, 0o755)
+	if err != nil {
+		return "", fmt.Errorf("couldn't create data directory: %v", err)
+	}
+
+	return pickBook(directory)
+}
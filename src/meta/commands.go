@@ -1,6 +1,7 @@
 package meta
 
 import (
+	"fmt"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -50,6 +51,26 @@ func (ccs *CompleteCommandSet) Autocomplete(path Command) []string {
 	return ccs.globalCommandSet.Autocomplete(path)
 }
 
+// Implemented by command messages that take arguments, like `:lock 2024`.
+// The message in the command set acts as a template, WithArgs returns the message to actually send.
+type CommandWithArgs interface {
+	WithArgs(args []string) (tea.Msg, error)
+}
+
+// Gives the arguments typed after a command to its message
+func ApplyCommandArgs(name string, message tea.Msg, args []string) (tea.Msg, error) {
+	withArgs, ok := message.(CommandWithArgs)
+	if !ok {
+		if len(args) != 0 {
+			return nil, fmt.Errorf("command %q doesn't take arguments", name)
+		}
+
+		return message, nil
+	}
+
+	return withArgs.WithArgs(args)
+}
+
 type commandWithValue struct {
 	path  Command
 	value tea.Msg
@@ -63,6 +84,26 @@ func globalCommands() Trie[tea.Msg] {
 		{Command{"q", "a"}, QuitMsg{All: true}},
 		{Command(strings.Split("messages", "")), ShowNotificationsMsg{}},
 		{Command(strings.Split("import", "")), ShowBankImporterMsg{}},
+		{Command(strings.Split("check", "")), ShowIntegrityCheckMsg{}},
+		{Command(strings.Split("lock", "")), LockPeriodMsg{}},
+		{Command(strings.Split("unlock", "")), UnlockPeriodMsg{}},
+		{Command(strings.Split("yearend", "")), ShowYearEndWizardMsg{}},
+		{Command(strings.Split("rate", "")), SetExchangeRateMsg{}},
+		{Command(strings.Split("revalue", "")), ShowRevaluationWizardMsg{}},
+		{Command(strings.Split("trash", "")), ShowTrashMsg{}},
+		{Command(strings.Split("purge", "")), PurgeTrashMsg{}},
+		{Command(strings.Split("search", "")), ShowFullTextSearchMsg{}},
+		{Command(strings.Split("totals", "")), ShowDimensionTotalsMsg{}},
+		{Command(strings.Split("recurring", "")), ShowRecurringMsg{}},
+		{Command(strings.Split("template", "")), UseTemplateMsg{}},
+		{Command(strings.Split("deletetemplate", "")), DeleteTemplateMsg{}},
+		{Command(strings.Split("budgets", "")), ShowBudgetReportMsg{}},
+		{Command(strings.Split("chart", "")), ShowChartOfAccountsMsg{}},
+		{Command(strings.Split("setup", "")), ShowChartTemplatesMsg{}},
+		{Command(strings.Split("taxcode", "")), SetTaxCodeMsg{}},
+		{Command(strings.Split("taxcodes", "")), ShowTaxCodesMsg{}},
+		{Command(strings.Split("vat", "")), ShowVatReturnMsg{}},
+		{Command(strings.Split("aging", "")), ShowAgingMsg{}},
 		{Command(strings.Split("refreshcache", "")), RefreshCacheMsg{}},
 		{Command(strings.Split("debugcache", "")), DebugPrintCacheMsg{}},
 	})
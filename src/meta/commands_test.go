@@ -21,6 +21,26 @@ func TestGlobalCommandsReachable(t *testing.T) {
 		{"messages", ShowNotificationsMsg{}},
 		{"import", ShowBankImporterMsg{}},
 		{"refreshcache", RefreshCacheMsg{}},
+		{"check", ShowIntegrityCheckMsg{}},
+		{"lock", LockPeriodMsg{}},
+		{"unlock", UnlockPeriodMsg{}},
+		{"yearend", ShowYearEndWizardMsg{}},
+		{"rate", SetExchangeRateMsg{}},
+		{"revalue", ShowRevaluationWizardMsg{}},
+		{"trash", ShowTrashMsg{}},
+		{"purge", PurgeTrashMsg{}},
+		{"search", ShowFullTextSearchMsg{}},
+		{"totals", ShowDimensionTotalsMsg{}},
+		{"recurring", ShowRecurringMsg{}},
+		{"template", UseTemplateMsg{}},
+		{"deletetemplate", DeleteTemplateMsg{}},
+		{"budgets", ShowBudgetReportMsg{}},
+		{"chart", ShowChartOfAccountsMsg{}},
+		{"setup", ShowChartTemplatesMsg{}},
+		{"taxcode", SetTaxCodeMsg{}},
+		{"taxcodes", ShowTaxCodesMsg{}},
+		{"vat", ShowVatReturnMsg{}},
+		{"aging", ShowAgingMsg{}},
 	}
 
 	for _, test := range tests {
@@ -71,3 +91,127 @@ func TestCompleteCommandSetAutocompleteViewTakesPriority(t *testing.T) {
 	result := ccs.Autocomplete(strings.Split("q", ""))
 	assert.Equal(t, strings.Split("query", ""), result)
 }
+
+func TestApplyCommandArgs(t *testing.T) {
+	msg, err := ApplyCommandArgs("quit", QuitMsg{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, QuitMsg{}, msg)
+
+	_, err = ApplyCommandArgs("quit", QuitMsg{}, []string{"now"})
+	assert.EqualError(t, err, `command "quit" doesn't take arguments`)
+
+	msg, err = ApplyCommandArgs("lock", LockPeriodMsg{}, []string{"2024"})
+	require.NoError(t, err)
+	assert.Equal(t, LockPeriodMsg{Period: "2024"}, msg)
+
+	_, err = ApplyCommandArgs("lock", LockPeriodMsg{}, nil)
+	assert.ErrorContains(t, err, "usage: lock <period>")
+
+	msg, err = ApplyCommandArgs("yearend", ShowYearEndWizardMsg{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, ShowYearEndWizardMsg{}, msg)
+
+	msg, err = ApplyCommandArgs("yearend", ShowYearEndWizardMsg{}, []string{"2024"})
+	require.NoError(t, err)
+	assert.Equal(t, ShowYearEndWizardMsg{Year: 2024}, msg)
+
+	_, err = ApplyCommandArgs("yearend", ShowYearEndWizardMsg{}, []string{"24"})
+	assert.EqualError(t, err, `invalid year "24"`)
+
+	msg, err = ApplyCommandArgs("rate", SetExchangeRateMsg{}, []string{"USD", "24-12-31", "0.92"})
+	require.NoError(t, err)
+	assert.Equal(t, SetExchangeRateMsg{Currency: "USD", Date: "24-12-31", Rate: "0.92"}, msg)
+
+	_, err = ApplyCommandArgs("rate", SetExchangeRateMsg{}, []string{"USD"})
+	assert.ErrorContains(t, err, "usage: rate <currency>")
+
+	msg, err = ApplyCommandArgs("revalue", ShowRevaluationWizardMsg{}, []string{"24-12-31"})
+	require.NoError(t, err)
+	assert.Equal(t, ShowRevaluationWizardMsg{Date: "24-12-31"}, msg)
+
+	msg, err = ApplyCommandArgs("aging", ShowAgingMsg{}, []string{"due", "24-12-31"})
+	require.NoError(t, err)
+	assert.Equal(t, ShowAgingMsg{Date: "24-12-31", ByDueDate: true}, msg)
+
+	_, err = ApplyCommandArgs("aging", ShowAgingMsg{}, []string{"24-12-31", "25-01-31"})
+	assert.EqualError(t, err, "usage: aging [yy-MM-dd] [due]")
+
+	_, err = ApplyCommandArgs("purge", PurgeTrashMsg{}, nil)
+	assert.EqualError(t, err, "usage: purge <days>, like purge 30 to remove what was deleted more than 30 days ago")
+
+	msg, err = ApplyCommandArgs("purge", PurgeTrashMsg{}, []string{"0"})
+	require.NoError(t, err)
+	assert.Equal(t, PurgeTrashMsg{Days: 0}, msg)
+
+	_, err = ApplyCommandArgs("purge", PurgeTrashMsg{}, []string{"-1"})
+	assert.EqualError(t, err, `invalid number of days "-1"`)
+
+	msg, err = ApplyCommandArgs("search", ShowFullTextSearchMsg{}, []string{"coffee", "beans"})
+	require.NoError(t, err)
+	assert.Equal(t, ShowFullTextSearchMsg{Query: "coffee beans"}, msg)
+
+	_, err = ApplyCommandArgs("search", ShowFullTextSearchMsg{}, nil)
+	assert.EqualError(t, err, "usage: search <query>")
+
+	msg, err = ApplyCommandArgs("totals", ShowDimensionTotalsMsg{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, ShowDimensionTotalsMsg{}, msg)
+
+	msg, err = ApplyCommandArgs("totals", ShowDimensionTotalsMsg{}, []string{"project"})
+	require.NoError(t, err)
+	assert.Equal(t, ShowDimensionTotalsMsg{Dimension: "project"}, msg)
+
+	_, err = ApplyCommandArgs("totals", ShowDimensionTotalsMsg{}, []string{"project", "region"})
+	assert.EqualError(t, err, "usage: totals [dimension]")
+
+	msg, err = ApplyCommandArgs("recur", MakeRecurringMsg{Entry: 3}, []string{"monthly", "12"})
+	require.NoError(t, err)
+	assert.Equal(t, MakeRecurringMsg{Entry: 3, Frequency: "monthly", Limit: "12"}, msg)
+
+	_, err = ApplyCommandArgs("recur", MakeRecurringMsg{Entry: 3}, nil)
+	assert.EqualError(t, err, "usage: recur <daily|weekly|monthly|yearly> [count|yy-MM-dd]")
+
+	msg, err = ApplyCommandArgs("template", UseTemplateMsg{}, []string{"groceries", "12.10"})
+	require.NoError(t, err)
+	assert.Equal(t, UseTemplateMsg{Name: "groceries", Total: "12.10"}, msg)
+
+	msg, err = ApplyCommandArgs("savetemplate", SaveTemplateMsg{Entry: 3}, []string{"groceries", "split"})
+	require.NoError(t, err)
+	assert.Equal(t, SaveTemplateMsg{Entry: 3, Name: "groceries", Split: true}, msg)
+
+	_, err = ApplyCommandArgs("savetemplate", SaveTemplateMsg{Entry: 3}, []string{"groceries", "halved"})
+	assert.EqualError(t, err, "usage: savetemplate <name> [split]")
+
+	msg, err = ApplyCommandArgs("budget", SetBudgetMsg{Ledger: 2}, []string{"monthly", "500", "#3"})
+	require.NoError(t, err)
+	assert.Equal(t, SetBudgetMsg{Ledger: 2, Period: "monthly", Amount: "500", Scope: "#3"}, msg)
+
+	_, err = ApplyCommandArgs("budget", SetBudgetMsg{Ledger: 2}, []string{"monthly"})
+	assert.EqualError(t, err, "usage: budget <monthly|yearly> <amount|none> [tag|#account]")
+
+	msg, err = ApplyCommandArgs("statement", StartStatementMsg{}, []string{"24-03-31", "1234.56"})
+	require.NoError(t, err)
+	assert.Equal(t, StartStatementMsg{Date: "24-03-31", Balance: "1234.56"}, msg)
+
+	msg, err = ApplyCommandArgs("statement", StartStatementMsg{}, []string{"none"})
+	require.NoError(t, err)
+	assert.Equal(t, StartStatementMsg{Stop: true}, msg)
+
+	_, err = ApplyCommandArgs("statement", StartStatementMsg{}, []string{"24-03-31"})
+	assert.EqualError(t, err, "usage: statement <yy-MM-dd> <closing balance>|none")
+
+	msg, err = ApplyCommandArgs("budgets", ShowBudgetReportMsg{}, []string{"2024"})
+	require.NoError(t, err)
+	assert.Equal(t, ShowBudgetReportMsg{Year: "2024"}, msg)
+
+	msg, err = ApplyCommandArgs("taxcode", SetTaxCodeMsg{}, []string{"V21", "purchase", "21", "#4", "tax:5b"})
+	require.NoError(t, err)
+	assert.Equal(t, SetTaxCodeMsg{Code: "V21", Type: "purchase", Rate: "21", Ledger: "#4", Options: []string{"tax:5b"}}, msg)
+
+	msg, err = ApplyCommandArgs("taxcode", SetTaxCodeMsg{}, []string{"V21", "none"})
+	require.NoError(t, err)
+	assert.Equal(t, SetTaxCodeMsg{Code: "V21", Remove: true}, msg)
+
+	_, err = ApplyCommandArgs("vat", ShowVatReturnMsg{}, nil)
+	assert.EqualError(t, err, "usage: vat <period>, e.g. vat 2024-Q1")
+}
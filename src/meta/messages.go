@@ -1,6 +1,11 @@
 package meta
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
 	tea "github.com/charmbracelet/bubbletea"
 )
 
@@ -35,6 +40,418 @@ type ShowTextModalMsg struct {
 
 type ShowGlobalSearchMsg struct{}
 
+// For `:check`
+type ShowIntegrityCheckMsg struct{}
+
+// Shows the audit log of an entry, from its detail view
+type ShowEntryHistoryMsg struct {
+	Entry int
+}
+
+// Shows the files attached to an entry, from its detail view.
+// New files get attached to Row, or to the entry as a whole if it's nil.
+type ShowAttachmentsMsg struct {
+	Entry int
+	Row   *int
+}
+
+// For `:recurring`, shows the occurrences of recurring entries that are due, also shown on startup
+type ShowRecurringMsg struct{}
+
+// For `:setup`, offers ready-made ledgers and journals to start an empty book with, also shown when opening one
+type ShowChartTemplatesMsg struct{}
+
+// For `:recur <frequency> [count|yy-MM-dd]` from an entry's detail view, Limit is empty for no limit
+type MakeRecurringMsg struct {
+	Entry     int
+	Frequency string
+	Limit     string
+}
+
+func (msg MakeRecurringMsg) WithArgs(args []string) (tea.Msg, error) {
+	switch len(args) {
+	case 1:
+		return MakeRecurringMsg{Entry: msg.Entry, Frequency: args[0]}, nil
+
+	case 2:
+		return MakeRecurringMsg{Entry: msg.Entry, Frequency: args[0], Limit: args[1]}, nil
+
+	default:
+		return nil, errors.New("usage: recur <daily|weekly|monthly|yearly> [count|yy-MM-dd]")
+	}
+}
+
+// For `:template [name] [total]`, opens the entry create view filled in from the template.
+// Without a name it lists the templates instead.
+type UseTemplateMsg struct {
+	Name  string
+	Total string
+}
+
+func (msg UseTemplateMsg) WithArgs(args []string) (tea.Msg, error) {
+	switch len(args) {
+	case 0:
+		return msg, nil
+
+	case 1:
+		return UseTemplateMsg{Name: args[0]}, nil
+
+	case 2:
+		return UseTemplateMsg{Name: args[0], Total: args[1]}, nil
+
+	default:
+		return nil, errors.New("usage: template [name] [total]")
+	}
+}
+
+// For `:savetemplate <name> [split]` from an entry's detail view.
+// With split the template keeps the rows' amounts as percentages of the total, instead of the amounts themselves.
+type SaveTemplateMsg struct {
+	Entry int
+	Name  string
+	Split bool
+}
+
+func (msg SaveTemplateMsg) WithArgs(args []string) (tea.Msg, error) {
+	switch {
+	case len(args) == 1:
+		return SaveTemplateMsg{Entry: msg.Entry, Name: args[0]}, nil
+
+	case len(args) == 2 && args[1] == "split":
+		return SaveTemplateMsg{Entry: msg.Entry, Name: args[0], Split: true}, nil
+
+	default:
+		return nil, errors.New("usage: savetemplate <name> [split]")
+	}
+}
+
+// For `:deletetemplate <name>`
+type DeleteTemplateMsg struct {
+	Name string
+}
+
+func (msg DeleteTemplateMsg) WithArgs(args []string) (tea.Msg, error) {
+	if len(args) != 1 {
+		return nil, errors.New("usage: deletetemplate <name>")
+	}
+
+	return DeleteTemplateMsg{Name: args[0]}, nil
+}
+
+// For `:budget <monthly|yearly> <amount|none> [tag|#account]` from a ledger's detail view.
+// Scope is a tag, or an account id after a #, empty for the whole ledger. Amount "none" removes the budget.
+type SetBudgetMsg struct {
+	Ledger int
+	Period string
+	Amount string
+	Scope  string
+}
+
+func (msg SetBudgetMsg) WithArgs(args []string) (tea.Msg, error) {
+	switch len(args) {
+	case 2:
+		return SetBudgetMsg{Ledger: msg.Ledger, Period: args[0], Amount: args[1]}, nil
+
+	case 3:
+		return SetBudgetMsg{Ledger: msg.Ledger, Period: args[0], Amount: args[1], Scope: args[2]}, nil
+
+	default:
+		return nil, errors.New("usage: budget <monthly|yearly> <amount|none> [tag|#account]")
+	}
+}
+
+// For `:statement <yy-MM-dd> <closing balance>` from a ledger's detail view,
+// starts reconciling the ledger against a bank statement. `:statement none` stops without saving.
+type StartStatementMsg struct {
+	Date    string
+	Balance string
+	Stop    bool
+}
+
+func (msg StartStatementMsg) WithArgs(args []string) (tea.Msg, error) {
+	switch {
+	case len(args) == 1 && args[0] == "none":
+		return StartStatementMsg{Stop: true}, nil
+
+	case len(args) == 2:
+		return StartStatementMsg{Date: args[0], Balance: args[1]}, nil
+
+	default:
+		return nil, errors.New("usage: statement <yy-MM-dd> <closing balance>|none")
+	}
+}
+
+// For `:statements` from a ledger's detail view, lists the statements it was reconciled against
+type ShowStatementsMsg struct {
+	Ledger int
+}
+
+// For `:chart`, shows every ledger in the tree with its own and its rolled-up balance
+type ShowChartOfAccountsMsg struct{}
+
+// For `:budgets [year]`, an empty Year means this year
+type ShowBudgetReportMsg struct {
+	Year string
+}
+
+func (msg ShowBudgetReportMsg) WithArgs(args []string) (tea.Msg, error) {
+	switch len(args) {
+	case 0:
+		return msg, nil
+
+	case 1:
+		return ShowBudgetReportMsg{Year: args[0]}, nil
+
+	default:
+		return nil, errors.New("usage: budgets [year]")
+	}
+}
+
+// For `:taxcode <code> <sales|purchase> <rate> <#ledger> [reverse] [turnover:box] [tax:box] [deductible:box]`,
+// or `:taxcode <code> none` to remove it. Ledger is the VAT ledger the tax is booked on.
+type SetTaxCodeMsg struct {
+	Code    string
+	Remove  bool
+	Type    string
+	Rate    string
+	Ledger  string
+	Options []string
+}
+
+func (msg SetTaxCodeMsg) WithArgs(args []string) (tea.Msg, error) {
+	switch {
+	case len(args) == 2 && args[1] == "none":
+		return SetTaxCodeMsg{Code: args[0], Remove: true}, nil
+
+	case len(args) >= 4:
+		return SetTaxCodeMsg{Code: args[0], Type: args[1], Rate: args[2], Ledger: args[3], Options: args[4:]}, nil
+
+	default:
+		return nil, errors.New("usage: taxcode <code> <sales|purchase> <rate> <#ledger> [reverse] [turnover:box] [tax:box] [deductible:box], or taxcode <code> none")
+	}
+}
+
+// For `:taxcodes`
+type ShowTaxCodesMsg struct{}
+
+// For `:vat <period>`, e.g. `:vat 2024-Q1`
+type ShowVatReturnMsg struct {
+	Period string
+}
+
+func (msg ShowVatReturnMsg) WithArgs(args []string) (tea.Msg, error) {
+	if len(args) != 1 {
+		return nil, errors.New("usage: vat <period>, e.g. vat 2024-Q1")
+	}
+
+	return ShowVatReturnMsg{Period: args[0]}, nil
+}
+
+// For `:post` from a draft invoice's detail view, numbers the invoice and books it
+type PostInvoiceMsg struct {
+	Invoice int
+}
+
+// For `:paid [no]` from an invoice's detail view, `:paid no` marks it as unpaid again
+type SetInvoicePaidMsg struct {
+	Invoice int
+	Paid    bool
+}
+
+func (msg SetInvoicePaidMsg) WithArgs(args []string) (tea.Msg, error) {
+	switch {
+	case len(args) == 0:
+		return SetInvoicePaidMsg{Invoice: msg.Invoice, Paid: true}, nil
+
+	case len(args) == 1 && args[0] == "no":
+		return SetInvoicePaidMsg{Invoice: msg.Invoice, Paid: false}, nil
+
+	default:
+		return nil, errors.New("usage: paid [no]")
+	}
+}
+
+// For `:export <html|text> [path]` from an invoice's detail view, an empty Path means invoice-<number> in the working directory
+type ExportInvoiceMsg struct {
+	Invoice int
+	Format  string
+	Path    string
+}
+
+func (msg ExportInvoiceMsg) WithArgs(args []string) (tea.Msg, error) {
+	switch len(args) {
+	case 1:
+		return ExportInvoiceMsg{Invoice: msg.Invoice, Format: args[0]}, nil
+
+	case 2:
+		return ExportInvoiceMsg{Invoice: msg.Invoice, Format: args[0], Path: args[1]}, nil
+
+	default:
+		return nil, errors.New("usage: export <html|text> [path]")
+	}
+}
+
+// For `:aging [yy-MM-dd] [due]`, an empty Date means today.
+// With due, amounts of posted invoices age from their due date instead of the date they were booked.
+type ShowAgingMsg struct {
+	Date      string
+	ByDueDate bool
+}
+
+func (msg ShowAgingMsg) WithArgs(args []string) (tea.Msg, error) {
+	result := ShowAgingMsg{}
+
+	for _, arg := range args {
+		switch {
+		case arg == "due" && !result.ByDueDate:
+			result.ByDueDate = true
+
+		case arg != "due" && result.Date == "":
+			result.Date = arg
+
+		default:
+			return nil, errors.New("usage: aging [yy-MM-dd] [due]")
+		}
+	}
+
+	return result, nil
+}
+
+// For `:lock <period>`, e.g. `:lock 2024`, `:lock 2024-Q1` or `:lock 2024-03`
+type LockPeriodMsg struct {
+	Period string
+}
+
+func (msg LockPeriodMsg) WithArgs(args []string) (tea.Msg, error) {
+	if len(args) != 1 {
+		return nil, errors.New("usage: lock <period>, e.g. lock 2024, lock 2024-Q1 or lock 2024-03")
+	}
+
+	return LockPeriodMsg{Period: args[0]}, nil
+}
+
+// For `:yearend [year]`, Year 0 means last year
+type ShowYearEndWizardMsg struct {
+	Year int
+}
+
+func (msg ShowYearEndWizardMsg) WithArgs(args []string) (tea.Msg, error) {
+	switch len(args) {
+	case 0:
+		return msg, nil
+
+	case 1:
+		year, err := strconv.Atoi(args[0])
+		if err != nil || year < 1000 || year > 9999 {
+			return nil, fmt.Errorf("invalid year %q", args[0])
+		}
+
+		return ShowYearEndWizardMsg{Year: year}, nil
+
+	default:
+		return nil, errors.New("usage: yearend [year]")
+	}
+}
+
+// For `:unlock <period>`
+type UnlockPeriodMsg struct {
+	Period string
+}
+
+func (msg UnlockPeriodMsg) WithArgs(args []string) (tea.Msg, error) {
+	if len(args) != 1 {
+		return nil, errors.New("usage: unlock <period>, e.g. unlock 2024, unlock 2024-Q1 or unlock 2024-03")
+	}
+
+	return UnlockPeriodMsg{Period: args[0]}, nil
+}
+
+// For `:rate <currency> <date> <rate>`, e.g. `:rate USD 24-12-31 0.92`
+type SetExchangeRateMsg struct {
+	Currency string
+	Date     string
+	Rate     string
+}
+
+func (msg SetExchangeRateMsg) WithArgs(args []string) (tea.Msg, error) {
+	if len(args) != 3 {
+		return nil, errors.New("usage: rate <currency> <yy-MM-dd> <rate>, e.g. rate USD 24-12-31 0.92")
+	}
+
+	return SetExchangeRateMsg{Currency: args[0], Date: args[1], Rate: args[2]}, nil
+}
+
+// For `:trash`
+type ShowTrashMsg struct{}
+
+// For `:purge <days>`, permanently removes what was deleted more than Days ago.
+// The days have to be given, so emptying the trash is never a typo away.
+type PurgeTrashMsg struct {
+	Days int
+}
+
+func (msg PurgeTrashMsg) WithArgs(args []string) (tea.Msg, error) {
+	if len(args) != 1 {
+		return nil, errors.New("usage: purge <days>, like purge 30 to remove what was deleted more than 30 days ago")
+	}
+
+	days, err := strconv.Atoi(args[0])
+	if err != nil || days < 0 {
+		return nil, fmt.Errorf("invalid number of days %q", args[0])
+	}
+
+	return PurgeTrashMsg{Days: days}, nil
+}
+
+// For `:search <query>`, searches descriptions, documents and notes across the whole book
+type ShowFullTextSearchMsg struct {
+	Query string
+}
+
+func (msg ShowFullTextSearchMsg) WithArgs(args []string) (tea.Msg, error) {
+	if len(args) == 0 {
+		return nil, errors.New("usage: search <query>")
+	}
+
+	return ShowFullTextSearchMsg{Query: strings.Join(args, " ")}, nil
+}
+
+// For `:totals [dimension]`, totals the tagged rows per value of the dimension, or per tag without one
+type ShowDimensionTotalsMsg struct {
+	Dimension string
+}
+
+func (msg ShowDimensionTotalsMsg) WithArgs(args []string) (tea.Msg, error) {
+	switch len(args) {
+	case 0:
+		return msg, nil
+
+	case 1:
+		return ShowDimensionTotalsMsg{Dimension: args[0]}, nil
+
+	default:
+		return nil, errors.New("usage: totals [dimension]")
+	}
+}
+
+// For `:revalue [yy-MM-dd]`, an empty Date means today
+type ShowRevaluationWizardMsg struct {
+	Date string
+}
+
+func (msg ShowRevaluationWizardMsg) WithArgs(args []string) (tea.Msg, error) {
+	switch len(args) {
+	case 0:
+		return msg, nil
+
+	case 1:
+		return ShowRevaluationWizardMsg{Date: args[0]}, nil
+
+	default:
+		return nil, errors.New("usage: revalue [yy-MM-dd]")
+	}
+}
+
 type FetchNotificationHistoryMsg struct{}
 
 type NotificationHistoryLoadedMsg struct {
@@ -52,6 +469,7 @@ const (
 	ENTRIESAPP  AppType = "ENTRIES"
 	JOURNALSAPP AppType = "JOURNALS"
 	ACCOUNTSAPP AppType = "ACCOUNTS"
+	INVOICESAPP AppType = "INVOICES"
 )
 
 type ModelType string
@@ -62,6 +480,8 @@ const (
 	ENTRYROWMODEL ModelType = "ENTRYROW"
 	JOURNALMODEL  ModelType = "JOURNAL"
 	ACCOUNTMODEL  ModelType = "ACCOUNT"
+	BUDGETMODEL   ModelType = "BUDGET"
+	INVOICEMODEL  ModelType = "INVOICE"
 )
 
 type DataLoadedMsg struct {
@@ -95,6 +515,14 @@ type JumpVerticalMsg struct {
 	Down bool
 }
 
+// For the za motion, folds or unfolds the children of the highlighted item in a tree
+type ToggleFoldMsg struct{}
+
+// For the zM and zR motions, folds or unfolds every item in a tree
+type FoldAllMsg struct {
+	Folded bool
+}
+
 type SwitchModeMsg struct {
 	InputMode
 	// vim treats search as command mode, so I am too
@@ -155,6 +583,25 @@ type ToggleShowReconciledMsg struct{}
 
 type ReconcileMsg struct{}
 
+// Matches the rows marked as reconciled into a group, also when they don't total 0 yet, like a partial payment
+type MatchRowsMsg struct{}
+
+// Splits up the reconciliation group of the active row
+type UnmatchRowsMsg struct{}
+
+// For `:automatch` from an account's or ledger's detail view, App says which of the two Id is.
+// The view checks it can reconcile and has nothing uncommitted, then shows the proposals with ShowAutoMatchMsg.
+type AutoMatchMsg struct {
+	App AppType
+	Id  int
+}
+
+// Proposes sets of unreconciled rows that net to 0, to reconcile them in bulk
+type ShowAutoMatchMsg struct {
+	App AppType
+	Id  int
+}
+
 type RefreshCacheMsg struct{}
 
 type DebugPrintCacheMsg struct{}
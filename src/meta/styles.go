@@ -10,6 +10,7 @@ var (
 	LEDGERSCOLOUR  = lipgloss.Color("#3E7D56")
 	ACCOUNTSCOLOUR = lipgloss.Color("#006B85")
 	JOURNALSCOLOUR = lipgloss.Color("#915E5E")
+	INVOICESCOLOUR = lipgloss.Color("#6A4C93")
 )
 
 var tabBorder = lipgloss.Border{
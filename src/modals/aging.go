@@ -0,0 +1,173 @@
+package modals
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"terminaccounting/bubbles/list"
+	"terminaccounting/database"
+	"terminaccounting/meta"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmoiron/sqlx"
+)
+
+// Shows per account how long its unreconciled balance has been outstanding
+type agingModal struct {
+	DB *sqlx.DB
+
+	width, height int
+
+	date      string
+	byDueDate bool
+
+	// nil until the report is loaded
+	aging *database.Aging
+	list  list.Model
+}
+
+func newAgingModal(DB *sqlx.DB, date string, byDueDate bool) *agingModal {
+	if date == "" {
+		date = database.Today().String()
+	}
+
+	return &agingModal{
+		DB: DB,
+
+		date:      date,
+		byDueDate: byDueDate,
+
+		list: list.New(0, 0),
+	}
+}
+
+func (am *agingModal) Init() tea.Cmd {
+	date, err := database.ToDate(am.date)
+	if err != nil {
+		return tea.Batch(
+			meta.MessageCmd(meta.QuitMsg{}),
+			meta.MessageCmd(fmt.Errorf("invalid date %q, expected yy-MM-dd", am.date)),
+		)
+	}
+
+	aging, err := database.AgingReport(am.DB, date, am.byDueDate)
+	if err != nil {
+		return tea.Batch(meta.MessageCmd(meta.QuitMsg{}), meta.MessageCmd(err))
+	}
+
+	return meta.MessageCmd(meta.DataLoadedMsg{Data: aging})
+}
+
+func (am *agingModal) Update(message tea.Msg) (Modal, tea.Cmd) {
+	switch message := message.(type) {
+	case tea.WindowSizeMsg:
+		am.width = message.Width
+		am.height = message.Height
+
+		var cmd tea.Cmd
+		// -3 for the title and column names, -6 for the footer
+		am.list, cmd = am.list.Update(tea.WindowSizeMsg{Width: message.Width, Height: message.Height - 9})
+
+		return am, cmd
+
+	case meta.NavigateMsg:
+		am.list.Navigate(message.Direction == meta.DOWN)
+
+		return am, nil
+
+	case meta.JumpVerticalMsg:
+		am.list.Jump(message.Down)
+
+		return am, nil
+
+	case meta.UpdateSearchMsg:
+		var cmd tea.Cmd
+		am.list, cmd = am.list.Update(list.FuzzyFilterMsg{Query: message.Query})
+
+		return am, cmd
+
+	case meta.DataLoadedMsg:
+		aging := message.Data.(database.Aging)
+		am.aging = &aging
+		am.list.SetItems(toItemSlice(aging.Lines))
+
+		return am, nil
+
+	default:
+		panic(fmt.Sprintf("unexpected tea.Msg: %#v", message))
+	}
+}
+
+func (am *agingModal) View() string {
+	var result strings.Builder
+
+	titleStyle := lipgloss.NewStyle().Bold(true).MarginBottom(1)
+
+	if am.aging == nil {
+		result.WriteString(titleStyle.Render("Loading aging report..."))
+
+		return result.String()
+	}
+
+	basis := "booking dates"
+	if am.aging.ByDueDate {
+		basis = "due dates"
+	}
+	result.WriteString(titleStyle.Render(fmt.Sprintf("Outstanding on %s by %s, use gd to go to an account", am.aging.Date, basis)))
+	result.WriteString("\n")
+
+	result.WriteString(lipgloss.NewStyle().Bold(true).Render(database.AgingHeader()))
+	result.WriteString("\n")
+
+	result.WriteString(am.list.View())
+	result.WriteString("\n")
+
+	result.WriteString(strings.Join(am.aging.RenderFooter(), "\n"))
+
+	return result.String()
+}
+
+func (am *agingModal) AllowsInsertMode() bool {
+	return false
+}
+
+func (am *agingModal) AllowsSearchMode() bool {
+	return true
+}
+
+func (am *agingModal) MotionSet() meta.Trie[tea.Msg] {
+	var result meta.Trie[tea.Msg]
+
+	result.Insert(meta.Motion{"j"}, meta.NavigateMsg{Direction: meta.DOWN})
+	result.Insert(meta.Motion{"k"}, meta.NavigateMsg{Direction: meta.UP})
+
+	result.Insert(meta.Motion{"g", "g"}, meta.JumpVerticalMsg{Down: false})
+	result.Insert(meta.Motion{"G"}, meta.JumpVerticalMsg{Down: true})
+
+	var gotoDetailViewCmd tea.Cmd = func() tea.Msg {
+		activeItem := am.list.ActiveItem()
+
+		if activeItem == nil {
+			return errors.New("no account shown to go to detail view of")
+		}
+
+		appType := meta.ACCOUNTSAPP
+		return meta.SwitchAppViewMsg{
+			App:      &appType,
+			ViewType: meta.DETAILVIEWTYPE,
+			Data:     (*activeItem).(database.AgingLine).Account,
+		}
+	}
+	result.Insert(meta.Motion{"g", "d"}, gotoDetailViewCmd)
+
+	return result
+}
+
+func (am *agingModal) CommandSet() meta.Trie[tea.Msg] {
+	return meta.Trie[tea.Msg]{}
+}
+
+func (am *agingModal) Reload() Modal {
+	return newAgingModal(am.DB, am.date, am.byDueDate)
+}
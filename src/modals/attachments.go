@@ -0,0 +1,325 @@
+package modals
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"terminaccounting/bubbles/list"
+	"terminaccounting/database"
+	"terminaccounting/meta"
+
+	"github.com/charmbracelet/bubbles/filepicker"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmoiron/sqlx"
+)
+
+// Starts picking a file to attach
+type pickAttachmentMsg struct{}
+
+// Hands the highlighted attachment to the system opener
+type openAttachmentMsg struct{}
+
+// Removes the highlighted attachment
+type detachAttachmentMsg struct{}
+
+// Hands the file to the desktop's default program for it, without waiting for that to exit.
+// A variable so tests don't actually open anything.
+var systemOpen = func(path string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", path)
+
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", path)
+
+	default:
+		cmd = exec.Command("xdg-open", path)
+	}
+
+	err := cmd.Start()
+	if err != nil {
+		return fmt.Errorf("couldn't open %s: %v", path, err)
+	}
+
+	go cmd.Wait()
+
+	return nil
+}
+
+// Lists the files attached to an entry, attaches new ones with a file picker and opens them
+type attachmentsModal struct {
+	DB *sqlx.DB
+
+	width, height int
+
+	entryId int
+	// Where new files get attached, nil for the entry as a whole
+	rowId *int
+
+	// nil until the attachments have loaded
+	attachments []database.Attachment
+	list        list.Model
+
+	// nil unless a file is being picked
+	picker *filepicker.Model
+}
+
+func newAttachmentsModal(DB *sqlx.DB, entryId int, rowId *int) *attachmentsModal {
+	return &attachmentsModal{
+		DB: DB,
+
+		entryId: entryId,
+		rowId:   rowId,
+
+		list: list.New(0, 0),
+	}
+}
+
+func (am *attachmentsModal) Init() tea.Cmd {
+	return am.makeLoadAttachmentsCmd()
+}
+
+func (am *attachmentsModal) makeLoadAttachmentsCmd() tea.Cmd {
+	return func() tea.Msg {
+		attachments, err := database.SelectAttachments(am.DB, am.entryId)
+		if err != nil {
+			return fmt.Errorf("FAILED TO LOAD ATTACHMENTS OF ENTRY %d: %v", am.entryId, err)
+		}
+
+		return meta.DataLoadedMsg{Data: attachments}
+	}
+}
+
+func (am *attachmentsModal) Update(message tea.Msg) (Modal, tea.Cmd) {
+	switch message := message.(type) {
+	case tea.WindowSizeMsg:
+		am.width = message.Width
+		am.height = message.Height
+
+		if am.picker != nil {
+			am.picker.SetHeight(am.pickerHeight())
+		}
+
+		var cmd tea.Cmd
+		// -2 for the title and its margin, -2 for the hint and its margin
+		am.list, cmd = am.list.Update(tea.WindowSizeMsg{Width: message.Width, Height: message.Height - 4})
+
+		return am, cmd
+
+	case meta.NavigateMsg:
+		am.list.Navigate(message.Direction == meta.DOWN)
+
+		return am, nil
+
+	case meta.JumpVerticalMsg:
+		am.list.Jump(message.Down)
+
+		return am, nil
+
+	case meta.UpdateSearchMsg:
+		var cmd tea.Cmd
+		am.list, cmd = am.list.Update(list.FuzzyFilterMsg{Query: message.Query})
+
+		return am, cmd
+
+	case meta.DataLoadedMsg:
+		am.attachments = message.Data.([]database.Attachment)
+		am.list.SetItems(toItemSlice(am.attachments))
+
+		return am, nil
+
+	case pickAttachmentMsg:
+		directory, err := os.UserHomeDir()
+		if err != nil {
+			directory = "."
+		}
+
+		picker := filepicker.New()
+		picker.CurrentDirectory = directory
+		picker.AutoHeight = false
+		picker.SetHeight(am.pickerHeight())
+		am.picker = &picker
+
+		return am, tea.Batch(am.picker.Init(), meta.MessageCmd(meta.SwitchModeMsg{InputMode: meta.INSERTMODE}))
+
+	case openAttachmentMsg:
+		activeItem := am.list.ActiveItem()
+		if activeItem == nil {
+			return am, meta.MessageCmd(errors.New("no attachment to open"))
+		}
+		attachment := (*activeItem).(database.Attachment)
+
+		path, err := database.ExtractAttachment(am.DB, attachment)
+		if err != nil {
+			return am, meta.MessageCmd(err)
+		}
+
+		err = systemOpen(path)
+		if err != nil {
+			return am, meta.MessageCmd(err)
+		}
+
+		return am, meta.MessageCmd(meta.NotificationMessageMsg{Message: fmt.Sprintf("Opened %s", attachment.Name)})
+
+	case detachAttachmentMsg:
+		activeItem := am.list.ActiveItem()
+		if activeItem == nil {
+			return am, meta.MessageCmd(errors.New("no attachment to remove"))
+		}
+		attachment := (*activeItem).(database.Attachment)
+
+		err := database.DetachFile(am.DB, attachment.Id)
+		if err != nil {
+			return am, meta.MessageCmd(err)
+		}
+
+		notification := meta.NotificationMessageMsg{Message: fmt.Sprintf("Removed attachment %s", attachment.Name)}
+
+		return am, tea.Batch(meta.MessageCmd(notification), am.makeLoadAttachmentsCmd())
+
+	case meta.SwitchFocusMsg:
+		return am, nil
+
+	case tea.KeyMsg:
+		// Only reachable in insert mode, which is only allowed while picking
+		if am.picker == nil {
+			return am, nil
+		}
+
+		return am.updatePicker(message)
+
+	default:
+		// The file picker's own messages, like the contents of a directory it read
+		if am.picker != nil {
+			return am.updatePicker(message)
+		}
+
+		panic(fmt.Sprintf("unexpected tea.Msg: %#v", message))
+	}
+}
+
+func (am *attachmentsModal) updatePicker(message tea.Msg) (Modal, tea.Cmd) {
+	picker, cmd := am.picker.Update(message)
+	am.picker = &picker
+
+	didSelect, path := am.picker.DidSelectFile(message)
+	if !didSelect {
+		return am, cmd
+	}
+
+	attachment, err := database.AttachFile(am.DB, am.entryId, am.rowId, path)
+	if err != nil {
+		return am, meta.MessageCmd(err)
+	}
+
+	am.picker = nil
+
+	notification := meta.NotificationMessageMsg{Message: fmt.Sprintf("Attached %s", attachment.Name)}
+
+	return am, tea.Batch(
+		meta.MessageCmd(meta.SwitchModeMsg{InputMode: meta.NORMALMODE}),
+		meta.MessageCmd(notification),
+		am.makeLoadAttachmentsCmd(),
+	)
+}
+
+// -2 for the title and its margin, -2 for the hint and its margin
+func (am *attachmentsModal) pickerHeight() int {
+	return max(am.height-4, 1)
+}
+
+func (am *attachmentsModal) attachingTo() string {
+	if am.rowId == nil {
+		return fmt.Sprintf("entry %d", am.entryId)
+	}
+
+	return fmt.Sprintf("row %d", *am.rowId)
+}
+
+func (am *attachmentsModal) View() string {
+	var result strings.Builder
+
+	titleStyle := lipgloss.NewStyle().Bold(true).MarginBottom(1)
+	hintStyle := lipgloss.NewStyle().Italic(true)
+
+	if am.picker != nil {
+		result.WriteString(titleStyle.Render(fmt.Sprintf("Attach a file to %s", am.attachingTo())))
+		result.WriteString("\n")
+
+		result.WriteString(am.picker.View())
+		result.WriteString("\n\n")
+
+		result.WriteString(hintStyle.Render("In insert mode: j/k to move, l to open a directory, h to go up, enter to attach, esc to cancel"))
+
+		return result.String()
+	}
+
+	switch {
+	case am.attachments == nil:
+		result.WriteString(titleStyle.Render("Loading attachments..."))
+
+	case len(am.attachments) == 0:
+		result.WriteString(titleStyle.Render(fmt.Sprintf("Nothing attached to entry %d", am.entryId)))
+
+	default:
+		result.WriteString(titleStyle.Render(fmt.Sprintf("%d file(s) attached to entry %d", len(am.attachments), am.entryId)))
+	}
+	result.WriteString("\n")
+
+	result.WriteString(am.list.View())
+	result.WriteString("\n\n")
+
+	hint := fmt.Sprintf("a to attach a file to %s, o or :open to open, x or :detach to remove", am.attachingTo())
+	result.WriteString(hintStyle.Render(hint))
+
+	return result.String()
+}
+
+// Leaving insert mode cancels picking a file
+func (am *attachmentsModal) leaveInsertMode() {
+	am.picker = nil
+}
+
+func (am *attachmentsModal) AllowsInsertMode() bool {
+	return am.picker != nil
+}
+
+func (am *attachmentsModal) AllowsSearchMode() bool {
+	return am.picker == nil
+}
+
+func (am *attachmentsModal) MotionSet() meta.Trie[tea.Msg] {
+	var result meta.Trie[tea.Msg]
+
+	result.Insert(meta.Motion{"j"}, meta.NavigateMsg{Direction: meta.DOWN})
+	result.Insert(meta.Motion{"k"}, meta.NavigateMsg{Direction: meta.UP})
+
+	result.Insert(meta.Motion{"g", "g"}, meta.JumpVerticalMsg{Down: false})
+	result.Insert(meta.Motion{"G"}, meta.JumpVerticalMsg{Down: true})
+
+	result.Insert(meta.Motion{"a"}, pickAttachmentMsg{})
+	result.Insert(meta.Motion{"o"}, openAttachmentMsg{})
+	result.Insert(meta.Motion{"enter"}, openAttachmentMsg{})
+	result.Insert(meta.Motion{"x"}, detachAttachmentMsg{})
+
+	return result
+}
+
+func (am *attachmentsModal) CommandSet() meta.Trie[tea.Msg] {
+	var result meta.Trie[tea.Msg]
+
+	result.Insert(meta.Command(strings.Split("attach", "")), pickAttachmentMsg{})
+	result.Insert(meta.Command(strings.Split("open", "")), openAttachmentMsg{})
+	result.Insert(meta.Command(strings.Split("detach", "")), detachAttachmentMsg{})
+
+	return result
+}
+
+func (am *attachmentsModal) Reload() Modal {
+	return newAttachmentsModal(am.DB, am.entryId, am.rowId)
+}
@@ -0,0 +1,276 @@
+package modals
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"terminaccounting/bubbles/list"
+	"terminaccounting/database"
+	"terminaccounting/meta"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmoiron/sqlx"
+)
+
+// Leaves the highlighted proposal out, or takes it back in
+type toggleProposalMsg struct{}
+
+// A proposal in the list, all of them are accepted until left out
+type proposalItem struct {
+	index    int
+	proposal database.MatchProposal
+	accepted bool
+}
+
+func (pi proposalItem) FilterValue() string {
+	return pi.proposal.FilterValue()
+}
+
+func (pi proposalItem) Render(isActive bool) string {
+	style := lipgloss.NewStyle()
+	if isActive {
+		style = style.Foreground(meta.ACCOUNTSCOLOUR)
+	}
+
+	accepted := "□"
+	if pi.accepted {
+		accepted = "■"
+	}
+
+	return style.Render(fmt.Sprintf("%s %4.2f  %s", accepted, pi.proposal.Score, pi.proposal))
+}
+
+// Proposes sets of unreconciled rows of an account or ledger that net to 0, and reconciles the accepted ones
+type autoMatchModal struct {
+	DB *sqlx.DB
+
+	width, height int
+
+	// ACCOUNTSAPP or LEDGERSAPP, saying which of the two id is
+	app meta.AppType
+	id  int
+
+	// nil until the proposals are loaded
+	proposals []proposalItem
+	list      list.Model
+}
+
+func newAutoMatchModal(DB *sqlx.DB, app meta.AppType, id int) *autoMatchModal {
+	return &autoMatchModal{
+		DB: DB,
+
+		app: app,
+		id:  id,
+
+		list: list.New(0, 0),
+	}
+}
+
+func (amm *autoMatchModal) Init() tea.Cmd {
+	var rows []database.EntryRow
+	var err error
+
+	switch amm.app {
+	case meta.ACCOUNTSAPP:
+		rows, err = database.SelectRowsByAccount(amm.DB, amm.id)
+
+	case meta.LEDGERSAPP:
+		rows, err = database.SelectRowsByLedgers(amm.DB, database.LedgerSubtree(database.AvailableLedgers(), amm.id))
+
+	default:
+		panic(fmt.Sprintf("unexpected meta.AppType: %#v", amm.app))
+	}
+
+	if err != nil {
+		return tea.Batch(meta.MessageCmd(meta.QuitMsg{}), meta.MessageCmd(err))
+	}
+
+	return meta.MessageCmd(meta.DataLoadedMsg{Data: database.ProposeMatches(rows)})
+}
+
+func (amm *autoMatchModal) Update(message tea.Msg) (Modal, tea.Cmd) {
+	switch message := message.(type) {
+	case tea.WindowSizeMsg:
+		amm.width = message.Width
+		amm.height = message.Height
+
+		var cmd tea.Cmd
+		// -2 for the title and its margin
+		amm.list, cmd = amm.list.Update(tea.WindowSizeMsg{Width: message.Width, Height: message.Height - 2})
+
+		return amm, cmd
+
+	case meta.NavigateMsg:
+		amm.list.Navigate(message.Direction == meta.DOWN)
+
+		return amm, nil
+
+	case meta.JumpVerticalMsg:
+		amm.list.Jump(message.Down)
+
+		return amm, nil
+
+	case meta.UpdateSearchMsg:
+		var cmd tea.Cmd
+		amm.list, cmd = amm.list.Update(list.FuzzyFilterMsg{Query: message.Query})
+
+		return amm, cmd
+
+	case meta.DataLoadedMsg:
+		amm.proposals = []proposalItem{}
+		for i, proposal := range message.Data.([]database.MatchProposal) {
+			amm.proposals = append(amm.proposals, proposalItem{index: i, proposal: proposal, accepted: true})
+		}
+		amm.list.SetItems(toItemSlice(amm.proposals))
+
+		return amm, nil
+
+	case toggleProposalMsg:
+		activeItem := amm.list.ActiveItem()
+		if activeItem == nil {
+			return amm, meta.MessageCmd(errors.New("there are no proposals"))
+		}
+
+		index := (*activeItem).(proposalItem).index
+		amm.proposals[index].accepted = !amm.proposals[index].accepted
+		amm.list.UpdateItems(toItemSlice(amm.proposals))
+
+		return amm, nil
+
+	case meta.CommitMsg:
+		return amm, amm.reconcileAccepted()
+
+	default:
+		panic(fmt.Sprintf("unexpected tea.Msg: %#v", message))
+	}
+}
+
+// Reconciles every accepted proposal as a group of its own, and goes back to the refreshed detail view
+func (amm *autoMatchModal) reconcileAccepted() tea.Cmd {
+	var accepted []database.MatchProposal
+	for _, item := range amm.proposals {
+		if item.accepted {
+			accepted = append(accepted, item.proposal)
+		}
+	}
+
+	if len(accepted) == 0 {
+		return meta.MessageCmd(errors.New("no proposals are accepted, there's nothing to reconcile"))
+	}
+
+	switchViewMsg, err := amm.switchBackMsg()
+	if err != nil {
+		return meta.MessageCmd(err)
+	}
+
+	// All proposals are reconciled in one go, so a failing one doesn't leave the others half done
+	var groups [][]*database.EntryRow
+	for _, proposal := range accepted {
+		var rows []*database.EntryRow
+		for _, row := range proposal.Rows {
+			row.Reconciled = true
+			rows = append(rows, &row)
+		}
+
+		groups = append(groups, rows)
+	}
+
+	changed, err := database.SetReconciledGroups(amm.DB, groups)
+	if err != nil {
+		return meta.MessageCmd(err)
+	}
+
+	notification := meta.NotificationMessageMsg{Message: fmt.Sprintf("reconciled %d proposals, updated %d rows", len(accepted), changed)}
+
+	return tea.Batch(meta.MessageCmd(notification), meta.MessageCmd(switchViewMsg))
+}
+
+// Switching to the detail view the proposals were made for reloads it, so it shows what got reconciled
+func (amm *autoMatchModal) switchBackMsg() (meta.SwitchAppViewMsg, error) {
+	app := amm.app
+	result := meta.SwitchAppViewMsg{App: &app, ViewType: meta.DETAILVIEWTYPE}
+
+	var err error
+	switch amm.app {
+	case meta.ACCOUNTSAPP:
+		result.Data, err = database.SelectAccount(amm.DB, amm.id)
+
+	case meta.LEDGERSAPP:
+		result.Data, err = database.SelectLedger(amm.DB, amm.id)
+
+	default:
+		panic(fmt.Sprintf("unexpected meta.AppType: %#v", amm.app))
+	}
+
+	return result, err
+}
+
+func (amm *autoMatchModal) View() string {
+	var result strings.Builder
+
+	titleStyle := lipgloss.NewStyle().Bold(true).MarginBottom(1)
+
+	if amm.proposals == nil {
+		result.WriteString(titleStyle.Render("Looking for rows that net to 0..."))
+
+		return result.String()
+	}
+
+	if len(amm.proposals) == 0 {
+		result.WriteString(titleStyle.Render("No sets of unreconciled rows net to 0"))
+
+		return result.String()
+	}
+
+	accepted := 0
+	for _, item := range amm.proposals {
+		if item.accepted {
+			accepted++
+		}
+	}
+
+	result.WriteString(titleStyle.Render(fmt.Sprintf(
+		"%d of %d proposals accepted, use enter to leave one out and :write to reconcile them",
+		accepted, len(amm.proposals),
+	)))
+	result.WriteString("\n")
+
+	result.WriteString(amm.list.View())
+
+	return result.String()
+}
+
+func (amm *autoMatchModal) AllowsInsertMode() bool {
+	return false
+}
+
+func (amm *autoMatchModal) AllowsSearchMode() bool {
+	return true
+}
+
+func (amm *autoMatchModal) MotionSet() meta.Trie[tea.Msg] {
+	var result meta.Trie[tea.Msg]
+
+	result.Insert(meta.Motion{"j"}, meta.NavigateMsg{Direction: meta.DOWN})
+	result.Insert(meta.Motion{"k"}, meta.NavigateMsg{Direction: meta.UP})
+
+	result.Insert(meta.Motion{"g", "g"}, meta.JumpVerticalMsg{Down: false})
+	result.Insert(meta.Motion{"G"}, meta.JumpVerticalMsg{Down: true})
+
+	result.Insert(meta.Motion{"enter"}, toggleProposalMsg{})
+
+	return result
+}
+
+func (amm *autoMatchModal) CommandSet() meta.Trie[tea.Msg] {
+	var result meta.Trie[tea.Msg]
+
+	result.Insert(meta.Command(strings.Split("write", "")), meta.CommitMsg{})
+
+	return result
+}
+
+func (amm *autoMatchModal) Reload() Modal {
+	return newAutoMatchModal(amm.DB, amm.app, amm.id)
+}
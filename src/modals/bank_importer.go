@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
@@ -19,12 +20,15 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/x/ansi"
+	"github.com/jmoiron/sqlx"
 	"github.com/ncruces/zenity"
 )
 
 // Hardcoded only ING bank, who cares about other banks frfr
 // Also hardcoded: semicolon-separated values, decimal commas
 type bankImporter struct {
+	DB *sqlx.DB
+
 	width, height int
 
 	fileLoaded bool
@@ -51,12 +55,14 @@ type bankParser interface {
 	compileRows(data [][]string, accountLedger, bankLedger int) ([]database.EntryRow, error)
 }
 
-func newBankImporter() *bankImporter {
-	parserPicker := itempicker.New([]itempicker.Item{ingParser{}, minimalParser{}})
+func newBankImporter(DB *sqlx.DB) *bankImporter {
+	parserPicker := itempicker.New([]itempicker.Item{ingParser{}, minimalParser{}, ofxParser{}})
 	journalPicker := itempicker.New(database.AvailableJournalsAsItempickerItems())
 	bankLedgerPicker := itempicker.New(database.AvailableLedgersAsItempickerItems())
 
 	return &bankImporter{
+		DB: DB,
+
 		preview:          viewport.New(0, 0),
 		parserPicker:     parserPicker,
 		journalPicker:    journalPicker,
@@ -78,7 +84,7 @@ func (bi *bankImporter) Init() tea.Cmd {
 		file, err := zenity.SelectFile(
 			zenity.Title("Select bank file to import"),
 			zenity.FileFilter{
-				Patterns: []string{"*.csv"},
+				Patterns: []string{"*.csv", "*.ofx", "*.qfx"},
 			},
 		)
 
@@ -114,18 +120,28 @@ func (bi *bankImporter) Update(message tea.Msg) (Modal, tea.Cmd) {
 	case meta.FileSelectedMsg:
 		bi.fileLoaded = true
 
-		data, err := bi.readFile(message.File)
+		var cmd tea.Cmd
+		var err error
+		switch strings.ToLower(filepath.Ext(message.File)) {
+		case ".ofx", ".qfx":
+			cmd, err = bi.loadOFX(message.File)
+
+		default:
+			var data [][]string
+			data, err = bi.readFile(message.File)
+			if err == nil {
+				bi.headers = data[0]
+				bi.data = data[1:]
+			}
+		}
 
 		if err != nil {
 			return bi, tea.Batch(meta.MessageCmd(err), meta.MessageCmd(meta.QuitMsg{}))
 		}
 
-		bi.headers = data[0]
-		bi.data = data[1:]
-
 		bi.colWidths = bi.calculateColWidths()
 
-		return bi, nil
+		return bi, cmd
 
 	case meta.SwitchFocusMsg:
 		if bi.activeInput == numInputs-1 {
@@ -297,6 +313,11 @@ func (bi *bankImporter) Update(message tea.Msg) (Modal, tea.Cmd) {
 			return bi, meta.MessageCmd(errors.New("no bank ledger selected (none available)"))
 		}
 
+		err := bi.checkColumns()
+		if err != nil {
+			return bi, meta.MessageCmd(err)
+		}
+
 		rows, err := bi.parserPicker.Value().(bankParser).compileRows(
 			bi.data,
 			accountsLedger.Id,
@@ -306,6 +327,14 @@ func (bi *bankImporter) Update(message tea.Msg) (Modal, tea.Cmd) {
 			return bi, meta.MessageCmd(err)
 		}
 
+		rows, skipped, err := bi.dropImportedRows(rows, bankLedger.(database.Ledger).Id)
+		if err != nil {
+			return bi, meta.MessageCmd(err)
+		}
+		if len(rows) == 0 {
+			return bi, meta.MessageCmd(fmt.Errorf("all %d transactions were imported before", skipped))
+		}
+
 		entriesAppType := meta.ENTRIESAPP
 
 		switchViewMsg := meta.SwitchAppViewMsg{
@@ -318,6 +347,12 @@ func (bi *bankImporter) Update(message tea.Msg) (Modal, tea.Cmd) {
 			},
 		}
 
+		if skipped != 0 {
+			notification := meta.NotificationMessageMsg{Message: fmt.Sprintf("skipped %d transactions that were imported before", skipped)}
+
+			return bi, tea.Batch(meta.MessageCmd(notification), meta.MessageCmd(switchViewMsg))
+		}
+
 		return bi, meta.MessageCmd(switchViewMsg)
 
 	default:
@@ -418,11 +453,7 @@ func (bi *bankImporter) View() string {
 
 	result.WriteString("\n\n")
 
-	_, err := bi.parserPicker.Value().(bankParser).compileRows(
-		bi.data,
-		database.GetAccountsLedger().Id,
-		bi.bankLedgerPicker.Value().(database.Ledger).Id,
-	)
+	err := bi.tryParse()
 	if err == nil {
 		result.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Render("parser succeeds"))
 	} else {
@@ -437,6 +468,92 @@ func (bi *bankImporter) View() string {
 	return result.String()
 }
 
+// Runs the selected parser for the preview, without needing valid ledgers to be selected
+func (bi *bankImporter) tryParse() error {
+	accountsLedger := database.GetAccountsLedger()
+	if accountsLedger == nil {
+		return errors.New("no accounts ledger available")
+	}
+
+	bankLedger := bi.bankLedgerPicker.Value()
+	if bankLedger == nil {
+		return errors.New("no bank ledger available")
+	}
+
+	err := bi.checkColumns()
+	if err != nil {
+		return err
+	}
+
+	_, err = bi.parserPicker.Value().(bankParser).compileRows(bi.data, accountsLedger.Id, bankLedger.(database.Ledger).Id)
+
+	return err
+}
+
+// The selected parser has to fit the file, a CSV file of another bank may have fewer columns than it uses
+func (bi *bankImporter) checkColumns() error {
+	parser := bi.parserPicker.Value().(bankParser)
+
+	needed := slices.Max(parser.usedColumns()) + 1
+	if len(bi.headers) < needed {
+		return fmt.Errorf("the %s format uses %d columns, but the file has %d", parser, needed, len(bi.headers))
+	}
+
+	return nil
+}
+
+// Reads an OFX statement, picks the OFX parser, and the bank ledger with the statement's account number if there is one
+func (bi *bankImporter) loadOFX(path string) (tea.Cmd, error) {
+	statement, err := readOFX(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(statement.rows) == 0 {
+		return nil, errors.New("the OFX file has no transactions")
+	}
+
+	bi.headers = ofxHeaders
+	bi.data = statement.rows
+
+	err = bi.parserPicker.SetValue(ofxParser{})
+	if err != nil {
+		return nil, err
+	}
+
+	bankLedger := database.GetLedgerByBankNumber(statement.account)
+	if bankLedger == nil {
+		return meta.MessageCmd(meta.NotificationMessageMsg{Message: fmt.Sprintf(
+			"no ledger has bank number %s, pick the bank ledger to import into", statement.account,
+		)}), nil
+	}
+
+	return nil, bi.bankLedgerPicker.SetValue(*bankLedger)
+}
+
+// Leaves out the transactions that were imported onto the bank ledger before, see database.EntryRow.ExternalId.
+// Every transaction is a pair of rows, the bank ledger's row last, see makeRows.
+// Returns the rows left and how many transactions were left out.
+func (bi *bankImporter) dropImportedRows(rows []database.EntryRow, bankLedger int) ([]database.EntryRow, int, error) {
+	imported, err := database.SelectExternalIds(bi.DB, bankLedger)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var result []database.EntryRow
+	skipped := 0
+	for transaction := range slices.Chunk(rows, 2) {
+		externalId := transaction[len(transaction)-1].ExternalId
+		if externalId != nil && slices.Contains(imported, *externalId) {
+			skipped++
+			continue
+		}
+
+		result = append(result, transaction...)
+	}
+
+	return result, skipped, nil
+}
+
 func (bi *bankImporter) Title() string {
 	// TODO?
 	return ""
@@ -474,7 +591,7 @@ func (bi *bankImporter) CommandSet() meta.Trie[tea.Msg] {
 }
 
 func (bi *bankImporter) Reload() Modal {
-	return newBankImporter()
+	return newBankImporter(bi.DB)
 }
 
 func (bi *bankImporter) readFile(path string) ([][]string, error) {
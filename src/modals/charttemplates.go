@@ -0,0 +1,147 @@
+package modals
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"terminaccounting/bubbles/list"
+	"terminaccounting/database"
+	"terminaccounting/meta"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmoiron/sqlx"
+)
+
+// Creates the ledgers and journals of the highlighted template
+type applyChartTemplateMsg struct{}
+
+// Offers the chart templates to start an empty book with
+type chartTemplatesModal struct {
+	DB *sqlx.DB
+
+	width, height int
+
+	list list.Model
+}
+
+func newChartTemplatesModal(DB *sqlx.DB) *chartTemplatesModal {
+	result := &chartTemplatesModal{
+		DB: DB,
+
+		list: list.New(0, 0),
+	}
+	result.list.SetItems(toItemSlice(database.ChartTemplates))
+
+	return result
+}
+
+func (ctm *chartTemplatesModal) Init() tea.Cmd {
+	return nil
+}
+
+func (ctm *chartTemplatesModal) Update(message tea.Msg) (Modal, tea.Cmd) {
+	switch message := message.(type) {
+	case tea.WindowSizeMsg:
+		ctm.width = message.Width
+		ctm.height = message.Height
+
+		var cmd tea.Cmd
+		// -2 for the title and its margin, -2 for the hint and its margin
+		ctm.list, cmd = ctm.list.Update(tea.WindowSizeMsg{Width: message.Width, Height: message.Height - 4})
+
+		return ctm, cmd
+
+	case meta.NavigateMsg:
+		ctm.list.Navigate(message.Direction == meta.DOWN)
+
+		return ctm, nil
+
+	case meta.JumpVerticalMsg:
+		ctm.list.Jump(message.Down)
+
+		return ctm, nil
+
+	case meta.UpdateSearchMsg:
+		var cmd tea.Cmd
+		ctm.list, cmd = ctm.list.Update(list.FuzzyFilterMsg{Query: message.Query})
+
+		return ctm, cmd
+
+	case applyChartTemplateMsg:
+		activeItem := ctm.list.ActiveItem()
+		if activeItem == nil {
+			return ctm, meta.MessageCmd(errors.New("no template selected"))
+		}
+		template := (*activeItem).(database.ChartTemplate)
+
+		err := template.Apply(ctm.DB)
+		if err != nil {
+			return ctm, meta.MessageCmd(err)
+		}
+
+		notification := meta.NotificationMessageMsg{Message: fmt.Sprintf(
+			"Created %d ledgers and %d journals from the %s template", len(template.Ledgers), len(template.Journals), template.Name,
+		)}
+
+		// Closes the modal and shows the new ledgers
+		ledgersApp := meta.LEDGERSAPP
+		switchCmd := meta.MessageCmd(meta.SwitchAppViewMsg{App: &ledgersApp, ViewType: meta.LISTVIEWTYPE})
+
+		return ctm, tea.Batch(meta.MessageCmd(notification), switchCmd)
+
+	default:
+		panic(fmt.Sprintf("unexpected tea.Msg: %#v", message))
+	}
+}
+
+func (ctm *chartTemplatesModal) View() string {
+	var result strings.Builder
+
+	titleStyle := lipgloss.NewStyle().Bold(true).MarginBottom(1)
+
+	result.WriteString(titleStyle.Render("Start this book from a template?"))
+	result.WriteString("\n")
+
+	result.WriteString(ctm.list.View())
+	result.WriteString("\n\n")
+
+	hint := "enter or :apply to create its ledgers and journals, :q to start from scratch"
+	result.WriteString(lipgloss.NewStyle().Italic(true).Render(hint))
+
+	return result.String()
+}
+
+func (ctm *chartTemplatesModal) AllowsInsertMode() bool {
+	return false
+}
+
+func (ctm *chartTemplatesModal) AllowsSearchMode() bool {
+	return true
+}
+
+func (ctm *chartTemplatesModal) MotionSet() meta.Trie[tea.Msg] {
+	var result meta.Trie[tea.Msg]
+
+	result.Insert(meta.Motion{"j"}, meta.NavigateMsg{Direction: meta.DOWN})
+	result.Insert(meta.Motion{"k"}, meta.NavigateMsg{Direction: meta.UP})
+
+	result.Insert(meta.Motion{"g", "g"}, meta.JumpVerticalMsg{Down: false})
+	result.Insert(meta.Motion{"G"}, meta.JumpVerticalMsg{Down: true})
+
+	result.Insert(meta.Motion{"enter"}, applyChartTemplateMsg{})
+
+	return result
+}
+
+func (ctm *chartTemplatesModal) CommandSet() meta.Trie[tea.Msg] {
+	var result meta.Trie[tea.Msg]
+
+	result.Insert(meta.Command(strings.Split("apply", "")), applyChartTemplateMsg{})
+
+	return result
+}
+
+func (ctm *chartTemplatesModal) Reload() Modal {
+	return newChartTemplatesModal(ctm.DB)
+}
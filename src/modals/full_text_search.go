@@ -0,0 +1,183 @@
+package modals
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"terminaccounting/bubbles/list"
+	"terminaccounting/database"
+	"terminaccounting/meta"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmoiron/sqlx"
+)
+
+// Shows the results of `:search`, best matches first
+type fullTextSearchModal struct {
+	DB *sqlx.DB
+
+	query string
+
+	width, height int
+
+	// nil until the search has run
+	results []database.SearchResult
+	list    list.Model
+}
+
+func newFullTextSearchModal(DB *sqlx.DB, query string) *fullTextSearchModal {
+	return &fullTextSearchModal{
+		DB: DB,
+
+		query: query,
+
+		list: list.New(0, 0),
+	}
+}
+
+func (ftsm *fullTextSearchModal) Init() tea.Cmd {
+	return func() tea.Msg {
+		results, err := database.Search(ftsm.DB, ftsm.query)
+		if err != nil {
+			return err
+		}
+
+		return meta.DataLoadedMsg{Data: results}
+	}
+}
+
+func (ftsm *fullTextSearchModal) Update(message tea.Msg) (Modal, tea.Cmd) {
+	switch message := message.(type) {
+	case tea.WindowSizeMsg:
+		ftsm.width = message.Width
+		ftsm.height = message.Height
+
+		var cmd tea.Cmd
+		// -2 for the title and its margin
+		ftsm.list, cmd = ftsm.list.Update(tea.WindowSizeMsg{Width: message.Width, Height: message.Height - 2})
+
+		return ftsm, cmd
+
+	case meta.NavigateMsg:
+		ftsm.list.Navigate(message.Direction == meta.DOWN)
+
+		return ftsm, nil
+
+	case meta.JumpVerticalMsg:
+		ftsm.list.Jump(message.Down)
+
+		return ftsm, nil
+
+	case meta.UpdateSearchMsg:
+		var cmd tea.Cmd
+		ftsm.list, cmd = ftsm.list.Update(list.FuzzyFilterMsg{Query: message.Query})
+
+		return ftsm, cmd
+
+	case meta.DataLoadedMsg:
+		ftsm.results = message.Data.([]database.SearchResult)
+		ftsm.list.SetItems(toItemSlice(ftsm.results))
+
+		return ftsm, nil
+
+	default:
+		panic(fmt.Sprintf("unexpected tea.Msg: %#v", message))
+	}
+}
+
+func (ftsm *fullTextSearchModal) View() string {
+	var result strings.Builder
+
+	titleStyle := lipgloss.NewStyle().Bold(true).MarginBottom(1)
+
+	switch {
+	case ftsm.results == nil:
+		result.WriteString(titleStyle.Render(fmt.Sprintf("Searching for %q...", ftsm.query)))
+
+	case len(ftsm.results) == 0:
+		result.WriteString(titleStyle.Render(fmt.Sprintf("Nothing found for %q", ftsm.query)))
+
+	default:
+		result.WriteString(titleStyle.Render(fmt.Sprintf("%d result(s) for %q", len(ftsm.results), ftsm.query)))
+	}
+	result.WriteString("\n")
+
+	result.WriteString(ftsm.list.View())
+
+	return result.String()
+}
+
+func (ftsm *fullTextSearchModal) AllowsInsertMode() bool {
+	return false
+}
+
+func (ftsm *fullTextSearchModal) AllowsSearchMode() bool {
+	return true
+}
+
+func (ftsm *fullTextSearchModal) MotionSet() meta.Trie[tea.Msg] {
+	var result meta.Trie[tea.Msg]
+
+	result.Insert(meta.Motion{"j"}, meta.NavigateMsg{Direction: meta.DOWN})
+	result.Insert(meta.Motion{"k"}, meta.NavigateMsg{Direction: meta.UP})
+
+	var gotoDetailViewCmd tea.Cmd
+	gotoDetailViewCmd = func() tea.Msg {
+		activeItem := ftsm.list.ActiveItem()
+
+		if activeItem == nil {
+			return errors.New("no results shown to go to detail view of")
+		}
+
+		searchResult := (*activeItem).(database.SearchResult)
+
+		var appType meta.AppType
+		var data any
+		var err error
+
+		switch searchResult.Model {
+		case meta.LEDGERMODEL:
+			appType = meta.LEDGERSAPP
+			data, err = database.SelectLedger(ftsm.DB, searchResult.Object)
+
+		case meta.ACCOUNTMODEL:
+			appType = meta.ACCOUNTSAPP
+			data, err = database.SelectAccount(ftsm.DB, searchResult.Object)
+
+		case meta.JOURNALMODEL:
+			appType = meta.JOURNALSAPP
+			data, err = database.SelectJournal(ftsm.DB, searchResult.Object)
+
+		case meta.ENTRYMODEL, meta.ENTRYROWMODEL:
+			appType = meta.ENTRIESAPP
+			data, err = database.SelectEntry(ftsm.DB, *searchResult.Entry)
+
+		default:
+			panic(fmt.Sprintf("unexpected model: %#v", searchResult.Model))
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to go to detail view: %s", err)
+		}
+
+		return meta.SwitchAppViewMsg{
+			App:      &appType,
+			ViewType: meta.DETAILVIEWTYPE,
+			Data:     data,
+		}
+	}
+	result.Insert(meta.Motion{"g", "d"}, gotoDetailViewCmd)
+
+	result.Insert(meta.Motion{"g", "g"}, meta.JumpVerticalMsg{Down: false})
+	result.Insert(meta.Motion{"G"}, meta.JumpVerticalMsg{Down: true})
+
+	return result
+}
+
+func (ftsm *fullTextSearchModal) CommandSet() meta.Trie[tea.Msg] {
+	return meta.Trie[tea.Msg]{}
+}
+
+func (ftsm *fullTextSearchModal) Reload() Modal {
+	return newFullTextSearchModal(ftsm.DB, ftsm.query)
+}
@@ -0,0 +1,250 @@
+package modals
+
+import (
+	"fmt"
+	"strings"
+	"terminaccounting/database"
+	"terminaccounting/meta"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmoiron/sqlx"
+)
+
+// Pins the highlighted version as the one the others are compared against, or unpins it
+type pinVersionMsg struct{}
+
+// Lists every recorded version of an entry and diffs any two of them
+type entryHistoryModal struct {
+	DB *sqlx.DB
+
+	width, height int
+
+	entryId int
+
+	// nil until the history has loaded
+	versions []database.EntryVersion
+
+	// The version being looked at
+	activeVersion int
+	// The version it's compared against, nil to compare against the one before it
+	baseVersion *int
+}
+
+func newEntryHistoryModal(DB *sqlx.DB, entryId int) *entryHistoryModal {
+	return &entryHistoryModal{
+		DB: DB,
+
+		entryId: entryId,
+	}
+}
+
+func (ehm *entryHistoryModal) Init() tea.Cmd {
+	return func() tea.Msg {
+		versions, err := database.SelectEntryHistory(ehm.DB, ehm.entryId)
+		if err != nil {
+			return fmt.Errorf("FAILED TO LOAD HISTORY OF ENTRY %d: %v", ehm.entryId, err)
+		}
+
+		return meta.DataLoadedMsg{Data: versions}
+	}
+}
+
+func (ehm *entryHistoryModal) Update(message tea.Msg) (Modal, tea.Cmd) {
+	switch message := message.(type) {
+	case tea.WindowSizeMsg:
+		ehm.width = message.Width
+		ehm.height = message.Height
+
+		return ehm, nil
+
+	case meta.DataLoadedMsg:
+		ehm.versions = message.Data.([]database.EntryVersion)
+		// Start at the latest version, compared to the one before it
+		ehm.activeVersion = max(len(ehm.versions)-1, 0)
+		ehm.baseVersion = nil
+
+		return ehm, nil
+
+	case meta.NavigateMsg:
+		switch message.Direction {
+		case meta.DOWN:
+			ehm.activeVersion = min(ehm.activeVersion+1, max(len(ehm.versions)-1, 0))
+
+		case meta.UP:
+			ehm.activeVersion = max(ehm.activeVersion-1, 0)
+		}
+
+		return ehm, nil
+
+	case meta.JumpVerticalMsg:
+		if message.Down {
+			ehm.activeVersion = max(len(ehm.versions)-1, 0)
+		} else {
+			ehm.activeVersion = 0
+		}
+
+		return ehm, nil
+
+	case pinVersionMsg:
+		if len(ehm.versions) == 0 {
+			return ehm, nil
+		}
+
+		if ehm.baseVersion != nil && *ehm.baseVersion == ehm.activeVersion {
+			ehm.baseVersion = nil
+		} else {
+			base := ehm.activeVersion
+			ehm.baseVersion = &base
+		}
+
+		return ehm, nil
+
+	default:
+		panic(fmt.Sprintf("unexpected tea.Msg: %#v", message))
+	}
+}
+
+func (ehm *entryHistoryModal) View() string {
+	var result strings.Builder
+
+	titleStyle := lipgloss.NewStyle().Bold(true).MarginBottom(1)
+	result.WriteString(titleStyle.Render(fmt.Sprintf("History of entry %d", ehm.entryId)))
+	result.WriteString("\n")
+
+	switch {
+	case ehm.versions == nil:
+		result.WriteString("Loading history...")
+
+		return result.String()
+
+	case len(ehm.versions) == 0:
+		// Entries from before the audit log existed have no history until they're changed
+		result.WriteString("No changes recorded for this entry")
+
+		return result.String()
+	}
+
+	result.WriteString(ehm.renderVersions())
+	result.WriteString("\n\n")
+
+	result.WriteString(ehm.renderDiff())
+	result.WriteString("\n\n")
+
+	result.WriteString(lipgloss.NewStyle().Italic(true).Render("enter to compare the other versions against the highlighted one (*)"))
+
+	return result.String()
+}
+
+func (ehm *entryHistoryModal) renderVersions() string {
+	highlightStyle := lipgloss.NewStyle().Foreground(lipgloss.ANSIColor(212))
+
+	var lines []string
+	for i, version := range ehm.versions {
+		marker := " "
+		if ehm.baseVersion != nil && *ehm.baseVersion == i {
+			marker = "*"
+		}
+
+		line := fmt.Sprintf("%s v%d  %s", marker, i+1, version)
+		if i == ehm.activeVersion {
+			line = highlightStyle.Render(line)
+		}
+
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (ehm *entryHistoryModal) renderDiff() string {
+	to := ehm.versions[ehm.activeVersion].Snapshot
+
+	var from database.EntrySnapshot
+	var header string
+	switch {
+	case ehm.baseVersion != nil:
+		from = ehm.versions[*ehm.baseVersion].Snapshot
+		header = fmt.Sprintf("Changes from v%d to v%d", *ehm.baseVersion+1, ehm.activeVersion+1)
+
+	case ehm.activeVersion > 0:
+		from = ehm.versions[ehm.activeVersion-1].Snapshot
+		header = fmt.Sprintf("Changes from v%d to v%d", ehm.activeVersion, ehm.activeVersion+1)
+
+	default:
+		// Before the first version the entry had no rows, its own fields aren't a change
+		from = database.EntrySnapshot{Entry: to.Entry}
+		header = "Changes in v1"
+	}
+
+	addedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	removedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+
+	var result strings.Builder
+	result.WriteString(lipgloss.NewStyle().Bold(true).Render(header))
+	result.WriteString("\n")
+
+	diff := database.DiffEntrySnapshots(from, to)
+	if diff.IsEmpty() {
+		result.WriteString("No differences")
+
+		return result.String()
+	}
+
+	var lines []string
+	for _, change := range diff.Fields {
+		lines = append(lines, fmt.Sprintf("~ %s: %q -> %q", change.Field, change.From, change.To))
+	}
+
+	for _, change := range diff.Rows {
+		switch {
+		case change.From == nil:
+			lines = append(lines, addedStyle.Render(fmt.Sprintf("+ row %d: %s", change.Row, database.SummariseRow(*change.To))))
+
+		case change.To == nil:
+			lines = append(lines, removedStyle.Render(fmt.Sprintf("- row %d: %s", change.Row, database.SummariseRow(*change.From))))
+
+		default:
+			var fields []string
+			for _, field := range change.Fields {
+				fields = append(fields, fmt.Sprintf("%s %q -> %q", field.Field, field.From, field.To))
+			}
+
+			lines = append(lines, fmt.Sprintf("~ row %d: %s", change.Row, strings.Join(fields, ", ")))
+		}
+	}
+
+	result.WriteString(strings.Join(lines, "\n"))
+
+	return result.String()
+}
+
+func (ehm *entryHistoryModal) AllowsInsertMode() bool {
+	return false
+}
+
+func (ehm *entryHistoryModal) AllowsSearchMode() bool {
+	return false
+}
+
+func (ehm *entryHistoryModal) MotionSet() meta.Trie[tea.Msg] {
+	var result meta.Trie[tea.Msg]
+
+	result.Insert(meta.Motion{"j"}, meta.NavigateMsg{Direction: meta.DOWN})
+	result.Insert(meta.Motion{"k"}, meta.NavigateMsg{Direction: meta.UP})
+
+	result.Insert(meta.Motion{"g", "g"}, meta.JumpVerticalMsg{Down: false})
+	result.Insert(meta.Motion{"G"}, meta.JumpVerticalMsg{Down: true})
+
+	result.Insert(meta.Motion{"enter"}, pinVersionMsg{})
+
+	return result
+}
+
+func (ehm *entryHistoryModal) CommandSet() meta.Trie[tea.Msg] {
+	return meta.Trie[tea.Msg]{}
+}
+
+func (ehm *entryHistoryModal) Reload() Modal {
+	return newEntryHistoryModal(ehm.DB, ehm.entryId)
+}
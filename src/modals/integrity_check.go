@@ -0,0 +1,189 @@
+package modals
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"terminaccounting/bubbles/list"
+	"terminaccounting/database"
+	"terminaccounting/meta"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmoiron/sqlx"
+)
+
+type integrityCheckModal struct {
+	DB *sqlx.DB
+
+	width, height int
+
+	// nil until the check has run
+	problems []database.IntegrityProblem
+	list     list.Model
+}
+
+func newIntegrityCheckModal(DB *sqlx.DB) *integrityCheckModal {
+	return &integrityCheckModal{
+		DB: DB,
+
+		list: list.New(0, 0),
+	}
+}
+
+func (icm *integrityCheckModal) Init() tea.Cmd {
+	return func() tea.Msg {
+		problems, err := database.CheckIntegrity(icm.DB)
+		if err != nil {
+			return err
+		}
+
+		// Non-nil, even when there are no problems, to mark the check as done
+		return meta.DataLoadedMsg{Data: append([]database.IntegrityProblem{}, problems...)}
+	}
+}
+
+func (icm *integrityCheckModal) Update(message tea.Msg) (Modal, tea.Cmd) {
+	switch message := message.(type) {
+	case tea.WindowSizeMsg:
+		icm.width = message.Width
+		icm.height = message.Height
+
+		var cmd tea.Cmd
+		// -2 for the summary line and its margin
+		icm.list, cmd = icm.list.Update(tea.WindowSizeMsg{Width: message.Width, Height: message.Height - 2})
+
+		return icm, cmd
+
+	case meta.NavigateMsg:
+		icm.list.Navigate(message.Direction == meta.DOWN)
+
+		return icm, nil
+
+	case meta.JumpVerticalMsg:
+		icm.list.Jump(message.Down)
+
+		return icm, nil
+
+	case meta.UpdateSearchMsg:
+		var cmd tea.Cmd
+		icm.list, cmd = icm.list.Update(list.FuzzyFilterMsg{Query: message.Query})
+
+		return icm, cmd
+
+	case meta.DataLoadedMsg:
+		icm.problems = message.Data.([]database.IntegrityProblem)
+		icm.list.SetItems(toItemSlice(icm.problems))
+
+		return icm, nil
+
+	default:
+		panic(fmt.Sprintf("unexpected tea.Msg: %#v", message))
+	}
+}
+
+func (icm *integrityCheckModal) View() string {
+	var result strings.Builder
+
+	titleStyle := lipgloss.NewStyle().Bold(true).MarginBottom(1)
+
+	switch {
+	case icm.problems == nil:
+		result.WriteString(titleStyle.Render("Checking book integrity..."))
+
+	case len(icm.problems) == 0:
+		result.WriteString(titleStyle.Render("No problems found"))
+
+	default:
+		result.WriteString(titleStyle.Render(fmt.Sprintf("Found %d problem(s), use gd to go to one", len(icm.problems))))
+	}
+	result.WriteString("\n")
+
+	result.WriteString(icm.list.View())
+
+	return result.String()
+}
+
+func (icm *integrityCheckModal) AllowsInsertMode() bool {
+	return false
+}
+
+func (icm *integrityCheckModal) AllowsSearchMode() bool {
+	return true
+}
+
+func (icm *integrityCheckModal) MotionSet() meta.Trie[tea.Msg] {
+	var result meta.Trie[tea.Msg]
+
+	result.Insert(meta.Motion{"j"}, meta.NavigateMsg{Direction: meta.DOWN})
+	result.Insert(meta.Motion{"k"}, meta.NavigateMsg{Direction: meta.UP})
+
+	var gotoDetailViewCmd tea.Cmd
+	gotoDetailViewCmd = func() tea.Msg {
+		activeItem := icm.list.ActiveItem()
+
+		if activeItem == nil {
+			return errors.New("no problems shown to go to detail view of")
+		}
+
+		problem := (*activeItem).(database.IntegrityProblem)
+
+		var appType meta.AppType
+		var data any
+
+		switch {
+		case problem.Entry != nil:
+			appType = meta.ENTRIESAPP
+
+			entry, err := database.SelectEntry(icm.DB, *problem.Entry)
+			if err != nil {
+				return fmt.Errorf("Failed to go to entry detail view: %s", err)
+			}
+
+			data = entry
+
+		case problem.Ledger != nil:
+			appType = meta.LEDGERSAPP
+
+			ledger, err := database.SelectLedger(icm.DB, *problem.Ledger)
+			if err != nil {
+				return fmt.Errorf("Failed to go to ledger detail view: %s", err)
+			}
+
+			data = ledger
+
+		case problem.Account != nil:
+			appType = meta.ACCOUNTSAPP
+
+			account, err := database.SelectAccount(icm.DB, *problem.Account)
+			if err != nil {
+				return fmt.Errorf("Failed to go to account detail view: %s", err)
+			}
+
+			data = account
+
+		default:
+			panic(fmt.Sprintf("integrity problem without target: %#v", problem))
+		}
+
+		return meta.SwitchAppViewMsg{
+			App:      &appType,
+			ViewType: meta.DETAILVIEWTYPE,
+			Data:     data,
+		}
+	}
+	result.Insert(meta.Motion{"g", "d"}, gotoDetailViewCmd)
+
+	result.Insert(meta.Motion{"g", "g"}, meta.JumpVerticalMsg{Down: false})
+	result.Insert(meta.Motion{"G"}, meta.JumpVerticalMsg{Down: true})
+
+	return result
+}
+
+func (icm *integrityCheckModal) CommandSet() meta.Trie[tea.Msg] {
+	return meta.Trie[tea.Msg]{}
+}
+
+func (icm *integrityCheckModal) Reload() Modal {
+	return newIntegrityCheckModal(icm.DB)
+}
@@ -1,8 +1,12 @@
 package modals
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"terminaccounting/database"
 	"terminaccounting/meta"
@@ -10,6 +14,7 @@ import (
 	"terminaccounting/view"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -48,10 +53,10 @@ var testCSVData = [][]string{
 
 // setupBankImporter creates a bankImporter with pre-loaded CSV data, bypassing the zenity
 // file picker that Init() would otherwise open.
-func setupBankImporter(t *testing.T) *bankImporter {
+func setupBankImporter(t *testing.T, DB *sqlx.DB) *bankImporter {
 	t.Helper()
 
-	bi := newBankImporter()
+	bi := newBankImporter(DB)
 	bi.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
 
 	bi.fileLoaded = true
@@ -63,8 +68,8 @@ func setupBankImporter(t *testing.T) *bankImporter {
 }
 
 func TestBankImporter_Rendering(t *testing.T) {
-	tat.SetupTestEnv(t)
-	bi := setupBankImporter(t)
+	DB := tat.SetupTestEnv(t)
+	bi := setupBankImporter(t, DB)
 
 	rendered := bi.View()
 
@@ -76,8 +81,8 @@ func TestBankImporter_Rendering(t *testing.T) {
 }
 
 func TestBankImporter_FocusNavigation(t *testing.T) {
-	tat.SetupTestEnv(t)
-	bi := setupBankImporter(t)
+	DB := tat.SetupTestEnv(t)
+	bi := setupBankImporter(t, DB)
 
 	assert.Equal(t, 0, bi.activeInput, "initial active input should be parser picker (0)")
 
@@ -119,7 +124,7 @@ func TestBankImporter_Commit(t *testing.T) {
 	require.NoError(t, err)
 	journal.Id = journalId
 
-	bi := setupBankImporter(t)
+	bi := setupBankImporter(t, DB)
 	require.NoError(t, bi.journalPicker.SetValue(journal))
 	require.NoError(t, bi.bankLedgerPicker.SetValue(bankLedger))
 
@@ -142,8 +147,8 @@ func TestBankImporter_Commit(t *testing.T) {
 }
 
 func TestBankImporter_Commit_NoJournal(t *testing.T) {
-	tat.SetupTestEnv(t)
-	bi := setupBankImporter(t)
+	DB := tat.SetupTestEnv(t)
+	bi := setupBankImporter(t, DB)
 
 	_, cmd := bi.Update(meta.CommitMsg{})
 	require.NotNil(t, cmd)
@@ -163,7 +168,7 @@ func TestBankImporter_Commit_NoBankLedger(t *testing.T) {
 	require.NoError(t, err)
 	journal.Id = journalId
 
-	bi := setupBankImporter(t)
+	bi := setupBankImporter(t, DB)
 
 	accountsLedger := database.Ledger{Name: "Accounts Ledger", Type: database.ASSETLEDGER, IsAccounts: true}
 	_, err = accountsLedger.Insert(DB)
@@ -180,8 +185,8 @@ func TestBankImporter_Commit_NoBankLedger(t *testing.T) {
 }
 
 func TestBankImporter_Navigate(t *testing.T) {
-	tat.SetupTestEnv(t)
-	bi := setupBankImporter(t)
+	DB := tat.SetupTestEnv(t)
+	bi := setupBankImporter(t, DB)
 	bi.activeInput = 3
 	bi.View() // populate viewport content so TotalLineCount() is correct
 
@@ -200,8 +205,8 @@ func TestBankImporter_Navigate(t *testing.T) {
 }
 
 func TestBankImporter_Navigate_RequiresPreviewFocus(t *testing.T) {
-	tat.SetupTestEnv(t)
-	bi := setupBankImporter(t)
+	DB := tat.SetupTestEnv(t)
+	bi := setupBankImporter(t, DB)
 
 	_, cmd := bi.Update(meta.NavigateMsg{Direction: meta.DOWN})
 	require.NotNil(t, cmd)
@@ -212,7 +217,7 @@ func TestBankImporter_Navigate_RequiresPreviewFocus(t *testing.T) {
 }
 
 func TestBankImporter_Navigate_ScrollsViewport(t *testing.T) {
-	tat.SetupTestEnv(t)
+	DB := tat.SetupTestEnv(t)
 
 	// Need more rows than preview viewport height (Height:40 -> preview.Height = 31)
 	manyRows := make([][]string, 40)
@@ -225,7 +230,7 @@ func TestBankImporter_Navigate_ScrollsViewport(t *testing.T) {
 		}
 	}
 
-	bi := newBankImporter()
+	bi := newBankImporter(DB)
 	bi.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
 	bi.fileLoaded = true
 	bi.headers = testCSVHeaders
@@ -246,8 +251,8 @@ func TestBankImporter_Navigate_ScrollsViewport(t *testing.T) {
 }
 
 func TestCalculateColWidths_TerminatesWhenAllColumnsAtMax(t *testing.T) {
-	tat.SetupTestEnv(t)
-	bi := newBankImporter()
+	DB := tat.SetupTestEnv(t)
+	bi := newBankImporter(DB)
 	bi.headers = []string{"A", "B"}
 	bi.data = [][]string{{"x", "y"}}
 	// Very wide window: remainingWidth >> sum(maxColWidths), so the loop reaches
@@ -390,3 +395,765 @@ func TestIngParser_CompileRows_MultipleRows(t *testing.T) {
 	require.NoError(t, err)
 	assert.Len(t, rows, 4, "2 CSV rows should produce 4 entry rows (2 per CSV row)")
 }
+
+const testOFXVersion1 = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+ENCODING:USASCII
+
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0<SEVERITY>INFO</STATUS><DTSERVER>20240131</SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1>
+<STMTTRNRS>
+<STMTRS>
+<CURDEF>EUR
+<BANKACCTFROM>
+<BANKID>ABNA
+<ACCTID>NL91ABNA0417164300
+<ACCTTYPE>CHECKING
+</BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240105120000.000[+1:CET]
+<TRNAMT>100.00
+<FITID>T1
+<NAME>Smith &amp; Sons
+<MEMO>Invoice 12
+<BANKACCTTO>
+<BANKID>INGB
+<ACCTID>NL01ABCD0000000001
+<ACCTTYPE>CHECKING
+</BANKACCTTO>
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240110
+<TRNAMT>-50.25
+<FITID>T2
+<MEMO>Bank fee
+</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>49.75<DTASOF>20240131</LEDGERBAL>
+</STMTRS>
+</STMTTRNRS>
+</BANKMSGSRSV1>
+</OFX>
+`
+
+const testOFXVersion2 = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="211" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+	<CREDITCARDMSGSRSV1>
+		<CCSTMTTRNRS>
+			<CCSTMTRS>
+				<CURDEF>EUR</CURDEF>
+				<CCACCTFROM><ACCTID>4111111111111111</ACCTID></CCACCTFROM>
+				<BANKTRANLIST>
+					<STMTTRN>
+						<TRNTYPE>DEBIT</TRNTYPE>
+						<DTPOSTED>20240203</DTPOSTED>
+						<TRNAMT>-12.5</TRNAMT>
+						<FITID>CC-1</FITID>
+						<NAME>Coffee</NAME>
+						<MEMO/>
+					</STMTTRN>
+				</BANKTRANLIST>
+			</CCSTMTRS>
+		</CCSTMTTRNRS>
+	</CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func TestParseOFX(t *testing.T) {
+	t.Run("version 1", func(t *testing.T) {
+		statement, err := parseOFX(testOFXVersion1)
+		require.NoError(t, err)
+
+		assert.Equal(t, "NL91ABNA0417164300", statement.account)
+		assert.Equal(t, [][]string{
+			{"20240105", "100.00", "T1", "Smith & Sons", "Invoice 12", "NL01ABCD0000000001"},
+			{"20240110", "-50.25", "T2", "", "Bank fee", ""},
+		}, statement.rows)
+	})
+
+	t.Run("version 2", func(t *testing.T) {
+		statement, err := parseOFX(testOFXVersion2)
+		require.NoError(t, err)
+
+		assert.Equal(t, "4111111111111111", statement.account)
+		assert.Equal(t, [][]string{{"20240203", "-12.5", "CC-1", "Coffee", "", ""}}, statement.rows)
+	})
+
+	t.Run("not OFX", func(t *testing.T) {
+		_, err := parseOFX("Date;Amount\n20240101;1,00\n")
+		assert.EqualError(t, err, "not an OFX file, there's no <OFX> element")
+	})
+}
+
+func TestOfxParser_CompileRows(t *testing.T) {
+	tat.SetupTestEnv(t)
+
+	statement, err := parseOFX(testOFXVersion1)
+	require.NoError(t, err)
+
+	rows, err := ofxParser{}.compileRows(statement.rows, 1, 2)
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+
+	assert.Equal(t, database.CurrencyValue(10000), rows[0].Value, "money coming in should be like an ING credit")
+	assert.Equal(t, database.CurrencyValue(-10000), rows[1].Value)
+	assert.Equal(t, "Smith & Sons", rows[1].Description)
+	assert.Nil(t, rows[0].ExternalId)
+	require.NotNil(t, rows[1].ExternalId)
+	assert.Equal(t, "T1", *rows[1].ExternalId)
+
+	assert.Equal(t, database.CurrencyValue(-5025), rows[2].Value)
+	assert.Equal(t, "Bank fee", rows[3].Description, "the memo is used without a name")
+	assert.Equal(t, "T2", *rows[3].ExternalId)
+
+	_, err = ofxParser{}.compileRows([][]string{{"20240101", "1.00", "", "", "", ""}}, 1, 2)
+	assert.EqualError(t, err, "the transaction of 20240101 for 1.00 has no FITID")
+}
+
+func TestBankImporter_OFX(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	_, err := (&database.Ledger{Name: "Accounts", Type: database.ASSETLEDGER, IsAccounts: true}).Insert(DB)
+	require.NoError(t, err)
+	_, err = (&database.Ledger{Name: "Savings", Type: database.ASSETLEDGER}).Insert(DB)
+	require.NoError(t, err)
+	bank, err := (&database.Ledger{Name: "Bank", Type: database.ASSETLEDGER, BankNumber: "NL91ABNA0417164300"}).Insert(DB)
+	require.NoError(t, err)
+	journal := database.Journal{Name: "Bank", Type: database.GENERALJOURNAL}
+	_, err = journal.Insert(DB)
+	require.NoError(t, err)
+	customer, err := database.Account{Name: "Smith", Type: database.DEBTOR, BankNumbers: meta.Notes{"NL01ABCD0000000001"}}.Insert(DB)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "statement.QFX")
+	require.NoError(t, os.WriteFile(path, []byte(testOFXVersion1), 0o644))
+
+	bi := newBankImporter(DB)
+	bi.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	_, cmd := bi.Update(meta.FileSelectedMsg{File: path})
+	assert.Nil(t, cmd)
+
+	assert.Equal(t, ofxParser{}, bi.parserPicker.Value())
+	assert.Equal(t, bank, bi.bankLedgerPicker.Value().(database.Ledger).Id, "the ledger is picked by the statement's account")
+	assert.Contains(t, bi.View(), "parser succeeds")
+
+	_, cmd = bi.Update(meta.CommitMsg{})
+	require.NotNil(t, cmd)
+	switchMsg, ok := cmd().(meta.SwitchAppViewMsg)
+	require.True(t, ok)
+
+	rows := switchMsg.Data.(view.EntryPrefillData).Rows
+	require.Len(t, rows, 4)
+	require.NotNil(t, rows[0].Account)
+	assert.Equal(t, customer, *rows[0].Account)
+
+	_, err = database.Entry{Journal: 1}.Insert(DB, rows[:2])
+	require.NoError(t, err)
+
+	_, cmd = bi.Update(meta.CommitMsg{})
+	require.NotNil(t, cmd)
+	batch, ok := cmd().(tea.BatchMsg)
+	require.True(t, ok)
+	require.Len(t, batch, 2)
+	assert.Equal(t, meta.NotificationMessageMsg{Message: "skipped 1 transactions that were imported before"}, batch[0]())
+	assert.Len(t, batch[1]().(meta.SwitchAppViewMsg).Data.(view.EntryPrefillData).Rows, 2)
+
+	// Rows on the accounts ledger need an account, the fee has none of its own
+	rows[2].Account = &customer
+	_, err = database.Entry{Journal: 1}.Insert(DB, rows[2:])
+	require.NoError(t, err)
+
+	_, cmd = bi.Update(meta.CommitMsg{})
+	require.NotNil(t, cmd)
+	assert.Equal(t, errors.New("all 2 transactions were imported before"), cmd())
+
+	t.Run("unknown account", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "card.ofx")
+		require.NoError(t, os.WriteFile(path, []byte(testOFXVersion2), 0o644))
+
+		bi := newBankImporter(DB)
+		_, cmd := bi.Update(meta.FileSelectedMsg{File: path})
+		require.NotNil(t, cmd)
+		assert.Equal(t, meta.NotificationMessageMsg{Message: "no ledger has bank number 4111111111111111, pick the bank ledger to import into"}, cmd())
+	})
+
+	t.Run("parser doesn't fit the file", func(t *testing.T) {
+		require.NoError(t, bi.parserPicker.SetValue(ingParser{}))
+
+		_, cmd := bi.Update(meta.CommitMsg{})
+		require.NotNil(t, cmd)
+		assert.Equal(t, errors.New("the ING format uses 9 columns, but the file has 6"), cmd())
+	})
+}
+
+func TestIntegrityCheckModal_NoProblems(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	tw := tat.NewTestWrapperSpecific(Modal(newIntegrityCheckModal(DB)))
+
+	tw.AssertViewContains(t, "No problems found")
+}
+
+func TestIntegrityCheckModal_GotoDetailView(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	journal := database.Journal{Name: "Test Journal", Type: database.GENERALJOURNAL}
+	_, err := journal.Insert(DB)
+	require.NoError(t, err)
+
+	_, err = DB.Exec(`INSERT INTO entries (journal, notes, suspense) VALUES (1, '[]', 0);`)
+	require.NoError(t, err)
+
+	icm := newIntegrityCheckModal(DB)
+	tw := tat.NewTestWrapperSpecific(Modal(icm))
+
+	tw.AssertViewContains(t, "Found 1 problem(s)")
+	tw.AssertViewContains(t, "Entry 1 has no rows")
+
+	motionSet := icm.MotionSet()
+	gotoDetailViewCmd, ok := motionSet.Get(meta.Motion{"g", "d"})
+	require.True(t, ok)
+
+	entry, err := database.SelectEntry(DB, 1)
+	require.NoError(t, err)
+
+	app := meta.ENTRIESAPP
+	assert.Equal(t, meta.SwitchAppViewMsg{App: &app, ViewType: meta.DETAILVIEWTYPE, Data: entry}, gotoDetailViewCmd.(tea.Cmd)())
+}
+
+func TestAgingModal_GotoDetailView(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	accounts, err := (&database.Ledger{Name: "Accounts", Type: database.ASSETLEDGER, IsAccounts: true}).Insert(DB)
+	require.NoError(t, err)
+	sales, err := (&database.Ledger{Name: "Sales", Type: database.INCOMELEDGER}).Insert(DB)
+	require.NoError(t, err)
+	customerId, err := database.Account{Name: "Customer", Type: database.DEBTOR}.Insert(DB)
+	require.NoError(t, err)
+	journalId, err := (&database.Journal{Name: "Test Journal", Type: database.GENERALJOURNAL}).Insert(DB)
+	require.NoError(t, err)
+
+	date, err := database.ToDate("24-01-01")
+	require.NoError(t, err)
+	_, err = database.Entry{Journal: journalId}.Insert(DB, []database.EntryRow{
+		{Date: date, Ledger: accounts, Account: &customerId, Value: 10000},
+		{Date: date, Ledger: sales, Value: -10000},
+	})
+	require.NoError(t, err)
+
+	am := newAgingModal(DB, "24-04-30", false)
+	tw := tat.NewTestWrapperSpecific(Modal(am))
+
+	tw.AssertViewContains(t, "Outstanding on 24-04-30 by booking dates")
+	tw.AssertViewContains(t, "Customer (debtor)")
+	tw.AssertViewContains(t, "Accounts ledger balance")
+
+	motionSet := am.MotionSet()
+	gotoDetailViewCmd, ok := motionSet.Get(meta.Motion{"g", "d"})
+	require.True(t, ok)
+
+	customer, err := database.SelectAccount(DB, customerId)
+	require.NoError(t, err)
+
+	app := meta.ACCOUNTSAPP
+	assert.Equal(t, meta.SwitchAppViewMsg{App: &app, ViewType: meta.DETAILVIEWTYPE, Data: customer}, gotoDetailViewCmd.(tea.Cmd)())
+}
+
+func TestStatementsModal(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	bank, err := (&database.Ledger{Name: "Bank", Type: database.ASSETLEDGER}).Insert(DB)
+	require.NoError(t, err)
+
+	sm := newStatementsModal(DB, bank)
+	tw := tat.NewTestWrapperSpecific(Modal(sm))
+	tw.AssertViewContains(t, "Ledger Bank wasn't reconciled against any statements yet")
+
+	date, err := database.ToDate("24-03-31")
+	require.NoError(t, err)
+	_, _, err = database.SaveStatement(DB, database.Statement{Ledger: bank, Date: date}, nil)
+	require.NoError(t, err)
+
+	sm = newStatementsModal(DB, bank)
+	tw = tat.NewTestWrapperSpecific(Modal(sm))
+	tw.AssertViewContains(t, "Statements ledger Bank was reconciled against")
+	tw.AssertViewContains(t, "24-03-31")
+}
+
+func TestYearEndWizard(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	insertLedger := func(name string, ledgerType database.LedgerType) int {
+		ledger := database.Ledger{Name: name, Type: ledgerType}
+		id, err := ledger.Insert(DB)
+		require.NoError(t, err)
+
+		return id
+	}
+	sales := insertLedger("Sales", database.INCOMELEDGER)
+	bank := insertLedger("Bank", database.ASSETLEDGER)
+	insertLedger("Equity", database.EQUITYLEDGER)
+
+	journal := database.Journal{Name: "General", Type: database.GENERALJOURNAL}
+	_, err := journal.Insert(DB)
+	require.NoError(t, err)
+
+	date, err := database.ToDate("24-03-01")
+	require.NoError(t, err)
+	_, err = database.Entry{Journal: 1}.Insert(DB, []database.EntryRow{
+		{Date: date, Ledger: bank, Value: 2500},
+		{Date: date, Ledger: sales, Value: -2500},
+	})
+	require.NoError(t, err)
+	require.NoError(t, database.UpdateCache(DB))
+
+	invalidCharacterErr := fmt.Errorf("%q is not a valid character for a year", "x")
+
+	yew := newYearEndWizard(DB, 2024)
+	tw := tat.NewTestWrapperSpecific(Modal(yew), invalidCharacterErr)
+
+	tw.AssertViewContains(t, "Balances at the end of 2024")
+	tw.AssertViewContains(t, "Result to equity: 2 rows")
+	tw.AssertViewContains(t, "Opening balances: 2 rows, dated 2025-01-01")
+
+	_, cmd := yew.Update(meta.CommitMsg{})
+	require.NotNil(t, cmd)
+
+	batch, ok := cmd().(tea.BatchMsg)
+	require.True(t, ok)
+	require.Len(t, batch, 2)
+	assert.Equal(t, meta.NotificationMessageMsg{Message: "Closed 2024, created 3 entries"}, batch[0]())
+
+	switchMsg, ok := batch[1]().(meta.SwitchAppViewMsg)
+	require.True(t, ok)
+	assert.Equal(t, meta.DETAILVIEWTYPE, switchMsg.ViewType)
+	assert.Equal(t, meta.Notes{"Year-end closing 2024: result to equity"}, switchMsg.Data.(database.Entry).Notes)
+
+	t.Run("year input", func(t *testing.T) {
+		tw.SendText("x")
+		tw.AssertLastMsgsEqual(t, invalidCharacterErr)
+
+		tw.Send(tea.KeyMsg{Type: tea.KeyBackspace})
+		tw.AssertViewContains(t, "invalid year \"202\"")
+
+		tw.SendText("4")
+		tw.AssertViewContains(t, "Nothing to close for 2024")
+	})
+}
+
+func TestYearEndWizard_NoEquityLedger(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	yew := newYearEndWizard(DB, 2024)
+
+	batch, ok := yew.Init()().(tea.BatchMsg)
+	require.True(t, ok)
+	require.Len(t, batch, 2)
+	assert.Equal(t, meta.QuitMsg{}, batch[0]())
+	assert.EqualError(t, batch[1]().(error), "no EQUITY ledger exists yet to close the year into")
+}
+
+func TestRevaluationWizard(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	insertLedger := func(name string, ledgerType database.LedgerType, currency string) int {
+		ledger := database.Ledger{Name: name, Type: ledgerType, Currency: currency}
+		id, err := ledger.Insert(DB)
+		require.NoError(t, err)
+
+		return id
+	}
+	paypal := insertLedger("PayPal", database.ASSETLEDGER, "USD")
+	sales := insertLedger("Sales", database.INCOMELEDGER, database.HOMECURRENCY)
+	insertLedger("Exchange rate differences", database.EXPENSELEDGER, database.HOMECURRENCY)
+
+	journal := database.Journal{Name: "General", Type: database.GENERALJOURNAL}
+	_, err := journal.Insert(DB)
+	require.NoError(t, err)
+
+	date, err := database.ToDate("24-03-01")
+	require.NoError(t, err)
+	foreign := database.CurrencyValue(10000)
+	_, err = database.Entry{Journal: 1}.Insert(DB, []database.EntryRow{
+		{Date: date, Ledger: paypal, Value: 9000, Currency: "USD", ForeignValue: &foreign},
+		{Date: date, Ledger: sales, Value: -9000},
+	})
+	require.NoError(t, err)
+	require.NoError(t, database.UpdateCache(DB))
+
+	rw := newRevaluationWizard(DB, "24-12-31")
+	tw := tat.NewTestWrapperSpecific(Modal(rw))
+
+	tw.AssertViewContains(t, "no USD exchange rate on or before 24-12-31")
+
+	yearEnd, err := database.ToDate("24-12-31")
+	require.NoError(t, err)
+	require.NoError(t, database.SetExchangeRate(DB, database.ExchangeRate{Currency: "USD", Date: yearEnd, Rate: 0.88}))
+
+	// Any change to the inputs recomputes the proposal
+	tw.Send(tea.KeyMsg{Type: tea.KeyBackspace})
+	tw.AssertViewContains(t, "isn't in yy-MM-dd")
+	tw.SendText("1")
+	tw.AssertViewContains(t, "Revaluation USD 24-12-31 at 0.88")
+	tw.AssertViewContains(t, "-2.00")
+
+	_, cmd := rw.Update(meta.CommitMsg{})
+	require.NotNil(t, cmd)
+
+	batch, ok := cmd().(tea.BatchMsg)
+	require.True(t, ok)
+	require.Len(t, batch, 2)
+	assert.Equal(t, meta.NotificationMessageMsg{Message: "Revalued foreign currencies at 24-12-31 in entry 2"}, batch[0]())
+
+	switchMsg, ok := batch[1]().(meta.SwitchAppViewMsg)
+	require.True(t, ok)
+	assert.Equal(t, 2, switchMsg.Data.(database.Entry).Id)
+
+	rows, err := database.SelectRowsByLedger(DB, paypal)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, database.CurrencyValue(-200), rows[1].Value)
+}
+
+func TestEntryHistoryModal(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	ledger := database.Ledger{Name: "Bank", Type: database.ASSETLEDGER}
+	_, err := ledger.Insert(DB)
+	require.NoError(t, err)
+	journal := database.Journal{Name: "General", Type: database.GENERALJOURNAL}
+	_, err = journal.Insert(DB)
+	require.NoError(t, err)
+	require.NoError(t, database.UpdateCache(DB))
+
+	date, err := database.ToDate("24-01-01")
+	require.NoError(t, err)
+	entry := database.Entry{Id: 1, Journal: 1}
+	_, err = entry.Insert(DB, []database.EntryRow{
+		{Date: date, Ledger: 1, Description: "deposit", Value: 1000},
+		{Date: date, Ledger: 1, Description: "withdrawal", Value: -1000},
+	})
+	require.NoError(t, err)
+
+	rows, err := database.SelectRowsByEntry(DB, 1)
+	require.NoError(t, err)
+	rows[0].Value = 2500
+	rows[1].Value = -2500
+	require.NoError(t, entry.Update(DB, rows))
+
+	tw := tat.NewTestWrapperSpecific(Modal(newEntryHistoryModal(DB, 1)))
+
+	tw.AssertViewContains(t, "History of entry 1")
+	tw.AssertViewContains(t, "v2")
+	tw.AssertViewContains(t, "Changes from v1 to v2")
+	tw.AssertViewContains(t, `~ row 1: Value "10.00" -> "25.00"`)
+
+	tw.Send(meta.NavigateMsg{Direction: meta.UP})
+	tw.AssertViewContains(t, "Changes in v1")
+	tw.AssertViewContains(t, `+ row 2: 24-01-01 Bank (1) -10.00 "withdrawal"`)
+
+	// Comparing a version against itself shows nothing
+	tw.Send(pinVersionMsg{})
+	tw.AssertViewContains(t, "Changes from v1 to v1")
+	tw.AssertViewContains(t, "No differences")
+
+	tw.Send(meta.JumpVerticalMsg{Down: true})
+	tw.AssertViewContains(t, "Changes from v1 to v2")
+}
+
+func TestEntryHistoryModal_NoHistory(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	tw := tat.NewTestWrapperSpecific(Modal(newEntryHistoryModal(DB, 1)))
+
+	tw.AssertViewContains(t, "No changes recorded for this entry")
+}
+
+func TestTrashModal(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	journal := database.Journal{Name: "General", Type: database.GENERALJOURNAL}
+	_, err := journal.Insert(DB)
+	require.NoError(t, err)
+	require.NoError(t, database.DeleteJournal(DB, 1))
+
+	tw := tat.NewTestWrapperSpecific(Modal(newTrashModal(DB)),
+		meta.NotificationMessageMsg{Message: "Restored journal General (1)"},
+		errors.New("nothing in the trash to restore"),
+	)
+
+	tw.AssertViewContains(t, "1 deleted item(s)")
+	tw.AssertViewContains(t, "General (1)")
+
+	tw.Send(restoreTrashItemMsg{})
+
+	tw.AssertViewContains(t, "The trash is empty")
+	assert.Len(t, database.AvailableJournals(), 1)
+
+	tw.Send(restoreTrashItemMsg{})
+	tw.AssertLastMsgsEqual(t, errors.New("nothing in the trash to restore"))
+}
+
+func TestFullTextSearchModal(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	ledger := database.Ledger{Name: "Bank", Type: database.ASSETLEDGER, Notes: meta.Notes{"At the cooperative bank"}}
+	_, err := ledger.Insert(DB)
+	require.NoError(t, err)
+
+	ftsm := newFullTextSearchModal(DB, "cooperative")
+	tw := tat.NewTestWrapperSpecific(Modal(ftsm))
+
+	tw.AssertViewContains(t, `1 result(s) for "cooperative"`)
+	tw.AssertViewContains(t, "At the cooperative bank")
+
+	motionSet := ftsm.MotionSet()
+	gotoDetailViewCmd, ok := motionSet.Get(meta.Motion{"g", "d"})
+	require.True(t, ok)
+
+	expected, err := database.SelectLedger(DB, 1)
+	require.NoError(t, err)
+
+	app := meta.LEDGERSAPP
+	assert.Equal(t, meta.SwitchAppViewMsg{App: &app, ViewType: meta.DETAILVIEWTYPE, Data: expected}, gotoDetailViewCmd.(tea.Cmd)())
+}
+
+func TestFullTextSearchModal_NoResults(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	tw := tat.NewTestWrapperSpecific(Modal(newFullTextSearchModal(DB, "nothing")))
+
+	tw.AssertViewContains(t, `Nothing found for "nothing"`)
+}
+
+func TestAttachmentsModal(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	ledger := database.Ledger{Name: "Bank", Type: database.ASSETLEDGER}
+	_, err := ledger.Insert(DB)
+	require.NoError(t, err)
+	journal := database.Journal{Name: "General", Type: database.GENERALJOURNAL}
+	_, err = journal.Insert(DB)
+	require.NoError(t, err)
+
+	date, err := database.ToDate("24-01-01")
+	require.NoError(t, err)
+	entry := database.Entry{Journal: 1}
+	_, err = entry.Insert(DB, []database.EntryRow{
+		{Date: date, Ledger: 1, Description: "deposit", Value: 1000},
+		{Date: date, Ledger: 1, Description: "withdrawal", Value: -1000},
+	})
+	require.NoError(t, err)
+
+	// The picker starts in the home directory
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	require.NoError(t, os.WriteFile(filepath.Join(home, "receipt.pdf"), []byte("%PDF receipt"), 0o600))
+
+	var opened string
+	originalSystemOpen := systemOpen
+	systemOpen = func(path string) error {
+		opened = path
+		return nil
+	}
+	t.Cleanup(func() {
+		systemOpen = originalSystemOpen
+	})
+
+	row := 2
+	tw := tat.NewTestWrapperSpecific(Modal(newAttachmentsModal(DB, 1, &row)),
+		meta.SwitchModeMsg{InputMode: meta.NORMALMODE},
+		meta.NotificationMessageMsg{Message: "Attached receipt.pdf"},
+		meta.NotificationMessageMsg{Message: "Opened receipt.pdf"},
+		meta.NotificationMessageMsg{Message: "Removed attachment receipt.pdf"},
+		errors.New("no attachment to open"),
+	)
+
+	tw.AssertViewContains(t, "Nothing attached to entry 1")
+	tw.AssertViewContains(t, "a to attach a file to row 2")
+
+	tw.Send(pickAttachmentMsg{})
+	tw.AssertViewContains(t, "Attach a file to row 2")
+	tw.AssertViewContains(t, "receipt.pdf")
+	tw.Execute(t, func(m Modal) {
+		assert.True(t, m.AllowsInsertMode())
+	})
+
+	// Leaving insert mode cancels the picker
+	tw.Execute(t, func(m Modal) {
+		manager := NewModalManager(DB)
+		manager.Modal = m
+		manager.LeaveInsertMode()
+
+		assert.False(t, m.AllowsInsertMode())
+	})
+	tw.AssertViewContains(t, "Nothing attached to entry 1")
+
+	tw.Send(pickAttachmentMsg{})
+	tw.AssertViewContains(t, "Attach a file to row 2")
+	tw.Send(tea.KeyMsg{Type: tea.KeyEnter})
+	tw.AssertViewContains(t, "1 file(s) attached to entry 1")
+	tw.AssertViewContains(t, "Row 2")
+
+	attachments, err := database.SelectAttachments(DB, 1)
+	require.NoError(t, err)
+	require.Len(t, attachments, 1)
+	assert.Equal(t, &row, attachments[0].Row)
+
+	tw.Send(openAttachmentMsg{})
+	tw.AssertLastMsgsEqual(t, meta.NotificationMessageMsg{Message: "Opened receipt.pdf"})
+	contents, err := os.ReadFile(opened)
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF receipt", string(contents))
+
+	tw.Send(detachAttachmentMsg{})
+	tw.AssertViewContains(t, "Nothing attached to entry 1")
+
+	tw.Send(openAttachmentMsg{})
+	tw.AssertLastMsgsEqual(t, errors.New("no attachment to open"))
+}
+
+func TestRecurringModal(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	ledger := database.Ledger{Name: "Bank", Type: database.ASSETLEDGER}
+	_, err := ledger.Insert(DB)
+	require.NoError(t, err)
+	journal := database.Journal{Name: "General", Type: database.GENERALJOURNAL}
+	_, err = journal.Insert(DB)
+	require.NoError(t, err)
+
+	// Three weeks ago, so two weekly occurrences are due
+	date := database.Date(time.Time(*database.Today()).AddDate(0, 0, -21))
+	entry := database.Entry{Journal: 1}
+	_, err = entry.Insert(DB, []database.EntryRow{
+		{Date: date, Ledger: 1, Description: "Insurance", Value: 1000},
+		{Date: date, Ledger: 1, Description: "Insurance", Value: -1000},
+	})
+	require.NoError(t, err)
+
+	_, err = database.MakeRecurring(DB, 1, database.WEEKLYFREQUENCY, nil, nil)
+	require.NoError(t, err)
+
+	tw := tat.NewTestWrapperSpecific(Modal(newRecurringModal(DB)),
+		meta.NotificationMessageMsg{Message: "Skipped Insurance of " + database.Date(time.Time(date).AddDate(0, 0, 7)).String()},
+		meta.NotificationMessageMsg{Message: "Posted 2 entry(s)"},
+		errors.New("post or skip the earlier occurrences of Insurance first"),
+		errors.New("nothing is due"),
+	)
+
+	tw.AssertViewContains(t, "3 recurring entry(s) due")
+	tw.AssertViewContains(t, "(weekly, #2)")
+
+	tw.Send(meta.NavigateMsg{Direction: meta.DOWN})
+	tw.Send(skipOccurrenceMsg{})
+	tw.AssertLastMsgsEqual(t, errors.New("post or skip the earlier occurrences of Insurance first"))
+
+	tw.Send(meta.JumpVerticalMsg{Down: false})
+	tw.Send(skipOccurrenceMsg{})
+	tw.AssertViewContains(t, "2 recurring entry(s) due")
+
+	tw.Send(postOccurrencesMsg{All: true})
+	tw.AssertViewContains(t, "No recurring entries are due")
+
+	entries, err := database.SelectEntries(DB)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+
+	tw.Send(postOccurrencesMsg{})
+	tw.AssertLastMsgsEqual(t, errors.New("nothing is due"))
+}
+
+func TestChartTemplatesModal(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	ctm := newChartTemplatesModal(DB)
+	tw := tat.NewTestWrapperSpecific(Modal(ctm))
+
+	tw.AssertViewContains(t, "Personal")
+	tw.AssertViewContains(t, "(33 ledgers, 5 journals)")
+
+	tw.Send(meta.NavigateMsg{Direction: meta.DOWN})
+
+	_, cmd := ctm.Update(applyChartTemplateMsg{})
+	require.NotNil(t, cmd)
+
+	batch, ok := cmd().(tea.BatchMsg)
+	require.True(t, ok)
+	require.Len(t, batch, 2)
+	assert.Equal(t, meta.NotificationMessageMsg{Message: "Created 26 ledgers and 5 journals from the Business template"}, batch[0]())
+
+	switchMsg, ok := batch[1]().(meta.SwitchAppViewMsg)
+	require.True(t, ok)
+	assert.Equal(t, meta.LEDGERSAPP, *switchMsg.App)
+
+	accountsLedger := database.GetAccountsLedger()
+	require.NotNil(t, accountsLedger)
+	assert.Equal(t, "Debtors", accountsLedger.Name)
+	assert.Len(t, database.AvailableJournals(), 5)
+
+	_, cmd = ctm.Update(applyChartTemplateMsg{})
+	assert.Equal(t, errors.New("chart templates are for empty books, this one already has ledgers or journals"), cmd())
+}
+
+func TestAutoMatchModal(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	bank, err := (&database.Ledger{Name: "Bank", Type: database.ASSETLEDGER}).Insert(DB)
+	require.NoError(t, err)
+	sales, err := (&database.Ledger{Name: "Sales", Type: database.INCOMELEDGER}).Insert(DB)
+	require.NoError(t, err)
+	_, err = (&database.Journal{Name: "General", Type: database.GENERALJOURNAL}).Insert(DB)
+	require.NoError(t, err)
+
+	book := func(date string, description string, value database.CurrencyValue) {
+		parsed, err := database.ToDate(date)
+		require.NoError(t, err)
+
+		_, err = database.Entry{Journal: 1}.Insert(DB, []database.EntryRow{
+			{Date: parsed, Ledger: bank, Description: description, Value: value},
+			{Date: parsed, Ledger: sales, Description: description, Value: -value},
+		})
+		require.NoError(t, err)
+	}
+	book("24-01-01", "Refund", 10000)
+	book("24-01-01", "Refund", -10000)
+	book("24-02-01", "Fee", 500)
+	book("24-02-15", "Fee returned", -500)
+	require.NoError(t, database.UpdateCache(DB))
+
+	amm := newAutoMatchModal(DB, meta.LEDGERSAPP, bank)
+	tw := tat.NewTestWrapperSpecific(Modal(amm))
+	tw.AssertViewContains(t, "2 of 2 proposals accepted")
+
+	// Leave out the fee, the cursor stays on it
+	tw.Send(meta.NavigateMsg{Direction: meta.DOWN})
+	tw.Send(toggleProposalMsg{})
+	tw.AssertViewContains(t, "1 of 2 proposals accepted")
+	assert.Equal(t, "Fee", (*amm.list.ActiveItem()).(proposalItem).proposal.Rows[0].Description)
+
+	_, cmd := amm.Update(meta.CommitMsg{})
+	require.NotNil(t, cmd)
+
+	batch, ok := cmd().(tea.BatchMsg)
+	require.True(t, ok)
+	require.Len(t, batch, 2)
+	assert.Equal(t, meta.NotificationMessageMsg{Message: "reconciled 1 proposals, updated 2 rows"}, batch[0]())
+
+	switchMsg, ok := batch[1]().(meta.SwitchAppViewMsg)
+	require.True(t, ok)
+	assert.Equal(t, meta.LEDGERSAPP, *switchMsg.App)
+	assert.Equal(t, "Bank", switchMsg.Data.(database.Ledger).Name)
+
+	rows, err := database.SelectRowsByLedgers(DB, []int{bank})
+	require.NoError(t, err)
+	for _, row := range rows {
+		assert.Equal(t, row.Description == "Refund", row.Reconciled, row.Description)
+	}
+}
@@ -21,6 +21,12 @@ type Modal interface {
 	Reload() Modal
 }
 
+// Implemented by modals that only show something while in insert mode, like a file picker,
+// so they can drop it again when insert mode is left
+type insertModeLeaver interface {
+	leaveInsertMode()
+}
+
 type ModalManager struct {
 	DB *sqlx.DB
 
@@ -66,7 +72,7 @@ func (mm *ModalManager) Update(message tea.Msg) (*ModalManager, tea.Cmd) {
 		return mm, tea.Batch(mm.Modal.Init(), cmd)
 
 	case meta.ShowBankImporterMsg:
-		mm.Modal = newBankImporter()
+		mm.Modal = newBankImporter(mm.DB)
 
 		var cmd tea.Cmd
 		mm.Modal, cmd = mm.Modal.Update(tea.WindowSizeMsg{
@@ -98,6 +104,138 @@ func (mm *ModalManager) Update(message tea.Msg) (*ModalManager, tea.Cmd) {
 
 		return mm, tea.Batch(mm.Modal.Init(), cmd)
 
+	case meta.ShowIntegrityCheckMsg:
+		mm.Modal = newIntegrityCheckModal(mm.DB)
+
+		var cmd tea.Cmd
+		mm.Modal, cmd = mm.Modal.Update(tea.WindowSizeMsg{
+			Width:  mm.width - 8,
+			Height: mm.height,
+		})
+
+		return mm, tea.Batch(mm.Modal.Init(), cmd)
+
+	case meta.ShowYearEndWizardMsg:
+		mm.Modal = newYearEndWizard(mm.DB, message.Year)
+
+		var cmd tea.Cmd
+		mm.Modal, cmd = mm.Modal.Update(tea.WindowSizeMsg{
+			Width:  mm.width - 8,
+			Height: mm.height,
+		})
+
+		return mm, tea.Batch(mm.Modal.Init(), cmd)
+
+	case meta.ShowRevaluationWizardMsg:
+		mm.Modal = newRevaluationWizard(mm.DB, message.Date)
+
+		var cmd tea.Cmd
+		mm.Modal, cmd = mm.Modal.Update(tea.WindowSizeMsg{
+			Width:  mm.width - 8,
+			Height: mm.height,
+		})
+
+		return mm, tea.Batch(mm.Modal.Init(), cmd)
+
+	case meta.ShowEntryHistoryMsg:
+		mm.Modal = newEntryHistoryModal(mm.DB, message.Entry)
+
+		var cmd tea.Cmd
+		mm.Modal, cmd = mm.Modal.Update(tea.WindowSizeMsg{
+			Width:  mm.width - 8,
+			Height: mm.height,
+		})
+
+		return mm, tea.Batch(mm.Modal.Init(), cmd)
+
+	case meta.ShowTrashMsg:
+		mm.Modal = newTrashModal(mm.DB)
+
+		var cmd tea.Cmd
+		mm.Modal, cmd = mm.Modal.Update(tea.WindowSizeMsg{
+			Width:  mm.width - 8,
+			Height: mm.height,
+		})
+
+		return mm, tea.Batch(mm.Modal.Init(), cmd)
+
+	case meta.ShowFullTextSearchMsg:
+		mm.Modal = newFullTextSearchModal(mm.DB, message.Query)
+
+		var cmd tea.Cmd
+		mm.Modal, cmd = mm.Modal.Update(tea.WindowSizeMsg{
+			Width:  mm.width - 8,
+			Height: mm.height,
+		})
+
+		return mm, tea.Batch(mm.Modal.Init(), cmd)
+
+	case meta.ShowAttachmentsMsg:
+		mm.Modal = newAttachmentsModal(mm.DB, message.Entry, message.Row)
+
+		var cmd tea.Cmd
+		mm.Modal, cmd = mm.Modal.Update(tea.WindowSizeMsg{
+			Width:  mm.width - 8,
+			Height: mm.height,
+		})
+
+		return mm, tea.Batch(mm.Modal.Init(), cmd)
+
+	case meta.ShowRecurringMsg:
+		mm.Modal = newRecurringModal(mm.DB)
+
+		var cmd tea.Cmd
+		mm.Modal, cmd = mm.Modal.Update(tea.WindowSizeMsg{
+			Width:  mm.width - 8,
+			Height: mm.height,
+		})
+
+		return mm, tea.Batch(mm.Modal.Init(), cmd)
+
+	case meta.ShowChartTemplatesMsg:
+		mm.Modal = newChartTemplatesModal(mm.DB)
+
+		var cmd tea.Cmd
+		mm.Modal, cmd = mm.Modal.Update(tea.WindowSizeMsg{
+			Width:  mm.width - 8,
+			Height: mm.height,
+		})
+
+		return mm, tea.Batch(mm.Modal.Init(), cmd)
+
+	case meta.ShowAgingMsg:
+		mm.Modal = newAgingModal(mm.DB, message.Date, message.ByDueDate)
+
+		var cmd tea.Cmd
+		mm.Modal, cmd = mm.Modal.Update(tea.WindowSizeMsg{
+			Width:  mm.width - 8,
+			Height: mm.height,
+		})
+
+		return mm, tea.Batch(mm.Modal.Init(), cmd)
+
+	case meta.ShowAutoMatchMsg:
+		mm.Modal = newAutoMatchModal(mm.DB, message.App, message.Id)
+
+		var cmd tea.Cmd
+		mm.Modal, cmd = mm.Modal.Update(tea.WindowSizeMsg{
+			Width:  mm.width - 8,
+			Height: mm.height,
+		})
+
+		return mm, tea.Batch(mm.Modal.Init(), cmd)
+
+	case meta.ShowStatementsMsg:
+		mm.Modal = newStatementsModal(mm.DB, message.Ledger)
+
+		var cmd tea.Cmd
+		mm.Modal, cmd = mm.Modal.Update(tea.WindowSizeMsg{
+			Width:  mm.width - 8,
+			Height: mm.height,
+		})
+
+		return mm, tea.Batch(mm.Modal.Init(), cmd)
+
 	case meta.ReloadViewMsg:
 		mm.Modal = mm.Modal.Reload()
 
@@ -135,6 +273,12 @@ func (mm *ModalManager) CurrentViewAllowsInsertMode() bool {
 	return mm.Modal.AllowsInsertMode()
 }
 
+func (mm *ModalManager) LeaveInsertMode() {
+	if leaver, ok := mm.Modal.(insertModeLeaver); ok {
+		leaver.leaveInsertMode()
+	}
+}
+
 func (mm *ModalManager) CurrentViewAllowsSearchMode() bool {
 	return mm.Modal.AllowsSearchMode()
 }
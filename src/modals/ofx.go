@@ -0,0 +1,189 @@
+package modals
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"strings"
+	"terminaccounting/database"
+	"time"
+)
+
+// The columns the transactions of an OFX statement are read into, so they're previewed like a CSV file
+var ofxHeaders = []string{"Date", "Amount", "FITID", "Name", "Memo", "Counter account"}
+
+type ofxStatement struct {
+	// The ACCTID of the bank or credit card account the statement is of
+	account string
+
+	rows [][]string
+}
+
+func readOFX(path string) (ofxStatement, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ofxStatement{}, err
+	}
+
+	return parseOFX(string(content))
+}
+
+// Reads the transactions of OFX 1.x and 2.x statements, QFX files are OFX as well.
+// 1.x is SGML, where elements holding a value don't need to be closed, 2.x is XML.
+// Both are read the same way: a tag followed by a value is an element, a tag without one opens an aggregate.
+func parseOFX(content string) (ofxStatement, error) {
+	var result ofxStatement
+
+	// Skips the headers, of 1.x files these aren't tags at all
+	start := strings.Index(strings.ToUpper(content), "<OFX>")
+	if start == -1 {
+		return result, errors.New("not an OFX file, there's no <OFX> element")
+	}
+	content = content[start:]
+
+	// The aggregates the next element is in, innermost last
+	var open []string
+	// The elements of the transaction being read, nil outside of a transaction
+	var transaction map[string]string
+
+	for {
+		tagStart := strings.IndexByte(content, '<')
+		if tagStart == -1 {
+			break
+		}
+		tagLength := strings.IndexByte(content[tagStart:], '>')
+		if tagLength == -1 {
+			return result, errors.New("the OFX file ends halfway a tag")
+		}
+
+		tag := strings.ToUpper(strings.TrimSpace(content[tagStart+1 : tagStart+tagLength]))
+		content = content[tagStart+tagLength+1:]
+
+		valueLength := strings.IndexByte(content, '<')
+		if valueLength == -1 {
+			valueLength = len(content)
+		}
+		value := html.UnescapeString(strings.TrimSpace(content[:valueLength]))
+
+		switch {
+		case strings.HasPrefix(tag, "?"), strings.HasPrefix(tag, "!"), strings.HasSuffix(tag, "/"):
+			// Processing instructions, comments and empty elements
+
+		case strings.HasPrefix(tag, "/"):
+			name := tag[1:]
+
+			// Elements with a value are only closed in 2.x files, and aren't in open
+			index := -1
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == name {
+					index = i
+					break
+				}
+			}
+			if index == -1 {
+				continue
+			}
+			open = open[:index]
+
+			if name == "STMTTRN" && transaction != nil {
+				result.rows = append(result.rows, []string{
+					transaction["DTPOSTED"][:min(len(transaction["DTPOSTED"]), len("20060102"))],
+					transaction["TRNAMT"],
+					transaction["FITID"],
+					transaction["NAME"],
+					transaction["MEMO"],
+					transaction["COUNTERACCOUNT"],
+				})
+				transaction = nil
+			}
+
+		case value == "":
+			open = append(open, tag)
+
+			if tag == "STMTTRN" {
+				transaction = make(map[string]string)
+			}
+
+		default:
+			var parent string
+			if len(open) != 0 {
+				parent = open[len(open)-1]
+			}
+
+			switch {
+			case tag == "ACCTID" && (parent == "BANKACCTFROM" || parent == "CCACCTFROM"):
+				if result.account != "" && result.account != value {
+					return result, fmt.Errorf("the OFX file has statements of several accounts, %s and %s", result.account, value)
+				}
+
+				result.account = value
+
+			case tag == "ACCTID" && (parent == "BANKACCTTO" || parent == "CCACCTTO") && transaction != nil:
+				transaction["COUNTERACCOUNT"] = value
+
+			case parent == "STMTTRN" && transaction != nil:
+				transaction[tag] = value
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// A parser for OFX and QFX statements, as read by readOFX.
+// The bank ledger's row keeps the FITID of its transaction, so the transaction isn't imported twice.
+type ofxParser struct{}
+
+func (op ofxParser) String() string {
+	return "OFX/QFX"
+}
+
+func (op ofxParser) CompareId() int {
+	return 2
+}
+
+// Row format: see ofxHeaders
+func (op ofxParser) usedColumns() []int {
+	return []int{0, 1, 2, 3, 4, 5}
+}
+
+func (op ofxParser) compileRows(data [][]string, accountLedger, bankLedger int) ([]database.EntryRow, error) {
+	var result []database.EntryRow
+
+	for _, row := range data {
+		date, err := time.Parse("20060102", row[0])
+		if err != nil {
+			return result, err
+		}
+
+		if row[2] == "" {
+			return nil, fmt.Errorf("the transaction of %s for %s has no FITID", row[0], row[1])
+		}
+
+		// Amounts are signed, with a decimal point, or a comma in some countries
+		value := strings.TrimPrefix(row[1], "+")
+		isDebit := strings.HasPrefix(value, "-")
+		value = strings.ReplaceAll(strings.TrimPrefix(value, "-"), ".", ",")
+		if strings.HasPrefix(value, ",") {
+			value = "0" + value
+		}
+
+		description := row[3]
+		if description == "" {
+			description = row[4]
+		}
+
+		rows, err := makeRows(date, row[5], value, description, isDebit, accountLedger, bankLedger)
+		if err != nil {
+			return nil, err
+		}
+
+		fitId := row[2]
+		rows[1].ExternalId = &fitId
+
+		result = append(result, rows[:]...)
+	}
+
+	return result, nil
+}
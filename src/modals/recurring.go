@@ -0,0 +1,229 @@
+package modals
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"terminaccounting/bubbles/list"
+	"terminaccounting/database"
+	"terminaccounting/meta"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmoiron/sqlx"
+)
+
+// Posts the highlighted occurrence, or all of them if All is set
+type postOccurrencesMsg struct {
+	All bool
+}
+
+// Skips the highlighted occurrence
+type skipOccurrenceMsg struct{}
+
+// Stops the recurring entry of the highlighted occurrence from recurring
+type stopRecurringMsg struct{}
+
+// Lists the occurrences of recurring entries that are due, and posts or skips them
+type recurringModal struct {
+	DB *sqlx.DB
+
+	width, height int
+
+	// nil until the occurrences have loaded
+	occurrences []database.RecurringOccurrence
+	list        list.Model
+}
+
+func newRecurringModal(DB *sqlx.DB) *recurringModal {
+	return &recurringModal{
+		DB: DB,
+
+		list: list.New(0, 0),
+	}
+}
+
+func (rm *recurringModal) Init() tea.Cmd {
+	return rm.makeLoadOccurrencesCmd()
+}
+
+func (rm *recurringModal) makeLoadOccurrencesCmd() tea.Cmd {
+	return func() tea.Msg {
+		occurrences, err := database.DueOccurrences(rm.DB, *database.Today())
+		if err != nil {
+			return err
+		}
+
+		return meta.DataLoadedMsg{Data: occurrences}
+	}
+}
+
+func (rm *recurringModal) Update(message tea.Msg) (Modal, tea.Cmd) {
+	switch message := message.(type) {
+	case tea.WindowSizeMsg:
+		rm.width = message.Width
+		rm.height = message.Height
+
+		var cmd tea.Cmd
+		// -2 for the title and its margin, -2 for the hint and its margin
+		rm.list, cmd = rm.list.Update(tea.WindowSizeMsg{Width: message.Width, Height: message.Height - 4})
+
+		return rm, cmd
+
+	case meta.NavigateMsg:
+		rm.list.Navigate(message.Direction == meta.DOWN)
+
+		return rm, nil
+
+	case meta.JumpVerticalMsg:
+		rm.list.Jump(message.Down)
+
+		return rm, nil
+
+	case meta.UpdateSearchMsg:
+		var cmd tea.Cmd
+		rm.list, cmd = rm.list.Update(list.FuzzyFilterMsg{Query: message.Query})
+
+		return rm, cmd
+
+	case meta.DataLoadedMsg:
+		rm.occurrences = message.Data.([]database.RecurringOccurrence)
+		rm.list.SetItems(toItemSlice(rm.occurrences))
+
+		return rm, nil
+
+	case postOccurrencesMsg:
+		toPost := rm.occurrences
+		if !message.All {
+			occurrence, err := rm.activeOccurrence()
+			if err != nil {
+				return rm, meta.MessageCmd(err)
+			}
+
+			toPost = []database.RecurringOccurrence{occurrence}
+		}
+
+		if len(toPost) == 0 {
+			return rm, meta.MessageCmd(errors.New("nothing is due"))
+		}
+
+		posted, err := database.PostOccurrences(rm.DB, toPost)
+		if err != nil {
+			return rm, meta.MessageCmd(err)
+		}
+
+		notification := meta.NotificationMessageMsg{Message: fmt.Sprintf("Posted %d entry(s)", len(posted))}
+
+		return rm, tea.Batch(meta.MessageCmd(notification), rm.makeLoadOccurrencesCmd())
+
+	case skipOccurrenceMsg:
+		occurrence, err := rm.activeOccurrence()
+		if err != nil {
+			return rm, meta.MessageCmd(err)
+		}
+
+		err = database.SkipOccurrence(rm.DB, occurrence)
+		if err != nil {
+			return rm, meta.MessageCmd(err)
+		}
+
+		notification := meta.NotificationMessageMsg{
+			Message: fmt.Sprintf("Skipped %s of %s", occurrence.Recurring.Name, occurrence.Date),
+		}
+
+		return rm, tea.Batch(meta.MessageCmd(notification), rm.makeLoadOccurrencesCmd())
+
+	case stopRecurringMsg:
+		occurrence, err := rm.activeOccurrence()
+		if err != nil {
+			return rm, meta.MessageCmd(err)
+		}
+
+		err = database.DeleteRecurringEntry(rm.DB, occurrence.Recurring.Id)
+		if err != nil {
+			return rm, meta.MessageCmd(err)
+		}
+
+		notification := meta.NotificationMessageMsg{
+			Message: fmt.Sprintf("%s no longer recurs", occurrence.Recurring.Name),
+		}
+
+		return rm, tea.Batch(meta.MessageCmd(notification), rm.makeLoadOccurrencesCmd())
+
+	default:
+		panic(fmt.Sprintf("unexpected tea.Msg: %#v", message))
+	}
+}
+
+func (rm *recurringModal) activeOccurrence() (database.RecurringOccurrence, error) {
+	activeItem := rm.list.ActiveItem()
+	if activeItem == nil {
+		return database.RecurringOccurrence{}, errors.New("nothing is due")
+	}
+
+	return (*activeItem).(database.RecurringOccurrence), nil
+}
+
+func (rm *recurringModal) View() string {
+	var result strings.Builder
+
+	titleStyle := lipgloss.NewStyle().Bold(true).MarginBottom(1)
+
+	switch {
+	case rm.occurrences == nil:
+		result.WriteString(titleStyle.Render("Loading recurring entries..."))
+
+	case len(rm.occurrences) == 0:
+		result.WriteString(titleStyle.Render("No recurring entries are due"))
+
+	default:
+		result.WriteString(titleStyle.Render(fmt.Sprintf("%d recurring entry(s) due", len(rm.occurrences))))
+	}
+	result.WriteString("\n")
+
+	result.WriteString(rm.list.View())
+	result.WriteString("\n\n")
+
+	hint := "p to post, P or :post to post all, x or :skip to skip, :stop to stop recurring"
+	result.WriteString(lipgloss.NewStyle().Italic(true).Render(hint))
+
+	return result.String()
+}
+
+func (rm *recurringModal) AllowsInsertMode() bool {
+	return false
+}
+
+func (rm *recurringModal) AllowsSearchMode() bool {
+	return true
+}
+
+func (rm *recurringModal) MotionSet() meta.Trie[tea.Msg] {
+	var result meta.Trie[tea.Msg]
+
+	result.Insert(meta.Motion{"j"}, meta.NavigateMsg{Direction: meta.DOWN})
+	result.Insert(meta.Motion{"k"}, meta.NavigateMsg{Direction: meta.UP})
+
+	result.Insert(meta.Motion{"g", "g"}, meta.JumpVerticalMsg{Down: false})
+	result.Insert(meta.Motion{"G"}, meta.JumpVerticalMsg{Down: true})
+
+	result.Insert(meta.Motion{"p"}, postOccurrencesMsg{})
+	result.Insert(meta.Motion{"P"}, postOccurrencesMsg{All: true})
+	result.Insert(meta.Motion{"x"}, skipOccurrenceMsg{})
+
+	return result
+}
+
+func (rm *recurringModal) CommandSet() meta.Trie[tea.Msg] {
+	var result meta.Trie[tea.Msg]
+
+	result.Insert(meta.Command(strings.Split("post", "")), postOccurrencesMsg{All: true})
+	result.Insert(meta.Command(strings.Split("skip", "")), skipOccurrenceMsg{})
+	result.Insert(meta.Command(strings.Split("stop", "")), stopRecurringMsg{})
+
+	return result
+}
+
+func (rm *recurringModal) Reload() Modal {
+	return newRecurringModal(rm.DB)
+}
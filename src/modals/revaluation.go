@@ -0,0 +1,317 @@
+package modals
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"terminaccounting/bubbles/itempicker"
+	"terminaccounting/database"
+	"terminaccounting/meta"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmoiron/sqlx"
+)
+
+// Books the unrealised exchange rate differences of all foreign currency balances at a date
+type revaluationWizard struct {
+	DB *sqlx.DB
+
+	width, height int
+
+	activeInput int
+
+	dateInput     textinput.Model
+	ledgerPicker  itempicker.Model
+	journalPicker itempicker.Model
+
+	// The revaluation for the current inputs, only valid if proposalErr is nil
+	revaluation database.Revaluation
+	proposalErr error
+}
+
+func newRevaluationWizard(DB *sqlx.DB, date string) *revaluationWizard {
+	if date == "" {
+		date = database.Today().String()
+	}
+
+	dateInput := textinput.New()
+	dateInput.Cursor.SetMode(cursor.CursorStatic)
+	dateInput.CharLimit = 8
+	dateInput.Placeholder = "yy-MM-dd"
+	dateInput.Prompt = ""
+	dateInput.SetValue(date)
+	dateInput.Focus()
+
+	var resultLedgers []itempicker.Item
+	for _, ledger := range database.AvailableLedgers() {
+		if ledger.Type == database.INCOMELEDGER || ledger.Type == database.EXPENSELEDGER {
+			resultLedgers = append(resultLedgers, ledger)
+		}
+	}
+
+	var generalJournals []itempicker.Item
+	for _, journal := range database.AvailableJournals() {
+		if journal.Type == database.GENERALJOURNAL {
+			generalJournals = append(generalJournals, journal)
+		}
+	}
+
+	result := &revaluationWizard{
+		DB: DB,
+
+		dateInput:     dateInput,
+		ledgerPicker:  itempicker.New(resultLedgers),
+		journalPicker: itempicker.New(generalJournals),
+	}
+	result.propose()
+
+	return result
+}
+
+func (rw *revaluationWizard) Init() tea.Cmd {
+	if rw.ledgerPicker.Value() == nil {
+		return tea.Batch(
+			meta.MessageCmd(meta.QuitMsg{}),
+			meta.MessageCmd(errors.New("no INCOME or EXPENSE ledger exists yet to book the differences on")),
+		)
+	}
+
+	if rw.journalPicker.Value() == nil {
+		return tea.Batch(
+			meta.MessageCmd(meta.QuitMsg{}),
+			meta.MessageCmd(errors.New("no GENERAL journal exists yet to post the revaluation to")),
+		)
+	}
+
+	return nil
+}
+
+func (rw *revaluationWizard) Update(message tea.Msg) (Modal, tea.Cmd) {
+	numInputs := 3
+
+	switch message := message.(type) {
+	case tea.WindowSizeMsg:
+		rw.width = message.Width
+		rw.height = message.Height
+
+		return rw, nil
+
+	case meta.SwitchFocusMsg:
+		switch message.Direction {
+		case meta.NEXT:
+			rw.activeInput++
+			rw.activeInput %= numInputs
+
+		case meta.PREVIOUS:
+			rw.activeInput--
+
+			if rw.activeInput < 0 {
+				rw.activeInput += numInputs
+			}
+
+		default:
+			panic(fmt.Sprintf("unexpected meta.Sequence: %#v", message.Direction))
+		}
+
+		return rw, nil
+
+	case tea.KeyMsg:
+		var cmd tea.Cmd
+
+		switch rw.activeInput {
+		case 0:
+			rw.dateInput, cmd = rw.dateInput.Update(message)
+
+		case 1:
+			rw.ledgerPicker, cmd = rw.ledgerPicker.Update(message)
+
+		case 2:
+			rw.journalPicker, cmd = rw.journalPicker.Update(message)
+
+		default:
+			panic(fmt.Sprintf("unexpected rw.activeInput: %#v", rw.activeInput))
+		}
+
+		rw.propose()
+
+		return rw, cmd
+
+	case meta.UpdateSearchMsg:
+		selectMessage := itempicker.FuzzySelectMsg{Query: message.Query}
+
+		var cmd tea.Cmd
+		switch rw.activeInput {
+		case 1:
+			rw.ledgerPicker, cmd = rw.ledgerPicker.Update(selectMessage)
+
+		case 2:
+			rw.journalPicker, cmd = rw.journalPicker.Update(selectMessage)
+		}
+
+		rw.propose()
+
+		return rw, cmd
+
+	case meta.CommitMsg:
+		if rw.proposalErr != nil {
+			return rw, meta.MessageCmd(rw.proposalErr)
+		}
+
+		journal := rw.journalPicker.Value()
+		if journal == nil {
+			return rw, meta.MessageCmd(errors.New("no journal selected (none available)"))
+		}
+
+		id, err := database.PostRevaluation(rw.DB, rw.revaluation, journal.(database.Journal))
+		if err != nil {
+			return rw, meta.MessageCmd(err)
+		}
+
+		entry, err := database.SelectEntry(rw.DB, id)
+		if err != nil {
+			return rw, meta.MessageCmd(err)
+		}
+
+		notification := meta.NotificationMessageMsg{
+			Message: fmt.Sprintf("Revalued foreign currencies at %s in entry %d", rw.revaluation.Date, id),
+		}
+
+		entriesAppType := meta.ENTRIESAPP
+		switchViewMsg := meta.SwitchAppViewMsg{
+			App:      &entriesAppType,
+			ViewType: meta.DETAILVIEWTYPE,
+			Data:     entry,
+		}
+
+		return rw, tea.Batch(meta.MessageCmd(notification), meta.MessageCmd(switchViewMsg))
+
+	default:
+		panic(fmt.Sprintf("unexpected tea.Msg: %#v", message))
+	}
+}
+
+// Recomputes the revaluation after an input changed
+func (rw *revaluationWizard) propose() {
+	date, err := database.ToDate(rw.dateInput.Value())
+	if err != nil {
+		rw.proposalErr = fmt.Errorf("date %q isn't in yy-MM-dd", rw.dateInput.Value())
+		return
+	}
+
+	resultLedger := rw.ledgerPicker.Value()
+	if resultLedger == nil {
+		rw.proposalErr = errors.New("no result ledger selected (none available)")
+		return
+	}
+
+	rw.revaluation, rw.proposalErr = database.ProposeRevaluation(rw.DB, date, resultLedger.(database.Ledger))
+}
+
+func (rw *revaluationWizard) View() string {
+	style := lipgloss.NewStyle()
+	highlightStyle := style.Foreground(lipgloss.ANSIColor(212))
+	cellStyle := lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1)
+
+	inputStyles := []lipgloss.Style{style, style, style}
+	inputStyles[rw.activeInput] = highlightStyle
+
+	var result strings.Builder
+
+	result.WriteString(lipgloss.NewStyle().Bold(true).Render("Revaluation of foreign currencies"))
+	result.WriteString("\n")
+
+	result.WriteString(lipgloss.JoinHorizontal(
+		lipgloss.Top,
+		cellStyle.Render("Date "+inputStyles[0].Render(rw.dateInput.View())),
+		" ",
+		cellStyle.Render("Differences to "+inputStyles[1].Render(rw.ledgerPicker.View())),
+		" ",
+		cellStyle.Render("Journal "+inputStyles[2].Render(rw.journalPicker.View())),
+	))
+	result.WriteString("\n\n")
+
+	if rw.proposalErr != nil {
+		result.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Render(rw.proposalErr.Error()))
+
+		return result.String()
+	}
+
+	if rw.revaluation.IsEmpty() {
+		result.WriteString(fmt.Sprintf("Nothing to revalue at %s", rw.revaluation.Date))
+
+		return result.String()
+	}
+
+	result.WriteString(rw.renderRows())
+	result.WriteString("\n\n")
+
+	result.WriteString(lipgloss.NewStyle().Italic(true).Render(":write to post the entry"))
+
+	return result.String()
+}
+
+func (rw *revaluationWizard) renderRows() string {
+	nameStyle := lipgloss.NewStyle().Width(40)
+	valueStyle := lipgloss.NewStyle().Width(14).Align(lipgloss.Right)
+
+	availableLedgers := database.AvailableLedgers()
+	availableAccounts := database.AvailableAccounts()
+
+	var lines []string
+	for _, row := range rw.revaluation.Rows {
+		var name string
+
+		ledgerIdx := slices.IndexFunc(availableLedgers, func(ledger database.Ledger) bool { return ledger.Id == row.Ledger })
+		if ledgerIdx == -1 {
+			name = fmt.Sprintf("<deleted ledger %d>", row.Ledger)
+		} else {
+			name = availableLedgers[ledgerIdx].String()
+		}
+
+		if row.Account != nil {
+			accountIdx := slices.IndexFunc(availableAccounts, func(account database.Account) bool { return account.Id == *row.Account })
+			if accountIdx == -1 {
+				name += fmt.Sprintf(" / <deleted account %d>", *row.Account)
+			} else {
+				name += " / " + availableAccounts[accountIdx].String()
+			}
+		}
+
+		lines = append(lines, nameStyle.Render(name)+valueStyle.Render(row.Value.String())+"  "+row.Description)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (rw *revaluationWizard) AllowsInsertMode() bool {
+	return true
+}
+
+func (rw *revaluationWizard) AllowsSearchMode() bool {
+	return rw.activeInput == 1 || rw.activeInput == 2
+}
+
+func (rw *revaluationWizard) MotionSet() meta.Trie[tea.Msg] {
+	var motions meta.Trie[tea.Msg]
+
+	motions.Insert(meta.Motion{"shift+tab"}, meta.SwitchFocusMsg{Direction: meta.PREVIOUS})
+	motions.Insert(meta.Motion{"tab"}, meta.SwitchFocusMsg{Direction: meta.NEXT})
+
+	return motions
+}
+
+func (rw *revaluationWizard) CommandSet() meta.Trie[tea.Msg] {
+	var result meta.Trie[tea.Msg]
+
+	result.Insert(meta.Command(strings.Split("write", "")), meta.CommitMsg{})
+
+	return result
+}
+
+func (rw *revaluationWizard) Reload() Modal {
+	return newRevaluationWizard(rw.DB, rw.dateInput.Value())
+}
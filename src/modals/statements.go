@@ -0,0 +1,147 @@
+package modals
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"terminaccounting/bubbles/list"
+	"terminaccounting/database"
+	"terminaccounting/meta"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmoiron/sqlx"
+)
+
+// Shows the bank statements a ledger was reconciled against, newest first
+type statementsModal struct {
+	DB *sqlx.DB
+
+	width, height int
+
+	ledger int
+
+	// nil until the statements are loaded
+	statements []database.Statement
+	list       list.Model
+}
+
+func newStatementsModal(DB *sqlx.DB, ledger int) *statementsModal {
+	return &statementsModal{
+		DB: DB,
+
+		ledger: ledger,
+
+		list: list.New(0, 0),
+	}
+}
+
+func (sm *statementsModal) Init() tea.Cmd {
+	statements, err := database.SelectStatements(sm.DB, sm.ledger)
+	if err != nil {
+		return tea.Batch(meta.MessageCmd(meta.QuitMsg{}), meta.MessageCmd(err))
+	}
+
+	return meta.MessageCmd(meta.DataLoadedMsg{Data: statements})
+}
+
+func (sm *statementsModal) Update(message tea.Msg) (Modal, tea.Cmd) {
+	switch message := message.(type) {
+	case tea.WindowSizeMsg:
+		sm.width = message.Width
+		sm.height = message.Height
+
+		var cmd tea.Cmd
+		// -3 for the title and column names
+		sm.list, cmd = sm.list.Update(tea.WindowSizeMsg{Width: message.Width, Height: message.Height - 3})
+
+		return sm, cmd
+
+	case meta.NavigateMsg:
+		sm.list.Navigate(message.Direction == meta.DOWN)
+
+		return sm, nil
+
+	case meta.JumpVerticalMsg:
+		sm.list.Jump(message.Down)
+
+		return sm, nil
+
+	case meta.UpdateSearchMsg:
+		var cmd tea.Cmd
+		sm.list, cmd = sm.list.Update(list.FuzzyFilterMsg{Query: message.Query})
+
+		return sm, cmd
+
+	case meta.DataLoadedMsg:
+		sm.statements = message.Data.([]database.Statement)
+		sm.list.SetItems(toItemSlice(sm.statements))
+
+		return sm, nil
+
+	default:
+		panic(fmt.Sprintf("unexpected tea.Msg: %#v", message))
+	}
+}
+
+func (sm *statementsModal) View() string {
+	var result strings.Builder
+
+	titleStyle := lipgloss.NewStyle().Bold(true).MarginBottom(1)
+
+	ledgerName := fmt.Sprintf("%d", sm.ledger)
+	ledgers := database.AvailableLedgers()
+	if index := slices.IndexFunc(ledgers, func(ledger database.Ledger) bool { return ledger.Id == sm.ledger }); index != -1 {
+		ledgerName = ledgers[index].Name
+	}
+
+	if sm.statements == nil {
+		result.WriteString(titleStyle.Render("Loading statements..."))
+
+		return result.String()
+	}
+
+	if len(sm.statements) == 0 {
+		result.WriteString(titleStyle.Render(fmt.Sprintf("Ledger %s wasn't reconciled against any statements yet, start with :statement <yy-MM-dd> <closing balance>", ledgerName)))
+
+		return result.String()
+	}
+
+	result.WriteString(titleStyle.Render(fmt.Sprintf("Statements ledger %s was reconciled against", ledgerName)))
+	result.WriteString("\n")
+
+	result.WriteString(lipgloss.NewStyle().Bold(true).Render(database.StatementsHeader()))
+	result.WriteString("\n")
+
+	result.WriteString(sm.list.View())
+
+	return result.String()
+}
+
+func (sm *statementsModal) AllowsInsertMode() bool {
+	return false
+}
+
+func (sm *statementsModal) AllowsSearchMode() bool {
+	return true
+}
+
+func (sm *statementsModal) MotionSet() meta.Trie[tea.Msg] {
+	var result meta.Trie[tea.Msg]
+
+	result.Insert(meta.Motion{"j"}, meta.NavigateMsg{Direction: meta.DOWN})
+	result.Insert(meta.Motion{"k"}, meta.NavigateMsg{Direction: meta.UP})
+
+	result.Insert(meta.Motion{"g", "g"}, meta.JumpVerticalMsg{Down: false})
+	result.Insert(meta.Motion{"G"}, meta.JumpVerticalMsg{Down: true})
+
+	return result
+}
+
+func (sm *statementsModal) CommandSet() meta.Trie[tea.Msg] {
+	return meta.Trie[tea.Msg]{}
+}
+
+func (sm *statementsModal) Reload() Modal {
+	return newStatementsModal(sm.DB, sm.ledger)
+}
@@ -0,0 +1,167 @@
+package modals
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"terminaccounting/bubbles/list"
+	"terminaccounting/database"
+	"terminaccounting/meta"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmoiron/sqlx"
+)
+
+// Restores the highlighted trash item
+type restoreTrashItemMsg struct{}
+
+// Lists deleted ledgers, accounts, journals and entries, and restores them
+type trashModal struct {
+	DB *sqlx.DB
+
+	width, height int
+
+	// nil until the trash has loaded
+	items []database.TrashItem
+	list  list.Model
+}
+
+func newTrashModal(DB *sqlx.DB) *trashModal {
+	return &trashModal{
+		DB: DB,
+
+		list: list.New(0, 0),
+	}
+}
+
+func (tm *trashModal) Init() tea.Cmd {
+	return tm.makeLoadTrashCmd()
+}
+
+func (tm *trashModal) makeLoadTrashCmd() tea.Cmd {
+	return func() tea.Msg {
+		items, err := database.SelectTrash(tm.DB)
+		if err != nil {
+			return fmt.Errorf("FAILED TO LOAD TRASH: %v", err)
+		}
+
+		return meta.DataLoadedMsg{Data: items}
+	}
+}
+
+func (tm *trashModal) Update(message tea.Msg) (Modal, tea.Cmd) {
+	switch message := message.(type) {
+	case tea.WindowSizeMsg:
+		tm.width = message.Width
+		tm.height = message.Height
+
+		var cmd tea.Cmd
+		// -2 for the title and its margin, -2 for the hint and its margin
+		tm.list, cmd = tm.list.Update(tea.WindowSizeMsg{Width: message.Width, Height: message.Height - 4})
+
+		return tm, cmd
+
+	case meta.NavigateMsg:
+		tm.list.Navigate(message.Direction == meta.DOWN)
+
+		return tm, nil
+
+	case meta.JumpVerticalMsg:
+		tm.list.Jump(message.Down)
+
+		return tm, nil
+
+	case meta.UpdateSearchMsg:
+		var cmd tea.Cmd
+		tm.list, cmd = tm.list.Update(list.FuzzyFilterMsg{Query: message.Query})
+
+		return tm, cmd
+
+	case meta.DataLoadedMsg:
+		tm.items = message.Data.([]database.TrashItem)
+		tm.list.SetItems(toItemSlice(tm.items))
+
+		return tm, nil
+
+	case restoreTrashItemMsg:
+		activeItem := tm.list.ActiveItem()
+		if activeItem == nil {
+			return tm, meta.MessageCmd(errors.New("nothing in the trash to restore"))
+		}
+
+		restored, err := database.RestoreFromTrash(tm.DB, (*activeItem).(database.TrashItem).Id)
+		if err != nil {
+			return tm, meta.MessageCmd(err)
+		}
+
+		notification := meta.NotificationMessageMsg{
+			Message: fmt.Sprintf("Restored %s %s", strings.ToLower(string(restored.Model)), restored.Name),
+		}
+
+		return tm, tea.Batch(meta.MessageCmd(notification), tm.makeLoadTrashCmd())
+
+	default:
+		panic(fmt.Sprintf("unexpected tea.Msg: %#v", message))
+	}
+}
+
+func (tm *trashModal) View() string {
+	var result strings.Builder
+
+	titleStyle := lipgloss.NewStyle().Bold(true).MarginBottom(1)
+
+	switch {
+	case tm.items == nil:
+		result.WriteString(titleStyle.Render("Loading trash..."))
+
+	case len(tm.items) == 0:
+		result.WriteString(titleStyle.Render("The trash is empty"))
+
+	default:
+		result.WriteString(titleStyle.Render(fmt.Sprintf("%d deleted item(s)", len(tm.items))))
+	}
+	result.WriteString("\n")
+
+	result.WriteString(tm.list.View())
+	result.WriteString("\n\n")
+
+	hint := "u to restore, :purge <days> to remove what was deleted more than that many days ago"
+	result.WriteString(lipgloss.NewStyle().Italic(true).Render(hint))
+
+	return result.String()
+}
+
+func (tm *trashModal) AllowsInsertMode() bool {
+	return false
+}
+
+func (tm *trashModal) AllowsSearchMode() bool {
+	return true
+}
+
+func (tm *trashModal) MotionSet() meta.Trie[tea.Msg] {
+	var result meta.Trie[tea.Msg]
+
+	result.Insert(meta.Motion{"j"}, meta.NavigateMsg{Direction: meta.DOWN})
+	result.Insert(meta.Motion{"k"}, meta.NavigateMsg{Direction: meta.UP})
+
+	result.Insert(meta.Motion{"g", "g"}, meta.JumpVerticalMsg{Down: false})
+	result.Insert(meta.Motion{"G"}, meta.JumpVerticalMsg{Down: true})
+
+	result.Insert(meta.Motion{"u"}, restoreTrashItemMsg{}) // [u]ndo the deletion
+
+	return result
+}
+
+func (tm *trashModal) CommandSet() meta.Trie[tea.Msg] {
+	var result meta.Trie[tea.Msg]
+
+	result.Insert(meta.Command(strings.Split("restore", "")), restoreTrashItemMsg{})
+
+	return result
+}
+
+func (tm *trashModal) Reload() Modal {
+	return newTrashModal(tm.DB)
+}
@@ -0,0 +1,324 @@
+package modals
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"terminaccounting/bubbles/itempicker"
+	"terminaccounting/database"
+	"terminaccounting/meta"
+	"time"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmoiron/sqlx"
+)
+
+// Closes the INCOME and EXPENSE ledgers of a year into an equity ledger,
+// and carries the balance sheet over into the next year
+type yearEndWizard struct {
+	DB *sqlx.DB
+
+	width, height int
+
+	activeInput int
+
+	yearInput     textinput.Model
+	equityPicker  itempicker.Model
+	journalPicker itempicker.Model
+
+	// The closing for the current inputs, only valid if proposalErr is nil
+	closing     database.YearEndClosing
+	proposalErr error
+}
+
+func newYearEndWizard(DB *sqlx.DB, year int) *yearEndWizard {
+	if year == 0 {
+		year = time.Now().Year() - 1
+	}
+
+	yearInput := textinput.New()
+	yearInput.Cursor.SetMode(cursor.CursorStatic)
+	yearInput.CharLimit = 4
+	yearInput.Prompt = ""
+	yearInput.SetValue(strconv.Itoa(year))
+	yearInput.Focus()
+
+	var equityLedgers []itempicker.Item
+	for _, ledger := range database.AvailableLedgers() {
+		if ledger.Type == database.EQUITYLEDGER {
+			equityLedgers = append(equityLedgers, ledger)
+		}
+	}
+
+	var generalJournals []itempicker.Item
+	for _, journal := range database.AvailableJournals() {
+		if journal.Type == database.GENERALJOURNAL {
+			generalJournals = append(generalJournals, journal)
+		}
+	}
+
+	result := &yearEndWizard{
+		DB: DB,
+
+		yearInput:     yearInput,
+		equityPicker:  itempicker.New(equityLedgers),
+		journalPicker: itempicker.New(generalJournals),
+	}
+	result.propose()
+
+	return result
+}
+
+func (yew *yearEndWizard) Init() tea.Cmd {
+	if yew.equityPicker.Value() == nil {
+		return tea.Batch(
+			meta.MessageCmd(meta.QuitMsg{}),
+			meta.MessageCmd(errors.New("no EQUITY ledger exists yet to close the year into")),
+		)
+	}
+
+	if yew.journalPicker.Value() == nil {
+		return tea.Batch(
+			meta.MessageCmd(meta.QuitMsg{}),
+			meta.MessageCmd(errors.New("no GENERAL journal exists yet to post the closing to")),
+		)
+	}
+
+	return nil
+}
+
+func (yew *yearEndWizard) Update(message tea.Msg) (Modal, tea.Cmd) {
+	numInputs := 3
+
+	switch message := message.(type) {
+	case tea.WindowSizeMsg:
+		yew.width = message.Width
+		yew.height = message.Height
+
+		return yew, nil
+
+	case meta.SwitchFocusMsg:
+		switch message.Direction {
+		case meta.NEXT:
+			yew.activeInput++
+			yew.activeInput %= numInputs
+
+		case meta.PREVIOUS:
+			yew.activeInput--
+
+			if yew.activeInput < 0 {
+				yew.activeInput += numInputs
+			}
+
+		default:
+			panic(fmt.Sprintf("unexpected meta.Sequence: %#v", message.Direction))
+		}
+
+		return yew, nil
+
+	case tea.KeyMsg:
+		var cmd tea.Cmd
+
+		switch yew.activeInput {
+		case 0:
+			if len(message.Runes) == 1 && !unicode.IsDigit(message.Runes[0]) {
+				return yew, meta.MessageCmd(fmt.Errorf("%q is not a valid character for a year", message))
+			}
+
+			yew.yearInput, cmd = yew.yearInput.Update(message)
+
+		case 1:
+			yew.equityPicker, cmd = yew.equityPicker.Update(message)
+
+		case 2:
+			yew.journalPicker, cmd = yew.journalPicker.Update(message)
+
+		default:
+			panic(fmt.Sprintf("unexpected yew.activeInput: %#v", yew.activeInput))
+		}
+
+		yew.propose()
+
+		return yew, cmd
+
+	case meta.UpdateSearchMsg:
+		selectMessage := itempicker.FuzzySelectMsg{Query: message.Query}
+
+		var cmd tea.Cmd
+		switch yew.activeInput {
+		case 1:
+			yew.equityPicker, cmd = yew.equityPicker.Update(selectMessage)
+
+		case 2:
+			yew.journalPicker, cmd = yew.journalPicker.Update(selectMessage)
+		}
+
+		yew.propose()
+
+		return yew, cmd
+
+	case meta.CommitMsg:
+		if yew.proposalErr != nil {
+			return yew, meta.MessageCmd(yew.proposalErr)
+		}
+
+		journal := yew.journalPicker.Value()
+		if journal == nil {
+			return yew, meta.MessageCmd(errors.New("no journal selected (none available)"))
+		}
+
+		ids, err := database.PostYearEnd(yew.DB, yew.closing, journal.(database.Journal))
+		if err != nil {
+			return yew, meta.MessageCmd(err)
+		}
+
+		closingEntry, err := database.SelectEntry(yew.DB, ids[0])
+		if err != nil {
+			return yew, meta.MessageCmd(err)
+		}
+
+		notification := meta.NotificationMessageMsg{
+			Message: fmt.Sprintf("Closed %d, created %d entries", yew.closing.Year, len(ids)),
+		}
+
+		entriesAppType := meta.ENTRIESAPP
+		switchViewMsg := meta.SwitchAppViewMsg{
+			App:      &entriesAppType,
+			ViewType: meta.DETAILVIEWTYPE,
+			Data:     closingEntry,
+		}
+
+		return yew, tea.Batch(meta.MessageCmd(notification), meta.MessageCmd(switchViewMsg))
+
+	default:
+		panic(fmt.Sprintf("unexpected tea.Msg: %#v", message))
+	}
+}
+
+// Recomputes the closing after an input changed
+func (yew *yearEndWizard) propose() {
+	year, err := strconv.Atoi(yew.yearInput.Value())
+	if err != nil || len(yew.yearInput.Value()) != 4 {
+		yew.proposalErr = fmt.Errorf("invalid year %q", yew.yearInput.Value())
+		return
+	}
+
+	equityLedger := yew.equityPicker.Value()
+	if equityLedger == nil {
+		yew.proposalErr = errors.New("no equity ledger selected (none available)")
+		return
+	}
+
+	yew.closing, yew.proposalErr = database.ProposeYearEnd(yew.DB, year, equityLedger.(database.Ledger))
+}
+
+func (yew *yearEndWizard) View() string {
+	style := lipgloss.NewStyle()
+	highlightStyle := style.Foreground(lipgloss.ANSIColor(212))
+	cellStyle := lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1)
+
+	inputStyles := []lipgloss.Style{style, style, style}
+	inputStyles[yew.activeInput] = highlightStyle
+
+	var result strings.Builder
+
+	result.WriteString(lipgloss.NewStyle().Bold(true).Render("Year-end closing"))
+	result.WriteString("\n")
+
+	result.WriteString(lipgloss.JoinHorizontal(
+		lipgloss.Top,
+		cellStyle.Render("Year "+inputStyles[0].Render(yew.yearInput.View())),
+		" ",
+		cellStyle.Render("Equity ledger "+inputStyles[1].Render(yew.equityPicker.View())),
+		" ",
+		cellStyle.Render("Journal "+inputStyles[2].Render(yew.journalPicker.View())),
+	))
+	result.WriteString("\n\n")
+
+	if yew.proposalErr != nil {
+		result.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Render(yew.proposalErr.Error()))
+
+		return result.String()
+	}
+
+	if yew.closing.IsEmpty() {
+		result.WriteString(fmt.Sprintf("Nothing to close for %d", yew.closing.Year))
+
+		return result.String()
+	}
+
+	result.WriteString(yew.renderTotals())
+	result.WriteString("\n\n")
+
+	result.WriteString(fmt.Sprintf("Result to equity: %d rows, dated %d-12-31\n", len(yew.closing.Closing), yew.closing.Year))
+	result.WriteString(fmt.Sprintf("Closing balance sheet: %d rows, dated %d-12-31\n", len(yew.closing.BalanceClosing), yew.closing.Year))
+	result.WriteString(fmt.Sprintf("Opening balances: %d rows, dated %d-01-01\n", len(yew.closing.Opening), yew.closing.Year+1))
+	result.WriteString("\n")
+
+	result.WriteString(lipgloss.NewStyle().Italic(true).Render(":write to post the entries"))
+
+	return result.String()
+}
+
+func (yew *yearEndWizard) renderTotals() string {
+	labelStyle := lipgloss.NewStyle().Width(12)
+	valueStyle := lipgloss.NewStyle().Width(14).Align(lipgloss.Right)
+
+	ledgerTypes := []database.LedgerType{
+		database.INCOMELEDGER,
+		database.EXPENSELEDGER,
+		database.ASSETLEDGER,
+		database.LIABILITYLEDGER,
+		database.EQUITYLEDGER,
+	}
+
+	var lines []string
+	lines = append(lines, fmt.Sprintf("Balances at the end of %d, before closing", yew.closing.Year))
+	for _, ledgerType := range ledgerTypes {
+		lines = append(lines, labelStyle.Render(ledgerType.String())+valueStyle.Render(yew.closing.Totals[ledgerType].String()))
+
+		if ledgerType == database.EXPENSELEDGER {
+			// Income is negative, negate so a profit shows as positive
+			result := yew.closing.Totals[database.INCOMELEDGER].Add(yew.closing.Totals[database.EXPENSELEDGER])
+			lines = append(lines, labelStyle.Bold(true).Render("Result")+valueStyle.Bold(true).Render((-result).String()))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (yew *yearEndWizard) AllowsInsertMode() bool {
+	return true
+}
+
+func (yew *yearEndWizard) AllowsSearchMode() bool {
+	return yew.activeInput == 1 || yew.activeInput == 2
+}
+
+func (yew *yearEndWizard) MotionSet() meta.Trie[tea.Msg] {
+	var motions meta.Trie[tea.Msg]
+
+	motions.Insert(meta.Motion{"shift+tab"}, meta.SwitchFocusMsg{Direction: meta.PREVIOUS})
+	motions.Insert(meta.Motion{"tab"}, meta.SwitchFocusMsg{Direction: meta.NEXT})
+
+	return motions
+}
+
+func (yew *yearEndWizard) CommandSet() meta.Trie[tea.Msg] {
+	var result meta.Trie[tea.Msg]
+
+	result.Insert(meta.Command(strings.Split("write", "")), meta.CommitMsg{})
+
+	return result
+}
+
+func (yew *yearEndWizard) Reload() Modal {
+	year, _ := strconv.Atoi(yew.yearInput.Value())
+
+	return newYearEndWizard(yew.DB, year)
+}
@@ -39,6 +39,9 @@ func (tw *TestWrapper[T]) GoToTab(tab meta.AppType) *TestWrapper[T] {
 		tw.Send(meta.SwitchTabMsg{Direction: meta.NEXT}).Send(meta.SwitchTabMsg{Direction: meta.NEXT})
 
 	case meta.JOURNALSAPP:
+		tw.Send(meta.SwitchTabMsg{Direction: meta.PREVIOUS}).Send(meta.SwitchTabMsg{Direction: meta.PREVIOUS})
+
+	case meta.INVOICESAPP:
 		tw.Send(meta.SwitchTabMsg{Direction: meta.PREVIOUS})
 
 	default:
@@ -212,6 +212,14 @@ func (tw *TestWrapper[T]) AssertViewContains(t *testing.T, expected string) {
 	})
 }
 
+func (tw *TestWrapper[T]) AssertViewNotContains(t *testing.T, unexpected string) {
+	t.Helper()
+
+	tw.Execute(t, func(T) {
+		assert.NotContains(t, tw.view(), unexpected)
+	})
+}
+
 func (tw *TestWrapper[T]) AssertLastMsgsEqual(t *testing.T, expected ...tea.Msg) {
 	t.Helper()
 
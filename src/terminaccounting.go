@@ -4,10 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strconv"
 	"strings"
 	"terminaccounting/database"
 	"terminaccounting/meta"
 	"terminaccounting/modals"
+	"terminaccounting/view"
+	"time"
 
 	"github.com/charmbracelet/bubbles/cursor"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -30,6 +34,9 @@ type terminaccounting struct {
 	displayNotification bool
 	fatalError          error // To print to screen on exit
 
+	// Set by main for empty books, to offer the chart templates on startup
+	offerChartTemplates bool
+
 	// current vimesque input mode
 	inputMode meta.InputMode
 	// current motion
@@ -72,9 +79,31 @@ func (ta *terminaccounting) Init() tea.Cmd {
 		cmds = append(cmds, meta.MessageCmd(err))
 	}
 
+	cmds = append(cmds, ta.makeShowDueOccurrencesCmd())
+
+	if ta.offerChartTemplates {
+		cmds = append(cmds, meta.MessageCmd(meta.ShowChartTemplatesMsg{}))
+	}
+
 	return tea.Batch(cmds...)
 }
 
+// Shows the recurring entries' occurrences that came due since the book was last opened, if there are any
+func (ta *terminaccounting) makeShowDueOccurrencesCmd() tea.Cmd {
+	return func() tea.Msg {
+		due, err := database.DueOccurrences(ta.DB, *database.Today())
+		if err != nil {
+			return err
+		}
+
+		if len(due) == 0 {
+			return nil
+		}
+
+		return meta.ShowRecurringMsg{}
+	}
+}
+
 func (ta *terminaccounting) Update(message tea.Msg) (tea.Model, tea.Cmd) {
 	switch message := message.(type) {
 	case meta.QuitMsg:
@@ -127,7 +156,7 @@ func (ta *terminaccounting) Update(message tea.Msg) (tea.Model, tea.Cmd) {
 
 		return ta, tea.Batch(cmds...)
 
-	case meta.ShowTextModalMsg, meta.ShowNotificationsMsg, meta.ShowBankImporterMsg, meta.SwitchAppViewMsg, meta.ShowGlobalSearchMsg:
+	case meta.ShowTextModalMsg, meta.ShowNotificationsMsg, meta.ShowBankImporterMsg, meta.SwitchAppViewMsg, meta.ShowGlobalSearchMsg, meta.ShowIntegrityCheckMsg, meta.ShowYearEndWizardMsg, meta.ShowRevaluationWizardMsg, meta.ShowEntryHistoryMsg, meta.ShowTrashMsg, meta.ShowFullTextSearchMsg, meta.ShowAttachmentsMsg, meta.ShowRecurringMsg, meta.ShowChartTemplatesMsg, meta.ShowAgingMsg, meta.ShowStatementsMsg, meta.ShowAutoMatchMsg:
 		return ta.handleViewSwitch(message)
 
 	case meta.NotificationMessageMsg:
@@ -169,13 +198,212 @@ func (ta *terminaccounting) Update(message tea.Msg) (tea.Model, tea.Cmd) {
 
 		return ta, nil
 
+	case meta.LockPeriodMsg:
+		period, err := database.ParsePeriod(message.Period)
+		if err != nil {
+			return ta, meta.MessageCmd(err)
+		}
+
+		err = database.LockPeriod(ta.DB, period)
+		if err != nil {
+			return ta, meta.MessageCmd(err)
+		}
+
+		return ta, meta.MessageCmd(meta.NotificationMessageMsg{Message: fmt.Sprintf("Locked period %s", period)})
+
+	case meta.SetExchangeRateMsg:
+		currency, err := database.ParseCurrency(message.Currency)
+		if err != nil {
+			return ta, meta.MessageCmd(err)
+		}
+
+		date, err := database.ToDate(message.Date)
+		if err != nil {
+			return ta, meta.MessageCmd(fmt.Errorf("date %q isn't in yy-MM-dd", message.Date))
+		}
+
+		rate, err := strconv.ParseFloat(message.Rate, 64)
+		if err != nil {
+			return ta, meta.MessageCmd(fmt.Errorf("invalid exchange rate %q", message.Rate))
+		}
+
+		exchangeRate := database.ExchangeRate{Currency: currency, Date: date, Rate: rate}
+		err = database.SetExchangeRate(ta.DB, exchangeRate)
+		if err != nil {
+			return ta, meta.MessageCmd(err)
+		}
+
+		return ta, meta.MessageCmd(meta.NotificationMessageMsg{Message: fmt.Sprintf("Set exchange rate %s", exchangeRate)})
+
+	case meta.PurgeTrashMsg:
+		purged, err := database.PurgeTrash(ta.DB, time.Now().AddDate(0, 0, -message.Days))
+		if err != nil {
+			return ta, meta.MessageCmd(err)
+		}
+
+		return ta, meta.MessageCmd(meta.NotificationMessageMsg{
+			Message: fmt.Sprintf("Purged %d item(s) deleted more than %d day(s) ago from the trash", purged, message.Days),
+		})
+
+	case meta.ShowDimensionTotalsMsg:
+		totals, err := database.TotalsByDimension(ta.DB, message.Dimension)
+		if err != nil {
+			return ta, meta.MessageCmd(err)
+		}
+
+		return ta, meta.MessageCmd(meta.ShowTextModalMsg{Text: database.RenderDimensionTotals(message.Dimension, totals)})
+
+	case meta.MakeRecurringMsg:
+		frequency, err := database.ParseFrequency(message.Frequency)
+		if err != nil {
+			return ta, meta.MessageCmd(err)
+		}
+
+		// The limit is either a number of occurrences or an end date
+		var endDate *database.Date
+		var count *int
+		if message.Limit != "" {
+			if parsed, err := strconv.Atoi(message.Limit); err == nil {
+				count = &parsed
+			} else if date, err := database.ToDate(message.Limit); err == nil {
+				endDate = &date
+			} else {
+				return ta, meta.MessageCmd(fmt.Errorf("%q is neither a number of occurrences nor a date in yy-MM-dd", message.Limit))
+			}
+		}
+
+		recurring, err := database.MakeRecurring(ta.DB, message.Entry, frequency, endDate, count)
+		if err != nil {
+			return ta, meta.MessageCmd(err)
+		}
+
+		return ta, meta.MessageCmd(meta.NotificationMessageMsg{
+			Message: fmt.Sprintf("%s now recurs %s, due occurrences are shown on startup and with :recurring", recurring.Name, recurring.Frequency),
+		})
+
+	case meta.UseTemplateMsg:
+		return ta, ta.useTemplate(message)
+
+	case meta.SaveTemplateMsg:
+		template, err := database.SaveTemplate(ta.DB, message.Entry, message.Name, message.Split)
+		if err != nil {
+			return ta, meta.MessageCmd(err)
+		}
+
+		return ta, meta.MessageCmd(meta.NotificationMessageMsg{Message: fmt.Sprintf("Saved template %s", template.Name)})
+
+	case meta.DeleteTemplateMsg:
+		err := database.DeleteTemplate(ta.DB, message.Name)
+		if err != nil {
+			return ta, meta.MessageCmd(err)
+		}
+
+		return ta, meta.MessageCmd(meta.NotificationMessageMsg{Message: fmt.Sprintf("Removed template %s", message.Name)})
+
+	case meta.ShowChartOfAccountsMsg:
+		lines, err := database.ChartOfAccounts(ta.DB)
+		if err != nil {
+			return ta, meta.MessageCmd(err)
+		}
+
+		return ta, meta.MessageCmd(meta.ShowTextModalMsg{Text: database.RenderChartOfAccounts(lines)})
+
+	case meta.ShowBudgetReportMsg:
+		year := time.Now().Year()
+		if message.Year != "" {
+			parsed, err := strconv.Atoi(message.Year)
+			if err != nil {
+				return ta, meta.MessageCmd(fmt.Errorf("invalid year %q", message.Year))
+			}
+			year = parsed
+		}
+
+		lines, err := database.BudgetReport(ta.DB, year)
+		if err != nil {
+			return ta, meta.MessageCmd(err)
+		}
+
+		return ta, meta.MessageCmd(meta.ShowTextModalMsg{Text: database.RenderBudgetReport(year, lines)})
+
+	case meta.SetBudgetMsg:
+		return ta, ta.setBudget(message)
+
+	case meta.SetTaxCodeMsg:
+		return ta, ta.setTaxCode(message)
+
+	case meta.ShowTaxCodesMsg:
+		return ta, meta.MessageCmd(meta.ShowTextModalMsg{Text: database.RenderTaxCodes(database.AvailableTaxCodes())})
+
+	case meta.ShowVatReturnMsg:
+		period, err := database.ParsePeriod(message.Period)
+		if err != nil {
+			return ta, meta.MessageCmd(err)
+		}
+
+		boxes, err := database.VatReturn(ta.DB, period)
+		if err != nil {
+			return ta, meta.MessageCmd(err)
+		}
+
+		return ta, meta.MessageCmd(meta.ShowTextModalMsg{Text: database.RenderVatReturn(period, boxes)})
+
+	case meta.PostInvoiceMsg:
+		invoice, err := database.PostInvoice(ta.DB, message.Invoice)
+		if err != nil {
+			return ta, meta.MessageCmd(err)
+		}
+
+		return ta, ta.reloadInvoice(invoice, fmt.Sprintf("Posted %s in entry %d", invoice.Name(), *invoice.Entry))
+
+	case meta.SetInvoicePaidMsg:
+		invoice, err := database.SetInvoicePaid(ta.DB, message.Invoice, message.Paid)
+		if err != nil {
+			return ta, meta.MessageCmd(err)
+		}
+
+		return ta, ta.reloadInvoice(invoice, fmt.Sprintf("Marked %s as %s", invoice.Name(), strings.ToLower(string(invoice.Status))))
+
+	case meta.ExportInvoiceMsg:
+		format, err := database.ParseInvoiceFormat(message.Format)
+		if err != nil {
+			return ta, meta.MessageCmd(err)
+		}
+
+		invoice, err := database.SelectInvoice(ta.DB, message.Invoice)
+		if err != nil {
+			return ta, meta.MessageCmd(err)
+		}
+
+		path, err := database.ExportInvoice(invoice, format, message.Path)
+		if err != nil {
+			return ta, meta.MessageCmd(err)
+		}
+
+		return ta, meta.MessageCmd(meta.NotificationMessageMsg{Message: fmt.Sprintf("Exported %s to %s", invoice.Name(), path)})
+
+	case meta.UnlockPeriodMsg:
+		period, err := database.ParsePeriod(message.Period)
+		if err != nil {
+			return ta, meta.MessageCmd(err)
+		}
+
+		err = database.UnlockPeriod(ta.DB, period)
+		if err != nil {
+			return ta, meta.MessageCmd(err)
+		}
+
+		return ta, meta.MessageCmd(meta.NotificationMessageMsg{Message: fmt.Sprintf("Unlocked period %s", period)})
+
 	case meta.DebugPrintCacheMsg:
 		slog.Debug("Database cache", "ledgers", database.AvailableLedgers(), "accounts", database.AvailableAccounts(), "journals", database.AvailableJournals())
 
 		return ta, nil
 	}
 
-	if ta.showModal {
+	// Data an app loaded still goes to that app while a modal is shown, like the modal shown on startup.
+	// Modals load their own data without a TargetApp.
+	dataLoaded, isDataLoaded := message.(meta.DataLoadedMsg)
+	if ta.showModal && (!isDataLoaded || dataLoaded.TargetApp == "") {
 		var cmd tea.Cmd
 		ta.modalManager, cmd = ta.modalManager.Update(message)
 
@@ -188,6 +416,125 @@ func (ta *terminaccounting) Update(message tea.Msg) (tea.Model, tea.Cmd) {
 	return ta, cmd
 }
 
+// Sets or removes the budget, and has the ledger's detail view show it
+func (ta *terminaccounting) setBudget(message meta.SetBudgetMsg) tea.Cmd {
+	budget, err := database.ParseBudget(message.Ledger, message.Period, message.Amount, message.Scope)
+	if err != nil {
+		return meta.MessageCmd(err)
+	}
+
+	var notification string
+	if message.Amount == "none" {
+		err := database.RemoveBudget(ta.DB, budget)
+		if err != nil {
+			return meta.MessageCmd(err)
+		}
+
+		notification = fmt.Sprintf("Removed budget for %s", budget)
+	} else {
+		err := database.SetBudget(ta.DB, budget)
+		if err != nil {
+			return meta.MessageCmd(err)
+		}
+
+		notification = fmt.Sprintf("Set budget for %s to %s %s", budget, budget.Amount, strings.ToLower(message.Period))
+	}
+
+	cmds := []tea.Cmd{meta.MessageCmd(meta.NotificationMessageMsg{Message: notification})}
+
+	// The ledger's detail view is the only one showing budgets
+	if _, ok := ta.appManager.appTypeToApp(meta.LEDGERSAPP).AcceptedModels()[meta.BUDGETMODEL]; ok {
+		cmds = append(cmds, database.MakeLoadLedgerBudgetsCmd(ta.DB, message.Ledger))
+	}
+
+	return tea.Batch(cmds...)
+}
+
+// Notifies of the change to the invoice, and has its detail view show it
+func (ta *terminaccounting) reloadInvoice(invoice database.Invoice, notification string) tea.Cmd {
+	cmds := []tea.Cmd{meta.MessageCmd(meta.NotificationMessageMsg{Message: notification})}
+
+	// The list view accepts invoices as well, but as a list
+	if ta.appManager.appTypeToApp(meta.INVOICESAPP).CurrentViewType() == meta.DETAILVIEWTYPE {
+		cmds = append(cmds, database.MakeLoadInvoicesDetailCmd(ta.DB, invoice.Id))
+	}
+
+	return tea.Batch(cmds...)
+}
+
+// Sets or removes the tax code
+func (ta *terminaccounting) setTaxCode(message meta.SetTaxCodeMsg) tea.Cmd {
+	if message.Remove {
+		err := database.RemoveTaxCode(ta.DB, message.Code)
+		if err != nil {
+			return meta.MessageCmd(err)
+		}
+
+		return meta.MessageCmd(meta.NotificationMessageMsg{Message: fmt.Sprintf("Removed tax code %s", message.Code)})
+	}
+
+	taxCode, err := database.ParseTaxCode(message.Code, message.Type, message.Rate, message.Ledger, message.Options)
+	if err != nil {
+		return meta.MessageCmd(err)
+	}
+
+	err = database.SetTaxCode(ta.DB, taxCode)
+	if err != nil {
+		return meta.MessageCmd(err)
+	}
+
+	return meta.MessageCmd(meta.NotificationMessageMsg{Message: "Set tax code " + taxCode.Describe()})
+}
+
+// Opens the entry create view filled in from the template, or lists the templates if no name is given
+func (ta *terminaccounting) useTemplate(message meta.UseTemplateMsg) tea.Cmd {
+	if message.Name == "" {
+		templates, err := database.SelectTemplates(ta.DB)
+		if err != nil {
+			return meta.MessageCmd(err)
+		}
+
+		return meta.MessageCmd(meta.ShowTextModalMsg{Text: database.RenderTemplates(templates)})
+	}
+
+	var total *database.CurrencyValue
+	if message.Total != "" {
+		parsed, err := database.ParseNonNegativeValue(message.Total)
+		if err != nil {
+			return meta.MessageCmd(fmt.Errorf("invalid total %q", message.Total))
+		}
+		total = &parsed
+	}
+
+	template, err := database.SelectTemplateByName(ta.DB, message.Name)
+	if err != nil {
+		return meta.MessageCmd(err)
+	}
+
+	availableJournals := database.AvailableJournals()
+	journalIndex := slices.IndexFunc(availableJournals, func(journal database.Journal) bool { return journal.Id == template.Journal })
+	if journalIndex == -1 {
+		return meta.MessageCmd(fmt.Errorf("template %s uses journal %d, which was deleted", template.Name, template.Journal))
+	}
+
+	rows, err := template.Instantiate(*database.Today(), total)
+	if err != nil {
+		return meta.MessageCmd(err)
+	}
+
+	entriesApp := meta.ENTRIESAPP
+
+	return meta.MessageCmd(meta.SwitchAppViewMsg{
+		App:      &entriesApp,
+		ViewType: meta.CREATEVIEWTYPE,
+		Data: view.EntryPrefillData{
+			Journal: availableJournals[journalIndex],
+			Rows:    rows,
+			Notes:   template.Notes,
+		},
+	})
+}
+
 func (ta *terminaccounting) View() string {
 	var result strings.Builder
 
@@ -261,6 +608,10 @@ func (ta *terminaccounting) switchMode(message meta.SwitchModeMsg) tea.Cmd {
 		return meta.MessageCmd(errors.New("Search currently not allowed"))
 	}
 
+	if ta.inputMode == meta.INSERTMODE && message.InputMode != meta.INSERTMODE && ta.showModal {
+		ta.modalManager.LeaveInsertMode()
+	}
+
 	ta.inputMode = message.InputMode
 
 	if message.InputMode == meta.COMMANDMODE {
@@ -307,8 +658,10 @@ func (ta *terminaccounting) executeCommand() (*terminaccounting, tea.Cmd) {
 
 	if ta.currentCommandIsSearch {
 		cmd = meta.MessageCmd(meta.UpdateSearchMsg{Query: command})
-	} else if command != "" {
-		command := strings.Split(command, "")
+	} else if fields := strings.Fields(command); len(fields) != 0 {
+		// Anything after the command name are its arguments, e.g. `:lock 2024`
+		command := strings.Split(fields[0], "")
+		args := fields[1:]
 
 		if completion := ta.commandSet().Autocomplete(command); completion != nil {
 			slog.Debug("Autocompleted command",
@@ -321,7 +674,12 @@ func (ta *terminaccounting) executeCommand() (*terminaccounting, tea.Cmd) {
 
 		commandMsg, ok := ta.commandSet().Get(command)
 		if ok {
-			cmd = meta.MessageCmd(commandMsg)
+			commandMsg, err := meta.ApplyCommandArgs(strings.Join(command, ""), commandMsg, args)
+			if err != nil {
+				cmd = meta.MessageCmd(err)
+			} else {
+				cmd = meta.MessageCmd(commandMsg)
+			}
 		} else {
 			cmd = meta.MessageCmd(fmt.Errorf("invalid command: %q", strings.Join(command, "")))
 		}
@@ -458,7 +816,7 @@ func (ta *terminaccounting) handleViewSwitch(message tea.Msg) (*terminaccounting
 
 		return ta, cmd
 
-	case meta.ShowTextModalMsg, meta.ShowNotificationsMsg, meta.ShowBankImporterMsg, meta.ShowGlobalSearchMsg:
+	case meta.ShowTextModalMsg, meta.ShowNotificationsMsg, meta.ShowBankImporterMsg, meta.ShowGlobalSearchMsg, meta.ShowIntegrityCheckMsg, meta.ShowYearEndWizardMsg, meta.ShowRevaluationWizardMsg, meta.ShowEntryHistoryMsg, meta.ShowTrashMsg, meta.ShowFullTextSearchMsg, meta.ShowAttachmentsMsg, meta.ShowRecurringMsg, meta.ShowChartTemplatesMsg, meta.ShowAgingMsg, meta.ShowStatementsMsg, meta.ShowAutoMatchMsg:
 		var cmd tea.Cmd
 		ta.modalManager, cmd = ta.modalManager.Update(message)
 
@@ -2,9 +2,12 @@ package main
 
 import (
 	"errors"
+	"path/filepath"
+	"terminaccounting/database"
 	"terminaccounting/meta"
 	tat "terminaccounting/tat"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/stretchr/testify/assert"
@@ -261,6 +264,316 @@ func TestExecuteCommand_CacheCommands(t *testing.T) {
 	})
 }
 
+func TestExecuteCommand_LockPeriod(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+	tw := tat.NewTestWrapperGeneric(newTerminaccounting(DB))
+
+	tw.SwitchMode(meta.COMMANDMODE, false).
+		SendText("lock 2024-Q2").
+		Send(tea.KeyMsg{Type: tea.KeyEnter})
+
+	tw.AssertLastMsgsEqual(t, meta.LockPeriodMsg{Period: "2024-Q2"}, meta.NotificationMessageMsg{Message: "Locked period 2024-Q2"})
+
+	periods, err := database.SelectPeriods(DB)
+	require.NoError(t, err)
+	require.Len(t, periods, 1)
+	assert.True(t, periods[0].Locked)
+
+	tw.SwitchMode(meta.COMMANDMODE, false).
+		SendText("unlock 2024-Q2").
+		Send(tea.KeyMsg{Type: tea.KeyEnter})
+
+	tw.AssertLastMsgsEqual(t, meta.UnlockPeriodMsg{Period: "2024-Q2"}, meta.NotificationMessageMsg{Message: "Unlocked period 2024-Q2"})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		tw.SwitchMode(meta.COMMANDMODE, false).
+			SendText("lock").
+			Send(tea.KeyMsg{Type: tea.KeyEnter})
+
+		tw.Execute(t, func(ta *terminaccounting) {
+			assert.Contains(t, ta.notifications[len(ta.notifications)-1].Text, "usage: lock <period>")
+		})
+
+		tw.SwitchMode(meta.COMMANDMODE, false).
+			SendText("lock yesterday").
+			Send(tea.KeyMsg{Type: tea.KeyEnter})
+
+		tw.Execute(t, func(ta *terminaccounting) {
+			assert.Contains(t, ta.notifications[len(ta.notifications)-1].Text, `invalid period "yesterday"`)
+		})
+	})
+}
+
+func TestExecuteCommand_SetExchangeRate(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+	tw := tat.NewTestWrapperGeneric(newTerminaccounting(DB))
+
+	tw.SwitchMode(meta.COMMANDMODE, false).
+		SendText("rate usd 24-12-31 0.92").
+		Send(tea.KeyMsg{Type: tea.KeyEnter})
+
+	tw.AssertLastMsgsEqual(t,
+		meta.SetExchangeRateMsg{Currency: "usd", Date: "24-12-31", Rate: "0.92"},
+		meta.NotificationMessageMsg{Message: "Set exchange rate USD 24-12-31 at 0.92"},
+	)
+
+	rates := database.AvailableExchangeRates()
+	require.Len(t, rates, 1)
+	assert.Equal(t, "USD", rates[0].Currency)
+	assert.Equal(t, 0.92, rates[0].Rate)
+
+	tw.SwitchMode(meta.COMMANDMODE, false).
+		SendText("rate USD 24-12-31 lots").
+		Send(tea.KeyMsg{Type: tea.KeyEnter})
+
+	tw.Execute(t, func(ta *terminaccounting) {
+		assert.Contains(t, ta.notifications[len(ta.notifications)-1].Text, `invalid exchange rate "lots"`)
+	})
+}
+
+func TestExecuteCommand_PurgeTrash(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+	tw := tat.NewTestWrapperGeneric(newTerminaccounting(DB))
+
+	journal := database.Journal{Name: "General", Type: database.GENERALJOURNAL}
+	_, err := journal.Insert(DB)
+	require.NoError(t, err)
+	require.NoError(t, database.DeleteJournal(DB, 1))
+
+	// Without a number of days nothing is purged
+	tw.SwitchMode(meta.COMMANDMODE, false).
+		SendText("purge").
+		Send(tea.KeyMsg{Type: tea.KeyEnter})
+
+	tw.Execute(t, func(ta *terminaccounting) {
+		assert.Contains(t, ta.notifications[len(ta.notifications)-1].Text, "usage: purge <days>")
+	})
+
+	trash, err := database.SelectTrash(DB)
+	require.NoError(t, err)
+	assert.Len(t, trash, 1)
+
+	tw.SwitchMode(meta.COMMANDMODE, false).
+		SendText("purge 30").
+		Send(tea.KeyMsg{Type: tea.KeyEnter})
+
+	tw.AssertLastMsgsEqual(t,
+		meta.PurgeTrashMsg{Days: 30},
+		meta.NotificationMessageMsg{Message: "Purged 0 item(s) deleted more than 30 day(s) ago from the trash"},
+	)
+
+	tw.SwitchMode(meta.COMMANDMODE, false).
+		SendText("purge 0").
+		Send(tea.KeyMsg{Type: tea.KeyEnter})
+
+	tw.AssertLastMsgsEqual(t,
+		meta.PurgeTrashMsg{Days: 0},
+		meta.NotificationMessageMsg{Message: "Purged 1 item(s) deleted more than 0 day(s) ago from the trash"},
+	)
+}
+
+func TestMakeRecurringMsg(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	ledger := database.Ledger{Name: "Bank", Type: database.ASSETLEDGER}
+	_, err := ledger.Insert(DB)
+	require.NoError(t, err)
+	journal := database.Journal{Name: "General", Type: database.GENERALJOURNAL}
+	_, err = journal.Insert(DB)
+	require.NoError(t, err)
+
+	// Eight days ago, so the next weekly occurrence is due
+	date := database.Date(time.Time(*database.Today()).AddDate(0, 0, -8))
+	entry := database.Entry{Journal: 1}
+	_, err = entry.Insert(DB, []database.EntryRow{
+		{Date: date, Ledger: 1, Description: "Subscription", Value: 1000},
+		{Date: date, Ledger: 1, Description: "Subscription", Value: -1000},
+	})
+	require.NoError(t, err)
+
+	tw := tat.NewTestWrapperGeneric(newTerminaccounting(DB))
+
+	tw.Send(meta.MakeRecurringMsg{Entry: 1, Frequency: "weekly", Limit: "someday"})
+	tw.Execute(t, func(ta *terminaccounting) {
+		assert.Equal(t, `"someday" is neither a number of occurrences nor a date in yy-MM-dd`, ta.notifications[len(ta.notifications)-1].Text)
+	})
+
+	tw.Send(meta.MakeRecurringMsg{Entry: 1, Frequency: "weekly", Limit: "52"})
+	tw.Execute(t, func(ta *terminaccounting) {
+		assert.Equal(t, "Subscription now recurs weekly, due occurrences are shown on startup and with :recurring", ta.notifications[len(ta.notifications)-1].Text)
+	})
+
+	// Opening the book again shows what's due
+	tw = tat.NewTestWrapperGeneric(newTerminaccounting(DB))
+	tw.Execute(t, func(ta *terminaccounting) {
+		assert.True(t, ta.showModal)
+	})
+	tw.AssertViewContains(t, "1 recurring entry(s) due")
+}
+
+func TestUseTemplateMsg(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	ledger := database.Ledger{Name: "Groceries", Type: database.EXPENSELEDGER}
+	_, err := ledger.Insert(DB)
+	require.NoError(t, err)
+	journal := database.Journal{Name: "Purchases", Type: database.GENERALJOURNAL}
+	_, err = journal.Insert(DB)
+	require.NoError(t, err)
+
+	date, err := database.ToDate("24-01-01")
+	require.NoError(t, err)
+	entry := database.Entry{Journal: 1}
+	_, err = entry.Insert(DB, []database.EntryRow{
+		{Date: date, Ledger: 1, Description: "Weekly shopping", Value: 5000},
+		{Date: date, Ledger: 1, Description: "Weekly shopping", Value: -5000},
+	})
+	require.NoError(t, err)
+
+	tw := tat.NewTestWrapperGeneric(newTerminaccounting(DB))
+
+	tw.Send(meta.SaveTemplateMsg{Entry: 1, Name: "shopping", Split: true})
+	tw.Execute(t, func(ta *terminaccounting) {
+		assert.Equal(t, "Saved template shopping", ta.notifications[len(ta.notifications)-1].Text)
+	})
+
+	tw.Send(meta.UseTemplateMsg{Name: "shopping", Total: "-5"})
+	tw.Execute(t, func(ta *terminaccounting) {
+		assert.Equal(t, `invalid total "-5"`, ta.notifications[len(ta.notifications)-1].Text)
+	})
+
+	tw.Send(meta.UseTemplateMsg{Name: "shopping", Total: "64.20"})
+	tw.Execute(t, func(ta *terminaccounting) {
+		assert.Equal(t, meta.CREATEVIEWTYPE, ta.appManager.currentViewType())
+	})
+	tw.AssertViewContains(t, "Purchases")
+	tw.AssertViewContains(t, "Weekly shopping")
+	tw.AssertViewContains(t, "64.20")
+
+	tw.Send(meta.UseTemplateMsg{})
+	tw.AssertViewContains(t, "shopping")
+	tw.AssertViewContains(t, "Purchases (1), 2 row(s)")
+}
+
+func TestSetBudgetMsg(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	ledger := database.Ledger{Name: "Groceries", Type: database.EXPENSELEDGER}
+	_, err := ledger.Insert(DB)
+	require.NoError(t, err)
+	require.NoError(t, database.UpdateCache(DB))
+
+	tw := tat.NewTestWrapperGeneric(newTerminaccounting(DB))
+
+	lastNotification := func() string {
+		var result string
+		tw.Execute(t, func(ta *terminaccounting) {
+			result = ta.notifications[len(ta.notifications)-1].Text
+		})
+		return result
+	}
+
+	tw.Send(meta.SetBudgetMsg{Ledger: 1, Period: "weekly", Amount: "50"})
+	assert.Equal(t, `invalid budget period "weekly", use monthly or yearly`, lastNotification())
+
+	tw.Send(meta.SetBudgetMsg{Ledger: 1, Period: "monthly", Amount: "-50"})
+	assert.Equal(t, `invalid budget amount "-50"`, lastNotification())
+
+	tw.Send(meta.SetBudgetMsg{Ledger: 1, Period: "monthly", Amount: "400", Scope: "organic"})
+	assert.Equal(t, "Set budget for Groceries (1) organic to 400.00 monthly", lastNotification())
+
+	budgets, err := database.SelectBudgets(DB)
+	require.NoError(t, err)
+	require.Len(t, budgets, 1)
+	assert.Equal(t, "organic", budgets[0].Tag)
+
+	tw.Send(meta.SetBudgetMsg{Ledger: 1, Period: "monthly", Amount: "none", Scope: "organic"})
+	assert.Equal(t, "Removed budget for Groceries (1) organic", lastNotification())
+
+	budgets, err = database.SelectBudgets(DB)
+	require.NoError(t, err)
+	assert.Empty(t, budgets)
+}
+
+func TestInvoiceMsgs(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	accountId, err := database.Account{Name: "Customer", Type: database.DEBTOR}.Insert(DB)
+	require.NoError(t, err)
+	journalId, err := (&database.Journal{Name: "Sales", Type: database.INCOMEJOURNAL}).Insert(DB)
+	require.NoError(t, err)
+	ledgerId, err := (&database.Ledger{Name: "Revenue", Type: database.INCOMELEDGER}).Insert(DB)
+	require.NoError(t, err)
+
+	date, err := database.ToDate("24-05-01")
+	require.NoError(t, err)
+	invoiceId, err := database.Invoice{
+		Account: accountId,
+		Journal: journalId,
+		Ledger:  ledgerId,
+		Date:    date,
+		DueDate: date,
+		Lines:   database.InvoiceLines{{Description: "Consulting", Quantity: 1, UnitPrice: 10000}},
+	}.Insert(DB)
+	require.NoError(t, err)
+
+	tw := tat.NewTestWrapperGeneric(newTerminaccounting(DB))
+	tw.Send(tea.WindowSizeMsg{Width: 120, Height: 40})
+	tw.GoToTab(meta.INVOICESAPP).SwitchView(meta.DETAILVIEWTYPE, database.Invoice{Id: invoiceId})
+	tw.AssertViewContains(t, "Draft 1")
+
+	lastNotification := func() string {
+		var result string
+		tw.Execute(t, func(ta *terminaccounting) {
+			result = ta.notifications[len(ta.notifications)-1].Text
+		})
+		return result
+	}
+
+	tw.Send(meta.SetInvoicePaidMsg{Invoice: invoiceId, Paid: true})
+	assert.Equal(t, "Draft 1 isn't posted yet, post it with :post first", lastNotification())
+
+	tw.Send(meta.PostInvoiceMsg{Invoice: invoiceId})
+	assert.Equal(t, "posting an invoice books it on the accounts ledger, but none is set, mark one in the ledgers tab", lastNotification())
+
+	_, err = (&database.Ledger{Name: "Debtors", Type: database.ASSETLEDGER, IsAccounts: true}).Insert(DB)
+	require.NoError(t, err)
+
+	tw.Send(meta.PostInvoiceMsg{Invoice: invoiceId})
+	assert.Equal(t, "Posted Invoice 2024-0001 in entry 1", lastNotification())
+	tw.AssertViewContains(t, "Invoice 2024-0001")
+
+	tw.Send(meta.SetInvoicePaidMsg{Invoice: invoiceId, Paid: true})
+	assert.Equal(t, "Marked Invoice 2024-0001 as paid", lastNotification())
+
+	path := filepath.Join(t.TempDir(), "invoice.txt")
+	tw.Send(meta.ExportInvoiceMsg{Invoice: invoiceId, Format: "text", Path: path})
+	assert.Equal(t, "Exported Invoice 2024-0001 to "+path, lastNotification())
+	assert.FileExists(t, path)
+}
+
+func TestOfferChartTemplates(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	ta := newTerminaccounting(DB)
+	ta.offerChartTemplates = true
+
+	tw := tat.NewTestWrapperGeneric(ta)
+
+	tw.AssertViewContains(t, "Start this book from a template?")
+	tw.Execute(t, func(ta *terminaccounting) {
+		assert.True(t, ta.showModal)
+	})
+
+	tw.SendText("j").Send(tea.KeyMsg{Type: tea.KeyEnter})
+
+	tw.AssertViewContains(t, "0110 Equipment")
+	tw.Execute(t, func(ta *terminaccounting) {
+		assert.False(t, ta.showModal)
+		assert.Equal(t, meta.LEDGERSAPP, ta.appManager.apps[ta.appManager.activeApp].Type())
+	})
+}
+
 func TestExecuteCommand_EmptyCommand(t *testing.T) {
 	DB := tat.SetupTestEnv(t)
 	tw := tat.NewTestWrapperGeneric(newTerminaccounting(DB))
@@ -340,9 +653,9 @@ func TestRepeatingMotionUnit(t *testing.T) {
 
 		tw.SendText("5gt")
 
-		// Starting at tab 0, 5 forward switches: 0→1→2→3→0→1 = tab 1
+		// Starting at tab 0, 5 forward switches: 0→1→2→3→4→0 = tab 0
 		tw.Execute(t, func(ta *terminaccounting) {
-			assert.Equal(t, 1, ta.appManager.activeApp)
+			assert.Equal(t, 0, ta.appManager.activeApp)
 		})
 	})
 
@@ -352,9 +665,9 @@ func TestRepeatingMotionUnit(t *testing.T) {
 
 		tw.SendText("3gT")
 
-		// Starting at tab 0, 3 backward switches: 0→3→2→1 = tab 1
+		// Starting at tab 0, 3 backward switches: 0→4→3→2 = tab 2
 		tw.Execute(t, func(ta *terminaccounting) {
-			assert.Equal(t, 1, ta.appManager.activeApp)
+			assert.Equal(t, 2, ta.appManager.activeApp)
 		})
 	})
 
@@ -364,9 +677,9 @@ func TestRepeatingMotionUnit(t *testing.T) {
 
 		tw.SendText("12gt")
 
-		// Starting at tab 0, 12 forward switches: 12 % 4 = 0, final tab = 0
+		// Starting at tab 0, 12 forward switches: 12 % 5 = 2, final tab = 2
 		tw.Execute(t, func(ta *terminaccounting) {
-			assert.Equal(t, 0, ta.appManager.activeApp)
+			assert.Equal(t, 2, ta.appManager.activeApp)
 		})
 	})
 
@@ -417,7 +730,7 @@ func TestSwitchApp(t *testing.T) {
 		expectedActiveApp int
 	}{
 		{"switch tab simple", []string{"gt"}, 1},
-		{"wrap backwards", []string{"gT", "gT"}, 3},
+		{"wrap backwards", []string{"gT", "gT"}, 4},
 		{"wrap forwards", []string{"gt"}, 0},
 	}
 
@@ -88,8 +88,8 @@ func (dv *accountsDetailView) metadata() metadata {
 	}
 
 	return metadata{
-		names:  []string{"Type", "Bank numbers"},
-		values: []string{dv.model.Type.String(), bankNumbersRendered},
+		names:  []string{"Type", "Bank numbers", "Currency"},
+		values: []string{dv.model.Type.String(), bankNumbersRendered, renderCurrency(dv.model.Currency)},
 	}
 }
 
@@ -136,7 +136,11 @@ func (dv *accountsDetailView) MotionSet() meta.Trie[tea.Msg] {
 }
 
 func (dv *accountsDetailView) CommandSet() meta.Trie[tea.Msg] {
-	return genericDetailViewCommandSet()
+	result := genericDetailViewCommandSet()
+
+	result.Insert(meta.Command(strings.Split("automatch", "")), meta.AutoMatchMsg{App: meta.ACCOUNTSAPP, Id: dv.modelId})
+
+	return result
 }
 
 func (dv *accountsDetailView) Reload() View {
@@ -191,8 +195,10 @@ func NewAccountsCreateView(DB *sqlx.DB) *accountsCreateView {
 	notesInput.FocusedStyle.CursorLine = notesFocusStyle
 	notesInput.FocusedStyle.LineNumber = notesFocusStyle
 
-	inputs := []any{nameInput, typeInput, bankNumbersInput, notesInput}
-	names := []string{"Name", "Type", "Bank numbers", "Notes"}
+	currencyInput := newCurrencyInput()
+
+	inputs := []any{nameInput, typeInput, bankNumbersInput, notesInput, currencyInput}
+	names := []string{"Name", "Type", "Bank numbers", "Notes", "Currency"}
 
 	return &accountsCreateView{
 		DB: DB,
@@ -214,12 +220,17 @@ func (cv *accountsCreateView) Update(message tea.Msg) (View, tea.Cmd) {
 		accountType := cv.inputManager.inputs[1].value().(database.AccountType)
 		bankNumbers := meta.CompileNotes(cv.inputManager.inputs[2].value().(string))
 		notes := meta.CompileNotes(cv.inputManager.inputs[3].value().(string))
+		currency, err := database.ParseCurrency(cv.inputManager.inputs[4].value().(string))
+		if err != nil {
+			return cv, meta.MessageCmd(err)
+		}
 
 		newAccount := database.Account{
 			Name:        name,
 			Type:        accountType,
 			BankNumbers: bankNumbers,
 			Notes:       notes,
+			Currency:    currency,
 		}
 
 		id, err := newAccount.Insert(cv.DB)
@@ -338,8 +349,10 @@ func NewAccountsUpdateView(DB *sqlx.DB, modelId int) *accountsUpdateView {
 	notesInput.FocusedStyle.CursorLine = notesFocusStyle
 	notesInput.FocusedStyle.LineNumber = notesFocusStyle
 
-	inputs := []any{nameInput, typeInput, bankNumbersInput, notesInput}
-	names := []string{"Name", "Type", "Bank numbers", "Notes"}
+	currencyInput := newCurrencyInput()
+
+	inputs := []any{nameInput, typeInput, bankNumbersInput, notesInput, currencyInput}
+	names := []string{"Name", "Type", "Bank numbers", "Notes", "Currency"}
 
 	return &accountsUpdateView{
 		DB: DB,
@@ -368,6 +381,7 @@ func (uv *accountsUpdateView) Update(message tea.Msg) (View, tea.Cmd) {
 		err := uv.inputManager.inputs[1].setValue(account.Type)
 		uv.inputManager.inputs[2].setValue(account.BankNumbers.Collapse())
 		uv.inputManager.inputs[3].setValue(account.Notes.Collapse())
+		uv.inputManager.inputs[4].setValue(account.Currency)
 
 		return uv, meta.MessageCmd(err)
 
@@ -382,6 +396,8 @@ func (uv *accountsUpdateView) Update(message tea.Msg) (View, tea.Cmd) {
 			startingValue = uv.startingValue.BankNumbers
 		case 3:
 			startingValue = uv.startingValue.Notes.Collapse()
+		case 4:
+			startingValue = uv.startingValue.Currency
 		default:
 			panic(fmt.Sprintf("unexpected activeInput: %d", uv.inputManager.activeInput))
 		}
@@ -395,6 +411,10 @@ func (uv *accountsUpdateView) Update(message tea.Msg) (View, tea.Cmd) {
 		accountType := uv.inputManager.inputs[1].value().(database.AccountType)
 		bankNumbers := meta.CompileNotes(uv.inputManager.inputs[2].value().(string))
 		notes := meta.CompileNotes(uv.inputManager.inputs[3].value().(string))
+		currency, err := database.ParseCurrency(uv.inputManager.inputs[4].value().(string))
+		if err != nil {
+			return uv, meta.MessageCmd(err)
+		}
 
 		account := database.Account{
 			Id:          uv.modelId,
@@ -402,9 +422,10 @@ func (uv *accountsUpdateView) Update(message tea.Msg) (View, tea.Cmd) {
 			Type:        accountType,
 			BankNumbers: bankNumbers,
 			Notes:       notes,
+			Currency:    currency,
 		}
 
-		err := account.Update(uv.DB)
+		err = account.Update(uv.DB)
 		if err != nil {
 			return uv, meta.MessageCmd(err)
 		}
@@ -593,11 +614,11 @@ func (dv *accountsDeleteView) Reload() View {
 }
 
 func (dv *accountsDeleteView) inputValues() []string {
-	return []string{dv.model.Name, dv.model.Type.String(), dv.model.BankNumbers.Collapse(), dv.model.Notes.Collapse()}
+	return []string{dv.model.Name, dv.model.Type.String(), dv.model.BankNumbers.Collapse(), dv.model.Notes.Collapse(), renderCurrency(dv.model.Currency)}
 }
 
 func (dv *accountsDeleteView) inputNames() []string {
-	return []string{"Name", "Type", "Bank numbers", "Notes"}
+	return []string{"Name", "Type", "Bank numbers", "Notes", "Currency"}
 }
 
 func (dv *accountsDeleteView) makeGoToDetailViewCmd() tea.Cmd {
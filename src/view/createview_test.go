@@ -151,6 +151,46 @@ func TestJournalsCreateView_Commit(t *testing.T) {
 	assert.IsType(t, meta.SwitchAppViewMsg{}, tw.LastCmdResults[1])
 }
 
+func TestInvoicesCreateView_Commit(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	_, err := database.Account{Name: "Supplier", Type: database.CREDITOR}.Insert(DB)
+	require.NoError(t, err)
+	customerId, err := database.Account{Name: "Customer", Type: database.DEBTOR}.Insert(DB)
+	require.NoError(t, err)
+	_, err = (&database.Journal{Name: "Sales", Type: database.INCOMEJOURNAL}).Insert(DB)
+	require.NoError(t, err)
+	_, err = (&database.Ledger{Name: "Revenue", Type: database.INCOMELEDGER}).Insert(DB)
+	require.NoError(t, err)
+
+	v := NewInvoicesCreateView(DB)
+	tw := tat.NewTestWrapperSpecific(View(v), meta.NotificationMessageMsg{
+		Message: "Successfully created draft invoice 1, post it with :post from its detail view",
+	}, meta.SwitchAppViewMsg{
+		ViewType: meta.UPDATEVIEWTYPE,
+		Data:     1,
+	})
+
+	// Only the debtor can be picked as customer
+	im := v.getInputManager()
+	assert.Equal(t, &database.Account{Id: customerId, Name: "Customer", Type: database.DEBTOR}, im.inputs[INVOICESACCOUNTINPUT].value())
+
+	require.NoError(t, im.inputs[INVOICESDATEINPUT].setValue("24-06-01"))
+	require.NoError(t, im.inputs[INVOICESLINESINPUT].setValue("4 x 25.00 Lessons"))
+
+	tw.Send(meta.CommitMsg{})
+
+	invoices, err := database.SelectInvoices(DB)
+	require.NoError(t, err)
+	require.Len(t, invoices, 1)
+	assert.Equal(t, customerId, invoices[0].Account)
+	assert.Equal(t, database.DRAFTINVOICE, invoices[0].Status)
+	assert.Equal(t, "24-07-01", invoices[0].DueDate.String())
+	assert.Equal(t, database.InvoiceLines{{Description: "Lessons", Quantity: 4, UnitPrice: 2500}}, invoices[0].Lines)
+
+	require.Len(t, tw.LastCmdResults, 2)
+}
+
 func TestEntryCreateView_Rendering(t *testing.T) {
 	DB := tat.SetupTestEnv(t)
 	cv := NewEntryCreateView(DB)
@@ -274,24 +314,54 @@ func TestEntryCreateView_Commit_NoJournal(t *testing.T) {
 func TestEntryCreateView_Commit_UnbalancedRows(t *testing.T) {
 	DB := tat.SetupTestEnv(t)
 
+	ledger := database.Ledger{Name: "Test Ledger", Type: database.EXPENSELEDGER}
+	ledgerId, err := ledger.Insert(DB)
+	require.NoError(t, err)
+	ledger.Id = ledgerId
+
 	journal := database.Journal{Name: "Test Journal", Type: database.GENERALJOURNAL}
 	journalId, err := journal.Insert(DB)
 	require.NoError(t, err)
 	journal.Id = journalId
 
+	unbalancedErr := errors.New("entry is unbalanced, its rows total 20.00 instead of 0.00; balance it, or use :suspense to save it unfinished")
+
 	cv := NewEntryCreateView(DB)
 	tw := tat.NewTestWrapperSpecific(View(cv),
-		errors.New("entry has nonzero total value 20.00"),
+		unbalancedErr,
+		meta.NotificationMessageMsg{Message: "Marked entry as suspense, it can be saved unbalanced"},
+		meta.NotificationMessageMsg{Message: "Successfully created Entry \"1\""},
+		meta.SwitchAppViewMsg{ViewType: meta.UPDATEVIEWTYPE, Data: 1},
 	)
 
 	cv.journalInput.SetValue(journal)
+	cv.entryRowsManager.rowMutators[0].ledgerInput.SetValue(ledger)
 	cv.entryRowsManager.rowMutators[0].debitInput.SetValue("50.00")
+	cv.entryRowsManager.rowMutators[1].ledgerInput.SetValue(ledger)
 	cv.entryRowsManager.rowMutators[1].creditInput.SetValue("30.00")
 
-	tw.Send(meta.CommitMsg{})
+	t.Run("rejected", func(t *testing.T) {
+		tw.Send(meta.CommitMsg{})
 
-	require.Len(t, tw.LastCmdResults, 1)
-	assert.Error(t, tw.LastCmdResults[0].(error))
+		require.Len(t, tw.LastCmdResults, 1)
+		assert.EqualError(t, tw.LastCmdResults[0].(error), unbalancedErr.Error())
+
+		entries, err := database.SelectEntries(DB)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("suspense", func(t *testing.T) {
+		tw.Send(ToggleSuspenseMsg{})
+		tw.AssertViewContains(t, "SUSPENSE")
+
+		tw.Send(meta.CommitMsg{})
+
+		entries, err := database.SelectEntries(DB)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.True(t, entries[0].Suspense)
+	})
 }
 
 func TestEntryCreateView_CreateRow(t *testing.T) {
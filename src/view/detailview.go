@@ -127,34 +127,149 @@ func genericDetailViewUpdate(gdv genericDetailView, message tea.Msg) (View, tea.
 		return gdv, nil
 
 	case meta.CommitMsg:
+		if viewer.statement != nil {
+			return gdv, commitStatement(gdv)
+		}
+
 		if !viewer.rowsAreChanged() {
 			return gdv, meta.MessageCmd(meta.NotificationMessageMsg{Message: "there are no changes in reconciliation to commit"})
 		}
 
-		total := database.CalculateTotal(viewer.getReconciledRows())
-		if total != 0 {
-			return gdv, meta.MessageCmd(fmt.Errorf("total of reconciled rows not 0 but %s", total))
+		for _, row := range viewer.getUnmarkedRows() {
+			if row.Reconciliation != nil {
+				return gdv, meta.MessageCmd(fmt.Errorf("a row is matched in group %d, use :unmatch to undo the whole group", *row.Reconciliation))
+			}
+		}
+
+		// Partially matched rows get reconciled along with the rows they were matched with
+		groupRows := viewer.getGroupRows(viewer.getMarkedRows())
+
+		// Every ledger, and every account on the accounts ledger, gets a group of its own that has to total 0
+		err := database.CheckSettled(slices.Concat(viewer.getMarkedRows(), groupRows))
+		if err != nil {
+			return gdv, meta.MessageCmd(err)
+		}
+
+		for _, row := range groupRows {
+			row.Reconciled = true
 		}
 
 		changed, err := database.SetReconciled(gdv.getDB(), viewer.rows)
 		if err != nil {
+			for _, row := range groupRows {
+				row.Reconciled = false
+			}
+
 			return gdv, meta.MessageCmd(err)
 		}
 
 		notification := meta.NotificationMessageMsg{Message: fmt.Sprintf("set reconciled status, updated %d rows", changed)}
 
-		// Reset dv.originalRows
-		for i, row := range viewer.rows {
-			viewer.originalRows[i] = *row
-		}
+		viewer.resetOriginalRows()
 
 		return gdv, meta.MessageCmd(notification)
 
+	case meta.MatchRowsMsg:
+		if !gdv.getCanReconcile() {
+			return gdv, meta.MessageCmd(errors.New("reconciling is disabled in this view"))
+		}
+
+		if len(viewer.getUnmarkedRows()) != 0 {
+			return gdv, meta.MessageCmd(errors.New("there are rows no longer marked as reconciled, :write them before matching"))
+		}
+
+		markedRows := viewer.getMarkedRows()
+		if len(markedRows) == 0 {
+			return gdv, meta.MessageCmd(errors.New("there are no rows to match, mark them with enter first"))
+		}
+
+		groups, err := database.MatchRows(gdv.getDB(), slices.Concat(markedRows, viewer.getGroupRows(markedRows)))
+		if err != nil {
+			return gdv, meta.MessageCmd(err)
+		}
+
+		viewer.resetOriginalRows()
+
+		var summaries []string
+		for _, group := range groups {
+			summaries = append(summaries, group.String())
+		}
+
+		return gdv, meta.MessageCmd(meta.NotificationMessageMsg{Message: "matched rows, " + strings.Join(summaries, ", ")})
+
+	case meta.AutoMatchMsg:
+		if !gdv.getCanReconcile() {
+			return gdv, meta.MessageCmd(errors.New("reconciling is disabled in this view"))
+		}
+
+		if viewer.rowsAreChanged() || viewer.statement != nil {
+			return gdv, meta.MessageCmd(errors.New("there are uncommitted changes in reconciliation, :write them before auto-matching"))
+		}
+
+		return gdv, meta.MessageCmd(meta.ShowAutoMatchMsg(message))
+
+	case meta.UnmatchRowsMsg:
+		if !gdv.getCanReconcile() {
+			return gdv, meta.MessageCmd(errors.New("reconciling is disabled in this view"))
+		}
+
+		if viewer.rowsAreChanged() {
+			return gdv, meta.MessageCmd(errors.New("there are uncommitted changes in reconciliation, :write them before unmatching"))
+		}
+
+		activeEntryRow := viewer.getActiveRow()
+		if activeEntryRow == nil {
+			return gdv, meta.MessageCmd(errors.New("there are no rows to unmatch"))
+		}
+
+		if activeEntryRow.Reconciliation == nil {
+			return gdv, meta.MessageCmd(errors.New("this row isn't matched with any other rows"))
+		}
+
+		group := *activeEntryRow.Reconciliation
+		changed, err := database.UnmatchGroup(gdv.getDB(), group)
+		if err != nil {
+			return gdv, meta.MessageCmd(err)
+		}
+
+		for _, row := range viewer.rows {
+			if row.Reconciliation != nil && *row.Reconciliation == group {
+				row.Reconciliation = nil
+				row.Reconciled = false
+			}
+		}
+
+		viewer.resetOriginalRows()
+
+		return gdv, meta.MessageCmd(meta.NotificationMessageMsg{Message: fmt.Sprintf("unmatched group %d, unreconciled %d rows", group, changed)})
+
 	default:
 		panic(fmt.Sprintf("unexpected tea.Msg: %#v", message))
 	}
 }
 
+// Saves the statement being reconciled against, once the ticked rows add up to its closing balance
+func commitStatement(gdv genericDetailView) tea.Cmd {
+	viewer := gdv.getViewer()
+
+	difference := viewer.statement.Difference(viewer.rows)
+	if difference != 0 {
+		return meta.MessageCmd(fmt.Errorf("the reconciled rows are %s off from the statement's closing balance", difference))
+	}
+
+	statement, changed, err := database.SaveStatement(gdv.getDB(), *viewer.statement, viewer.rows)
+	if err != nil {
+		return meta.MessageCmd(err)
+	}
+
+	viewer.statement = nil
+	viewer.resetOriginalRows()
+
+	return meta.MessageCmd(meta.NotificationMessageMsg{
+		Message: fmt.Sprintf("saved the statement of %s as statement %d, updated %d rows", statement.Date, statement.Id, changed),
+	})
+}
+
 func genericDetailViewView(gdv genericDetailView) string {
 	var result strings.Builder
 
@@ -193,6 +308,8 @@ func genericDetailViewCommandSet() meta.Trie[tea.Msg] {
 	var result meta.Trie[tea.Msg]
 
 	result.Insert(meta.Command(strings.Split("write", "")), meta.CommitMsg{})
+	result.Insert(meta.Command(strings.Split("match", "")), meta.MatchRowsMsg{})
+	result.Insert(meta.Command(strings.Split("unmatch", "")), meta.UnmatchRowsMsg{})
 
 	return result
 }
@@ -239,6 +356,9 @@ type entryRowViewer struct {
 	// If nil, no filter
 	filterQuery *string
 
+	// The bank statement being reconciled against, nil when reconciling rows against each other
+	statement *database.Statement
+
 	showReconciled bool
 
 	headers   []string
@@ -251,7 +371,7 @@ func newEntryRowViewer(colour lipgloss.Color) *entryRowViewer {
 
 		viewport: viewport.New(0, 0),
 
-		headers: []string{"Date", "Ledger", "Account", "Description", "Debit", "Credit", "Reconciled"},
+		headers: []string{"Date", "Ledger", "Account", "Description", "Debit", "Credit", "Group", "Reconciled"},
 	}
 
 	return result
@@ -346,7 +466,7 @@ func (erv *entryRowViewer) View() string {
 
 	erv.setViewportContent()
 
-	result.WriteString(erv.renderRow(erv.headers, true, false))
+	result.WriteString(erv.renderRow(erv.columnHeaders(), true, false))
 
 	result.WriteString("\n")
 
@@ -363,9 +483,24 @@ func (erv *entryRowViewer) View() string {
 
 	result.WriteString(fmt.Sprintf("Total: %s", database.CalculateTotal(erv.rows)))
 
+	for _, currencyTotal := range database.CalculateCurrencyTotals(erv.rows) {
+		result.WriteString(fmt.Sprintf(", %s %s booked as %s", currencyTotal.Currency, currencyTotal.Foreign, currencyTotal.Booked))
+	}
+
 	result.WriteString("\n")
 
-	if erv.rowsAreChanged() {
+	if erv.statement != nil {
+		difference := erv.statement.Difference(erv.rows)
+
+		differenceRendered := difference.String()
+		if difference == 0 {
+			differenceRendered = lipgloss.NewStyle().Foreground(lipgloss.Color("#00FF00")).Render(differenceRendered)
+		}
+
+		result.WriteString(fmt.Sprintf("Statement of %s closing at %s, difference: %s", erv.statement.Date, erv.statement.ClosingBalance, differenceRendered))
+
+		result.WriteString("\n")
+	} else if erv.rowsAreChanged() {
 		totalReconciled := database.CalculateTotal(erv.getReconciledRows())
 
 		var totalReconciledRendered string
@@ -392,10 +527,11 @@ func (erv *entryRowViewer) View() string {
 
 func (erv *entryRowViewer) calculateColumnWidths() {
 	dateWidth := 10 // This is simply the width of a date field
+	groupWidth := len("Group")
 	reconciledWidth := len("Reconciled")
 
 	var colWidths []int
-	remainingWidth := erv.width - dateWidth - reconciledWidth
+	remainingWidth := erv.width - dateWidth - groupWidth - reconciledWidth
 	descriptionWidth := remainingWidth / 3
 
 	// If showing scrollbar
@@ -406,14 +542,35 @@ func (erv *entryRowViewer) calculateColumnWidths() {
 		erv.viewport.Width = erv.width
 	}
 
-	// -12 because of the 2-wide padding between columns, 6x
-	// /4 because there are four other columns
-	othersWidth := (remainingWidth - descriptionWidth - 12) / 4
-	colWidths = []int{dateWidth, othersWidth, othersWidth, descriptionWidth, othersWidth, othersWidth, reconciledWidth}
+	if erv.hasTags() {
+		// -16 because of the 2-wide padding between columns, 8x
+		// /5 because there are five other columns
+		othersWidth := (remainingWidth - descriptionWidth - 16) / 5
+		colWidths = []int{dateWidth, othersWidth, othersWidth, descriptionWidth, othersWidth, othersWidth, othersWidth, groupWidth, reconciledWidth}
+	} else {
+		// -14 because of the 2-wide padding between columns, 7x
+		// /4 because there are four other columns
+		othersWidth := (remainingWidth - descriptionWidth - 14) / 4
+		colWidths = []int{dateWidth, othersWidth, othersWidth, descriptionWidth, othersWidth, othersWidth, groupWidth, reconciledWidth}
+	}
 
 	erv.colWidths = colWidths
 }
 
+// The tags column is only shown when there are tags to show
+func (erv *entryRowViewer) hasTags() bool {
+	return slices.ContainsFunc(erv.rows, func(row *database.EntryRow) bool { return row.Tags != "" })
+}
+
+func (erv *entryRowViewer) columnHeaders() []string {
+	if !erv.hasTags() {
+		return erv.headers
+	}
+
+	// Before the group and reconciled columns
+	return slices.Insert(slices.Clone(erv.headers), len(erv.headers)-2, "Tags")
+}
+
 // Takes the rows, and depending on state, updates the shownRows and viewRows based off of them
 func (erv *entryRowViewer) updateViewRows() {
 	if erv.showReconciled {
@@ -437,13 +594,26 @@ func (erv *entryRowViewer) updateViewRows() {
 		if row.Value == 0 {
 			panic(fmt.Sprintf("row %#v had zero debit and credit?", row))
 		}
+		rendered := row.Value.Abs().String()
+		if row.ForeignValue != nil {
+			rendered += fmt.Sprintf(" (%s %s)", row.Currency, row.ForeignValue.Abs())
+		}
+
 		if row.Value > 0 {
-			viewRow = append(viewRow, row.Value.String(), "")
+			viewRow = append(viewRow, rendered, "")
 		} else {
-			viewRow = append(viewRow, "", (-row.Value).String())
+			viewRow = append(viewRow, "", rendered)
 		}
 
-		viewRow = append(viewRow, renderBoolean(row.Reconciled))
+		if erv.hasTags() {
+			viewRow = append(viewRow, string(row.Tags))
+		}
+
+		group := ""
+		if row.Reconciliation != nil {
+			group = fmt.Sprintf("%d", *row.Reconciliation)
+		}
+		viewRow = append(viewRow, group, renderBoolean(row.Reconciled))
 
 		viewRows = append(viewRows, viewRow)
 	}
@@ -459,9 +629,11 @@ func getRowLedgerAndAccount(row *database.EntryRow,
 		return ledger.Id == row.Ledger
 	})
 	if availableLedgerIndex == -1 {
-		panic(fmt.Sprintf("ledger for row %#v wasn't found in cache", row))
+		// Possible in books where the ledger got deleted anyway, see `:check`
+		ledger = database.Ledger{Id: row.Ledger, Name: fmt.Sprintf("<deleted ledger %d>", row.Ledger)}
+	} else {
+		ledger = availableLedgers[availableLedgerIndex]
 	}
-	ledger = availableLedgers[availableLedgerIndex]
 
 	var account *database.Account
 	if row.Account == nil {
@@ -472,10 +644,10 @@ func getRowLedgerAndAccount(row *database.EntryRow,
 		})
 
 		if availableAccountIndex == -1 {
-			panic(fmt.Sprintf("account for row %#v wasn't found in cache", row))
+			account = &database.Account{Id: *row.Account, Name: fmt.Sprintf("<deleted account %d>", *row.Account)}
+		} else {
+			account = &availableAccounts[availableAccountIndex]
 		}
-
-		account = &availableAccounts[availableAccountIndex]
 	}
 
 	return ledger, account
@@ -517,6 +689,12 @@ func filterRows(rows []*database.EntryRow, filter *string) []*database.EntryRow
 			result = append(result, row)
 			continue
 		}
+
+		// Matches tags, and dimensions like "project:x" as a whole
+		if slices.ContainsFunc(row.Tags.List(), func(tag string) bool { return strings.Contains(tag, *filter) }) {
+			result = append(result, row)
+			continue
+		}
 	}
 
 	return result
@@ -593,11 +771,13 @@ func (erv *entryRowViewer) renderRow(values []string, isHeader, highlight bool)
 	return result.String()
 }
 
+// The reconciled rows that settle each other.
+// Rows reconciled against a statement add up to its closing balance instead, so they're left out.
 func (erv *entryRowViewer) getReconciledRows() []*database.EntryRow {
 	var result []*database.EntryRow
 
 	for _, row := range erv.rows {
-		if row.Reconciled {
+		if row.Reconciled && row.Statement == nil {
 			result = append(result, row)
 		}
 	}
@@ -624,3 +804,63 @@ func (erv *entryRowViewer) rowsAreChanged() bool {
 		func(row *database.EntryRow, originalRow database.EntryRow) bool { return *row == originalRow },
 	)
 }
+
+// The rows marked as reconciled since they were last committed
+func (erv *entryRowViewer) getMarkedRows() []*database.EntryRow {
+	var result []*database.EntryRow
+
+	for i, row := range erv.rows {
+		if row.Reconciled && !erv.originalRows[i].Reconciled {
+			result = append(result, row)
+		}
+	}
+
+	return result
+}
+
+// The rows no longer marked as reconciled since they were last committed
+func (erv *entryRowViewer) getUnmarkedRows() []*database.EntryRow {
+	var result []*database.EntryRow
+
+	for i, row := range erv.rows {
+		if !row.Reconciled && erv.originalRows[i].Reconciled {
+			result = append(result, row)
+		}
+	}
+
+	return result
+}
+
+// The other unreconciled rows in the reconciliation groups of the given rows, they have to be matched together
+func (erv *entryRowViewer) getGroupRows(rows []*database.EntryRow) []*database.EntryRow {
+	var groups []int
+	for _, row := range rows {
+		if row.Reconciliation != nil {
+			groups = append(groups, *row.Reconciliation)
+		}
+	}
+
+	var result []*database.EntryRow
+	for _, row := range erv.rows {
+		if row.Reconciled || row.Reconciliation == nil || slices.Contains(rows, row) {
+			continue
+		}
+
+		if slices.Contains(groups, *row.Reconciliation) {
+			result = append(result, row)
+		}
+	}
+
+	return result
+}
+
+// After committing, the rows as they are now are the ones stored
+func (erv *entryRowViewer) resetOriginalRows() {
+	for i, row := range erv.rows {
+		erv.originalRows[i] = *row
+	}
+
+	erv.updateViewRows()
+	erv.activeRow = max(0, min(erv.activeRow, len(erv.shownRows)-1))
+	erv.scrollViewport()
+}
@@ -1,6 +1,8 @@
 package view
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -27,7 +29,7 @@ func TestGenericDetailView_DataLoaded(t *testing.T) {
 	jID, err := journal.Insert(DB)
 	require.NoError(t, err)
 
-	entry := database.Entry{Journal: jID}
+	entry := database.Entry{Journal: jID, Suspense: true}
 	rows := []database.EntryRow{
 		{
 			Date:    database.Date(time.Now()),
@@ -127,6 +129,206 @@ func TestGenericDetailView_Reconciliation(t *testing.T) {
 	assert.True(t, storedRows[0].Reconciled)
 }
 
+func TestLedgersDetailView_ReconcilePerAccount(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	debtors := database.Ledger{Name: "Debtors", Type: database.ASSETLEDGER, IsAccounts: true}
+	debtorsId, err := debtors.Insert(DB)
+	require.NoError(t, err)
+
+	first, err := database.Account{Name: "First", Type: database.DEBTOR}.Insert(DB)
+	require.NoError(t, err)
+	second, err := database.Account{Name: "Second", Type: database.DEBTOR}.Insert(DB)
+	require.NoError(t, err)
+
+	journal := database.Journal{Name: "Test Journal", Type: database.GENERALJOURNAL}
+	jID, err := journal.Insert(DB)
+	require.NoError(t, err)
+
+	_, err = database.Entry{Journal: jID}.Insert(DB, []database.EntryRow{
+		{Date: database.Date(time.Now()), Ledger: debtorsId, Account: &first, Value: 10000},
+		{Date: database.Date(time.Now()), Ledger: debtorsId, Account: &second, Value: -10000},
+	})
+	require.NoError(t, err)
+	require.NoError(t, database.UpdateCache(DB))
+
+	dv := NewLedgersDetailView(DB, debtorsId)
+	tw := tat.NewTestWrapperSpecific(View(dv),
+		fmt.Errorf("the rows of First (%d) on Debtors (%d) total 100.00 instead of 0.00", first, debtorsId),
+	)
+
+	// The rows total 0 together, but settle nothing within either account
+	tw.Send(meta.ReconcileMsg{}, meta.NavigateMsg{Direction: meta.DOWN}, meta.ReconcileMsg{}, meta.CommitMsg{})
+	require.Len(t, tw.LastCmdResults, 1)
+	assert.EqualError(t, tw.LastCmdResults[0].(error), fmt.Sprintf("the rows of First (%d) on Debtors (%d) total 100.00 instead of 0.00", first, debtorsId))
+
+	storedRows, err := database.SelectRowsByLedger(DB, debtorsId)
+	require.NoError(t, err)
+	for _, row := range storedRows {
+		assert.False(t, row.Reconciled)
+	}
+}
+
+func TestLedgersDetailView_ParentCantReconcile(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	bank := database.Ledger{Name: "Bank", Type: database.ASSETLEDGER}
+	bankId, err := bank.Insert(DB)
+	require.NoError(t, err)
+
+	checking := database.Ledger{Name: "Checking", Type: database.ASSETLEDGER, Parent: &bankId}
+	checkingId, err := checking.Insert(DB)
+	require.NoError(t, err)
+	savings := database.Ledger{Name: "Savings", Type: database.ASSETLEDGER, Parent: &bankId}
+	savingsId, err := savings.Insert(DB)
+	require.NoError(t, err)
+
+	journal := database.Journal{Name: "Test Journal", Type: database.GENERALJOURNAL}
+	jID, err := journal.Insert(DB)
+	require.NoError(t, err)
+
+	// A transfer between the sub-ledgers totals 0 on the parent, but not on either sub-ledger
+	_, err = database.Entry{Journal: jID}.Insert(DB, []database.EntryRow{
+		{Date: database.Date(time.Now()), Ledger: checkingId, Value: 10000},
+		{Date: database.Date(time.Now()), Ledger: savingsId, Value: -10000},
+	})
+	require.NoError(t, err)
+	require.NoError(t, database.UpdateCache(DB))
+
+	dv := NewLedgersDetailView(DB, bankId)
+	tw := tat.NewTestWrapperSpecific(View(dv), errors.New("reconciling is disabled in this view"))
+
+	tw.Execute(t, func(view View) {
+		v := view.(*ledgersDetailView)
+
+		require.Len(t, v.viewer.rows, 2, "the rows of the sub-ledgers are shown")
+		assert.False(t, v.getCanReconcile())
+	})
+
+	tw.Send(meta.ReconcileMsg{})
+	require.Len(t, tw.LastCmdResults, 1)
+	assert.EqualError(t, tw.LastCmdResults[0].(error), "reconciling is disabled in this view")
+
+	child := NewLedgersDetailView(DB, checkingId)
+	tat.NewTestWrapperSpecific(View(child)).Execute(t, func(view View) {
+		assert.True(t, view.(*ledgersDetailView).getCanReconcile())
+	})
+}
+
+func TestGenericDetailView_MatchAndUnmatch(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	ledger := database.Ledger{Name: "Test Ledger", Type: database.ASSETLEDGER, IsAccounts: true}
+	lID, err := ledger.Insert(DB)
+	require.NoError(t, err)
+
+	account := database.Account{Name: "Test Account", Type: database.DEBTOR}
+	aID, err := account.Insert(DB)
+	require.NoError(t, err)
+
+	journal := database.Journal{Name: "Test Journal", Type: database.GENERALJOURNAL}
+	jID, err := journal.Insert(DB)
+	require.NoError(t, err)
+
+	// An invoice and a partial payment of it
+	entry := database.Entry{Journal: jID, Suspense: true}
+	_, err = entry.Insert(DB, []database.EntryRow{
+		{Date: database.Date(time.Now()), Ledger: lID, Account: &aID, Value: 10000},
+		{Date: database.Date(time.Now()), Ledger: lID, Account: &aID, Value: -6000},
+	})
+	require.NoError(t, err)
+	require.NoError(t, database.UpdateCache(DB))
+
+	dv := NewAccountsDetailView(DB, aID)
+	tw := tat.NewTestWrapperSpecific(View(dv),
+		meta.NotificationMessageMsg{Message: "matched rows, group 1 of 2 rows is partially matched, 40.00 is still open"},
+		meta.NotificationMessageMsg{Message: "unmatched group 1, unreconciled 2 rows"},
+		errors.New("this row isn't matched with any other rows"),
+	)
+
+	tw.Send(meta.UnmatchRowsMsg{})
+	require.Len(t, tw.LastCmdResults, 1)
+	assert.EqualError(t, tw.LastCmdResults[0].(error), "this row isn't matched with any other rows")
+
+	tw.Send(meta.ReconcileMsg{}, meta.NavigateMsg{Direction: meta.DOWN}, meta.ReconcileMsg{}, meta.MatchRowsMsg{})
+
+	// A partial match stays unreconciled, but shows its group
+	tw.Execute(t, func(view View) {
+		v := view.(*accountsDetailView)
+
+		require.Len(t, v.viewer.shownRows, 2)
+		assert.False(t, v.viewer.rowsAreChanged())
+		for _, row := range v.viewer.rows {
+			assert.False(t, row.Reconciled)
+			require.NotNil(t, row.Reconciliation)
+			assert.Equal(t, 1, *row.Reconciliation)
+		}
+	})
+	tw.AssertViewContains(t, "Group")
+
+	tw.Send(meta.UnmatchRowsMsg{})
+
+	storedRows, err := database.SelectRowsByAccount(DB, aID)
+	require.NoError(t, err)
+	for _, row := range storedRows {
+		assert.Nil(t, row.Reconciliation)
+	}
+}
+
+func TestLedgersDetailView_Statement(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	bank := database.Ledger{Name: "Bank", Type: database.ASSETLEDGER}
+	bankId, err := bank.Insert(DB)
+	require.NoError(t, err)
+
+	sales := database.Ledger{Name: "Sales", Type: database.INCOMELEDGER}
+	salesId, err := sales.Insert(DB)
+	require.NoError(t, err)
+
+	journal := database.Journal{Name: "Test Journal", Type: database.GENERALJOURNAL}
+	jID, err := journal.Insert(DB)
+	require.NoError(t, err)
+
+	_, err = database.Entry{Journal: jID}.Insert(DB, []database.EntryRow{
+		{Date: database.Date(time.Now()), Ledger: bankId, Value: 10000},
+		{Date: database.Date(time.Now()), Ledger: salesId, Value: -10000},
+	})
+	require.NoError(t, err)
+	require.NoError(t, database.UpdateCache(DB))
+
+	dv := NewLedgersDetailView(DB, bankId)
+	tw := tat.NewTestWrapperSpecific(View(dv),
+		meta.NotificationMessageMsg{Message: "reconciling against the statement of 24-03-31, tick its rows with enter and :write once the difference is 0"},
+		meta.NotificationMessageMsg{Message: "saved the statement of 24-03-31 as statement 1, updated 1 rows"},
+		errors.New("the reconciled rows are 100.00 off from the statement's closing balance"),
+	)
+
+	tw.Send(meta.StartStatementMsg{Date: "24-03-31", Balance: "100"})
+	tw.AssertViewContains(t, "Statement of 24-03-31 closing at 100.00, difference: 100.00")
+
+	tw.Send(meta.CommitMsg{})
+	require.Len(t, tw.LastCmdResults, 1)
+	assert.EqualError(t, tw.LastCmdResults[0].(error), "the reconciled rows are 100.00 off from the statement's closing balance")
+
+	tw.Send(meta.ReconcileMsg{})
+	tw.AssertViewContains(t, "difference: 0.00")
+
+	tw.Send(meta.CommitMsg{})
+
+	tw.Execute(t, func(view View) {
+		v := view.(*ledgersDetailView)
+
+		assert.Nil(t, v.viewer.statement)
+		assert.False(t, v.viewer.rowsAreChanged())
+	})
+
+	statements, err := database.SelectStatements(DB, bankId)
+	require.NoError(t, err)
+	require.Len(t, statements, 1)
+	assert.Equal(t, 1, statements[0].Rows)
+}
+
 func TestGenericDetailView_ToggleShowReconciled(t *testing.T) {
 	DB := tat.SetupTestEnv(t)
 
@@ -142,7 +344,7 @@ func TestGenericDetailView_ToggleShowReconciled(t *testing.T) {
 	jID, err := journal.Insert(DB)
 	require.NoError(t, err)
 
-	entry := database.Entry{Journal: jID}
+	entry := database.Entry{Journal: jID, Suspense: true}
 	rows := []database.EntryRow{
 		{
 			Date:       database.Date(time.Now()),
@@ -197,7 +399,7 @@ func TestGenericDetailView_ToggleShowReconciled_AllRowsReconciled(t *testing.T)
 	jID, err := journal.Insert(DB)
 	require.NoError(t, err)
 
-	entry := database.Entry{Journal: jID}
+	entry := database.Entry{Journal: jID, Suspense: true}
 	rows := []database.EntryRow{
 		{
 			Date:       database.Date(time.Now()),
@@ -249,7 +451,7 @@ func TestGenericDetailView_Navigation(t *testing.T) {
 	jID, err := journal.Insert(DB)
 	require.NoError(t, err)
 
-	entry := database.Entry{Journal: jID}
+	entry := database.Entry{Journal: jID, Suspense: true}
 	rows := []database.EntryRow{
 		{
 			Date:    database.Date(time.Now()),
@@ -355,7 +557,7 @@ func TestJournalsDetailView_Navigation(t *testing.T) {
 
 	// Insert 3 entries
 	for i := 0; i < 3; i++ {
-		entry := database.Entry{Journal: jID}
+		entry := database.Entry{Journal: jID, Suspense: true}
 		_, err = entry.Insert(DB, rows)
 		require.NoError(t, err)
 	}
@@ -420,11 +622,11 @@ func TestJournalsDetailView_Filter(t *testing.T) {
 		},
 	}
 
-	entry1 := database.Entry{Journal: jID, Notes: []string{"Apple"}}
+	entry1 := database.Entry{Journal: jID, Suspense: true, Notes: []string{"Apple"}}
 	_, err = entry1.Insert(DB, rows)
 	require.NoError(t, err)
 
-	entry2 := database.Entry{Journal: jID, Notes: []string{"Banana"}}
+	entry2 := database.Entry{Journal: jID, Suspense: true, Notes: []string{"Banana"}}
 	_, err = entry2.Insert(DB, rows)
 	require.NoError(t, err)
 
@@ -451,3 +653,93 @@ func TestJournalsDetailView_Filter(t *testing.T) {
 		assert.Len(t, v.listModel.VisibleItems(), 2)
 	})
 }
+
+func TestEntryDetailView_DeletedReferences(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	// Foreign keys aren't enforced in the test database, so this is what a corrupted book looks like
+	_, err := DB.Exec(`
+		INSERT INTO entries (journal, notes, suspense) VALUES (5, '[]', 1);
+		INSERT INTO entryrows (entry, date, ledger, account, description, document, value, reconciled)
+			VALUES (1, '2024-01-01', 6, 7, 'orphan', NULL, 100, 0);`)
+	require.NoError(t, err)
+
+	dv := NewEntriesDetailView(DB, 1)
+	tw := tat.NewTestWrapperSpecific(View(dv))
+
+	tw.AssertViewContains(t, "<deleted journal 5>")
+	// The columns are too narrow to show the full names
+	tw.AssertViewContains(t, "<deleted…")
+
+	accountId := 7
+	ledger, account := getRowLedgerAndAccount(&database.EntryRow{Ledger: 6, Account: &accountId}, nil, nil)
+	assert.Equal(t, "<deleted ledger 6>", ledger.Name)
+	require.NotNil(t, account)
+	assert.Equal(t, "<deleted account 7>", account.Name)
+}
+
+func TestEntryDetailView_LockedPeriod(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	ledger := database.Ledger{Name: "Test Ledger", Type: database.ASSETLEDGER}
+	lID, err := ledger.Insert(DB)
+	require.NoError(t, err)
+
+	journal := database.Journal{Name: "Test Journal", Type: database.GENERALJOURNAL}
+	jID, err := journal.Insert(DB)
+	require.NoError(t, err)
+
+	date, err := database.ToDate("24-02-10")
+	require.NoError(t, err)
+
+	entry := database.Entry{Journal: jID}
+	eID, err := entry.Insert(DB, []database.EntryRow{
+		{Date: date, Ledger: lID, Value: 100},
+		{Date: date, Ledger: lID, Value: -100},
+	})
+	require.NoError(t, err)
+	require.NoError(t, database.UpdateCache(DB))
+
+	tw := tat.NewTestWrapperSpecific(View(NewEntriesDetailView(DB, eID)))
+	tw.AssertViewContains(t, "Period: open")
+
+	period, err := database.ParsePeriod("2024-02")
+	require.NoError(t, err)
+	require.NoError(t, database.LockPeriod(DB, period))
+
+	tw.AssertViewContains(t, "Period: LOCKED (2024-02)")
+}
+
+func TestLedgersDetailView_Budget(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	ledger := database.Ledger{Name: "Groceries", Type: database.EXPENSELEDGER}
+	lID, err := ledger.Insert(DB)
+	require.NoError(t, err)
+	bank := database.Ledger{Name: "Bank", Type: database.ASSETLEDGER}
+	bID, err := bank.Insert(DB)
+	require.NoError(t, err)
+
+	journal := database.Journal{Name: "Test Journal", Type: database.GENERALJOURNAL}
+	jID, err := journal.Insert(DB)
+	require.NoError(t, err)
+
+	today := *database.Today()
+	entry := database.Entry{Journal: jID}
+	_, err = entry.Insert(DB, []database.EntryRow{
+		{Date: today, Ledger: lID, Value: 12000, Tags: "organic"},
+		{Date: today, Ledger: bID, Value: -12000},
+	})
+	require.NoError(t, err)
+	require.NoError(t, database.UpdateCache(DB))
+
+	require.NoError(t, database.SetBudget(DB, database.Budget{Ledger: lID, Period: database.MONTHPERIOD, Amount: 50000}))
+	require.NoError(t, database.SetBudget(DB, database.Budget{Ledger: lID, Tag: "organic", Period: database.YEARPERIOD, Amount: 10000}))
+
+	tw := tat.NewTestWrapperSpecific(View(NewLedgersDetailView(DB, lID)))
+
+	month := database.PeriodOf(database.MONTHPERIOD, today)
+	year := database.PeriodOf(database.YEARPERIOD, today)
+	tw.AssertViewContains(t, "Budget: 120.00 of 500.00 in "+month.String())
+	tw.AssertViewContains(t, "Budget organic: 120.00 of 100.00 in "+year.String())
+}
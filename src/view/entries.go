@@ -92,19 +92,43 @@ func (dv *entryDetailView) metadata() metadata {
 		return j.Id == dv.model.Journal
 	})
 
-	if journalIndex == -1 {
-		if dv.model.Id != 0 {
-			// Data seems loaded (SQLITE autoincrement starts at 1), but journal not found?
-			panic(fmt.Sprintf("journal %d not found for entry %d", dv.model.Journal, dv.model.Id))
-		}
-
+	// SQLITE autoincrement starts at 1, so the data isn't loaded yet
+	if journalIndex == -1 && dv.model.Id == 0 {
 		return metadata{}
 	}
 
-	return metadata{
+	// Possible in books where the journal got deleted anyway, see `:check`
+	journalName := fmt.Sprintf("<deleted journal %d>", dv.model.Journal)
+	if journalIndex != -1 {
+		journalName = availableJournals[journalIndex].Name
+	}
+
+	result := metadata{
 		names:  []string{"Journal"},
-		values: []string{availableJournals[journalIndex].Name},
+		values: []string{journalName},
 	}
+
+	if dv.model.Suspense {
+		result.names = append(result.names, "Status")
+		result.values = append(result.values, suspenseStyle.Render("SUSPENSE"))
+	}
+
+	if len(dv.viewer.rows) != 0 {
+		result.names = append(result.names, "Period")
+		result.values = append(result.values, renderPeriodState(dv.viewer.rows))
+	}
+
+	return result
+}
+
+func renderPeriodState(rows []*database.EntryRow) string {
+	for _, row := range rows {
+		if period := database.LockedPeriodOf(row.Date); period != nil {
+			return lockedStyle.Render(fmt.Sprintf("LOCKED (%s)", period))
+		}
+	}
+
+	return "open"
 }
 
 func (dv *entryDetailView) getWidth() int {
@@ -143,12 +167,27 @@ func (dv *entryDetailView) MotionSet() meta.Trie[tea.Msg] {
 
 	result.Insert(meta.Motion{"g", "x"}, meta.SwitchAppViewMsg{ViewType: meta.DELETEVIEWTYPE, Data: dv.modelId})
 	result.Insert(meta.Motion{"g", "e"}, meta.SwitchAppViewMsg{ViewType: meta.UPDATEVIEWTYPE, Data: dv.modelId})
+	result.Insert(meta.Motion{"g", "h"}, meta.ShowEntryHistoryMsg{Entry: dv.modelId}) // [g]oto [h]istory
+
+	// [g]oto [a]ttachments, attaching to the active row, or to the entry as a whole with A
+	var activeRowId *int
+	if activeRow := dv.viewer.getActiveRow(); activeRow != nil {
+		id := activeRow.Id
+		activeRowId = &id
+	}
+	result.Insert(meta.Motion{"g", "a"}, meta.ShowAttachmentsMsg{Entry: dv.modelId, Row: activeRowId})
+	result.Insert(meta.Motion{"g", "A"}, meta.ShowAttachmentsMsg{Entry: dv.modelId})
 
 	return result
 }
 
 func (dv *entryDetailView) CommandSet() meta.Trie[tea.Msg] {
-	return genericDetailViewCommandSet()
+	result := genericDetailViewCommandSet()
+
+	result.Insert(meta.Command(strings.Split("recur", "")), meta.MakeRecurringMsg{Entry: dv.modelId})
+	result.Insert(meta.Command(strings.Split("savetemplate", "")), meta.SaveTemplateMsg{Entry: dv.modelId})
+
+	return result
 }
 
 func (dv *entryDetailView) Reload() View {
@@ -159,6 +198,9 @@ func (dv *entryDetailView) getViewer() *entryRowViewer {
 	return dv.viewer
 }
 
+var suspenseStyle = lipgloss.NewStyle().Foreground(lipgloss.ANSIColor(3)).Bold(true)
+var lockedStyle = lipgloss.NewStyle().Foreground(lipgloss.ANSIColor(1)).Bold(true)
+
 // NOTE: entries doesn't use the genericMutateView, because with the row creating it's too idiosyncratic
 
 const (
@@ -174,6 +216,7 @@ type entryCreateView struct {
 	notesInput       textarea.Model
 	entryRowsManager *rowsMutateManager
 	activeInput      int
+	suspense         bool
 
 	colour lipgloss.Color
 }
@@ -232,10 +275,11 @@ type rowMutator struct {
 	ledgerInput      itempicker.Model
 	accountInput     itempicker.Model
 	descriptionInput textinput.Model
-	// TODO: documentInput as some file selector thing
-	// https://github.com/charmbracelet/bubbles/tree/master/filepicker
-	debitInput  textinput.Model
-	creditInput textinput.Model
+	// Files are attached from the detail view instead, see meta.ShowAttachmentsMsg
+	debitInput   textinput.Model
+	creditInput  textinput.Model
+	taxCodeInput itempicker.Model
+	tagsInput    textinput.Model
 
 	originalValue *database.EntryRow
 }
@@ -259,6 +303,10 @@ func newRowMutator(startDate *database.Date, originalValue *database.EntryRow) *
 	debitInput.Cursor.SetMode(cursor.CursorStatic)
 	creditInput := textinput.New()
 	creditInput.Cursor.SetMode(cursor.CursorStatic)
+	taxCodeInput := itempicker.New(database.AvailableTaxCodesAsItempickerItems())
+	tagsInput := textinput.New()
+	tagsInput.Cursor.SetMode(cursor.CursorStatic)
+	tagsInput.Placeholder = "tag key:value"
 
 	result := rowMutator{
 		dateInput:        dateInput,
@@ -267,6 +315,8 @@ func newRowMutator(startDate *database.Date, originalValue *database.EntryRow) *
 		descriptionInput: descriptionInput,
 		debitInput:       debitInput,
 		creditInput:      creditInput,
+		taxCodeInput:     taxCodeInput,
+		tagsInput:        tagsInput,
 
 		originalValue: originalValue,
 	}
@@ -274,6 +324,27 @@ func newRowMutator(startDate *database.Date, originalValue *database.EntryRow) *
 	return &result
 }
 
+// The currency of the selected ledger and account, and whether the typed amounts are in it.
+// Rows that only adjust the booked value of a foreign balance, like revaluations, are typed in the home currency.
+func (rm *rowMutator) currency() (currency string, isForeign bool) {
+	ledger, ok := rm.ledgerInput.Value().(database.Ledger)
+	if !ok {
+		return database.HOMECURRENCY, false
+	}
+	account, _ := rm.accountInput.Value().(*database.Account)
+
+	currency = database.CurrencyOf(ledger, account)
+	if currency == database.HOMECURRENCY {
+		return currency, false
+	}
+
+	if rm.originalValue != nil && rm.originalValue.Currency == currency && rm.originalValue.ForeignValue == nil {
+		return currency, false
+	}
+
+	return currency, true
+}
+
 func (rm *rowMutator) resetToOriginalValue() error {
 	if rm.originalValue == nil {
 		return errors.New("Row has no starting value to reset to")
@@ -315,6 +386,12 @@ func (rm *rowMutator) setActiveColour(col int, colour lipgloss.Color) {
 		rm.creditInput.TextStyle = style
 		rm.creditInput.PromptStyle = style
 		rm.creditInput.Cursor.Style = style
+	case 6:
+		rm.taxCodeInput.Colour = colour
+	case 7:
+		rm.tagsInput.TextStyle = style
+		rm.tagsInput.PromptStyle = style
+		rm.tagsInput.Cursor.Style = style
 	}
 }
 
@@ -333,6 +410,10 @@ func (rm *rowMutator) clearColours() {
 	rm.creditInput.TextStyle = lipgloss.Style{}
 	rm.creditInput.PromptStyle = lipgloss.Style{}
 	rm.creditInput.Cursor.Style = lipgloss.Style{}
+	rm.taxCodeInput.Colour = ""
+	rm.tagsInput.TextStyle = lipgloss.Style{}
+	rm.tagsInput.PromptStyle = lipgloss.Style{}
+	rm.tagsInput.Cursor.Style = lipgloss.Style{}
 }
 
 func (cv *entryCreateView) Init() tea.Cmd {
@@ -354,13 +435,14 @@ func (cv *entryCreateView) Update(message tea.Msg) (View, tea.Cmd) {
 		}
 
 		newEntry := database.Entry{
-			Journal: entryJournal.(database.Journal).Id,
-			Notes:   meta.CompileNotes(entryNotes),
+			Journal:  entryJournal.(database.Journal).Id,
+			Notes:    meta.CompileNotes(entryNotes),
+			Suspense: cv.suspense,
 		}
 
 		id, err := newEntry.Insert(cv.DB, entryRows)
 		if err != nil {
-			return cv, meta.MessageCmd(err)
+			return cv, meta.MessageCmd(explainEntryError(err))
 		}
 
 		var cmds []tea.Cmd
@@ -408,7 +490,7 @@ func (cv *entryCreateView) AllowsSearchMode() bool {
 
 	_, activeCol := cv.getManager().getActiveCoords()
 
-	return activeCol == 1 || activeCol == 2
+	return activeCol == 1 || activeCol == 2 || activeCol == 6
 }
 
 func (cv *entryCreateView) AcceptedModels() map[meta.ModelType]struct{} {
@@ -420,6 +502,7 @@ func (cv *entryCreateView) AcceptedModels() map[meta.ModelType]struct{} {
 }
 
 type DeleteEntryRowMsg struct{}
+type ToggleSuspenseMsg struct{}
 type CreateEntryRowMsg struct {
 	After bool
 }
@@ -452,6 +535,10 @@ func (cv *entryCreateView) getActiveInput() *int {
 	return &cv.activeInput
 }
 
+func (cv *entryCreateView) getSuspense() *bool {
+	return &cv.suspense
+}
+
 type entryUpdateView struct {
 	DB *sqlx.DB
 
@@ -459,6 +546,7 @@ type entryUpdateView struct {
 	notesInput       textarea.Model
 	entryRowsManager *rowsMutateManager
 	activeInput      int
+	suspense         bool
 
 	modelId           int
 	startingEntry     database.Entry
@@ -523,14 +611,15 @@ func (uv *entryUpdateView) Update(message tea.Msg) (View, tea.Cmd) {
 		}
 
 		newEntry := database.Entry{
-			Id:      uv.startingEntry.Id,
-			Journal: entryJournal.(database.Journal).Id,
-			Notes:   meta.CompileNotes(entryNotes),
+			Id:       uv.startingEntry.Id,
+			Journal:  entryJournal.(database.Journal).Id,
+			Notes:    meta.CompileNotes(entryNotes),
+			Suspense: uv.suspense,
 		}
 
 		err = newEntry.Update(uv.DB, entryRows)
 		if err != nil {
-			return uv, meta.MessageCmd(err)
+			return uv, meta.MessageCmd(explainEntryError(err))
 		}
 
 		return uv, meta.MessageCmd(meta.NotificationMessageMsg{Message: fmt.Sprintf(
@@ -557,6 +646,8 @@ func (uv *entryUpdateView) Update(message tea.Msg) (View, tea.Cmd) {
 
 			uv.notesInput.SetValue(entry.Notes.Collapse())
 
+			uv.suspense = entry.Suspense
+
 			return uv, nil
 
 		case meta.ENTRYROWMODEL:
@@ -648,7 +739,7 @@ func (cv *entryUpdateView) AllowsSearchMode() bool {
 
 	_, activeCol := cv.getManager().getActiveCoords()
 
-	return activeCol == 1 || activeCol == 2
+	return activeCol == 1 || activeCol == 2 || activeCol == 6
 }
 
 func (uv *entryUpdateView) AcceptedModels() map[meta.ModelType]struct{} {
@@ -695,6 +786,10 @@ func (uv *entryUpdateView) getActiveInput() *int {
 	return &uv.activeInput
 }
 
+func (uv *entryUpdateView) getSuspense() *bool {
+	return &uv.suspense
+}
+
 type rowsMutateManager struct {
 	width, height int
 
@@ -717,10 +812,10 @@ func newRowsMutateManager() *rowsMutateManager {
 	rows[1] = newRowMutator(database.Today(), nil)
 
 	result := &rowsMutateManager{
-		headers:     []string{"Row", "Date", "Ledger", "Account", "Description", "Debit", "Credit"},
+		headers:     []string{"Row", "Date", "Ledger", "Account", "Description", "Debit", "Credit", "Tax", "Tags"},
 		rowMutators: rows,
 
-		colWidths: []int{0, 0, 0, 0, 0, 0, 0}, // Initialise with right number of elements
+		colWidths: []int{0, 0, 0, 0, 0, 0, 0, 0, 0}, // Initialise with right number of elements
 		viewport:  viewport.New(0, 0),
 	}
 
@@ -733,7 +828,7 @@ func (rmm *rowsMutateManager) Update(message tea.Msg) (*rowsMutateManager, tea.C
 		rmm.width = message.Width
 		rmm.height = message.Height
 
-		rmm.viewport.Width = max(message.Width, 83) // See calculateColumnWidths for why 83
+		rmm.viewport.Width = max(message.Width, 119) // See calculateColumnWidths for why 119
 		rmm.viewport.Height = max(message.Height, 10)
 		rmm.calculateColumnWidths()
 		rmm.updateRowMutatorWidths(rmm.colWidths)
@@ -774,6 +869,10 @@ func (rmm *rowsMutateManager) Update(message tea.Msg) (*rowsMutateManager, tea.C
 			if row.debitInput.Value() != "" {
 				row.debitInput.SetValue("")
 			}
+		case 6:
+			row.taxCodeInput, cmd = row.taxCodeInput.Update(message)
+		case 7:
+			row.tagsInput, cmd = row.tagsInput.Update(message)
 		}
 
 		rmm.rowMutators[highlightRow] = row
@@ -845,6 +944,8 @@ func (rmm *rowsMutateManager) Update(message tea.Msg) (*rowsMutateManager, tea.C
 			input = &rmm.rowMutators[row].ledgerInput
 		case 2:
 			input = &rmm.rowMutators[row].accountInput
+		case 6:
+			input = &rmm.rowMutators[row].taxCodeInput
 		default:
 			panic(fmt.Sprintf("unexpected active column: %#v", col))
 		}
@@ -883,6 +984,17 @@ func (rmm *rowsMutateManager) View() string {
 		totalRendered = red.Render("error")
 	}
 
+	// Foreign amounts are typed in their own currency, so point out which rows those are
+	var foreignRows []string
+	for i, row := range rmm.rowMutators {
+		if currency, isForeign := row.currency(); isForeign {
+			foreignRows = append(foreignRows, fmt.Sprintf("row %d in %s", i, currency))
+		}
+	}
+	if len(foreignRows) != 0 {
+		totalRendered += fmt.Sprintf(" (home currency; %s)", strings.Join(foreignRows, ", "))
+	}
+
 	result.WriteString("\n")
 
 	result.WriteString(totalRendered)
@@ -895,8 +1007,14 @@ func (rmm *rowsMutateManager) calculateColumnWidths() {
 	// 8 for yy-MM-dd and 2 for prompt and 1 for cursor
 	dateWidth := 8 + 2 + 1
 
-	// -12 for 2-wide padding between columns 6x
-	remainingWidth := rmm.viewport.Width - idxWidth - dateWidth - 12
+	// Wide enough for codes like H21 with the prompt
+	taxCodeWidth := 8
+
+	// -16 for 2-wide padding between columns 8x
+	remainingWidth := rmm.viewport.Width - idxWidth - dateWidth - taxCodeWidth - 16
+
+	tagsWidth := max(remainingWidth/6, 14)
+	remainingWidth -= tagsWidth
 
 	descriptionWidth := max(remainingWidth/3, 20)
 	remainingWidth -= descriptionWidth
@@ -908,7 +1026,7 @@ func (rmm *rowsMutateManager) calculateColumnWidths() {
 	valuesWidth := max((remainingWidth)/5, 8)
 	remainingWidth -= 2 * valuesWidth
 
-	// Total minimum width: 4 + 13 + 20 + 2 * 15 + 2 * 8 = 83
+	// Total minimum width: 4 + 11 + 8 + 14 + 20 + 2 * 15 + 2 * 8 + 16 = 119
 
 	// Distribute remaining width
 	for ; remainingWidth >= 4; remainingWidth -= 4 {
@@ -917,7 +1035,7 @@ func (rmm *rowsMutateManager) calculateColumnWidths() {
 		accountWidth += 1
 	}
 
-	rmm.colWidths = []int{idxWidth, dateWidth, ledgerWidth, accountWidth, descriptionWidth, valuesWidth, valuesWidth}
+	rmm.colWidths = []int{idxWidth, dateWidth, ledgerWidth, accountWidth, descriptionWidth, valuesWidth, valuesWidth, taxCodeWidth, tagsWidth}
 }
 
 func (rmm *rowsMutateManager) updateRowMutatorWidths(colWidths []int) {
@@ -925,17 +1043,20 @@ func (rmm *rowsMutateManager) updateRowMutatorWidths(colWidths []int) {
 		rowMutator.dateInput.Width = colWidths[1]
 		rowMutator.ledgerInput.MaxWidth = colWidths[2]
 		rowMutator.accountInput.MaxWidth = colWidths[3]
+		rowMutator.taxCodeInput.MaxWidth = colWidths[7]
 		// The -2 are for the prompt "> ", and then -1 for the cursor
 		// Because for some reason the textinput model doesn't count the cursor in the width
 		rowMutator.descriptionInput.Width = colWidths[4] - 2 - 1
 		rowMutator.debitInput.Width = colWidths[5] - 2 - 1
 		rowMutator.creditInput.Width = colWidths[6] - 2 - 1
+		rowMutator.tagsInput.Width = colWidths[8] - 2 - 1
 
 		// Redraw the models to handle overflow
 		rowMutator.dateInput.SetCursor(rowMutator.dateInput.Position())
 		rowMutator.descriptionInput.SetCursor(rowMutator.descriptionInput.Position())
 		rowMutator.debitInput.SetCursor(rowMutator.debitInput.Position())
 		rowMutator.creditInput.SetCursor(rowMutator.creditInput.Position())
+		rowMutator.tagsInput.SetCursor(rowMutator.tagsInput.Position())
 	}
 }
 
@@ -993,6 +1114,8 @@ func (rmm *rowsMutateManager) makeShownRows() [][]string {
 		currentRow = append(currentRow, row.descriptionInput.View())
 		currentRow = append(currentRow, row.debitInput.View())
 		currentRow = append(currentRow, row.creditInput.View())
+		currentRow = append(currentRow, row.taxCodeInput.View())
+		currentRow = append(currentRow, row.tagsInput.View())
 
 		result = append(result, currentRow)
 	}
@@ -1026,15 +1149,12 @@ func (rmm *rowsMutateManager) scrollViewport() {
 func (rmm *rowsMutateManager) compileRows() ([]database.EntryRow, error) {
 	result := make([]database.EntryRow, rmm.numRows())
 
-	total, err := rmm.calculateCurrentTotal()
+	// Balance is checked when saving, so that suspense entries can be unbalanced
+	_, err := rmm.calculateCurrentTotal()
 	if err != nil {
 		return nil, err
 	}
 
-	if total != 0 {
-		return nil, fmt.Errorf("entry has nonzero total value %s", total)
-	}
-
 	for i, formRow := range rmm.rowMutators {
 		formLedger := formRow.ledgerInput.Value()
 		if formLedger == nil {
@@ -1050,6 +1170,11 @@ func (rmm *rowsMutateManager) compileRows() ([]database.EntryRow, error) {
 			accountId = &formAccount.(*database.Account).Id
 		}
 
+		var taxCodeId *int
+		if taxCode, ok := formRow.taxCodeInput.Value().(*database.TaxCode); ok && taxCode != nil {
+			taxCodeId = &taxCode.Id
+		}
+
 		// TODO: Validate the date thingy
 		date, err := database.ToDate(formRow.dateInput.Value())
 		if err != nil {
@@ -1057,6 +1182,10 @@ func (rmm *rowsMutateManager) compileRows() ([]database.EntryRow, error) {
 		}
 
 		formDescription := formRow.descriptionInput.Value()
+		tags, err := database.ParseTags(formRow.tagsInput.Value())
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", i, err)
+		}
 		formDebit := formRow.debitInput.Value()
 		formCredit := formRow.creditInput.Value()
 
@@ -1103,11 +1232,39 @@ func (rmm *rowsMutateManager) compileRows() ([]database.EntryRow, error) {
 			Description: formDescription,
 			Document:    nil, // TODO
 			Value:       value,
-			Reconciled:  false,
+			Tags:        tags,
+			TaxCode:     taxCodeId,
+		}
+
+		// Edited rows keep their id, so the entry's history can follow them, and their reconciliation.
+		// Imported rows keep the id of the bank's transaction, so it isn't imported again.
+		if formRow.originalValue != nil {
+			result[i].Id = formRow.originalValue.Id
+			result[i].Reconciled = formRow.originalValue.Reconciled
+			result[i].Reconciliation = formRow.originalValue.Reconciliation
+			result[i].Statement = formRow.originalValue.Statement
+			result[i].ExternalId = formRow.originalValue.ExternalId
+		}
+
+		currency, isForeign := formRow.currency()
+		result[i].Currency = currency
+		if isForeign {
+			rate, err := database.ExchangeRateOn(currency, date)
+			if err != nil {
+				return nil, err
+			}
+
+			result[i].ForeignValue = &value
+			result[i].Value = rate.ToHome(value)
+
+			if result[i].Value == 0 {
+				return nil, fmt.Errorf("row %d is worth 0.00 in the home currency at %s", i, rate)
+			}
 		}
 	}
 
-	return result, nil
+	// The tax rows aren't in the form, they're generated from the rows with a tax code
+	return database.GenerateTaxRows(result)
 }
 
 func (uv *entryUpdateView) makeGoToDetailViewCmd() tea.Cmd {
@@ -1132,7 +1289,7 @@ func (rmm *rowsMutateManager) switchFocus(direction meta.Sequence) (preceeded, e
 
 	case meta.NEXT:
 		if oldRow == rmm.numRows()-1 && oldCol == rmm.numInputsPerRow()-1 {
-			rmm.rowMutators[oldRow].creditInput.Blur()
+			rmm.rowMutators[oldRow].tagsInput.Blur()
 			rmm.isActive = false
 			return false, true
 		}
@@ -1146,26 +1303,55 @@ func (rmm *rowsMutateManager) switchFocus(direction meta.Sequence) (preceeded, e
 	return false, false
 }
 
+// The total of the rows in the home currency, foreign amounts are converted at the rate of their row's date
 func (rmm *rowsMutateManager) calculateCurrentTotal() (database.CurrencyValue, error) {
 	var total database.CurrencyValue
 
 	for _, row := range rmm.rowMutators {
+		var change database.CurrencyValue
+
 		if row.debitInput.Value() != "" {
-			change, err := database.ParseCurrencyValue(row.debitInput.Value())
+			debit, err := database.ParseCurrencyValue(row.debitInput.Value())
 			if err != nil {
 				return 0, err
 			}
 
-			total = total.Add(change)
+			change = change.Add(debit)
 		}
 		if row.creditInput.Value() != "" {
-			change, err := database.ParseCurrencyValue(row.creditInput.Value())
+			credit, err := database.ParseCurrencyValue(row.creditInput.Value())
+			if err != nil {
+				return 0, err
+			}
+
+			change = change.Subtract(credit)
+		}
+
+		if currency, isForeign := row.currency(); isForeign && change != 0 {
+			date, err := database.ToDate(row.dateInput.Value())
+			if err != nil {
+				return 0, err
+			}
+
+			rate, err := database.ExchangeRateOn(currency, date)
+			if err != nil {
+				return 0, err
+			}
+
+			change = rate.ToHome(change)
+		}
+
+		// The tax rows are only generated when saving, but count towards the total already
+		if taxCode, ok := row.taxCodeInput.Value().(*database.TaxCode); ok && taxCode != nil {
+			tax, err := database.PendingTax(taxCode.Id, change)
 			if err != nil {
 				return 0, err
 			}
 
-			total = total.Subtract(change)
+			change = change.Add(tax)
 		}
+
+		total = total.Add(change)
 	}
 
 	return total, nil
@@ -1180,7 +1366,7 @@ func (rmm *rowsMutateManager) numInputs() int {
 }
 
 func (rmm *rowsMutateManager) numInputsPerRow() int {
-	return 6
+	return 8
 }
 
 func (rmm *rowsMutateManager) getActiveCoords() (row, col int) {
@@ -1195,7 +1381,7 @@ func (rmm *rowsMutateManager) focus(direction meta.Sequence) {
 	switch direction {
 	case meta.PREVIOUS:
 		rmm.activeInput = numInputs - 1
-		rmm.rowMutators[rmm.numRows()-1].creditInput.Focus()
+		rmm.rowMutators[rmm.numRows()-1].tagsInput.Focus()
 
 	case meta.NEXT:
 		rmm.activeInput = 0
@@ -1243,6 +1429,8 @@ func (rmm *rowsMutateManager) setActiveCoords(newRow, newCol int) {
 		rmm.rowMutators[oldRow].debitInput.Blur()
 	case 5:
 		rmm.rowMutators[oldRow].creditInput.Blur()
+	case 7:
+		rmm.rowMutators[oldRow].tagsInput.Blur()
 	}
 
 	rmm.activeInput = newRow*numPerRow + newCol
@@ -1256,17 +1444,25 @@ func (rmm *rowsMutateManager) setActiveCoords(newRow, newCol int) {
 		rmm.rowMutators[newRow].debitInput.Focus()
 	case 5:
 		rmm.rowMutators[newRow].creditInput.Focus()
+	case 7:
+		rmm.rowMutators[newRow].tagsInput.Focus()
 	}
 }
 
-// Converts a slice of EntryRow to a slice of EntryRowCreateView
+// Converts a slice of EntryRow to a slice of EntryRowCreateView.
+// Generated tax rows are left out, compileRows generates them again from the rows with a tax code.
 func decompileRows(rows []database.EntryRow) ([]*rowMutator, error) {
-	result := make([]*rowMutator, len(rows))
+	var result []*rowMutator
 
 	availableLedgers := database.AvailableLedgers()
 	availableAccounts := database.AvailableAccounts()
+	availableTaxCodes := database.AvailableTaxCodes()
+
+	for _, row := range rows {
+		if row.TaxRole.IsGenerated() {
+			continue
+		}
 
-	for i, row := range rows {
 		availableLedgerIndex := slices.IndexFunc(availableLedgers, func(ledger database.Ledger) bool {
 			return ledger.Id == row.Ledger
 		})
@@ -1300,15 +1496,38 @@ func decompileRows(rows []database.EntryRow) ([]*rowMutator, error) {
 			return nil, err
 		}
 
+		// A removed tax code leaves the row without one
+		var taxCode *database.TaxCode
+		if row.TaxCode != nil {
+			availableTaxCodeIndex := slices.IndexFunc(availableTaxCodes, func(taxCode database.TaxCode) bool {
+				return taxCode.Id == *row.TaxCode
+			})
+			if availableTaxCodeIndex != -1 {
+				taxCode = &availableTaxCodes[availableTaxCodeIndex]
+			}
+		}
+
+		err = formRow.taxCodeInput.SetValue(taxCode)
+		if err != nil {
+			return nil, err
+		}
+
 		formRow.descriptionInput.SetValue(row.Description)
+		formRow.tagsInput.SetValue(string(row.Tags))
+
+		// Foreign rows are edited in their own currency
+		value := row.Value
+		if row.ForeignValue != nil {
+			value = *row.ForeignValue
+		}
 
-		if row.Value > 0 {
-			formRow.debitInput.SetValue(row.Value.String())
-		} else if row.Value < 0 {
-			formRow.creditInput.SetValue((-row.Value).String())
+		if value > 0 {
+			formRow.debitInput.SetValue(value.String())
+		} else if value < 0 {
+			formRow.creditInput.SetValue((-value).String())
 		}
 
-		result[i] = formRow
+		result = append(result, formRow)
 	}
 
 	return result, nil
@@ -1434,6 +1653,17 @@ type entryMutateView interface {
 	getManager() *rowsMutateManager
 
 	getActiveInput() *int
+	getSuspense() *bool
+}
+
+// Points the user to the suspense override if the entry was rejected for being unbalanced
+func explainEntryError(err error) error {
+	var unbalancedErr database.UnbalancedEntryError
+	if errors.As(err, &unbalancedErr) {
+		return fmt.Errorf("%w; balance it, or use :suspense to save it unfinished", err)
+	}
+
+	return err
 }
 
 func entryMutateViewUpdate(view entryMutateView, message tea.Msg) (View, tea.Cmd) {
@@ -1560,6 +1790,16 @@ func entryMutateViewUpdate(view entryMutateView, message tea.Msg) (View, tea.Cmd
 
 		return view, cmd
 
+	case ToggleSuspenseMsg:
+		suspense := view.getSuspense()
+		*suspense = !*suspense
+
+		if *suspense {
+			return view, meta.MessageCmd(meta.NotificationMessageMsg{Message: "Marked entry as suspense, it can be saved unbalanced"})
+		}
+
+		return view, meta.MessageCmd(meta.NotificationMessageMsg{Message: "Unmarked entry as suspense"})
+
 	case CreateEntryRowMsg:
 		if *activeInput != ENTRIESROWINPUT {
 			return view, meta.MessageCmd(errors.New("no entry row highlighted while trying to create one"))
@@ -1609,6 +1849,10 @@ func entryMutateViewView(view entryMutateView) string {
 	var result strings.Builder
 
 	result.WriteString(meta.TitleStyle.Render(view.title()))
+	if *view.getSuspense() {
+		result.WriteString(" ")
+		result.WriteString(suspenseStyle.Render("SUSPENSE: may be saved unbalanced"))
+	}
 	result.WriteString("\n")
 
 	sectionStyle := lipgloss.NewStyle().
@@ -1689,6 +1933,7 @@ func entryMutateViewCommandSet() meta.Trie[tea.Msg] {
 	var commands meta.Trie[tea.Msg]
 
 	commands.Insert(meta.Command(strings.Split("write", "")), meta.CommitMsg{})
+	commands.Insert(meta.Command(strings.Split("suspense", "")), ToggleSuspenseMsg{})
 
 	return commands
 }
@@ -0,0 +1,711 @@
+package view
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"terminaccounting/bubbles/itempicker"
+	"terminaccounting/database"
+	"terminaccounting/meta"
+	"time"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmoiron/sqlx"
+)
+
+type invoicesDetailView struct {
+	width, height int
+
+	DB *sqlx.DB
+
+	modelId int
+	model   database.Invoice
+}
+
+func NewInvoicesDetailView(DB *sqlx.DB, modelId int) *invoicesDetailView {
+	return &invoicesDetailView{
+		DB: DB,
+
+		modelId: modelId,
+	}
+}
+
+func (dv *invoicesDetailView) Init() tea.Cmd {
+	return database.MakeLoadInvoicesDetailCmd(dv.DB, dv.modelId)
+}
+
+func (dv *invoicesDetailView) Update(message tea.Msg) (View, tea.Cmd) {
+	switch message := message.(type) {
+	case meta.DataLoadedMsg:
+		dv.model = message.Data.(database.Invoice)
+
+		return dv, nil
+
+	case tea.WindowSizeMsg:
+		dv.width = message.Width
+		dv.height = message.Height
+
+		return dv, nil
+
+	default:
+		panic(fmt.Sprintf("unexpected tea.Msg: %#v", message))
+	}
+}
+
+func (dv *invoicesDetailView) View() string {
+	var result strings.Builder
+
+	result.WriteString(renderHeader(dv.title(), dv.metadata(), dv.width))
+	result.WriteString("\n")
+
+	// Shows the invoice like it gets exported
+	rendered, err := database.RenderInvoiceText(dv.model)
+	if err != nil {
+		rendered = err.Error()
+	}
+	result.WriteString(rendered)
+
+	return result.String()
+}
+
+func (dv *invoicesDetailView) title() string {
+	style := lipgloss.NewStyle().Background(meta.INVOICESCOLOUR).Padding(0, 1)
+	return style.Render(dv.model.Name())
+}
+
+func (dv *invoicesDetailView) metadata() metadata {
+	taxCode := lipgloss.NewStyle().Italic(true).Render("none")
+	if dv.model.TaxCode != nil {
+		for _, code := range database.AvailableTaxCodes() {
+			if code.Id == *dv.model.TaxCode {
+				taxCode = code.Code
+			}
+		}
+	}
+
+	entry := lipgloss.NewStyle().Italic(true).Render("not posted")
+	if dv.model.Entry != nil {
+		entry = strconv.Itoa(*dv.model.Entry)
+	}
+
+	return metadata{
+		names:  []string{"Status", "Journal", "Ledger", "Tax code", "Entry"},
+		values: []string{dv.model.StatusOn(*database.Today()), renderJournal(dv.model.Journal), renderLedger(dv.model.Ledger), taxCode, entry},
+	}
+}
+
+func (dv *invoicesDetailView) Type() meta.ViewType {
+	return meta.DETAILVIEWTYPE
+}
+
+func (dv *invoicesDetailView) AllowsInsertMode() bool {
+	return false
+}
+
+func (dv *invoicesDetailView) AllowsSearchMode() bool {
+	return false
+}
+
+func (dv *invoicesDetailView) AcceptedModels() map[meta.ModelType]struct{} {
+	return map[meta.ModelType]struct{}{
+		meta.INVOICEMODEL: {},
+	}
+}
+
+func (dv *invoicesDetailView) MotionSet() meta.Trie[tea.Msg] {
+	var motions meta.Trie[tea.Msg]
+
+	motions.Insert(meta.Motion{"g", "l"}, meta.SwitchAppViewMsg{ViewType: meta.LISTVIEWTYPE})
+	motions.Insert(meta.Motion{"g", "x"}, meta.SwitchAppViewMsg{ViewType: meta.DELETEVIEWTYPE, Data: dv.modelId})
+	motions.Insert(meta.Motion{"g", "e"}, meta.SwitchAppViewMsg{ViewType: meta.UPDATEVIEWTYPE, Data: dv.modelId})
+
+	motions.Insert(meta.Motion{"g", "d"}, dv.makeGoToEntryDetailViewCmd())
+
+	return motions
+}
+
+func (dv *invoicesDetailView) CommandSet() meta.Trie[tea.Msg] {
+	var commands meta.Trie[tea.Msg]
+
+	commands.Insert(meta.Command(strings.Split("post", "")), meta.PostInvoiceMsg{Invoice: dv.modelId})
+	commands.Insert(meta.Command(strings.Split("paid", "")), meta.SetInvoicePaidMsg{Invoice: dv.modelId, Paid: true})
+	commands.Insert(meta.Command(strings.Split("export", "")), meta.ExportInvoiceMsg{Invoice: dv.modelId})
+
+	return commands
+}
+
+func (dv *invoicesDetailView) Reload() View {
+	return NewInvoicesDetailView(dv.DB, dv.modelId)
+}
+
+// Goes to the entry posting the invoice created
+func (dv *invoicesDetailView) makeGoToEntryDetailViewCmd() tea.Cmd {
+	return func() tea.Msg {
+		entryId := dv.model.Entry
+		if entryId == nil {
+			return errors.New("the invoice isn't posted yet, so it has no entry")
+		}
+
+		entry, err := database.SelectEntry(dv.DB, *entryId)
+		if err != nil {
+			return err
+		}
+
+		targetApp := meta.ENTRIESAPP
+		return meta.SwitchAppViewMsg{App: &targetApp, ViewType: meta.DETAILVIEWTYPE, Data: entry}
+	}
+}
+
+func renderJournal(id int) string {
+	for _, journal := range database.AvailableJournals() {
+		if journal.Id == id {
+			return journal.String()
+		}
+	}
+
+	return fmt.Sprintf("%d", id)
+}
+
+func renderLedger(id int) string {
+	for _, ledger := range database.AvailableLedgers() {
+		if ledger.Id == id {
+			return ledger.String()
+		}
+	}
+
+	return fmt.Sprintf("%d", id)
+}
+
+const (
+	INVOICESACCOUNTINPUT int = iota
+	INVOICESJOURNALINPUT
+	INVOICESLEDGERINPUT
+	INVOICESTAXCODEINPUT
+	INVOICESDATEINPUT
+	INVOICESDUEDATEINPUT
+	INVOICESLINESINPUT
+	INVOICESNOTESINPUT
+)
+
+// Only offers what an invoice can refer to: debtors, income journals and ledgers, and sales tax codes
+func newInvoiceInputManager() *inputManager {
+	var accounts, journals, ledgers []itempicker.Item
+	for _, account := range database.AvailableAccounts() {
+		if account.Type == database.DEBTOR {
+			accounts = append(accounts, &account)
+		}
+	}
+	for _, journal := range database.AvailableJournals() {
+		if journal.Type == database.INCOMEJOURNAL {
+			journals = append(journals, journal)
+		}
+	}
+	for _, ledger := range database.AvailableLedgers() {
+		if ledger.Type == database.INCOMELEDGER {
+			ledgers = append(ledgers, ledger)
+		}
+	}
+
+	taxCodes := []itempicker.Item{(*database.TaxCode)(nil)}
+	for _, taxCode := range database.AvailableTaxCodes() {
+		if taxCode.Type == database.SALESTAX {
+			taxCodes = append(taxCodes, &taxCode)
+		}
+	}
+
+	dateInput := textinput.New()
+	dateInput.Cursor.SetMode(cursor.CursorStatic)
+	dateInput.Placeholder = "yy-MM-dd"
+	dateInput.SetValue(database.Today().String())
+
+	dueDateInput := textinput.New()
+	dueDateInput.Cursor.SetMode(cursor.CursorStatic)
+	dueDateInput.Placeholder = fmt.Sprintf("%d days after the date", database.DEFAULT_PAYMENT_TERM)
+
+	linesInput := textarea.New()
+	linesInput.Cursor.SetMode(cursor.CursorStatic)
+	linesInput.Placeholder = "3 x 75.00 Consulting"
+	notesInput := textarea.New()
+	notesInput.Cursor.SetMode(cursor.CursorStatic)
+
+	focusStyle := lipgloss.NewStyle().Foreground(meta.INVOICESCOLOUR)
+	linesInput.FocusedStyle.Prompt = focusStyle
+	linesInput.FocusedStyle.Text = focusStyle
+	linesInput.FocusedStyle.CursorLine = focusStyle
+	linesInput.FocusedStyle.LineNumber = focusStyle
+	notesInput.FocusedStyle.Prompt = focusStyle
+	notesInput.FocusedStyle.Text = focusStyle
+	notesInput.FocusedStyle.CursorLine = focusStyle
+	notesInput.FocusedStyle.LineNumber = focusStyle
+
+	inputs := []any{
+		itempicker.New(accounts),
+		itempicker.New(journals),
+		itempicker.New(ledgers),
+		itempicker.New(taxCodes),
+		dateInput,
+		dueDateInput,
+		linesInput,
+		notesInput,
+	}
+	names := []string{"Customer", "Journal", "Ledger", "Tax code", "Date", "Due date", "Lines", "Notes"}
+
+	return newInputManager(inputs, names)
+}
+
+func compileInvoice(im *inputManager) (database.Invoice, error) {
+	account, ok := im.inputs[INVOICESACCOUNTINPUT].value().(*database.Account)
+	if !ok {
+		return database.Invoice{}, errors.New("no customer selected, invoices are for debtor accounts")
+	}
+	journal, ok := im.inputs[INVOICESJOURNALINPUT].value().(database.Journal)
+	if !ok {
+		return database.Invoice{}, errors.New("no journal selected, invoices are posted in an income journal")
+	}
+	ledger, ok := im.inputs[INVOICESLEDGERINPUT].value().(database.Ledger)
+	if !ok {
+		return database.Invoice{}, errors.New("no ledger selected, invoice lines are booked on an income ledger")
+	}
+
+	var taxCode *int
+	if picked := im.inputs[INVOICESTAXCODEINPUT].value().(*database.TaxCode); picked != nil {
+		taxCode = &picked.Id
+	}
+
+	date, err := database.ToDate(im.inputs[INVOICESDATEINPUT].value().(string))
+	if err != nil {
+		return database.Invoice{}, fmt.Errorf("invalid date: %v", err)
+	}
+
+	dueDate := database.Date(time.Time(date).AddDate(0, 0, database.DEFAULT_PAYMENT_TERM))
+	if input := im.inputs[INVOICESDUEDATEINPUT].value().(string); input != "" {
+		dueDate, err = database.ToDate(input)
+		if err != nil {
+			return database.Invoice{}, fmt.Errorf("invalid due date: %v", err)
+		}
+	}
+
+	lines, err := database.ParseInvoiceLines(im.inputs[INVOICESLINESINPUT].value().(string))
+	if err != nil {
+		return database.Invoice{}, err
+	}
+
+	return database.Invoice{
+		Account: account.Id,
+		Journal: journal.Id,
+		Ledger:  ledger.Id,
+		TaxCode: taxCode,
+		Date:    date,
+		DueDate: dueDate,
+		Lines:   lines,
+		Notes:   meta.CompileNotes(im.inputs[INVOICESNOTESINPUT].value().(string)),
+	}, nil
+}
+
+type invoicesCreateView struct {
+	DB *sqlx.DB
+
+	inputManager *inputManager
+
+	colour lipgloss.Color
+}
+
+func NewInvoicesCreateView(DB *sqlx.DB) *invoicesCreateView {
+	return &invoicesCreateView{
+		DB: DB,
+
+		inputManager: newInvoiceInputManager(),
+
+		colour: meta.INVOICESCOLOUR,
+	}
+}
+
+func (cv *invoicesCreateView) Init() tea.Cmd {
+	return nil
+}
+
+func (cv *invoicesCreateView) Update(message tea.Msg) (View, tea.Cmd) {
+	switch message.(type) {
+	case meta.CommitMsg:
+		invoice, err := compileInvoice(cv.inputManager)
+		if err != nil {
+			return cv, meta.MessageCmd(err)
+		}
+
+		id, err := invoice.Insert(cv.DB)
+		if err != nil {
+			return cv, meta.MessageCmd(err)
+		}
+
+		var cmds []tea.Cmd
+
+		cmds = append(cmds, meta.MessageCmd(meta.NotificationMessageMsg{Message: fmt.Sprintf(
+			"Successfully created draft invoice %d, post it with :post from its detail view", id,
+		)}))
+
+		cmds = append(cmds, meta.MessageCmd(meta.SwitchAppViewMsg{
+			ViewType: meta.UPDATEVIEWTYPE,
+			Data:     id,
+		}))
+
+		return cv, tea.Batch(cmds...)
+
+	case meta.NavigateMsg:
+		return cv, nil
+	}
+
+	return genericMutateViewUpdate(cv, message)
+}
+
+func (cv *invoicesCreateView) View() string {
+	return genericMutateViewView(cv, meta.INVOICESCOLOUR)
+}
+
+func (cv *invoicesCreateView) title() string {
+	style := lipgloss.NewStyle().Background(meta.INVOICESCOLOUR).Padding(0, 1)
+	return style.Render("Creating new invoice")
+}
+
+func (cv *invoicesCreateView) Type() meta.ViewType {
+	return meta.CREATEVIEWTYPE
+}
+
+func (cv *invoicesCreateView) AllowsInsertMode() bool {
+	return true
+}
+
+func (cv *invoicesCreateView) AllowsSearchMode() bool {
+	return cv.inputManager.activeInput <= INVOICESTAXCODEINPUT
+}
+
+func (cv *invoicesCreateView) AcceptedModels() map[meta.ModelType]struct{} {
+	return map[meta.ModelType]struct{}{}
+}
+
+func (cv *invoicesCreateView) MotionSet() meta.Trie[tea.Msg] {
+	var motions meta.Trie[tea.Msg]
+
+	motions.Insert(meta.Motion{"g", "l"}, meta.SwitchAppViewMsg{ViewType: meta.LISTVIEWTYPE})
+
+	motions.Insert(meta.Motion{"tab"}, meta.SwitchFocusMsg{Direction: meta.NEXT})
+	motions.Insert(meta.Motion{"shift+tab"}, meta.SwitchFocusMsg{Direction: meta.PREVIOUS})
+
+	return motions
+}
+
+func (cv *invoicesCreateView) CommandSet() meta.Trie[tea.Msg] {
+	var commands meta.Trie[tea.Msg]
+
+	commands.Insert(meta.Command(strings.Split("write", "")), meta.CommitMsg{})
+
+	return commands
+}
+
+func (cv *invoicesCreateView) Reload() View {
+	return NewInvoicesCreateView(cv.DB)
+}
+
+func (cv *invoicesCreateView) getInputManager() *inputManager {
+	return cv.inputManager
+}
+
+type invoicesUpdateView struct {
+	DB *sqlx.DB
+
+	inputManager *inputManager
+
+	modelId       int
+	startingValue database.Invoice
+
+	colour lipgloss.Color
+}
+
+func NewInvoicesUpdateView(DB *sqlx.DB, modelId int) *invoicesUpdateView {
+	return &invoicesUpdateView{
+		DB: DB,
+
+		inputManager: newInvoiceInputManager(),
+
+		modelId: modelId,
+
+		colour: meta.INVOICESCOLOUR,
+	}
+}
+
+func (uv *invoicesUpdateView) Init() tea.Cmd {
+	return database.MakeLoadInvoicesDetailCmd(uv.DB, uv.modelId)
+}
+
+func (uv *invoicesUpdateView) Update(message tea.Msg) (View, tea.Cmd) {
+	switch message := message.(type) {
+	case meta.DataLoadedMsg:
+		// Loaded the current(/"starting") properties of the invoice being edited
+		invoice := message.Data.(database.Invoice)
+
+		uv.startingValue = invoice
+
+		var errs []error
+		for input := range INVOICESNOTESINPUT + 1 {
+			errs = append(errs, uv.inputManager.inputs[input].setValue(uv.startingInputValue(input)))
+		}
+
+		return uv, meta.MessageCmd(errors.Join(errs...))
+
+	case meta.ResetInputFieldMsg:
+		activeInput := uv.inputManager.activeInput
+		err := uv.inputManager.inputs[activeInput].setValue(uv.startingInputValue(activeInput))
+
+		return uv, meta.MessageCmd(err)
+
+	case meta.CommitMsg:
+		invoice, err := compileInvoice(uv.inputManager)
+		if err != nil {
+			return uv, meta.MessageCmd(err)
+		}
+		invoice.Id = uv.modelId
+
+		err = invoice.Update(uv.DB)
+		if err != nil {
+			return uv, meta.MessageCmd(err)
+		}
+
+		return uv, meta.MessageCmd(meta.NotificationMessageMsg{Message: fmt.Sprintf(
+			"Successfully updated %s", invoice.Name(),
+		)})
+
+	case meta.NavigateMsg:
+		return uv, nil
+	}
+
+	return genericMutateViewUpdate(uv, message)
+}
+
+// The value of an input for the invoice as it was loaded
+func (uv *invoicesUpdateView) startingInputValue(input int) any {
+	invoice := uv.startingValue
+
+	switch input {
+	case INVOICESACCOUNTINPUT:
+		return &database.Account{Id: invoice.Account}
+	case INVOICESJOURNALINPUT:
+		return database.Journal{Id: invoice.Journal}
+	case INVOICESLEDGERINPUT:
+		return database.Ledger{Id: invoice.Ledger}
+	case INVOICESTAXCODEINPUT:
+		if invoice.TaxCode == nil {
+			return (*database.TaxCode)(nil)
+		}
+		return &database.TaxCode{Id: *invoice.TaxCode}
+	case INVOICESDATEINPUT:
+		return invoice.Date.String()
+	case INVOICESDUEDATEINPUT:
+		return invoice.DueDate.String()
+	case INVOICESLINESINPUT:
+		return invoice.Lines.String()
+	case INVOICESNOTESINPUT:
+		return invoice.Notes.Collapse()
+	default:
+		panic(fmt.Sprintf("unexpected activeInput: %d", input))
+	}
+}
+
+func (uv *invoicesUpdateView) View() string {
+	return genericMutateViewView(uv, meta.INVOICESCOLOUR)
+}
+
+func (uv *invoicesUpdateView) title() string {
+	style := lipgloss.NewStyle().Background(meta.INVOICESCOLOUR).Padding(0, 1)
+	return style.Render(fmt.Sprintf("Updating %s", uv.startingValue.Name()))
+}
+
+func (uv *invoicesUpdateView) Type() meta.ViewType {
+	return meta.UPDATEVIEWTYPE
+}
+
+func (uv *invoicesUpdateView) AllowsInsertMode() bool {
+	return true
+}
+
+func (uv *invoicesUpdateView) AllowsSearchMode() bool {
+	return uv.inputManager.activeInput <= INVOICESTAXCODEINPUT
+}
+
+func (uv *invoicesUpdateView) AcceptedModels() map[meta.ModelType]struct{} {
+	return map[meta.ModelType]struct{}{
+		meta.INVOICEMODEL: {},
+	}
+}
+
+func (uv *invoicesUpdateView) MotionSet() meta.Trie[tea.Msg] {
+	var motions meta.Trie[tea.Msg]
+
+	motions.Insert(meta.Motion{"g", "l"}, meta.SwitchAppViewMsg{ViewType: meta.LISTVIEWTYPE})
+
+	motions.Insert(meta.Motion{"tab"}, meta.SwitchFocusMsg{Direction: meta.NEXT})
+	motions.Insert(meta.Motion{"shift+tab"}, meta.SwitchFocusMsg{Direction: meta.PREVIOUS})
+
+	motions.Insert(meta.Motion{"u"}, meta.ResetInputFieldMsg{})
+
+	motions.Insert(meta.Motion{"g", "d"}, uv.makeGoToDetailViewCmd())
+
+	return motions
+}
+
+func (uv *invoicesUpdateView) CommandSet() meta.Trie[tea.Msg] {
+	var commands meta.Trie[tea.Msg]
+
+	commands.Insert(meta.Command(strings.Split("write", "")), meta.CommitMsg{})
+
+	return commands
+}
+
+func (uv *invoicesUpdateView) Reload() View {
+	return NewInvoicesUpdateView(uv.DB, uv.modelId)
+}
+
+func (uv *invoicesUpdateView) makeGoToDetailViewCmd() tea.Cmd {
+	return func() tea.Msg {
+		return meta.SwitchAppViewMsg{ViewType: meta.DETAILVIEWTYPE, Data: uv.startingValue}
+	}
+}
+
+func (uv *invoicesUpdateView) getInputManager() *inputManager {
+	return uv.inputManager
+}
+
+type invoicesDeleteView struct {
+	DB *sqlx.DB
+
+	width, height int
+
+	modelId int // only for retrieving the model itself initially
+	model   database.Invoice
+
+	colour lipgloss.Color
+}
+
+func NewInvoicesDeleteView(DB *sqlx.DB, modelId int) *invoicesDeleteView {
+	return &invoicesDeleteView{
+		DB: DB,
+
+		modelId: modelId,
+
+		colour: meta.INVOICESCOLOUR,
+	}
+}
+
+func (dv *invoicesDeleteView) Init() tea.Cmd {
+	return database.MakeLoadInvoicesDetailCmd(dv.DB, dv.modelId)
+}
+
+func (dv *invoicesDeleteView) Update(message tea.Msg) (View, tea.Cmd) {
+	switch message := message.(type) {
+	case meta.DataLoadedMsg:
+		dv.model = message.Data.(database.Invoice)
+
+		return dv, nil
+
+	case meta.CommitMsg:
+		err := database.DeleteInvoice(dv.DB, dv.modelId)
+		if err != nil {
+			return dv, meta.MessageCmd(err)
+		}
+
+		var cmds []tea.Cmd
+
+		cmds = append(cmds, meta.MessageCmd(meta.NotificationMessageMsg{Message: fmt.Sprintf(
+			"Successfully deleted %s", dv.model.Name(),
+		)}))
+
+		cmds = append(cmds, meta.MessageCmd(meta.SwitchAppViewMsg{ViewType: meta.LISTVIEWTYPE}))
+
+		return dv, tea.Batch(cmds...)
+
+	case tea.WindowSizeMsg:
+		dv.width = message.Width
+		dv.height = message.Height
+
+		return dv, nil
+
+	default:
+		panic(fmt.Sprintf("unexpected tea.Msg: %#v", message))
+	}
+}
+
+func (dv *invoicesDeleteView) View() string {
+	return genericDeleteViewView(dv, dv.width, dv.height)
+}
+
+func (dv *invoicesDeleteView) title() string {
+	style := lipgloss.NewStyle().Background(meta.INVOICESCOLOUR).Padding(0, 1)
+	return style.Render(fmt.Sprintf("Delete invoice: %s", dv.model.Name()))
+}
+
+func (dv *invoicesDeleteView) Type() meta.ViewType {
+	return meta.DELETEVIEWTYPE
+}
+
+func (dv *invoicesDeleteView) AllowsInsertMode() bool {
+	return false
+}
+
+func (dv *invoicesDeleteView) AllowsSearchMode() bool {
+	return false
+}
+
+func (dv *invoicesDeleteView) AcceptedModels() map[meta.ModelType]struct{} {
+	return map[meta.ModelType]struct{}{
+		meta.INVOICEMODEL: {},
+	}
+}
+
+func (dv *invoicesDeleteView) MotionSet() meta.Trie[tea.Msg] {
+	var motions meta.Trie[tea.Msg]
+
+	motions.Insert(meta.Motion{"g", "l"}, meta.SwitchAppViewMsg{ViewType: meta.LISTVIEWTYPE})
+
+	motions.Insert(meta.Motion{"g", "d"}, dv.makeGoToDetailViewCmd())
+
+	return motions
+}
+
+func (dv *invoicesDeleteView) CommandSet() meta.Trie[tea.Msg] {
+	var commands meta.Trie[tea.Msg]
+
+	commands.Insert(meta.Command(strings.Split("write", "")), meta.CommitMsg{})
+
+	return commands
+}
+
+func (dv *invoicesDeleteView) Reload() View {
+	return NewInvoicesDeleteView(dv.DB, dv.modelId)
+}
+
+func (dv *invoicesDeleteView) inputValues() []string {
+	return []string{renderAccount(dv.model.Account), dv.model.Date.String(), dv.model.Lines.String(), dv.model.Notes.Collapse()}
+}
+
+func (dv *invoicesDeleteView) inputNames() []string {
+	return []string{"Customer", "Date", "Lines", "Notes"}
+}
+
+func (dv *invoicesDeleteView) makeGoToDetailViewCmd() tea.Cmd {
+	return func() tea.Msg {
+		return meta.SwitchAppViewMsg{ViewType: meta.DETAILVIEWTYPE, Data: dv.model}
+	}
+}
+
+func renderAccount(id int) string {
+	for _, account := range database.AvailableAccounts() {
+		if account.Id == id {
+			return account.Name
+		}
+	}
+
+	return fmt.Sprintf("%d", id)
+}
@@ -1,6 +1,7 @@
 package view
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"terminaccounting/database"
@@ -10,6 +11,7 @@ import (
 	"terminaccounting/bubbles/itempicker"
 
 	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
@@ -17,6 +19,158 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
+// The list view, but with ledgers as a tree of parents and their sub-ledgers that can be folded
+type ledgersListView struct {
+	*ListView
+
+	ledgers []database.Ledger
+	// Ids of the ledgers whose sub-ledgers are hidden
+	folded map[int]bool
+	// Everything is unfolded while searching, so matches can't hide under a folded parent
+	searching bool
+}
+
+func NewLedgersListView(app meta.App) *ledgersListView {
+	return &ledgersListView{
+		ListView: NewListView(app),
+
+		folded: make(map[int]bool),
+	}
+}
+
+func (lv *ledgersListView) Update(message tea.Msg) (View, tea.Cmd) {
+	switch message := message.(type) {
+	case meta.DataLoadedMsg:
+		items := message.Data.([]list.Item)
+
+		lv.ledgers = make([]database.Ledger, len(items))
+		for i, item := range items {
+			lv.ledgers[i] = item.(database.Ledger)
+		}
+
+		lv.setItems()
+
+		return lv, nil
+
+	case meta.ToggleFoldMsg:
+		item, ok := lv.listModel.SelectedItem().(ledgerTreeItem)
+		if !ok || !item.node.HasChildren {
+			return lv, nil
+		}
+
+		lv.folded[item.node.Ledger.Id] = !lv.folded[item.node.Ledger.Id]
+		lv.setItems()
+
+		return lv, nil
+
+	case meta.FoldAllMsg:
+		lv.folded = make(map[int]bool)
+		if message.Folded {
+			for _, node := range database.LedgerTree(lv.ledgers) {
+				lv.folded[node.Ledger.Id] = node.HasChildren
+			}
+		}
+		lv.setItems()
+
+		return lv, nil
+
+	case meta.UpdateSearchMsg:
+		lv.searching = message.Query != ""
+		lv.setItems()
+	}
+
+	_, cmd := lv.ListView.Update(message)
+
+	return lv, cmd
+}
+
+// Shows the ledgers that aren't under a folded parent, keeping the selected ledger selected
+func (lv *ledgersListView) setItems() {
+	var selectedId *int
+	if item, ok := lv.listModel.SelectedItem().(ledgerTreeItem); ok {
+		selectedId = &item.node.Ledger.Id
+	}
+
+	var items []list.Item
+	selectedIndex := 0
+
+	// Depth of the folded ledger whose sub-ledgers are being skipped, -1 if none are
+	foldedDepth := -1
+	for _, node := range database.LedgerTree(lv.ledgers) {
+		if foldedDepth != -1 && node.Depth > foldedDepth {
+			continue
+		}
+		foldedDepth = -1
+
+		folded := lv.folded[node.Ledger.Id] && node.HasChildren && !lv.searching
+		if folded {
+			foldedDepth = node.Depth
+		}
+
+		if selectedId != nil && *selectedId == node.Ledger.Id {
+			selectedIndex = len(items)
+		}
+
+		items = append(items, ledgerTreeItem{node: node, folded: folded})
+	}
+
+	lv.listModel.SetItems(items)
+	lv.listModel.Select(selectedIndex)
+}
+
+func (lv *ledgersListView) MotionSet() meta.Trie[tea.Msg] {
+	motions := lv.ListView.MotionSet()
+
+	motions.Insert(meta.Motion{"g", "d"}, lv.makeGoToDetailViewCmd())
+
+	motions.Insert(meta.Motion{"z", "a"}, meta.ToggleFoldMsg{})
+	motions.Insert(meta.Motion{"z", "M"}, meta.FoldAllMsg{Folded: true})
+	motions.Insert(meta.Motion{"z", "R"}, meta.FoldAllMsg{Folded: false})
+
+	return motions
+}
+
+func (lv *ledgersListView) Reload() View {
+	return NewLedgersListView(lv.app)
+}
+
+func (lv *ledgersListView) makeGoToDetailViewCmd() tea.Cmd {
+	return func() tea.Msg {
+		item, ok := lv.listModel.SelectedItem().(ledgerTreeItem)
+
+		if !ok {
+			return errors.New("no item to goto detail view of")
+		}
+
+		return meta.SwitchAppViewMsg{ViewType: meta.DETAILVIEWTYPE, Data: item.node.Ledger}
+	}
+}
+
+type ledgerTreeItem struct {
+	node   database.LedgerNode
+	folded bool
+}
+
+func (lti ledgerTreeItem) FilterValue() string {
+	return lti.node.Ledger.FilterValue()
+}
+
+func (lti ledgerTreeItem) Title() string {
+	marker := "  "
+	if lti.node.HasChildren {
+		marker = "▾ "
+		if lti.folded {
+			marker = "▸ "
+		}
+	}
+
+	return strings.Repeat("  ", lti.node.Depth) + marker + lti.node.Ledger.Title()
+}
+
+func (lti ledgerTreeItem) Description() string {
+	return strings.Repeat("  ", lti.node.Depth) + "  " + lti.node.Ledger.Description()
+}
+
 type ledgersDetailView struct {
 	width, height int
 
@@ -28,6 +182,9 @@ type ledgersDetailView struct {
 
 	canReconcile bool
 
+	// Of the ledger, shown with how much of them is used in the current period
+	budgets []database.Budget
+
 	viewer *entryRowViewer
 }
 
@@ -46,6 +203,7 @@ func (dv *ledgersDetailView) Init() tea.Cmd {
 
 	cmds = append(cmds, database.MakeLoadLedgersDetailCmd(dv.DB, dv.modelId))
 	cmds = append(cmds, database.MakeLoadLedgersRowsCmd(dv.DB, dv.modelId))
+	cmds = append(cmds, database.MakeLoadLedgerBudgetsCmd(dv.DB, dv.modelId))
 
 	return tea.Batch(cmds...)
 }
@@ -72,6 +230,16 @@ func (dv *ledgersDetailView) Update(message tea.Msg) (View, tea.Cmd) {
 				panic(fmt.Sprintf("unexpected database.LedgerType: %#v", dv.model.Type))
 			}
 
+			// A parent ledger shows the rows of its sub-ledgers, which are reconciled per sub-ledger
+			if len(database.LedgerSubtree(database.AvailableLedgers(), dv.modelId)) > 1 {
+				dv.canReconcile = false
+			}
+
+			return dv, nil
+
+		case meta.BUDGETMODEL:
+			dv.budgets = message.Data.([]database.Budget)
+
 			return dv, nil
 
 		case meta.ENTRYROWMODEL:
@@ -80,25 +248,153 @@ func (dv *ledgersDetailView) Update(message tea.Msg) (View, tea.Cmd) {
 		default:
 			panic(fmt.Sprintf("unexpected meta.ModelType: %#v", message.Model))
 		}
+
+	case meta.StartStatementMsg:
+		return dv, dv.startStatement(message)
 	}
 
 	return genericDetailViewUpdate(dv, message)
 }
 
+// Starts or stops reconciling against a bank statement, the viewer keeps it until it's written
+func (dv *ledgersDetailView) startStatement(message meta.StartStatementMsg) tea.Cmd {
+	if message.Stop {
+		if dv.viewer.statement == nil {
+			return meta.MessageCmd(errors.New("not reconciling against a statement"))
+		}
+
+		dv.viewer.statement = nil
+
+		return meta.MessageCmd(meta.NotificationMessageMsg{Message: "stopped reconciling against the statement, it wasn't saved"})
+	}
+
+	if len(database.LedgerSubtree(database.AvailableLedgers(), dv.modelId)) > 1 {
+		return meta.MessageCmd(errors.New("a statement covers a single ledger, and this one has sub-ledgers"))
+	}
+
+	if !dv.canReconcile || dv.model.IsAccounts {
+		return meta.MessageCmd(fmt.Errorf("statements are for bank ledgers, ledger %s can't be reconciled against one", dv.model.Name))
+	}
+
+	date, err := database.ToDate(message.Date)
+	if err != nil {
+		return meta.MessageCmd(fmt.Errorf("invalid date %q, expected yy-MM-dd", message.Date))
+	}
+
+	// Overdrawn accounts close below 0, which ParseCurrencyValue doesn't take
+	input, negative := strings.CutPrefix(message.Balance, "-")
+	balance, err := database.ParseCurrencyValue(input)
+	if err != nil {
+		return meta.MessageCmd(fmt.Errorf("invalid closing balance %q", message.Balance))
+	}
+	if negative {
+		balance = -balance
+	}
+
+	dv.viewer.statement = &database.Statement{Ledger: dv.modelId, Date: date, ClosingBalance: balance}
+
+	return meta.MessageCmd(meta.NotificationMessageMsg{
+		Message: fmt.Sprintf("reconciling against the statement of %s, tick its rows with enter and :write once the difference is 0", date),
+	})
+}
+
 func (dv *ledgersDetailView) View() string {
 	return genericDetailViewView(dv)
 }
 
 func (dv *ledgersDetailView) title() string {
 	style := lipgloss.NewStyle().Background(meta.LEDGERSCOLOUR).Padding(0, 1)
-	return style.Render(fmt.Sprintf("Ledger %s", dv.model.Name))
+	return style.Render(fmt.Sprintf("Ledger %s", dv.model.Title()))
 }
 
 func (dv *ledgersDetailView) metadata() metadata {
-	return metadata{
-		names:  []string{"Type", "Is accounts ledger"},
-		values: []string{dv.model.Type.String(), renderBoolean(dv.model.IsAccounts)},
+	result := metadata{
+		names:  []string{"Type", "Is accounts ledger", "Currency", "Parent"},
+		values: []string{dv.model.Type.String(), renderBoolean(dv.model.IsAccounts), renderCurrency(dv.model.Currency), renderParentLedger(dv.model.Parent)},
 	}
+
+	// The rows, and so the balance, include those of the sub-ledgers
+	if subLedgers := len(database.LedgerSubtree(database.AvailableLedgers(), dv.modelId)) - 1; subLedgers != 0 {
+		result.names = append(result.names, "Sub-ledgers")
+		result.values = append(result.values, fmt.Sprintf("%d, rows included", subLedgers))
+	}
+
+	for _, budget := range dv.budgets {
+		name := "Budget"
+		if scope := budget.Scope(); scope != "" {
+			name = fmt.Sprintf("Budget %s", scope)
+		}
+
+		result.names = append(result.names, name)
+		result.values = append(result.values, renderBudgetUsage(budget, dv.viewer.rows))
+	}
+
+	return result
+}
+
+// How much of the budget is used in the current month or year, in red if it's exceeded
+func renderBudgetUsage(budget database.Budget, rows []*database.EntryRow) string {
+	period := database.PeriodOf(budget.Period, *database.Today())
+	actual := budget.Actual(rows, period)
+
+	usage := fmt.Sprintf("%s of %s in %s", actual, budget.Amount, period)
+	if actual > budget.Amount {
+		return overBudgetStyle.Render(usage)
+	}
+
+	return usage
+}
+
+var overBudgetStyle = lipgloss.NewStyle().Foreground(lipgloss.ANSIColor(1)).Bold(true)
+
+func renderParentLedger(parent *int) string {
+	if parent == nil {
+		return noParentLedger{}.String()
+	}
+
+	for _, ledger := range database.AvailableLedgers() {
+		if ledger.Id == *parent {
+			return ledger.String()
+		}
+	}
+
+	return fmt.Sprintf("%d", *parent)
+}
+
+// Picked as the parent of top-level ledgers
+type noParentLedger struct{}
+
+func (noParentLedger) String() string {
+	return lipgloss.NewStyle().Italic(true).Render("None")
+}
+
+func (noParentLedger) CompareId() int {
+	// Since sqlite autoincrements from 1, -1 will never be a valid ID
+	return -1
+}
+
+func newParentLedgerInput() itempicker.Model {
+	items := []itempicker.Item{noParentLedger{}}
+	items = append(items, database.AvailableLedgersAsItempickerItems()...)
+
+	return itempicker.New(items)
+}
+
+// The id of the ledger picked as parent, nil for none
+func pickedParentLedger(value any) *int {
+	if ledger, ok := value.(database.Ledger); ok {
+		return &ledger.Id
+	}
+
+	return nil
+}
+
+func parentLedgerItem(parent *int) itempicker.Item {
+	if parent == nil {
+		return noParentLedger{}
+	}
+
+	return database.Ledger{Id: *parent}
 }
 
 func (dv *ledgersDetailView) getWidth() int {
@@ -130,6 +426,7 @@ func (dv *ledgersDetailView) AcceptedModels() map[meta.ModelType]struct{} {
 	return map[meta.ModelType]struct{}{
 		meta.LEDGERMODEL:   {},
 		meta.ENTRYROWMODEL: {},
+		meta.BUDGETMODEL:   {},
 	}
 }
 
@@ -145,7 +442,14 @@ func (dv *ledgersDetailView) MotionSet() meta.Trie[tea.Msg] {
 }
 
 func (dv *ledgersDetailView) CommandSet() meta.Trie[tea.Msg] {
-	return genericDetailViewCommandSet()
+	result := genericDetailViewCommandSet()
+
+	result.Insert(meta.Command(strings.Split("budget", "")), meta.SetBudgetMsg{Ledger: dv.modelId})
+	result.Insert(meta.Command(strings.Split("statement", "")), meta.StartStatementMsg{})
+	result.Insert(meta.Command(strings.Split("statements", "")), meta.ShowStatementsMsg{Ledger: dv.modelId})
+	result.Insert(meta.Command(strings.Split("automatch", "")), meta.AutoMatchMsg{App: meta.LEDGERSAPP, Id: dv.modelId})
+
+	return result
 }
 
 func (dv *ledgersDetailView) Reload() View {
@@ -191,8 +495,20 @@ func NewLedgersCreateView(DB *sqlx.DB) *ledgersCreateView {
 
 	isAccountsInput := booleaninput.New()
 
-	inputs := []any{nameInput, typeInput, notesInput, isAccountsInput}
-	names := []string{"Name", "Type", "Notes", "Is accounts ledger?"}
+	currencyInput := newCurrencyInput()
+
+	codeInput := textinput.New()
+	codeInput.Cursor.SetMode(cursor.CursorStatic)
+	codeInput.Placeholder = "e.g. 4100"
+
+	parentInput := newParentLedgerInput()
+
+	bankNumberInput := textinput.New()
+	bankNumberInput.Cursor.SetMode(cursor.CursorStatic)
+	bankNumberInput.Placeholder = "for bank ledgers, e.g. NL91ABNA0417164300"
+
+	inputs := []any{nameInput, typeInput, notesInput, isAccountsInput, currencyInput, codeInput, parentInput, bankNumberInput}
+	names := []string{"Name", "Type", "Notes", "Is accounts ledger?", "Currency", "Code", "Parent", "Bank number"}
 
 	return &ledgersCreateView{
 		DB: DB,
@@ -221,6 +537,10 @@ func (cv *ledgersCreateView) Update(message tea.Msg) (View, tea.Cmd) {
 		ledgerType := cv.inputManager.inputs[1].value().(database.LedgerType)
 		notes := meta.CompileNotes(cv.inputManager.inputs[2].value().(string))
 		isAccounts := cv.inputManager.inputs[3].value().(bool)
+		currency, err := database.ParseCurrency(cv.inputManager.inputs[4].value().(string))
+		if err != nil {
+			return cv, meta.MessageCmd(err)
+		}
 
 		currentAccountsLedger := database.GetAccountsLedger()
 
@@ -233,6 +553,10 @@ func (cv *ledgersCreateView) Update(message tea.Msg) (View, tea.Cmd) {
 			Type:       ledgerType,
 			Notes:      notes,
 			IsAccounts: isAccounts,
+			Currency:   currency,
+			Code:       strings.TrimSpace(cv.inputManager.inputs[5].value().(string)),
+			Parent:     pickedParentLedger(cv.inputManager.inputs[6].value()),
+			BankNumber: strings.TrimSpace(cv.inputManager.inputs[7].value().(string)),
 		}
 
 		id, err := newLedger.Insert(cv.DB)
@@ -280,7 +604,7 @@ func (cv *ledgersCreateView) AllowsInsertMode() bool {
 }
 
 func (cv *ledgersCreateView) AllowsSearchMode() bool {
-	return cv.inputManager.activeInput == 1
+	return cv.inputManager.activeInput == 1 || cv.inputManager.activeInput == 6
 }
 
 func (cv *ledgersCreateView) AcceptedModels() map[meta.ModelType]struct{} {
@@ -352,8 +676,20 @@ func NewLedgersUpdateView(DB *sqlx.DB, modelId int) *ledgersUpdateView {
 
 	isAccountsInput := booleaninput.New()
 
-	inputs := []any{nameInput, typeInput, notesInput, isAccountsInput}
-	names := []string{"Name", "Type", "Notes", "Is accounts ledger?"}
+	currencyInput := newCurrencyInput()
+
+	codeInput := textinput.New()
+	codeInput.Cursor.SetMode(cursor.CursorStatic)
+	codeInput.Placeholder = "e.g. 4100"
+
+	parentInput := newParentLedgerInput()
+
+	bankNumberInput := textinput.New()
+	bankNumberInput.Cursor.SetMode(cursor.CursorStatic)
+	bankNumberInput.Placeholder = "for bank ledgers, e.g. NL91ABNA0417164300"
+
+	inputs := []any{nameInput, typeInput, notesInput, isAccountsInput, currencyInput, codeInput, parentInput, bankNumberInput}
+	names := []string{"Name", "Type", "Notes", "Is accounts ledger?", "Currency", "Code", "Parent", "Bank number"}
 
 	return &ledgersUpdateView{
 		DB: DB,
@@ -382,6 +718,12 @@ func (uv *ledgersUpdateView) Update(message tea.Msg) (View, tea.Cmd) {
 		err := uv.inputManager.inputs[1].setValue(ledger.Type)
 		uv.inputManager.inputs[2].setValue(ledger.Notes.Collapse())
 		uv.inputManager.inputs[3].setValue(ledger.IsAccounts)
+		uv.inputManager.inputs[4].setValue(ledger.Currency)
+		uv.inputManager.inputs[5].setValue(ledger.Code)
+		uv.inputManager.inputs[7].setValue(ledger.BankNumber)
+		if err == nil {
+			err = uv.inputManager.inputs[6].setValue(parentLedgerItem(ledger.Parent))
+		}
 
 		return uv, meta.MessageCmd(err)
 
@@ -396,6 +738,14 @@ func (uv *ledgersUpdateView) Update(message tea.Msg) (View, tea.Cmd) {
 			startingValue = uv.startingValue.Notes.Collapse()
 		case 3:
 			startingValue = uv.startingValue.IsAccounts
+		case 4:
+			startingValue = uv.startingValue.Currency
+		case 5:
+			startingValue = uv.startingValue.Code
+		case 6:
+			startingValue = parentLedgerItem(uv.startingValue.Parent)
+		case 7:
+			startingValue = uv.startingValue.BankNumber
 		default:
 			panic(fmt.Sprintf("unexpected activeInput: %d", uv.inputManager.activeInput))
 		}
@@ -409,6 +759,10 @@ func (uv *ledgersUpdateView) Update(message tea.Msg) (View, tea.Cmd) {
 		ledgerType := uv.inputManager.inputs[1].value().(database.LedgerType)
 		notes := meta.CompileNotes(uv.inputManager.inputs[2].value().(string))
 		isAccounts := uv.inputManager.inputs[3].value().(bool)
+		currency, err := database.ParseCurrency(uv.inputManager.inputs[4].value().(string))
+		if err != nil {
+			return uv, meta.MessageCmd(err)
+		}
 
 		currentAccountsLedger := database.GetAccountsLedger()
 
@@ -422,9 +776,13 @@ func (uv *ledgersUpdateView) Update(message tea.Msg) (View, tea.Cmd) {
 			Type:       ledgerType,
 			Notes:      notes,
 			IsAccounts: isAccounts,
+			Currency:   currency,
+			Code:       strings.TrimSpace(uv.inputManager.inputs[5].value().(string)),
+			Parent:     pickedParentLedger(uv.inputManager.inputs[6].value()),
+			BankNumber: strings.TrimSpace(uv.inputManager.inputs[7].value().(string)),
 		}
 
-		err := ledger.Update(uv.DB)
+		err = ledger.Update(uv.DB)
 		if err != nil {
 			return uv, meta.MessageCmd(err)
 		}
@@ -455,7 +813,7 @@ func (uv *ledgersUpdateView) AllowsInsertMode() bool {
 }
 
 func (uv *ledgersUpdateView) AllowsSearchMode() bool {
-	return uv.inputManager.activeInput == 1
+	return uv.inputManager.activeInput == 1 || uv.inputManager.activeInput == 6
 }
 
 func (uv *ledgersUpdateView) AcceptedModels() map[meta.ModelType]struct{} {
@@ -610,11 +968,11 @@ func (dv *ledgersDeleteView) Reload() View {
 }
 
 func (dv *ledgersDeleteView) inputValues() []string {
-	return []string{dv.model.Name, dv.model.Type.String(), dv.model.Notes.Collapse(), renderBoolean(dv.model.IsAccounts)}
+	return []string{dv.model.Name, dv.model.Type.String(), dv.model.Notes.Collapse(), renderBoolean(dv.model.IsAccounts), renderCurrency(dv.model.Currency), dv.model.Code, renderParentLedger(dv.model.Parent), dv.model.BankNumber}
 }
 
 func (dv *ledgersDeleteView) inputNames() []string {
-	return []string{"Name", "Type", "Notes", "Is accounts ledger"}
+	return []string{"Name", "Type", "Notes", "Is accounts ledger", "Currency", "Code", "Parent", "Bank number"}
 }
 
 func (dv *ledgersDeleteView) makeGoToDetailViewCmd() tea.Cmd {
@@ -121,6 +121,7 @@ func (lv *ListView) AcceptedModels() map[meta.ModelType]struct{} {
 		meta.LEDGERMODEL:  {},
 		meta.ENTRYMODEL:   {},
 		meta.JOURNALMODEL: {},
+		meta.INVOICEMODEL: {},
 	}
 }
 
@@ -10,6 +10,7 @@ import (
 
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func testGenericMutateView_Generic(t *testing.T, v genericMutateView, expectedInputNames []string) {
@@ -95,6 +96,8 @@ func newTestRowCreator(ledgers []database.Ledger, accounts []database.Account) *
 		descriptionInput: textinput.New(),
 		debitInput:       textinput.New(),
 		creditInput:      textinput.New(),
+		taxCodeInput:     itempicker.New([]itempicker.Item{(*database.TaxCode)(nil)}),
+		tagsInput:        textinput.New(),
 	}
 
 	return rc
@@ -156,10 +159,63 @@ func TestRowsMutateManager_CompileRows_Unbalanced(t *testing.T) {
 		rowMutators: []*rowMutator{rc1},
 	}
 
-	_, err := manager.compileRows()
-	if err == nil {
-		t.Error("Expected error for unbalanced rows, got nil")
+	// Balance is enforced by the database instead, so that suspense entries can be saved
+	rows, err := manager.compileRows()
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, database.CurrencyValue(1000), rows[0].Value)
+}
+
+func TestRowsMutateManager_CompileRows_ForeignCurrency(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	date, err := database.ToDate("24-01-01")
+	require.NoError(t, err)
+	require.NoError(t, database.SetExchangeRate(DB, database.ExchangeRate{Currency: "USD", Date: date, Rate: 0.9}))
+
+	home := database.Ledger{Id: 1, Name: "Sales"}
+	paypal := database.Ledger{Id: 2, Name: "PayPal", Currency: "USD"}
+	// Accounts override the currency of their ledger
+	customer := database.Account{Id: 1, Name: "Customer", Currency: "GBP"}
+
+	noAccount := []itempicker.Item{(*database.Account)(nil)}
+
+	rc1 := newTestRowCreator([]database.Ledger{paypal}, nil)
+	rc1.accountInput = itempicker.New(noAccount)
+	rc1.dateInput.SetValue("24-01-01")
+	rc1.debitInput.SetValue("10.00")
+
+	rc2 := newTestRowCreator([]database.Ledger{home}, nil)
+	rc2.accountInput = itempicker.New(noAccount)
+	rc2.dateInput.SetValue("24-01-01")
+	rc2.creditInput.SetValue("9.00")
+
+	manager := &rowsMutateManager{
+		rowMutators: []*rowMutator{rc1, rc2},
 	}
+
+	total, err := manager.calculateCurrentTotal()
+	require.NoError(t, err)
+	assert.Equal(t, database.CurrencyValue(0), total)
+
+	rows, err := manager.compileRows()
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+
+	foreign := database.CurrencyValue(1000)
+	assert.Equal(t, "USD", rows[0].Currency)
+	assert.Equal(t, &foreign, rows[0].ForeignValue)
+	assert.Equal(t, database.CurrencyValue(900), rows[0].Value)
+	assert.Equal(t, database.HOMECURRENCY, rows[1].Currency)
+	assert.Nil(t, rows[1].ForeignValue)
+
+	rc3 := newTestRowCreator([]database.Ledger{paypal}, []database.Account{customer})
+	rc3.dateInput.SetValue("24-01-01")
+	rc3.debitInput.SetValue("1.00")
+
+	manager.rowMutators = []*rowMutator{rc3}
+	_, err = manager.compileRows()
+	assert.EqualError(t, err, "no GBP exchange rate on or before 24-01-01, add one with :rate GBP 24-01-01 <rate>")
 }
 
 func TestRowsMutateManager_CalculateCurrentTotal(t *testing.T) {
@@ -216,7 +272,7 @@ func TestRowsMutateManager_DeleteRow(t *testing.T) {
 
 	manager := &rowsMutateManager{
 		rowMutators: []*rowMutator{rc1, rc2, rc3},
-		activeInput: 1 * 6, // Focus on second row (index 1)
+		activeInput: 1 * 8, // Focus on second row (index 1)
 	}
 
 	manager.deleteRow()
@@ -233,3 +289,59 @@ func TestRowsMutateManager_DeleteRow(t *testing.T) {
 		t.Error("Second row should be rc3")
 	}
 }
+
+func TestRowsMutateManager_CompileRows_TaxCode(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	sales := database.Ledger{Name: "Sales", Type: database.INCOMELEDGER}
+	id, err := sales.Insert(DB)
+	require.NoError(t, err)
+	sales.Id = id
+	bank := database.Ledger{Name: "Bank", Type: database.ASSETLEDGER}
+	id, err = bank.Insert(DB)
+	require.NoError(t, err)
+	bank.Id = id
+	vat := database.Ledger{Name: "VAT", Type: database.LIABILITYLEDGER}
+	vatId, err := vat.Insert(DB)
+	require.NoError(t, err)
+
+	require.NoError(t, database.SetTaxCode(DB, database.TaxCode{Code: "H21", Type: database.SALESTAX, Rate: 21, Ledger: vatId}))
+	h21 := database.AvailableTaxCodes()[0]
+
+	noAccount := []itempicker.Item{(*database.Account)(nil)}
+
+	rc1 := newTestRowCreator([]database.Ledger{bank}, nil)
+	rc1.accountInput = itempicker.New(noAccount)
+	rc1.dateInput.SetValue("24-01-01")
+	rc1.debitInput.SetValue("121.00")
+
+	rc2 := newTestRowCreator([]database.Ledger{sales}, nil)
+	rc2.accountInput = itempicker.New(noAccount)
+	rc2.taxCodeInput = itempicker.New(database.AvailableTaxCodesAsItempickerItems())
+	require.NoError(t, rc2.taxCodeInput.SetValue(&h21))
+	rc2.dateInput.SetValue("24-01-01")
+	rc2.creditInput.SetValue("100.00")
+
+	manager := &rowsMutateManager{
+		rowMutators: []*rowMutator{rc1, rc2},
+	}
+
+	// The tax row isn't in the form yet, but counts towards the total
+	total, err := manager.calculateCurrentTotal()
+	require.NoError(t, err)
+	assert.Equal(t, database.CurrencyValue(0), total)
+
+	rows, err := manager.compileRows()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, database.TAXBASEROLE, rows[1].TaxRole)
+	assert.Equal(t, vatId, rows[2].Ledger)
+	assert.Equal(t, database.CurrencyValue(-2100), rows[2].Value)
+	assert.Equal(t, database.PAYABLETAXROLE, rows[2].TaxRole)
+
+	// The generated row is left out when editing, and generated again when saving
+	decompiled, err := decompileRows(rows)
+	require.NoError(t, err)
+	require.Len(t, decompiled, 2)
+	assert.Equal(t, "H21", decompiled[1].taxCodeInput.Value().String())
+}
@@ -291,6 +291,39 @@ func TestJournalsUpdateView_DataLoaded(t *testing.T) {
 	assert.Equal(t, "Some notes", uv.inputManager.inputs[2].value())
 }
 
+func TestInvoicesUpdateView_DataLoaded(t *testing.T) {
+	DB := tat.SetupTestEnv(t)
+
+	customerId, err := database.Account{Name: "Customer", Type: database.DEBTOR}.Insert(DB)
+	require.NoError(t, err)
+	journalId, err := (&database.Journal{Name: "Sales", Type: database.INCOMEJOURNAL}).Insert(DB)
+	require.NoError(t, err)
+	ledgerId, err := (&database.Ledger{Name: "Revenue", Type: database.INCOMELEDGER}).Insert(DB)
+	require.NoError(t, err)
+
+	date, err := database.ToDate("24-06-01")
+	require.NoError(t, err)
+	invoiceId, err := database.Invoice{
+		Account: customerId,
+		Journal: journalId,
+		Ledger:  ledgerId,
+		Date:    date,
+		DueDate: date,
+		Lines:   database.InvoiceLines{{Description: "Lessons", Quantity: 4, UnitPrice: 2500}},
+		Notes:   meta.Notes{"Thanks"},
+	}.Insert(DB)
+	require.NoError(t, err)
+
+	uv := NewInvoicesUpdateView(DB, invoiceId)
+	tat.NewTestWrapperSpecific(View(uv))
+
+	assert.Equal(t, customerId, uv.startingValue.Account)
+	assert.Equal(t, (*database.TaxCode)(nil), uv.inputManager.inputs[INVOICESTAXCODEINPUT].value())
+	assert.Equal(t, "24-06-01", uv.inputManager.inputs[INVOICESDUEDATEINPUT].value())
+	assert.Equal(t, "4 x 25.00 Lessons", uv.inputManager.inputs[INVOICESLINESINPUT].value())
+	assert.Equal(t, "Thanks", uv.inputManager.inputs[INVOICESNOTESINPUT].value())
+}
+
 func TestJournalsUpdateView_Commit(t *testing.T) {
 	DB := tat.SetupTestEnv(t)
 
@@ -426,6 +459,9 @@ func TestEntryUpdateView_Commit(t *testing.T) {
 		meta.NotificationMessageMsg{Message: fmt.Sprintf("Successfully updated Entry \"%d\"", entryId)},
 	)
 
+	rowsBefore, err := database.SelectRowsByEntry(DB, entryId)
+	require.NoError(t, err)
+
 	uv.notesInput.SetValue("Updated notes")
 
 	tw.Send(meta.CommitMsg{})
@@ -435,6 +471,14 @@ func TestEntryUpdateView_Commit(t *testing.T) {
 	require.Len(t, entries, 1)
 	assert.Equal(t, meta.Notes{"Updated notes"}, entries[0].Notes)
 
+	// Rows are updated in place rather than recreated
+	rowsAfter, err := database.SelectRowsByEntry(DB, entryId)
+	require.NoError(t, err)
+	require.Len(t, rowsAfter, len(rowsBefore))
+	for i := range rowsBefore {
+		assert.Equal(t, rowsBefore[i].Id, rowsAfter[i].Id)
+	}
+
 	require.Len(t, tw.LastCmdResults, 1)
 	assert.Equal(t,
 		meta.NotificationMessageMsg{Message: fmt.Sprintf("Successfully updated Entry \"%d\"", entryId)},
@@ -7,8 +7,10 @@ import (
 	"strings"
 	"terminaccounting/bubbles/booleaninput"
 	"terminaccounting/bubbles/itempicker"
+	"terminaccounting/database"
 	"terminaccounting/meta"
 
+	"github.com/charmbracelet/bubbles/cursor"
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
@@ -46,6 +48,24 @@ func renderBoolean(reconciled bool) string {
 	return "□"
 }
 
+func renderCurrency(currency string) string {
+	if currency == database.HOMECURRENCY {
+		return lipgloss.NewStyle().Italic(true).Render("home")
+	}
+
+	return currency
+}
+
+// Three letters, left empty for the home currency
+func newCurrencyInput() textinput.Model {
+	result := textinput.New()
+	result.Cursor.SetMode(cursor.CursorStatic)
+	result.CharLimit = 3
+	result.Placeholder = "home currency"
+
+	return result
+}
+
 type metadata struct {
 	names  []string
 	values []string